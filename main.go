@@ -20,6 +20,8 @@ type mainApp struct {
 	timestampConverter *timestamp.TimestampConverter
 	hashTool           *hash.HashTool
 	content            *fyne.Container
+	menu               *widget.List
+	tool               int // 左侧菜单中选中的工具
 }
 
 func newMainApp(a fyne.App) *mainApp {
@@ -68,6 +70,7 @@ func (m *mainApp) makeUI() {
 	)
 
 	menu.OnSelected = func(id widget.ListItemID) {
+		m.tool = id
 		switch id {
 		case 0:
 			m.content.Objects[0] = m.markdownEditor.Container()
@@ -91,6 +94,26 @@ func (m *mainApp) makeUI() {
 	mainContainer := container.NewBorder(nil, nil, menuContainer, nil, m.content)
 
 	m.window.SetContent(mainContainer)
+	m.menu = menu
+}
+
+// restoreSession 恢复上次关闭时的工具、窗口大小和笔记标签页，关闭窗口时保存
+func (m *mainApp) restoreSession() {
+	tool, size := m.markdownEditor.RestoreSession()
+	if size.Width <= 0 || size.Height <= 0 {
+		size = fyne.NewSize(800, 600)
+	}
+	m.window.Resize(size)
+	if tool > 0 {
+		m.menu.Select(tool)
+	}
+
+	m.window.SetCloseIntercept(func() {
+		if err := m.markdownEditor.SaveSession(m.tool, m.window.Canvas().Size()); err != nil {
+			fyne.LogError("Failed to save session", err)
+		}
+		m.window.Close()
+	})
 }
 
 // 创建一个自定义布局来固定宽度
@@ -117,6 +140,6 @@ func (f *fixedWidthLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
 func main() {
 	a := app.New()
 	mainApp := newMainApp(a)
-	mainApp.window.Resize(fyne.NewSize(800, 600))
+	mainApp.restoreSession()
 	mainApp.window.ShowAndRun()
 }
@@ -0,0 +1,382 @@
+package markdown
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+var errNoAttachmentFolder = errors.New("未设置附件目录，无法区分附件与其他文件，请先在库设置中指定附件目录")
+
+// attachmentDir 返回笔记的附件目录
+func (m *MarkdownEditor) attachmentDir(notePath string) (string, error) {
+	folder := strings.TrimSpace(m.config.AttachmentFolder)
+	if folder == "" {
+		return filepath.Dir(notePath), nil
+	}
+	dir := filepath.Join(m.rootPath, filepath.FromSlash(folder))
+	if !m.insideRoot(dir) {
+		return "", errOutsideRoot
+	}
+	return dir, nil
+}
+
+// importAttachment 将 src 复制到附件目录，返回插入笔记用的 Markdown 链接
+func (m *MarkdownEditor) importAttachment(notePath, src string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return m.saveAttachment(notePath, filepath.Base(src), f)
+}
+
+// saveAttachment 将数据以不冲突的文件名写入附件目录，返回插入笔记用的 Markdown 链接
+func (m *MarkdownEditor) saveAttachment(notePath, name string, r io.Reader) (string, error) {
+	dest, err := m.writeAttachment(notePath, name, r)
+	if err != nil {
+		return "", err
+	}
+	m.treeView.Refresh()
+	return attachmentLink(notePath, dest), nil
+}
+
+// writeAttachment 将数据以不冲突的文件名写入附件目录，返回文件路径
+func (m *MarkdownEditor) writeAttachment(notePath, name string, r io.Reader) (string, error) {
+	dir, err := m.attachmentDir(notePath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	name, err = sanitizeName(name)
+	if err != nil {
+		return "", err
+	}
+	dest := uniquePath(filepath.Join(dir, name))
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+func attachmentLink(notePath, path string) string {
+	link := relativeLink(notePath, path)
+	if isImage(path) {
+		return fmt.Sprintf("![](%s)", link)
+	}
+	return fmt.Sprintf("[%s](%s)", filepath.Base(path), link)
+}
+
+// pasteAttachment 处理编辑器中的粘贴：剪贴板中是文件路径或图片时作为附件插入
+func (m *MarkdownEditor) pasteAttachment(editor *noteEntry) bool {
+	notePath := m.pathOf(editor)
+	if notePath == "" {
+		return false
+	}
+
+	text := m.window.Clipboard().Content()
+	if text != "" {
+		paths := clipboardFiles(text)
+		if len(paths) == 0 {
+			return false
+		}
+		m.insertAttachments(editor, notePath, paths)
+		return true
+	}
+
+	data := readClipboardImage()
+	if data == nil {
+		return false
+	}
+	name := "Pasted image " + time.Now().Format("20060102150405") + ".png"
+	link, err := m.saveAttachment(notePath, name, bytes.NewReader(data))
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return true
+	}
+	editor.insertText(link)
+	return true
+}
+
+// onDropped 将拖入窗口的文件作为附件插入当前笔记
+func (m *MarkdownEditor) onDropped(_ fyne.Position, uris []fyne.URI) {
+	path, editor := m.currentFile()
+	if editor == nil {
+		return
+	}
+
+	var paths []string
+	for _, u := range uris {
+		if u.Scheme() == "file" {
+			paths = append(paths, u.Path())
+		}
+	}
+	m.insertAttachments(editor, path, paths)
+}
+
+func (m *MarkdownEditor) insertAttachments(editor *noteEntry, notePath string, paths []string) {
+	var links []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		link, err := m.importAttachment(notePath, p)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		links = append(links, link)
+	}
+	if len(links) > 0 {
+		editor.insertText(strings.Join(links, "\n"))
+	}
+}
+
+// clipboardFiles 解析剪贴板中的 file:// URI 列表（文件管理器复制文件时得到），其他文本返回 nil
+func clipboardFiles(text string) []string {
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// 普通文本路径按文本粘贴，只有 file:// URI 才视为文件
+		if !strings.HasPrefix(line, "file://") {
+			return nil
+		}
+		u, err := url.Parse(line)
+		if err != nil {
+			return nil
+		}
+		line = u.Path
+		if runtime.GOOS == "windows" {
+			line = strings.TrimPrefix(line, "/")
+		}
+		if !filepath.IsAbs(line) {
+			return nil
+		}
+		if info, err := os.Stat(line); err != nil || info.IsDir() {
+			return nil
+		}
+		paths = append(paths, line)
+	}
+	return paths
+}
+
+var macClipboardData = regexp.MustCompile(`«data PNGf([0-9A-Fa-f]+)»`)
+
+// readClipboardImage 通过系统工具读取剪贴板中的 PNG 图片。fyne 的剪贴板只支持文本，
+// 找不到可用的工具或剪贴板中没有图片时返回 nil
+func readClipboardImage() []byte {
+	type reader struct {
+		name   string
+		args   []string
+		decode func([]byte) []byte
+	}
+
+	var readers []reader
+	switch runtime.GOOS {
+	case "darwin":
+		readers = []reader{
+			{name: "pngpaste", args: []string{"-"}},
+			{name: "osascript", args: []string{"-e", "the clipboard as «class PNGf»"}, decode: func(out []byte) []byte {
+				match := macClipboardData.FindSubmatch(out)
+				if match == nil {
+					return nil
+				}
+				data, _ := hex.DecodeString(string(match[1]))
+				return data
+			}},
+		}
+	case "windows":
+		script := "Add-Type -AssemblyName System.Windows.Forms; $i = [Windows.Forms.Clipboard]::GetImage(); " +
+			"if ($i) { $s = New-Object IO.MemoryStream; $i.Save($s, [Drawing.Imaging.ImageFormat]::Png); [Convert]::ToBase64String($s.ToArray()) }"
+		readers = []reader{
+			{name: "powershell", args: []string{"-NoProfile", "-STA", "-Command", script}, decode: func(out []byte) []byte {
+				data, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+				return data
+			}},
+		}
+	default:
+		readers = []reader{
+			{name: "wl-paste", args: []string{"--no-newline", "--type", "image/png"}},
+			{name: "xclip", args: []string{"-selection", "clipboard", "-t", "image/png", "-o"}},
+		}
+	}
+
+	for _, r := range readers {
+		if _, err := exec.LookPath(r.name); err != nil {
+			continue
+		}
+		out, err := exec.Command(r.name, r.args...).Output()
+		if err != nil {
+			continue
+		}
+		if r.decode != nil {
+			out = r.decode(out)
+		}
+		if bytes.HasPrefix(out, pngSignature) {
+			return out
+		}
+	}
+	return nil
+}
+
+// unusedAttachments 返回附件目录中没有被任何笔记引用的文件。未设置附件目录时无法
+// 区分附件与其他文件，直接报错；未解锁的加密笔记中的引用无法检查，其路径通过 locked 返回
+func (m *MarkdownEditor) unusedAttachments() (unused, locked []string, err error) {
+	folder := strings.TrimSpace(m.config.AttachmentFolder)
+	if folder == "" {
+		return nil, nil, errNoAttachmentFolder
+	}
+	dir := filepath.Join(m.rootPath, filepath.FromSlash(folder))
+
+	var candidates []string
+	err = walkFiles(dir, func(path string) error {
+		if !isNote(path) {
+			candidates = append(candidates, path)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	used := map[string]bool{}
+	usedNames := map[string]bool{}
+	err = walkNotes(m.rootPath, func(path string) error {
+		var content string
+		if editor, ok := m.openFiles[path]; ok {
+			// 已打开的笔记（包括已解锁的加密笔记）以编辑器内容为准
+			content = editor.Text
+		} else {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if isEncrypted(data) {
+				locked = append(locked, path)
+				return nil
+			}
+			content = string(data)
+		}
+		for _, link := range extractLinks(content) {
+			if link.Target == "" || isExternalLink(link.Target) {
+				continue
+			}
+			if link.Wiki {
+				// wiki 链接只写文件名，按名称匹配
+				usedNames[strings.ToLower(filepath.Base(link.Target))] = true
+				continue
+			}
+			used[resolveRelative(path, link.Target)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, path := range candidates {
+		if !used[path] && !usedNames[strings.ToLower(filepath.Base(path))] {
+			unused = append(unused, path)
+		}
+	}
+	sort.Strings(unused)
+	return unused, locked, nil
+}
+
+// showUnusedAttachments 显示未被引用的附件，并可以一键删除
+func (m *MarkdownEditor) showUnusedAttachments() {
+	unused, locked, err := m.unusedAttachments()
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	if len(unused) == 0 {
+		dialog.ShowInformation("Unused Attachments", "没有未被引用的附件", m.window)
+		return
+	}
+
+	list := widget.NewList(
+		func() int { return len(unused) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			rel, _ := filepath.Rel(m.rootPath, unused[id])
+			item.(*widget.Label).SetText(filepath.ToSlash(rel))
+		},
+	)
+
+	var d dialog.Dialog
+	deleteButton := widget.NewButton("Delete All", func() {
+		dialog.ShowConfirm("Delete", fmt.Sprintf("确定要删除这 %d 个附件吗？", len(unused)), func(ok bool) {
+			if !ok {
+				return
+			}
+			var errs []error
+			for _, path := range unused {
+				if err := os.Remove(path); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			d.Hide()
+			m.treeView.Refresh()
+			if len(errs) > 0 {
+				dialog.ShowError(errors.Join(errs...), m.window)
+			}
+		}, m.window)
+	})
+	closeButton := widget.NewButton("Close", func() { d.Hide() })
+
+	message := fmt.Sprintf("%d 个附件没有被任何笔记引用：", len(unused))
+	if len(locked) > 0 {
+		// 加密笔记中可能引用了这些附件，解锁前不允许批量删除
+		message = fmt.Sprintf("%d 个附件没有被引用，但有 %d 篇加密笔记未解锁，其中的引用无法检查。\n请先打开并解锁这些笔记再删除：", len(unused), len(locked))
+		deleteButton.Disable()
+	}
+
+	content := container.NewBorder(
+		widget.NewLabel(message),
+		container.NewHBox(layout.NewSpacer(), closeButton, deleteButton),
+		nil, nil, list)
+	d = dialog.NewCustomWithoutButtons("Unused Attachments", content, m.window)
+	d.Resize(fyne.NewSize(500, 400))
+	d.Show()
+}
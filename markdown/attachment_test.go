@@ -0,0 +1,97 @@
+package markdown
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestClipboardFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file URI 的路径格式与 Windows 不同")
+	}
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a b.png")
+	b := filepath.Join(dir, "b.pdf")
+	for _, path := range []string{a, b} {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uri := func(path string) string {
+		return (&url.URL{Scheme: "file", Path: path}).String()
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"single uri", uri(a), []string{a}},
+		{"uri list", uri(a) + "\r\n" + uri(b) + "\n", []string{a, b}},
+		{"comment lines", "# copied\n" + uri(b), []string{b}},
+		{"plain path", a, nil},
+		{"mixed", uri(a) + "\n" + b, nil},
+		{"missing file", uri(filepath.Join(dir, "missing.png")), nil},
+		{"directory", uri(dir), nil},
+		{"text", "hello world", nil},
+	}
+	for _, tt := range tests {
+		if got := clipboardFiles(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: clipboardFiles() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnusedAttachments(t *testing.T) {
+	m := newTestEditor(t)
+	files := map[string]string{
+		"attachments/used.png":   "x",
+		"attachments/wiki.png":   "x",
+		"attachments/unused.png": "x",
+		"other.txt":              "x",
+		"note.md":                "![](attachments/used.png) ![[wiki.png]]",
+	}
+	for name, content := range files {
+		path := filepath.Join(m.rootPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.config.AttachmentFolder = ""
+	if _, _, err := m.unusedAttachments(); err != errNoAttachmentFolder {
+		t.Errorf("without attachment folder: err = %v, want %v", err, errNoAttachmentFolder)
+	}
+
+	m.config.AttachmentFolder = "attachments"
+	unused, locked, err := m.unusedAttachments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(m.rootPath, "attachments", "unused.png")}; !reflect.DeepEqual(unused, want) || len(locked) != 0 {
+		t.Errorf("unusedAttachments() = %q, %q; want %q, none locked", unused, locked, want)
+	}
+
+	key, err := newNoteKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := key.seal([]byte("![](attachments/unused.png)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(m.rootPath, "secret.md")
+	if err := os.WriteFile(secret, sealed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, locked, _ := m.unusedAttachments(); !reflect.DeepEqual(locked, []string{secret}) {
+		t.Errorf("locked = %q, want %q", locked, []string{secret})
+	}
+}
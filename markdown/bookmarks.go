@@ -0,0 +1,310 @@
+package markdown
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// maxRecentFiles 是最近打开列表中保留的文件数
+const maxRecentFiles = 20
+
+// starIcon 是收藏按钮的图标，主题中没有星形图标
+var starIcon = theme.NewThemedResource(fyne.NewStaticResource("star.svg", []byte(
+	`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24"><path fill="#000000" d="M12 17.27L18.18 21l-1.64-7.03L22 9.24l-7.19-.61L12 2 9.19 8.63 2 9.24l5.46 4.73L5.82 21z"/></svg>`,
+)))
+
+// bookmark 是收藏的笔记、文件夹或笔记中的标题
+type bookmark struct {
+	Path    string `json:"path"`              // 相对于笔记库，使用 / 分隔
+	Heading string `json:"heading,omitempty"` // 收藏标题时为标题文字
+}
+
+func (b bookmark) label() string {
+	name := filepath.Base(filepath.FromSlash(b.Path))
+	if b.Heading != "" {
+		return strings.TrimSuffix(name, filepath.Ext(name)) + " › " + b.Heading
+	}
+	return name
+}
+
+// loadBookmarks 读取收藏和最近打开的文件，去掉已删除的条目
+func (m *MarkdownEditor) loadBookmarks() {
+	m.bookmarks, m.recent = nil, nil
+	if err := m.readMeta("bookmarks.json", &m.bookmarks); err != nil {
+		fyne.LogError("Failed to read bookmarks", err)
+	}
+	if err := m.readMeta("recent.json", &m.recent); err != nil {
+		fyne.LogError("Failed to read recent files", err)
+	}
+	m.pruneBookmarks()
+}
+
+func (m *MarkdownEditor) saveBookmarks() {
+	if err := m.writeMeta("bookmarks.json", m.bookmarks); err != nil {
+		fyne.LogError("Failed to save bookmarks", err)
+	}
+	if m.bookmarkList != nil {
+		m.bookmarkList.Refresh()
+	}
+}
+
+func (m *MarkdownEditor) saveRecent() {
+	if err := m.writeMeta("recent.json", m.recent); err != nil {
+		fyne.LogError("Failed to save recent files", err)
+	}
+}
+
+// addRecent 把文件移到最近打开列表的最前面
+func (m *MarkdownEditor) addRecent(path string) {
+	rel := m.vaultRelPath(path)
+	recent := []string{rel}
+	for _, p := range m.recent {
+		if p != rel && len(recent) < maxRecentFiles {
+			recent = append(recent, p)
+		}
+	}
+	m.recent = recent
+	m.saveRecent()
+}
+
+// pruneBookmarks 去掉文件已被删除的收藏和最近打开的文件
+func (m *MarkdownEditor) pruneBookmarks() {
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(m.rootPath, filepath.FromSlash(rel)))
+		return err == nil
+	}
+
+	bookmarks := m.bookmarks[:0]
+	for _, b := range m.bookmarks {
+		if exists(b.Path) {
+			bookmarks = append(bookmarks, b)
+		}
+	}
+	if len(bookmarks) != len(m.bookmarks) {
+		m.bookmarks = bookmarks
+		m.saveBookmarks()
+	}
+
+	recent := m.recent[:0]
+	for _, p := range m.recent {
+		if exists(p) {
+			recent = append(recent, p)
+		}
+	}
+	if len(recent) != len(m.recent) {
+		m.recent = recent
+		m.saveRecent()
+	}
+}
+
+// followMove 在文件或文件夹重命名、移动后更新收藏和最近打开的文件
+func (m *MarkdownEditor) followMove(oldPath, newPath string) {
+	move := func(rel string) (string, bool) {
+		sub, ok := relUnder(oldPath, filepath.Join(m.rootPath, filepath.FromSlash(rel)))
+		if !ok {
+			return rel, false
+		}
+		return m.vaultRelPath(filepath.Join(newPath, sub)), true
+	}
+
+	changed := false
+	for i, b := range m.bookmarks {
+		if p, ok := move(b.Path); ok {
+			m.bookmarks[i].Path = p
+			changed = true
+		}
+	}
+	if changed {
+		m.saveBookmarks()
+	}
+
+	changed = false
+	for i, rel := range m.recent {
+		if p, ok := move(rel); ok {
+			m.recent[i] = p
+			changed = true
+		}
+	}
+	if changed {
+		m.saveRecent()
+	}
+}
+
+// indexOfBookmark 返回收藏的位置，没有收藏时返回 -1
+func (m *MarkdownEditor) indexOfBookmark(b bookmark) int {
+	for i, x := range m.bookmarks {
+		if x == b {
+			return i
+		}
+	}
+	return -1
+}
+
+// toggleBookmark 收藏或取消收藏目录树中选中的文件或文件夹
+func (m *MarkdownEditor) toggleBookmark() {
+	if m.selectedNode == "" {
+		dialog.ShowInformation("提示", "请先选择一个文件或文件夹", m.window)
+		return
+	}
+	m.toggleNodeBookmark(m.selectedNode)
+}
+
+func (m *MarkdownEditor) toggleNodeBookmark(uid widget.TreeNodeID) {
+	b := bookmark{Path: m.vaultRelPath(m.uidToPath(uid))}
+	if i := m.indexOfBookmark(b); i >= 0 {
+		m.removeBookmark(i)
+		return
+	}
+	m.bookmarks = append(m.bookmarks, b)
+	m.saveBookmarks()
+}
+
+// bookmarkHeading 收藏当前笔记中光标所在位置的标题
+func (m *MarkdownEditor) bookmarkHeading() {
+	path, editor := m.currentFile()
+	if editor == nil {
+		dialog.ShowError(errors.New("请先打开一篇笔记"), m.window)
+		return
+	}
+	heading := ""
+	forEachTextLine(editor.Text, func(i int, line string) {
+		if _, text, ok := parseHeading(line); ok && text != "" && i <= editor.CursorRow {
+			heading = text
+		}
+	})
+	if heading == "" {
+		dialog.ShowError(errors.New("光标之前没有标题"), m.window)
+		return
+	}
+	b := bookmark{Path: m.vaultRelPath(path), Heading: heading}
+	if m.indexOfBookmark(b) < 0 {
+		m.bookmarks = append(m.bookmarks, b)
+		m.saveBookmarks()
+	}
+}
+
+func (m *MarkdownEditor) removeBookmark(i int) {
+	m.bookmarks = append(m.bookmarks[:i], m.bookmarks[i+1:]...)
+	m.saveBookmarks()
+}
+
+// moveBookmark 把第 i 个收藏上移（delta 为 -1）或下移（delta 为 1）
+func (m *MarkdownEditor) moveBookmark(i, delta int) {
+	j := i + delta
+	if j < 0 || j >= len(m.bookmarks) {
+		return
+	}
+	m.bookmarks[i], m.bookmarks[j] = m.bookmarks[j], m.bookmarks[i]
+	m.saveBookmarks()
+}
+
+// openBookmark 打开收藏的笔记并跳到收藏的标题，收藏的是文件夹时在目录树中展开并选中它
+func (m *MarkdownEditor) openBookmark(b bookmark) {
+	path := filepath.Join(m.rootPath, filepath.FromSlash(b.Path))
+	info, err := os.Stat(path)
+	if err != nil {
+		m.pruneBookmarks()
+		dialog.ShowError(err, m.window)
+		return
+	}
+	if info.IsDir() {
+		uid := m.pathToUID(path)
+		for dir := filepath.Dir(uid); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			m.treeView.OpenBranch(dir)
+		}
+		m.treeView.OpenBranch(uid)
+		m.treeView.Select(uid)
+		m.treeView.ScrollTo(uid)
+		return
+	}
+	if b.Heading == "" {
+		m.openFile(path)
+		return
+	}
+
+	line := -1
+	content, err := m.noteContent(path)
+	if editor, ok := m.openFiles[path]; ok {
+		content, err = editor.Text, nil // 已解密打开的加密笔记也可以跳到书签的标题
+	}
+	if err == nil {
+		forEachTextLine(content, func(i int, text string) {
+			if _, h, ok := parseHeading(text); ok && h == b.Heading && line < 0 {
+				line = i
+			}
+		})
+	}
+	// 标题已被修改时打开笔记开头
+	line = max(line, 0)
+	m.openFileAt(path, line)
+}
+
+// newBookmarkList 创建显示在目录树上方的收藏列表
+func (m *MarkdownEditor) newBookmarkList() fyne.CanvasObject {
+	m.bookmarkList = widget.NewList(
+		func() int { return len(m.bookmarks) },
+		func() fyne.CanvasObject {
+			buttons := container.NewHBox(
+				widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil),
+				widget.NewButtonWithIcon("", theme.MoveDownIcon(), nil),
+				widget.NewButtonWithIcon("", theme.ContentRemoveIcon(), nil),
+			)
+			return container.NewBorder(nil, nil, widget.NewIcon(theme.DocumentIcon()), buttons, widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			b := m.bookmarks[id]
+			c := item.(*fyne.Container)
+			label := c.Objects[0].(*widget.Label)
+			icon := c.Objects[1].(*widget.Icon)
+			buttons := c.Objects[2].(*fyne.Container).Objects
+
+			label.SetText(b.label())
+			switch {
+			case b.Heading != "":
+				icon.SetResource(theme.ListIcon())
+			case m.isBranch(m.pathToUID(filepath.Join(m.rootPath, filepath.FromSlash(b.Path)))):
+				icon.SetResource(theme.FolderIcon())
+			default:
+				icon.SetResource(theme.DocumentIcon())
+			}
+			buttons[0].(*widget.Button).OnTapped = func() { m.moveBookmark(id, -1) }
+			buttons[1].(*widget.Button).OnTapped = func() { m.moveBookmark(id, 1) }
+			buttons[2].(*widget.Button).OnTapped = func() { m.removeBookmark(id) }
+		},
+	)
+	m.bookmarkList.OnSelected = func(id widget.ListItemID) {
+		m.bookmarkList.UnselectAll()
+		m.openBookmark(m.bookmarks[id])
+	}
+
+	title := widget.NewLabel("Bookmarks")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	return container.NewBorder(title, nil, nil, nil, m.bookmarkList)
+}
+
+// showRecentMenu 在最近打开按钮下方弹出最近打开的文件
+func (m *MarkdownEditor) showRecentMenu() {
+	m.pruneBookmarks()
+	var items []*fyne.MenuItem
+	for _, rel := range m.recent {
+		path := filepath.Join(m.rootPath, filepath.FromSlash(rel))
+		items = append(items, fyne.NewMenuItem(rel, func() { m.openFile(path) }))
+	}
+	if len(items) == 0 {
+		empty := fyne.NewMenuItem("No recent files", nil)
+		empty.Disabled = true
+		items = append(items, empty)
+	}
+
+	c := fyne.CurrentApp().Driver().CanvasForObject(m.recentButton)
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(m.recentButton)
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), c, pos.Add(fyne.NewPos(0, m.recentButton.Size().Height)))
+}
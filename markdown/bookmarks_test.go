@@ -0,0 +1,102 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFollowMove(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     []string
+	}{
+		{"a", "c", []string{"c/x.md", "c/x.md", "ab.md", "b.md", "c"}},
+		{"a/x.md", "a/y.md", []string{"a/y.md", "a/y.md", "ab.md", "b.md", "a"}},
+		{"b.md", "sub/b.md", []string{"a/x.md", "a/x.md", "ab.md", "sub/b.md", "a"}},
+		{"missing", "other", []string{"a/x.md", "a/x.md", "ab.md", "b.md", "a"}},
+	}
+	for _, tt := range tests {
+		m := newTestEditor(t)
+		for _, name := range []string{"a/x.md", "ab.md", "b.md", "sub/keep.md"} {
+			path := filepath.Join(m.rootPath, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		m.bookmarks = []bookmark{{Path: "a/x.md"}, {Path: "a/x.md", Heading: "Intro"}, {Path: "ab.md"}, {Path: "b.md"}, {Path: "a"}}
+		m.recent = []string{"b.md", "a/x.md", "ab.md"}
+		m.saveBookmarks()
+		m.saveRecent()
+
+		oldPath := filepath.Join(m.rootPath, filepath.FromSlash(tt.old))
+		newPath := filepath.Join(m.rootPath, filepath.FromSlash(tt.new))
+		if _, err := os.Stat(oldPath); err == nil {
+			if err := os.Rename(oldPath, newPath); err != nil {
+				t.Fatal(err)
+			}
+		}
+		m.followMove(oldPath, newPath)
+
+		// 重新加载保存的收藏，移动后的路径仍然存在
+		m.loadBookmarks()
+		var got []string
+		for _, b := range m.bookmarks {
+			got = append(got, b.Path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("move %s -> %s: bookmarks = %q, want %q", tt.old, tt.new, got, tt.want)
+		}
+		if len(m.bookmarks) > 1 && m.bookmarks[1].Heading != "Intro" {
+			t.Errorf("move %s -> %s: heading bookmark lost its heading", tt.old, tt.new)
+		}
+		wantRecent := []string{tt.want[3], tt.want[0], tt.want[2]}
+		if !reflect.DeepEqual(m.recent, wantRecent) {
+			t.Errorf("move %s -> %s: recent = %q, want %q", tt.old, tt.new, m.recent, wantRecent)
+		}
+	}
+}
+
+func TestPruneBookmarks(t *testing.T) {
+	m := newTestEditor(t)
+	for _, name := range []string{"keep.md", "dir/note.md"} {
+		path := filepath.Join(m.rootPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.bookmarks = []bookmark{{Path: "keep.md"}, {Path: "gone.md"}, {Path: "dir"}, {Path: "keep.md", Heading: "H"}, {Path: "dir/gone.md"}}
+	m.recent = []string{"gone.md", "dir/note.md", "keep.md"}
+	m.saveBookmarks()
+	m.saveRecent()
+
+	// 重新加载时去掉已删除的文件
+	m.loadBookmarks()
+	want := []bookmark{{Path: "keep.md"}, {Path: "dir"}, {Path: "keep.md", Heading: "H"}}
+	if !reflect.DeepEqual(m.bookmarks, want) {
+		t.Errorf("bookmarks = %v, want %v", m.bookmarks, want)
+	}
+	if want := []string{"dir/note.md", "keep.md"}; !reflect.DeepEqual(m.recent, want) {
+		t.Errorf("recent = %q, want %q", m.recent, want)
+	}
+
+	if err := os.RemoveAll(filepath.Join(m.rootPath, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	m.pruneBookmarks()
+	m.bookmarks, m.recent = nil, nil
+	m.loadBookmarks()
+	if want := []bookmark{{Path: "keep.md"}, {Path: "keep.md", Heading: "H"}}; !reflect.DeepEqual(m.bookmarks, want) {
+		t.Errorf("after deleting dir: bookmarks = %v, want %v", m.bookmarks, want)
+	}
+	if want := []string{"keep.md"}; !reflect.DeepEqual(m.recent, want) {
+		t.Errorf("after deleting dir: recent = %q, want %q", m.recent, want)
+	}
+}
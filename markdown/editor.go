@@ -0,0 +1,384 @@
+package markdown
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 各类文字使用的主题颜色。只改变颜色而不改变字重，保证文字宽度与输入框计算光标位置时一致
+var hlColors = map[hlKind]fyne.ThemeColorName{
+	hlText:        theme.ColorNameForeground,
+	hlMarker:      theme.ColorNamePlaceHolder,
+	hlHeading:     theme.ColorNamePrimary,
+	hlEmphasis:    theme.ColorNameWarning,
+	hlCode:        theme.ColorNameSuccess,
+	hlLink:        theme.ColorNameHyperlink,
+	hlURL:         theme.ColorNamePlaceHolder,
+	hlList:        theme.ColorNamePrimary,
+	hlQuote:       theme.ColorNamePlaceHolder,
+	hlFrontMatter: theme.ColorNamePlaceHolder,
+}
+
+// sourceEditor 是笔记的源码编辑区。输入仍由 noteEntry 处理，但它自己的文字被隐藏，
+// 改为由 highlightLayer 在相同位置绘制带颜色的文字；左侧可以显示行号。
+// 输入框不再自己滚动，而是与高亮层、行号一起放在同一个滚动容器中
+type sourceEditor struct {
+	widget.BaseWidget
+
+	entry   *noteEntry
+	hl      highlighter
+	layer   *highlightLayer
+	gutter  *lineGutter
+	content *fyne.Container
+	scroll  *container.Scroll
+
+	maxLine int // 最长一行的字节数，变化时需要重新计算滚动范围
+	// onCursorChanged 在光标或选区变化后调用
+	onCursorChanged func()
+
+	// spell 为 nil 时不检查拼写；spellCache 按行文字缓存拼写错误的位置
+	spell        *spellChecker
+	spellCache   map[string][]hlSpan
+	spellVersion int
+}
+
+func newSourceEditor(entry *noteEntry) *sourceEditor {
+	s := &sourceEditor{entry: entry}
+	entry.Wrapping = fyne.TextWrapOff
+	entry.Scroll = container.ScrollNone
+
+	s.layer = &highlightLayer{editor: s}
+	s.layer.ExtendBaseWidget(s.layer)
+	s.gutter = &lineGutter{editor: s}
+	s.gutter.ExtendBaseWidget(s.gutter)
+	s.gutter.Hide()
+
+	text := container.NewStack(container.NewThemeOverride(entry, hiddenTextTheme{}), s.layer)
+	s.content = container.NewBorder(nil, nil, s.gutter, nil, text)
+	s.scroll = container.NewScroll(s.content)
+	s.scroll.OnScrolled = func(fyne.Position) { s.redraw() }
+
+	entry.OnCursorChanged = func() {
+		s.ensureCursorVisible()
+		s.gutter.Refresh()
+		if s.onCursorChanged != nil {
+			s.onCursorChanged()
+		}
+	}
+	s.textChanged(entry.Text)
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+func (s *sourceEditor) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(s.scroll)
+}
+
+// textChanged 在输入框内容变化后更新高亮，需要在 OnChanged 中调用
+func (s *sourceEditor) textChanged(text string) {
+	lines := len(s.hl.lines)
+	s.hl.update(text)
+
+	longest := 0
+	for _, line := range s.hl.lines {
+		if len(line.text) > longest {
+			longest = len(line.text)
+		}
+	}
+	if len(s.hl.lines) != lines || longest != s.maxLine {
+		// 行数或最长一行变化时输入框的大小会变，需要重新布局滚动内容
+		s.maxLine = longest
+		s.content.Refresh()
+		s.scroll.Refresh()
+	}
+	s.redraw()
+}
+
+func (s *sourceEditor) redraw() {
+	s.layer.Refresh()
+	if s.gutter.Visible() {
+		s.gutter.Refresh()
+	}
+}
+
+// setLineNumbers 显示或隐藏行号
+func (s *sourceEditor) setLineNumbers(show bool) {
+	if show {
+		s.gutter.Show()
+	} else {
+		s.gutter.Hide()
+	}
+	s.content.Refresh()
+	s.redraw()
+}
+
+// setMonospace 切换等宽字体
+func (s *sourceEditor) setMonospace(mono bool) {
+	if s.entry.TextStyle.Monospace == mono {
+		return
+	}
+	s.entry.TextStyle.Monospace = mono
+	s.entry.Refresh()
+	s.content.Refresh()
+	s.scroll.Refresh()
+	s.redraw()
+}
+
+// setSpellChecker 设置拼写检查，为 nil 时关闭
+func (s *sourceEditor) setSpellChecker(sc *spellChecker) {
+	s.spell = sc
+	s.spellCache = nil
+	s.redraw()
+}
+
+// misspelled 返回第 row 行中拼写错误的词
+func (s *sourceEditor) misspelled(row int) []hlSpan {
+	if s.spell == nil {
+		return nil
+	}
+	if s.spellCache == nil || s.spellVersion != s.spell.version {
+		s.spellCache = map[string][]hlSpan{}
+		s.spellVersion = s.spell.version
+	}
+	line := s.hl.lines[row]
+	// 同样的文字在代码块中和代码块外结果不同，缓存的键需要包含行首状态
+	key := line.text
+	if line.state != (hlState{}) {
+		key = "\x00" + line.state.fence + "\x00" + key
+	}
+	spans, ok := s.spellCache[key]
+	if !ok {
+		spans = s.spell.misspelled(line)
+		s.spellCache[key] = spans
+	}
+	return spans
+}
+
+// metrics 返回文字大小、内边距和行高，与 widget.Entry 的排版方式相同
+func (s *sourceEditor) metrics() (textSize, pad, lineHeight float32) {
+	textSize = theme.TextSize()
+	pad = theme.InnerPadding()
+	lineHeight = fyne.MeasureText("M", textSize, s.entry.TextStyle).Height
+	return
+}
+
+// visibleRows 返回滚动区域中可见的行
+func (s *sourceEditor) visibleRows() (first, last int) {
+	_, pad, lineHeight := s.metrics()
+	top := s.scroll.Offset.Y - pad
+	first = int(top / lineHeight)
+	last = int((top + s.scroll.Size().Height) / lineHeight)
+	if first < 0 {
+		first = 0
+	}
+	if last >= len(s.hl.lines) {
+		last = len(s.hl.lines) - 1
+	}
+	return first, last
+}
+
+// ensureCursorVisible 滚动到光标所在位置
+func (s *sourceEditor) ensureCursorVisible() {
+	row, col := s.entry.CursorRow, s.entry.CursorColumn
+	if row >= len(s.hl.lines) {
+		return
+	}
+	textSize, pad, lineHeight := s.metrics()
+	runes := []rune(s.hl.lines[row].text)
+	if col > len(runes) {
+		col = len(runes)
+	}
+	x := s.gutterWidth() + pad + fyne.MeasureText(string(runes[:col]), textSize, s.entry.TextStyle).Width
+	y := pad + float32(row)*lineHeight
+
+	view := s.scroll.Size()
+	offset := s.scroll.Offset
+	if y < offset.Y {
+		offset.Y = y - pad
+	} else if y+lineHeight > offset.Y+view.Height {
+		offset.Y = y + lineHeight + pad - view.Height
+	}
+	if x < offset.X+s.gutterWidth() {
+		offset.X = x - s.gutterWidth() - pad
+	} else if x > offset.X+view.Width-pad {
+		offset.X = x + pad - view.Width
+	}
+	offset.X = max(offset.X, 0)
+	offset.Y = max(offset.Y, 0)
+	if offset != s.scroll.Offset {
+		s.scroll.Offset = offset
+		s.scroll.Refresh()
+		s.redraw()
+	}
+}
+
+func (s *sourceEditor) gutterWidth() float32 {
+	if !s.gutter.Visible() {
+		return 0
+	}
+	return s.gutter.MinSize().Width
+}
+
+// hiddenTextTheme 让输入框的文字透明，文字由 highlightLayer 绘制；光标和选区仍由输入框绘制
+type hiddenTextTheme struct{}
+
+func (hiddenTextTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if name == theme.ColorNameForeground {
+		return color.Transparent
+	}
+	return theme.Current().Color(name, variant)
+}
+
+func (hiddenTextTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return theme.Current().Font(style)
+}
+
+func (hiddenTextTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return theme.Current().Icon(name)
+}
+
+func (hiddenTextTheme) Size(name fyne.ThemeSizeName) float32 {
+	return theme.Current().Size(name)
+}
+
+// highlightLayer 绘制可见行的高亮文字，覆盖在输入框上方
+type highlightLayer struct {
+	widget.BaseWidget
+	editor *sourceEditor
+}
+
+func (l *highlightLayer) CreateRenderer() fyne.WidgetRenderer {
+	return &highlightRenderer{layer: l}
+}
+
+type highlightRenderer struct {
+	layer   *highlightLayer
+	texts   []*canvas.Text // 复用的文字对象
+	lines   []*canvas.Line // 复用的拼写错误下划线
+	objects []fyne.CanvasObject
+}
+
+func (r *highlightRenderer) Layout(fyne.Size) {
+	s := r.layer.editor
+	textSize, pad, lineHeight := s.metrics()
+	style := s.entry.TextStyle
+
+	r.objects = r.objects[:0]
+	first, last := s.visibleRows()
+	n, underlines := 0, 0
+	for row := first; row <= last; row++ {
+		line := s.hl.lines[row]
+		y := pad + float32(row)*lineHeight
+		for _, span := range line.spans {
+			if n == len(r.texts) {
+				r.texts = append(r.texts, canvas.NewText("", nil))
+			}
+			t := r.texts[n]
+			n++
+			t.Text = line.text[span.start:span.end]
+			t.TextSize = textSize
+			t.TextStyle = style
+			t.Color = theme.Color(hlColors[span.kind])
+			t.Move(fyne.NewPos(pad+fyne.MeasureText(line.text[:span.start], textSize, style).Width, y))
+			t.Resize(fyne.NewSize(fyne.MeasureText(t.Text, textSize, style).Width, lineHeight))
+			r.objects = append(r.objects, t)
+		}
+		for _, span := range s.misspelled(row) {
+			if underlines == len(r.lines) {
+				r.lines = append(r.lines, canvas.NewLine(nil))
+			}
+			l := r.lines[underlines]
+			underlines++
+			l.StrokeColor = theme.Color(theme.ColorNameError)
+			l.StrokeWidth = 1
+			x := pad + fyne.MeasureText(line.text[:span.start], textSize, style).Width
+			l.Position1 = fyne.NewPos(x, y+lineHeight-1)
+			l.Position2 = fyne.NewPos(x+fyne.MeasureText(line.text[span.start:span.end], textSize, style).Width, y+lineHeight-1)
+			r.objects = append(r.objects, l)
+		}
+	}
+}
+
+func (r *highlightRenderer) MinSize() fyne.Size {
+	return fyne.Size{}
+}
+
+func (r *highlightRenderer) Refresh() {
+	r.Layout(r.layer.Size())
+	canvas.Refresh(r.layer)
+}
+
+func (r *highlightRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *highlightRenderer) Destroy() {}
+
+// lineGutter 在编辑区左侧显示可见行的行号，光标所在行使用前景色
+type lineGutter struct {
+	widget.BaseWidget
+	editor *sourceEditor
+}
+
+func (g *lineGutter) CreateRenderer() fyne.WidgetRenderer {
+	return &gutterRenderer{gutter: g}
+}
+
+type gutterRenderer struct {
+	gutter  *lineGutter
+	texts   []*canvas.Text
+	objects []fyne.CanvasObject
+}
+
+func (r *gutterRenderer) Layout(size fyne.Size) {
+	s := r.gutter.editor
+	textSize, pad, lineHeight := s.metrics()
+
+	r.objects = r.objects[:0]
+	first, last := s.visibleRows()
+	for i, row := 0, first; row <= last; i, row = i+1, row+1 {
+		if i == len(r.texts) {
+			t := canvas.NewText("", nil)
+			t.Alignment = fyne.TextAlignTrailing
+			r.texts = append(r.texts, t)
+		}
+		t := r.texts[i]
+		t.Text = strconv.Itoa(row + 1)
+		t.TextSize = textSize
+		t.TextStyle = s.entry.TextStyle
+		t.Color = theme.Color(theme.ColorNamePlaceHolder)
+		if row == s.entry.CursorRow {
+			t.Color = theme.Color(theme.ColorNameForeground)
+		}
+		t.Move(fyne.NewPos(0, pad+float32(row)*lineHeight))
+		t.Resize(fyne.NewSize(size.Width-pad, lineHeight))
+		r.objects = append(r.objects, t)
+	}
+}
+
+// MinSize 的宽度按总行数的位数计算
+func (r *gutterRenderer) MinSize() fyne.Size {
+	s := r.gutter.editor
+	textSize, pad, _ := s.metrics()
+	digits := len(strconv.Itoa(max(len(s.hl.lines), 10)))
+	width := fyne.MeasureText(strings.Repeat("9", digits), textSize, s.entry.TextStyle).Width
+	return fyne.NewSize(width+pad*2, 0)
+}
+
+func (r *gutterRenderer) Refresh() {
+	r.Layout(r.gutter.Size())
+	canvas.Refresh(r.gutter)
+}
+
+func (r *gutterRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *gutterRenderer) Destroy() {}
@@ -0,0 +1,305 @@
+package markdown
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密笔记以 PEM 格式保存：头部记录 scrypt 参数和盐，内容为 AES-256-GCM 的随机数加密文
+const encryptedNoteType = "NODIAN ENCRYPTED NOTE"
+
+// scrypt 参数，按 2017 年推荐的交互式登录强度设置
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	// 解密时接受的参数上限，避免被篡改的头部让派生密钥耗尽内存或时间
+	maxScryptN  = 1 << 20
+	maxScryptRP = 1 << 30
+)
+
+var (
+	errWrongPassphrase = errors.New("口令错误或笔记已损坏")
+	errEmptyPassphrase = errors.New("口令不能为空")
+	errPassphraseMatch = errors.New("两次输入的口令不一致")
+	errNotEncrypted    = errors.New("文件不是加密笔记")
+	errEncryptedNote   = errors.New("笔记已加密")
+	errScryptParams    = errors.New("加密笔记的 scrypt 参数无效")
+)
+
+// noteKey 是由口令派生出的密钥，只保存在内存中，保存笔记时用它重新加密
+type noteKey struct {
+	salt    []byte
+	n, r, p int
+	aead    cipher.AEAD
+}
+
+// isEncrypted 判断文件内容是否为加密笔记
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, "\r\n\t "), []byte("-----BEGIN "+encryptedNoteType+"-----"))
+}
+
+// newNoteKey 用随机的盐从口令派生新的密钥
+func newNoteKey(passphrase string) (*noteKey, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return deriveNoteKey(passphrase, salt, scryptN, scryptR, scryptP)
+}
+
+func deriveNoteKey(passphrase string, salt []byte, n, r, p int) (*noteKey, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &noteKey{salt: salt, n: n, r: r, p: p, aead: aead}, nil
+}
+
+// seal 加密笔记内容，每次使用新的随机数
+func (k *noteKey) seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	block := &pem.Block{
+		Type: encryptedNoteType,
+		Headers: map[string]string{
+			"Cipher": "AES-256-GCM",
+			"KDF":    "scrypt",
+			"N":      strconv.Itoa(k.n),
+			"R":      strconv.Itoa(k.r),
+			"P":      strconv.Itoa(k.p),
+			"Salt":   base64.StdEncoding.EncodeToString(k.salt),
+		},
+		Bytes: k.aead.Seal(nonce, nonce, plaintext, []byte(encryptedNoteType)),
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// openEncrypted 用口令解密笔记，返回明文和派生出的密钥
+func openEncrypted(data []byte, passphrase string) ([]byte, *noteKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encryptedNoteType {
+		return nil, nil, errNotEncrypted
+	}
+	h := block.Headers
+	if h["KDF"] != "scrypt" || h["Cipher"] != "AES-256-GCM" {
+		return nil, nil, errors.New("不支持的加密方式：" + h["KDF"] + " " + h["Cipher"])
+	}
+	salt, err := base64.StdEncoding.DecodeString(h["Salt"])
+	if err != nil {
+		return nil, nil, errWrongPassphrase
+	}
+	n, r, p, err := scryptParams(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := deriveNoteKey(passphrase, salt, n, r, p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := key.aead.NonceSize()
+	if len(block.Bytes) < size {
+		return nil, nil, errWrongPassphrase
+	}
+	plaintext, err := key.aead.Open(nil, block.Bytes[:size], block.Bytes[size:], []byte(encryptedNoteType))
+	if err != nil {
+		return nil, nil, errWrongPassphrase
+	}
+	return plaintext, key, nil
+}
+
+// scryptParams 读取头部中的 scrypt 参数并检查范围
+func scryptParams(h map[string]string) (n, r, p int, err error) {
+	if n, err = strconv.Atoi(h["N"]); err != nil {
+		return 0, 0, 0, errScryptParams
+	}
+	if r, err = strconv.Atoi(h["R"]); err != nil {
+		return 0, 0, 0, errScryptParams
+	}
+	if p, err = strconv.Atoi(h["P"]); err != nil {
+		return 0, 0, 0, errScryptParams
+	}
+	// N 必须是大于 1 的 2 的幂
+	if n <= 1 || n > maxScryptN || n&(n-1) != 0 || r <= 0 || p <= 0 || r >= maxScryptRP || p >= maxScryptRP || r*p >= maxScryptRP {
+		return 0, 0, 0, errScryptParams
+	}
+	return n, r, p, nil
+}
+
+// unlockFile 询问口令并解密笔记，成功后在标签页中打开
+func (m *MarkdownEditor) unlockFile(path string, data []byte) {
+	passphrase := widget.NewPasswordEntry()
+	m.showCustomFormDialog("Unlock "+filepath.Base(path), "Unlock", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Passphrase", passphrase),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		plaintext, key, err := openEncrypted(data, passphrase.Text)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		m.openEditor(path, string(plaintext), key)
+		m.resetLockTimer()
+	}, m.window)
+	m.window.Canvas().Focus(passphrase)
+}
+
+// encryptCurrentFile 为当前笔记设置口令，之后保存时写入加密后的内容
+func (m *MarkdownEditor) encryptCurrentFile() {
+	path, editor := m.currentFile()
+	if editor == nil {
+		dialog.ShowError(errors.New("请先打开一篇笔记"), m.window)
+		return
+	}
+
+	passphrase := widget.NewPasswordEntry()
+	confirm := widget.NewPasswordEntry()
+	title := "Encrypt " + filepath.Base(path)
+	if editor.key != nil {
+		title = "Change Passphrase"
+	}
+	m.showCustomFormDialog(title, "Encrypt", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Passphrase", passphrase),
+		widget.NewFormItem("Confirm", confirm),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		switch {
+		case passphrase.Text == "":
+			dialog.ShowError(errEmptyPassphrase, m.window)
+			return
+		case passphrase.Text != confirm.Text:
+			dialog.ShowError(errPassphraseMatch, m.window)
+			return
+		}
+		key, err := newNoteKey(passphrase.Text)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		editor.key = key
+		if err := m.saveFile(path, editor); err != nil {
+			dialog.ShowError(err, m.window)
+		}
+		m.resetLockTimer()
+	}, m.window)
+	m.window.Canvas().Focus(passphrase)
+}
+
+// decryptCurrentFile 去掉当前笔记的加密，以明文保存
+func (m *MarkdownEditor) decryptCurrentFile() {
+	path, editor := m.currentFile()
+	if editor == nil || editor.key == nil {
+		dialog.ShowError(errNotEncrypted, m.window)
+		return
+	}
+	dialog.ShowConfirm("Remove Encryption", "笔记将以明文保存，确定要去掉加密吗？", func(ok bool) {
+		if !ok {
+			return
+		}
+		key := editor.key
+		editor.key = nil
+		if err := m.saveFile(path, editor); err != nil {
+			editor.key = key
+			dialog.ShowError(err, m.window)
+		}
+	}, m.window)
+}
+
+// lockAll 保存并关闭所有已解密的笔记，清除编辑器中的明文
+func (m *MarkdownEditor) lockAll() {
+	if m.lockTimer != nil {
+		m.lockTimer.Stop()
+	}
+	m.lastActivity = time.Time{}
+	for path, editor := range m.openFiles {
+		if editor.key == nil {
+			continue
+		}
+		if m.isDirty(editor) {
+			if err := m.saveFile(path, editor); err != nil {
+				// 保存失败时保留标签页，避免丢失修改
+				fyne.LogError("Failed to save encrypted note", err)
+				continue
+			}
+		}
+		if tab := m.tabOf(editor); tab != nil {
+			m.tabs.Remove(tab)
+		}
+		delete(m.openFiles, path)
+		editor.key = nil
+		editor.OnChanged = nil
+		editor.SetText("")
+	}
+	m.updateStatus()
+}
+
+// resetLockTimer 在操作已解密的笔记时重新开始计时，超过设置的时间没有操作时锁定所有笔记。
+// 计时器在后台触发锁定；系统休眠时计时器可能推迟，所以界面事件中也会检查距离上一次操作的时间
+func (m *MarkdownEditor) resetLockTimer() {
+	if m.lockTimer != nil {
+		m.lockTimer.Stop()
+	}
+	if m.config.LockTimeout <= 0 || !m.hasUnlocked() {
+		m.lastActivity = time.Time{}
+		return
+	}
+	timeout := time.Duration(m.config.LockTimeout) * time.Minute
+	if !m.lastActivity.IsZero() && time.Since(m.lastActivity) > timeout {
+		m.lockAll()
+		return
+	}
+	m.lastActivity = time.Now()
+	m.lockTimer = time.AfterFunc(timeout, m.lockAll)
+}
+
+// hasUnlocked 判断是否有已解密的笔记处于打开状态
+func (m *MarkdownEditor) hasUnlocked() bool {
+	for _, editor := range m.openFiles {
+		if editor.key != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNote 写入笔记内容，加密笔记写入加密后的内容
+func writeNote(path, content string, key *noteKey) error {
+	data := []byte(content)
+	if key != nil {
+		var err error
+		if data, err = key.seal(data); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0644)
+}
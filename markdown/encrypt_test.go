@@ -0,0 +1,94 @@
+package markdown
+
+import (
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := newNoteKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := "# 日记\n\n今天 *很好*。\n"
+	sealed, err := key.seal([]byte(plaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(sealed) || isEncrypted([]byte(plaintext)) {
+		t.Fatalf("isEncrypted does not tell sealed notes from plain ones")
+	}
+	again, _ := key.seal([]byte(plaintext))
+	if string(again) == string(sealed) {
+		t.Error("sealing twice produced the same output")
+	}
+
+	got, opened, err := openEncrypted(sealed, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != plaintext {
+		t.Errorf("openEncrypted() = %q, want %q", got, plaintext)
+	}
+	// 打开后得到的密钥可以继续加密保存
+	resealed, err := opened.seal([]byte("changed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := openEncrypted(resealed, "secret"); err != nil || string(got) != "changed" {
+		t.Errorf("reopen resealed note = %q, %v", got, err)
+	}
+
+	if _, _, err := openEncrypted(sealed, "Secret"); err != errWrongPassphrase {
+		t.Errorf("wrong passphrase: err = %v, want %v", err, errWrongPassphrase)
+	}
+	if _, _, err := openEncrypted([]byte(plaintext), "secret"); err != errNotEncrypted {
+		t.Errorf("plain note: err = %v, want %v", err, errNotEncrypted)
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	key, err := newNoteKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := key.seal([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(b *pem.Block)
+		err    error
+	}{
+		{"flipped ciphertext", func(b *pem.Block) { b.Bytes[len(b.Bytes)-1] ^= 1 }, errWrongPassphrase},
+		{"flipped nonce", func(b *pem.Block) { b.Bytes[0] ^= 1 }, errWrongPassphrase},
+		{"truncated", func(b *pem.Block) { b.Bytes = b.Bytes[:4] }, errWrongPassphrase},
+		{"other salt", func(b *pem.Block) { b.Headers["Salt"] = "AAAAAAAAAAAAAAAAAAAAAA==" }, errWrongPassphrase},
+		{"bad salt", func(b *pem.Block) { b.Headers["Salt"] = "!" }, errWrongPassphrase},
+		{"missing N", func(b *pem.Block) { delete(b.Headers, "N") }, errScryptParams},
+		{"N not a number", func(b *pem.Block) { b.Headers["N"] = "lots" }, errScryptParams},
+		{"N not a power of two", func(b *pem.Block) { b.Headers["N"] = "1000" }, errScryptParams},
+		{"N too large", func(b *pem.Block) { b.Headers["N"] = "2097152" }, errScryptParams},
+		{"N one", func(b *pem.Block) { b.Headers["N"] = "1" }, errScryptParams},
+		{"R zero", func(b *pem.Block) { b.Headers["R"] = "0" }, errScryptParams},
+		{"P negative", func(b *pem.Block) { b.Headers["P"] = "-1" }, errScryptParams},
+		{"R*P too large", func(b *pem.Block) { b.Headers["R"], b.Headers["P"] = "32768", "32768" }, errScryptParams},
+		{"R*P overflow", func(b *pem.Block) { b.Headers["R"], b.Headers["P"] = "4611686018427387904", "4" }, errScryptParams},
+	}
+	for _, tt := range tests {
+		block, _ := pem.Decode(sealed)
+		tt.modify(block)
+		if _, _, err := openEncrypted(pem.EncodeToMemory(block), "secret"); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	block, _ := pem.Decode(sealed)
+	block.Headers["Cipher"] = "ChaCha20"
+	if _, _, err := openEncrypted(pem.EncodeToMemory(block), "secret"); err == nil {
+		t.Error("unsupported cipher: want an error")
+	}
+}
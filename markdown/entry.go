@@ -0,0 +1,218 @@
+package markdown
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// noteEntry 是笔记编辑区使用的输入框，在 widget.Entry 的基础上拦截粘贴等操作
+type noteEntry struct {
+	widget.Entry
+
+	// onPaste 在粘贴时调用，返回 true 表示已经处理，不再执行默认的文本粘贴
+	onPaste func() bool
+	// onShortcut 处理输入框自身不支持的快捷键，返回 true 表示已经处理
+	onShortcut func(shortcut *desktop.CustomShortcut) bool
+	// vim 不为 nil 时启用 Vim 模式
+	vim *vimState
+	// key 不为 nil 时笔记是加密笔记，保存时用它重新加密
+	key *noteKey
+
+	shiftDown bool
+}
+
+func newNoteEntry() *noteEntry {
+	e := &noteEntry{}
+	e.MultiLine = true
+	e.Wrapping = fyne.TextWrap(fyne.TextTruncateClip)
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+func (e *noteEntry) TypedShortcut(shortcut fyne.Shortcut) {
+	if _, ok := shortcut.(*fyne.ShortcutPaste); ok && e.onPaste != nil && e.onPaste() {
+		return
+	}
+	if custom, ok := shortcut.(*desktop.CustomShortcut); ok {
+		if e.vim != nil && e.vim.typedShortcut(custom) {
+			return
+		}
+		// 获得焦点的输入框会收到所有快捷键，窗口上注册的快捷键需要由这里转交
+		if e.onShortcut != nil && e.onShortcut(custom) {
+			return
+		}
+	}
+	e.Entry.TypedShortcut(shortcut)
+}
+
+func (e *noteEntry) TypedRune(r rune) {
+	if e.vim != nil && !e.Disabled() && e.vim.typedRune(r) {
+		return
+	}
+	if !e.Disabled() && e.autoPair(r) {
+		return
+	}
+	e.Entry.TypedRune(r)
+}
+
+func (e *noteEntry) TypedKey(key *fyne.KeyEvent) {
+	if e.vim != nil && !e.Disabled() && e.vim.typedKey(key) {
+		return
+	}
+	if !e.Disabled() {
+		switch key.Name {
+		case fyne.KeyReturn, fyne.KeyEnter:
+			if !e.shiftDown && e.continueList() {
+				return
+			}
+		case fyne.KeyTab:
+			if e.tableTab(e.shiftDown) || e.indentList(e.shiftDown) {
+				return
+			}
+		case fyne.KeyBackspace:
+			if e.deletePair() {
+				return
+			}
+		}
+	}
+	e.Entry.TypedKey(key)
+}
+
+// KeyDown 和 KeyUp 记录 Shift 是否按下，用于区分 Tab 和 Shift+Tab
+func (e *noteEntry) KeyDown(key *fyne.KeyEvent) {
+	if key.Name == desktop.KeyShiftLeft || key.Name == desktop.KeyShiftRight {
+		e.shiftDown = true
+	}
+	e.Entry.KeyDown(key)
+}
+
+func (e *noteEntry) KeyUp(key *fyne.KeyEvent) {
+	if key.Name == desktop.KeyShiftLeft || key.Name == desktop.KeyShiftRight {
+		e.shiftDown = false
+	}
+	e.Entry.KeyUp(key)
+}
+
+// insertText 在光标处插入文本（替换当前选区），并保留撤销记录
+func (e *noteEntry) insertText(text string) {
+	e.Entry.TypedShortcut(&fyne.ShortcutPaste{Clipboard: &stringClipboard{content: text}})
+}
+
+// clearSelection 取消当前选区，光标位置保持不变
+func (e *noteEntry) clearSelection() {
+	e.releaseShift()
+	defer e.restoreShift()
+
+	row, col := e.CursorRow, e.CursorColumn
+	e.Entry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyLeft})
+	e.CursorRow, e.CursorColumn = row, col
+}
+
+// releaseShift 和 restoreShift 在用户按住 Shift 时（如 Shift+Tab）临时松开它，
+// 否则输入框会把方向键当作扩展选区处理
+func (e *noteEntry) releaseShift() {
+	if e.shiftDown {
+		e.Entry.KeyUp(&fyne.KeyEvent{Name: desktop.KeyShiftLeft})
+	}
+}
+
+func (e *noteEntry) restoreShift() {
+	if e.shiftDown {
+		e.Entry.KeyDown(&fyne.KeyEvent{Name: desktop.KeyShiftLeft})
+	}
+}
+
+// selectRange 选中从 (row, col) 开始的 n 个字符
+func (e *noteEntry) selectRange(row, col, n int) {
+	lines := strings.Split(e.Text, "\n")
+	endRow, endCol := row, col
+	for endRow < len(lines) {
+		rest := utf8.RuneCountInString(lines[endRow]) - endCol
+		if n <= rest || endRow == len(lines)-1 {
+			endCol += min(n, rest)
+			break
+		}
+		n -= rest + 1
+		endRow, endCol = endRow+1, 0
+	}
+	e.selectBetween(row, col, endRow, endCol)
+}
+
+// selectBetween 选中从 (anchorRow, anchorCol) 到 (row, col) 的文字，光标停在 (row, col)。
+// Entry 没有公开设置选区的方法，这里按住 Shift 移动一次光标进入选择状态，再直接设置光标位置
+func (e *noteEntry) selectBetween(anchorRow, anchorCol, row, col int) {
+	e.clearSelection()
+	e.releaseShift()
+	e.CursorRow, e.CursorColumn = anchorRow, anchorCol
+
+	shift := &fyne.KeyEvent{Name: desktop.KeyShiftLeft}
+	e.Entry.KeyDown(shift)
+	e.Entry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyRight})
+	e.CursorRow, e.CursorColumn = row, col
+	e.Entry.KeyUp(shift)
+	e.restoreShift()
+	e.Refresh()
+}
+
+// replaceText 把编辑器的内容替换为 text，只替换发生变化的部分，修改会进入撤销记录
+func (e *noteEntry) replaceText(text string) {
+	old, updated := []rune(e.Text), []rune(text)
+	prefix := 0
+	for prefix < len(old) && prefix < len(updated) && old[prefix] == updated[prefix] {
+		prefix++
+	}
+	if prefix == len(old) && prefix == len(updated) {
+		return
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(updated)-prefix &&
+		old[len(old)-1-suffix] == updated[len(updated)-1-suffix] {
+		suffix++
+	}
+	row, col := 0, 0
+	for _, r := range old[:prefix] {
+		if r == '\n' {
+			row, col = row+1, 0
+		} else {
+			col++
+		}
+	}
+	e.replaceRange(row, col, len(old)-prefix-suffix, string(updated[prefix:len(updated)-suffix]))
+}
+
+// replaceRange 用 text 替换从 (row, col) 开始的 n 个字符，修改会进入撤销记录
+func (e *noteEntry) replaceRange(row, col, n int, text string) {
+	if n > 0 {
+		e.selectRange(row, col, n)
+	} else {
+		e.clearSelection()
+		e.CursorRow, e.CursorColumn = row, col
+	}
+	if text == "" {
+		// 粘贴空文本不会删除选区，改用退格键
+		if n > 0 {
+			e.releaseShift()
+			e.Entry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+			e.restoreShift()
+		}
+		return
+	}
+	e.insertText(text)
+}
+
+// stringClipboard 是一个只存在于内存中的剪贴板，用于借助 Entry 自带的粘贴逻辑插入文本
+type stringClipboard struct {
+	content string
+}
+
+func (c *stringClipboard) Content() string {
+	return c.content
+}
+
+func (c *stringClipboard) SetContent(content string) {
+	c.content = content
+}
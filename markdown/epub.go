@@ -0,0 +1,362 @@
+package markdown
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// epubIndexNames 是目录中保存书籍信息的笔记，按顺序查找；也可以使用与目录同名的笔记
+var epubIndexNames = []string{"index", "readme"}
+
+// epubChapter 是书中的一章
+type epubChapter struct {
+	path  string
+	file  string // 在 OEBPS 中的文件名
+	title string
+	body  string
+}
+
+// epubBook 把一个目录中的笔记打包为 EPUB 3
+type epubBook struct {
+	m      *MarkdownEditor
+	idx    *vaultIndex
+	folder string
+
+	title    string
+	authors  []string
+	language string
+	modified time.Time
+
+	chapters []*epubChapter
+	images   []string          // 按引用顺序排列的图片路径
+	imageIDs map[string]string // 图片路径 -> 在 OEBPS 中的文件名
+}
+
+// exportEPUB 将 folder 中的笔记导出为 EPUB。笔记按 front matter 中的 order 排序，没有 order 的按文件名排在后面；
+// 书名、作者和语言取自目录中的 index 笔记
+func (m *MarkdownEditor) exportEPUB(folder string) ([]byte, error) {
+	idx, err := m.buildIndex()
+	if err != nil {
+		return nil, err
+	}
+	b := &epubBook{
+		m:        m,
+		idx:      idx,
+		folder:   folder,
+		title:    filepath.Base(mustAbs(folder)),
+		language: "zh-CN",
+		imageIDs: map[string]string{},
+	}
+
+	var notes []*noteInfo
+	var index *noteInfo
+	for _, path := range idx.paths {
+		if _, ok := relUnder(folder, path); !ok {
+			continue
+		}
+		info := idx.notes[path]
+		if info.Encrypted {
+			continue
+		}
+		if index == nil && b.isIndex(path) {
+			index = info
+			continue
+		}
+		notes = append(notes, info)
+	}
+	if len(notes) == 0 && index == nil {
+		return nil, fmt.Errorf("目录 %s 中没有笔记", idx.relPath(folder))
+	}
+
+	if index != nil {
+		if title, ok := index.Meta["title"].(string); ok && strings.TrimSpace(title) != "" {
+			b.title = strings.TrimSpace(title)
+		}
+		b.authors = metaAuthors(index.Meta["author"])
+		if len(b.authors) == 0 {
+			b.authors = metaAuthors(index.Meta["authors"])
+		}
+		if lang, ok := index.Meta["language"].(string); ok && lang != "" {
+			b.language = lang
+		} else if lang, ok := index.Meta["lang"].(string); ok && lang != "" {
+			b.language = lang
+		}
+		// index 笔记的正文作为前言放在最前面
+		if strings.TrimSpace(stripFrontMatter(index.Content)) != "" {
+			b.chapters = append(b.chapters, &epubChapter{path: index.Path})
+		}
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		oi, iok := noteOrder(notes[i])
+		oj, jok := noteOrder(notes[j])
+		if iok != jok {
+			return iok
+		}
+		if iok && oi != oj {
+			return oi < oj
+		}
+		return strings.ToLower(idx.relPath(notes[i].Path)) < strings.ToLower(idx.relPath(notes[j].Path))
+	})
+	for _, info := range notes {
+		b.chapters = append(b.chapters, &epubChapter{path: info.Path})
+	}
+	for i, ch := range b.chapters {
+		ch.file = fmt.Sprintf("chapter%03d.xhtml", i+1)
+		if info := idx.notes[ch.path]; info.ModTime.After(b.modified) {
+			b.modified = info.ModTime
+		}
+	}
+	if b.modified.IsZero() {
+		b.modified = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	b.modified = b.modified.UTC().Truncate(time.Second)
+
+	if err := b.render(); err != nil {
+		return nil, err
+	}
+	return b.pack()
+}
+
+// isIndex 判断笔记是否为保存书籍信息的 index 笔记
+func (b *epubBook) isIndex(path string) bool {
+	if filepath.Dir(path) != filepath.Clean(b.folder) {
+		return false
+	}
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	for _, n := range epubIndexNames {
+		if name == n {
+			return true
+		}
+	}
+	return name == strings.ToLower(filepath.Base(mustAbs(b.folder)))
+}
+
+// metaAuthors 读取作者列表，字符串形式的作者只按逗号和顿号分隔，保留名字中的空格
+func metaAuthors(v interface{}) []string {
+	s, ok := v.(string)
+	if !ok {
+		return metaStrings(v)
+	}
+	var authors []string
+	for _, author := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' || r == '、' }) {
+		if author = strings.TrimSpace(author); author != "" {
+			authors = append(authors, author)
+		}
+	}
+	return authors
+}
+
+// noteOrder 返回 front matter 中的 order 字段
+func noteOrder(info *noteInfo) (float64, bool) {
+	switch v := info.Meta["order"].(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func (b *epubBook) render() error {
+	files := map[string]string{}
+	published := map[string]bool{}
+	for _, ch := range b.chapters {
+		files[ch.path] = ch.file
+		published[ch.path] = true
+	}
+
+	x := &htmlExporter{
+		m:         b.m,
+		idx:       b.idx,
+		linkNotes: true,
+		published: published,
+		xhtml:     true,
+		noteHref:  func(_, to string) string { return files[to] },
+		imageHref: func(_, path string) string { return b.image(path) },
+	}
+	for _, ch := range b.chapters {
+		body, title, err := x.render(ch.path, b.idx.notes[ch.path].Content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", b.idx.relPath(ch.path), err)
+		}
+		if !strings.HasPrefix(body, "<h1") {
+			body = "<h1>" + html.EscapeString(title) + "</h1>\n" + body
+		}
+		ch.title, ch.body = title, body
+	}
+	return nil
+}
+
+// image 登记章节中引用的图片，返回在书中的地址
+func (b *epubBook) image(path string) string {
+	name, ok := b.imageIDs[path]
+	if !ok {
+		name = fmt.Sprintf("images/image%03d%s", len(b.images)+1, strings.ToLower(filepath.Ext(path)))
+		b.imageIDs[path] = name
+		b.images = append(b.images, path)
+	}
+	return name
+}
+
+// identifier 根据书名和作者生成稳定的 UUID，同一本书每次导出得到相同的标识
+func (b *epubBook) identifier() string {
+	sum := sha1.Sum([]byte(b.title + "\x00" + strings.Join(b.authors, "\x00")))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// pack 生成 EPUB 文件，文件顺序和时间都是固定的，同样的笔记每次得到相同的输出
+func (b *epubBook) pack() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name string, data []byte, method uint16) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: b.modified})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	// mimetype 必须是第一个文件且不压缩
+	if err := write("mimetype", []byte("application/epub+zip"), zip.Store); err != nil {
+		return nil, err
+	}
+	if err := write("META-INF/container.xml", []byte(epubContainer), zip.Deflate); err != nil {
+		return nil, err
+	}
+	if err := write("OEBPS/content.opf", b.opf(), zip.Deflate); err != nil {
+		return nil, err
+	}
+	if err := write("OEBPS/nav.xhtml", b.nav(), zip.Deflate); err != nil {
+		return nil, err
+	}
+	if err := write("OEBPS/style.css", []byte(exportStyle), zip.Deflate); err != nil {
+		return nil, err
+	}
+	for _, ch := range b.chapters {
+		if err := write("OEBPS/"+ch.file, b.xhtml(ch.title, ch.body), zip.Deflate); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range b.images {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := write("OEBPS/"+b.imageIDs[path], data, zip.Store); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func (b *epubBook) opf() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">` + "\n")
+	buf.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	fmt.Fprintf(&buf, "    <dc:identifier id=\"book-id\">%s</dc:identifier>\n", b.identifier())
+	fmt.Fprintf(&buf, "    <dc:title>%s</dc:title>\n", html.EscapeString(b.title))
+	for _, author := range b.authors {
+		fmt.Fprintf(&buf, "    <dc:creator>%s</dc:creator>\n", html.EscapeString(author))
+	}
+	fmt.Fprintf(&buf, "    <dc:language>%s</dc:language>\n", html.EscapeString(b.language))
+	fmt.Fprintf(&buf, "    <meta property=\"dcterms:modified\">%s</meta>\n", b.modified.Format("2006-01-02T15:04:05Z"))
+	buf.WriteString("  </metadata>\n  <manifest>\n")
+	buf.WriteString(`    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	buf.WriteString(`    <item id="style" href="style.css" media-type="text/css"/>` + "\n")
+	for i, ch := range b.chapters {
+		fmt.Fprintf(&buf, "    <item id=\"chapter%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, ch.file)
+	}
+	for i, path := range b.images {
+		mediaType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+		fmt.Fprintf(&buf, "    <item id=\"image%d\" href=\"%s\" media-type=\"%s\"/>\n", i+1, b.imageIDs[path], mediaType)
+	}
+	buf.WriteString("  </manifest>\n  <spine>\n")
+	for i := range b.chapters {
+		fmt.Fprintf(&buf, "    <itemref idref=\"chapter%d\"/>\n", i+1)
+	}
+	buf.WriteString("  </spine>\n</package>\n")
+	return buf.Bytes()
+}
+
+func (b *epubBook) nav() []byte {
+	var body bytes.Buffer
+	body.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n<h1>目录</h1>\n<ol>\n")
+	for _, ch := range b.chapters {
+		fmt.Fprintf(&body, "<li><a href=\"%s\">%s</a></li>\n", ch.file, html.EscapeString(ch.title))
+	}
+	body.WriteString("</ol>\n</nav>\n")
+	return b.xhtml(b.title, body.String())
+}
+
+// xhtml 把正文包装为 EPUB 中的 XHTML 文档
+func (b *epubBook) xhtml(title, body string) []byte {
+	var buf bytes.Buffer
+	lang := html.EscapeString(b.language)
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<!DOCTYPE html>\n")
+	fmt.Fprintf(&buf, "<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" lang=\"%s\" xml:lang=\"%s\">\n", lang, lang)
+	fmt.Fprintf(&buf, "<head>\n<meta charset=\"utf-8\"/>\n<title>%s</title>\n", html.EscapeString(title))
+	buf.WriteString("<link rel=\"stylesheet\" type=\"text/css\" href=\"style.css\"/>\n</head>\n<body>\n")
+	buf.WriteString(body)
+	buf.WriteString("</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+// showExportEPUB 选择目录并导出为 EPUB
+func (m *MarkdownEditor) showExportEPUB() {
+	idx, err := m.buildIndex()
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	folderSelect := widget.NewSelect(idx.folders(), nil)
+	folderSelect.SetSelectedIndex(0)
+	if uid := m.selectedNode; uid != "" && m.isBranch(uid) {
+		folderSelect.SetSelected(filepath.ToSlash(string(uid)))
+	}
+
+	m.showCustomFormDialog("Export as EPUB", "Export", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Folder", folderSelect),
+	}, func(ok bool) {
+		if !ok || folderSelect.Selected == "" {
+			return
+		}
+		folder := filepath.Join(m.rootPath, filepath.FromSlash(folderSelect.Selected))
+		data, err := m.exportEPUB(folder)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		m.saveExport(folder, ".epub", data)
+	}, m.window)
+}
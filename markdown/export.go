@@ -0,0 +1,436 @@
+package markdown
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// exportStyle 是导出的 HTML 中内嵌的样式表
+const exportStyle = `
+body { max-width: 760px; margin: 2em auto; padding: 0 1em; font: 16px/1.7 -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; color: #24292f; background: #fff; }
+h1, h2, h3, h4, h5, h6 { line-height: 1.3; margin: 1.4em 0 .6em; }
+h1, h2 { border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+a { color: #0969da; text-decoration: none; }
+a:hover { text-decoration: underline; }
+code, pre { font-family: "SFMono-Regular", Menlo, Consolas, monospace; font-size: .9em; background: #f6f8fa; border-radius: 4px; }
+code { padding: .15em .35em; }
+pre { padding: 1em; overflow: auto; line-height: 1.45; }
+pre code { padding: 0; background: none; }
+blockquote { margin: 0; padding: 0 1em; color: #57606a; border-left: 4px solid #d0d7de; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #d0d7de; padding: .4em .8em; }
+th { background: #f6f8fa; }
+img { max-width: 100%; }
+hr { border: 0; border-top: 1px solid #d0d7de; }
+li > input[type=checkbox] { margin-right: .4em; }
+ul:has(> li > input[type=checkbox]) { list-style: none; padding-left: 1.2em; }
+@media (prefers-color-scheme: dark) {
+  body { color: #c9d1d9; background: #0d1117; }
+  a { color: #58a6ff; }
+  code, pre, th { background: #161b22; }
+  h1, h2, th, td, hr { border-color: #30363d; }
+  blockquote { color: #8b949e; border-color: #30363d; }
+}
+`
+
+// htmlExporter 把笔记转换为 HTML
+type htmlExporter struct {
+	m   *MarkdownEditor
+	idx *vaultIndex
+
+	// linkNotes 为 true 时 wiki 链接转换为指向 .html 文件的相对链接，否则输出为纯文本
+	linkNotes bool
+	// published 不为 nil 时只生成指向其中笔记的链接
+	published map[string]bool
+	// imageHref 返回页面中引用本地图片的地址，为 nil 时内联为 data URI
+	imageHref func(notePath, path string) string
+	// noteHref 返回页面中指向另一篇笔记的地址，为 nil 时使用 htmlLink
+	noteHref func(from, to string) string
+	// xhtml 为 true 时输出 XHTML，并且不输出笔记中的原始 HTML
+	xhtml bool
+}
+
+func (x *htmlExporter) markdown() goldmark.Markdown {
+	options := []renderer.Option{
+		renderer.WithNodeRenderers(util.Prioritized(&wikiLinkRenderer{xhtml: x.xhtml}, 100)),
+	}
+	if x.xhtml {
+		options = append(options, gmhtml.WithXHTML())
+	} else {
+		options = append(options, gmhtml.WithUnsafe())
+	}
+	return goldmark.New(
+		goldmark.WithExtensions(extension.GFM, wikiLinks{}),
+		goldmark.WithRendererOptions(options...),
+	)
+}
+
+// render 将笔记正文转换为 HTML 片段，同时返回笔记标题
+func (x *htmlExporter) render(notePath, content string) (string, string, error) {
+	md := x.markdown()
+	source := []byte(stripFrontMatter(content))
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	title := noteTitle(notePath, content, source, doc)
+	ids := map[string]int{}
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Heading:
+			t.SetAttributeString("id", []byte(uniqueID(ids, headingID(headingText(source, t)))))
+		case *ast.Image:
+			if src := x.imageSource(notePath, string(t.Destination)); src != "" {
+				t.Destination = []byte(src)
+			}
+		case *ast.Link:
+			if href := x.linkSource(notePath, string(t.Destination)); href != "" {
+				t.Destination = []byte(href)
+			}
+		case *wikiLinkNode:
+			x.resolveWikiLink(notePath, t)
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		return "", "", err
+	}
+	return buf.String(), title, nil
+}
+
+// noteTitle 返回笔记的标题：依次使用 front matter 中的 title、第一个一级标题和文件名
+func noteTitle(notePath, content string, source []byte, doc ast.Node) string {
+	if meta := parseFrontMatter(content); meta != nil {
+		if t, ok := meta["title"].(string); ok && strings.TrimSpace(t) != "" {
+			return strings.TrimSpace(t)
+		}
+	}
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if h, ok := n.(*ast.Heading); ok && h.Level == 1 {
+			return headingText(source, h)
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(notePath), filepath.Ext(notePath))
+}
+
+// resolveWikiLink 根据导出选项填写 wiki 链接的地址，嵌入的图片直接内联
+func (x *htmlExporter) resolveWikiLink(notePath string, n *wikiLinkNode) {
+	if n.Embed && isImage(n.Target) {
+		if path := x.m.findAttachment(notePath, n.Target); path != "" {
+			n.Href = x.image(notePath, path)
+		}
+		return
+	}
+	if !x.linkNotes || x.idx == nil {
+		return
+	}
+	target := notePath
+	if n.Target != "" {
+		target = x.idx.resolveName(notePath, n.Target)
+	}
+	if !x.canLink(target) {
+		return
+	}
+	href := ""
+	if target != notePath {
+		href = x.noteLink(notePath, target)
+	}
+	if n.Anchor != "" {
+		href += "#" + anchorID(n.Anchor)
+	}
+	n.Href = href
+}
+
+// imageSource 把本地图片转换为 data URI，外部图片或找不到的文件返回空字符串
+func (x *htmlExporter) imageSource(notePath, dest string) string {
+	if dest == "" || isExternalLink(dest) {
+		return ""
+	}
+	if unescaped, err := url.PathUnescape(dest); err == nil {
+		dest = unescaped
+	}
+	path := resolveRelative(notePath, dest)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return x.image(notePath, path)
+}
+
+func (x *htmlExporter) image(notePath, path string) string {
+	if x.imageHref != nil {
+		return x.imageHref(notePath, path)
+	}
+	return dataURI(path)
+}
+
+// linkSource 把指向笔记的 Markdown 链接改为指向导出的 .html 页面，不需要修改时返回空字符串
+func (x *htmlExporter) linkSource(notePath, dest string) string {
+	if !x.linkNotes || x.idx == nil || dest == "" || isExternalLink(dest) || strings.HasPrefix(dest, "#") {
+		return ""
+	}
+	target, anchor := splitAnchor(dest)
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	path := x.idx.resolve(notePath, noteLink{Target: target})
+	if !x.canLink(path) {
+		return ""
+	}
+	href := x.noteLink(notePath, path)
+	if anchor != "" {
+		href += "#" + anchor
+	}
+	return href
+}
+
+func (x *htmlExporter) noteLink(from, to string) string {
+	if x.noteHref != nil {
+		return x.noteHref(from, to)
+	}
+	return htmlLink(from, to)
+}
+
+// canLink 判断导出的页面能否链接到 path
+func (x *htmlExporter) canLink(path string) bool {
+	return path != "" && (x.published == nil || x.published[path])
+}
+
+// findAttachment 按 wiki 链接的写法查找附件：先找笔记所在目录，再找附件目录，最后在整个笔记库中按文件名查找
+func (m *MarkdownEditor) findAttachment(notePath, name string) string {
+	name = filepath.FromSlash(name)
+	candidates := []string{filepath.Join(filepath.Dir(notePath), name)}
+	if dir, err := m.attachmentDir(notePath); err == nil {
+		candidates = append(candidates, filepath.Join(dir, name))
+	}
+	candidates = append(candidates, filepath.Join(m.rootPath, name))
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	found := ""
+	base := strings.ToLower(filepath.Base(name))
+	walkFiles(m.rootPath, func(path string) error {
+		if strings.ToLower(filepath.Base(path)) == base {
+			found = path
+			return io.EOF // 找到后停止遍历
+		}
+		return nil
+	})
+	return found
+}
+
+// dataURI 读取文件并编码为 data URI，读取失败时返回空字符串
+func dataURI(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fyne.LogError("Failed to read image "+path, err)
+		return ""
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// htmlLink 返回从 from 导出的页面指向 to 导出的页面的相对链接
+func htmlLink(from, to string) string {
+	rel, err := filepath.Rel(filepath.Dir(from), to)
+	if err != nil {
+		rel = filepath.Base(to)
+	}
+	rel = strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel)) + ".html"
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// headingID 根据标题文字生成 HTML 中的 id：转为小写，空白替换为 -，去掉标点
+func headingID(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('-')
+		}
+	}
+	if b.Len() == 0 {
+		return "section"
+	}
+	return b.String()
+}
+
+// uniqueID 避免同一页面中出现重复的 id，重复时依次加上 -1、-2 等后缀
+func uniqueID(ids map[string]int, id string) string {
+	n := ids[id]
+	ids[id] = n + 1
+	if n == 0 {
+		return id
+	}
+	return id + "-" + strconv.Itoa(n)
+}
+
+// anchorID 把 wiki 链接中 # 之后的部分转换为页面内的 id，块 ID（^abc）保持原样
+func anchorID(anchor string) string {
+	if strings.HasPrefix(anchor, "^") {
+		return url.PathEscape(anchor)
+	}
+	return url.PathEscape(headingID(anchor))
+}
+
+// htmlDocument 把 HTML 片段包装为带有内嵌样式的完整页面
+func htmlDocument(title, body string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&buf, "<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&buf, "<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n", html.EscapeString(title), exportStyle)
+	buf.WriteString(body)
+	buf.WriteString("</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+// wikiLinkRenderer 输出 wiki 链接：嵌入的图片输出为 <img>，解析成功的链接输出为 <a>，其余输出为纯文本
+type wikiLinkRenderer struct {
+	xhtml bool
+}
+
+func (r *wikiLinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindWikiLink, r.render)
+}
+
+func (r *wikiLinkRenderer) render(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*wikiLinkNode)
+	switch {
+	case n.Embed && isImage(n.Target) && n.Href != "":
+		fmt.Fprintf(w, `<img src="%s" alt="%s"`, html.EscapeString(n.Href), html.EscapeString(n.Target))
+		// ![[图片|300]] 指定显示宽度
+		if width, err := strconv.Atoi(n.Label); err == nil && width > 0 {
+			fmt.Fprintf(w, ` width="%d"`, width)
+		}
+		if r.xhtml {
+			w.WriteString(" />")
+		} else {
+			w.WriteString(">")
+		}
+	case n.Href != "":
+		fmt.Fprintf(w, `<a href="%s" class="wikilink">%s</a>`, html.EscapeString(n.Href), html.EscapeString(n.Label))
+	default:
+		w.WriteString(html.EscapeString(n.Label))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// exportHTML 把笔记导出为单个 HTML 文件，本地图片以 data URI 内联
+func (m *MarkdownEditor) exportHTML(notePath string, linkNotes bool) ([]byte, error) {
+	content, err := m.noteContent(notePath)
+	if err != nil {
+		return nil, err
+	}
+	x := &htmlExporter{m: m, linkNotes: linkNotes}
+	if linkNotes {
+		if x.idx, err = m.buildIndex(); err != nil {
+			return nil, err
+		}
+	}
+	body, title, err := x.render(notePath, content)
+	if err != nil {
+		return nil, err
+	}
+	return htmlDocument(title, body), nil
+}
+
+// showExportHTML 将当前标签页中的笔记导出为 HTML
+func (m *MarkdownEditor) showExportHTML() {
+	notePath, _ := m.currentFile()
+	if notePath == "" {
+		dialog.ShowInformation("提示", "请先打开一篇笔记", m.window)
+		return
+	}
+
+	linkCheck := widget.NewCheck("Convert to relative links", nil)
+	linkCheck.SetChecked(m.config.ExportWikiLinks)
+	m.showCustomFormDialog("Export as HTML", "Export", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Wiki links", linkCheck),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		if m.config.ExportWikiLinks != linkCheck.Checked {
+			m.config.ExportWikiLinks = linkCheck.Checked
+			if err := m.saveConfig(); err != nil {
+				fyne.LogError("Failed to save vault config", err)
+			}
+		}
+
+		data, err := m.exportHTML(notePath, linkCheck.Checked)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		m.saveExport(notePath, ".html", data)
+	}, m.window)
+}
+
+// saveExport 弹出保存对话框，默认文件名与笔记同名
+func (m *MarkdownEditor) saveExport(notePath, ext string, data []byte) {
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := writer.Write(data); err != nil {
+			dialog.ShowError(err, m.window)
+		}
+	}, m.window)
+	save.SetFileName(strings.TrimSuffix(filepath.Base(notePath), filepath.Ext(notePath)) + ext)
+	if dir, err := storage.ListerForURI(storage.NewFileURI(filepath.Dir(notePath))); err == nil {
+		save.SetLocation(dir)
+	}
+	save.Show()
+}
@@ -0,0 +1,92 @@
+package markdown
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportHTML(t *testing.T) {
+	m := newTestEditor(t)
+	files := map[string]string{
+		"pic.png":        "\x89PNG\r\n\x1a\n",
+		"notes/other.md": "# Other\n## Sub Section\n",
+		"notes/page.md": "---\ntitle: A & B\n---\n# Heading\n## Same\n## Same\n## 中文 标题!\n" +
+			"Link to [[other#Sub Section|the other]] and [md](other.md#x) and [[missing]].\n\n" +
+			"![[pic.png|120]] ![x](../pic.png) ![r](https://e.com/a.png)\n\n- [x] done\n- [ ] todo\n\n<span>raw</span>\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(m.rootPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const head = "<h1 id=\"heading\">Heading</h1>\n<h2 id=\"same\">Same</h2>\n<h2 id=\"same-1\">Same</h2>\n" +
+		"<h2 id=\"中文-标题\">中文 标题!</h2>\n"
+	const tail = "<p><img src=\"data:image/png;base64,iVBORw0KGgo=\" alt=\"pic.png\" width=\"120\"> " +
+		"<img src=\"data:image/png;base64,iVBORw0KGgo=\" alt=\"x\"> <img src=\"https://e.com/a.png\" alt=\"r\"></p>\n" +
+		"<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n" +
+		"<li><input disabled=\"\" type=\"checkbox\"> todo</li>\n</ul>\n<p><span>raw</span></p>\n"
+	tests := []struct {
+		linkNotes bool
+		links     string
+	}{
+		{true, "<p>Link to <a href=\"other.html#sub-section\" class=\"wikilink\">the other</a> and " +
+			"<a href=\"other.html#x\">md</a> and missing.</p>\n"},
+		{false, "<p>Link to the other and <a href=\"other.md#x\">md</a> and missing.</p>\n"},
+	}
+	page := filepath.Join(m.rootPath, "notes", "page.md")
+	for _, tt := range tests {
+		data, err := m.exportHTML(page, tt.linkNotes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := string(data)
+		if !strings.Contains(doc, "<title>A &amp; B</title>") {
+			t.Errorf("linkNotes=%v: title not taken from front matter", tt.linkNotes)
+		}
+		body := doc[strings.Index(doc, "<body>\n")+len("<body>\n") : strings.Index(doc, "</body>")]
+		if want := head + tt.links + tail; body != want {
+			t.Errorf("linkNotes=%v: body =\n%s\nwant\n%s", tt.linkNotes, body, want)
+		}
+	}
+
+	key, err := newNoteKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := key.seal([]byte("# Secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(m.rootPath, "secret.md")
+	if err := os.WriteFile(secret, sealed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.exportHTML(secret, false); !errors.Is(err, errEncryptedNote) {
+		t.Errorf("exporting a locked note: err = %v, want %v", err, errEncryptedNote)
+	}
+}
+
+func TestHeadingID(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Hello World", "hello-world"},
+		{"  What's new?  ", "whats-new"},
+		{"snake_case-name", "snake_case-name"},
+		{"中文 标题", "中文-标题"},
+		{"!!!", "section"},
+	}
+	for _, tt := range tests {
+		if got := headingID(tt.text); got != tt.want {
+			t.Errorf("headingID(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
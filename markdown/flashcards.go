@@ -0,0 +1,358 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 带有 #flashcards 标签的笔记中的卡片有两种写法：
+//
+//	问题 :: 答案
+//
+// 或者问题和答案各占若干行，中间用单独一行的 ? 分隔，答案到空行为止：
+//
+//	问题的第一行
+//	问题的第二行
+//	?
+//	答案
+//
+// 复习记录保存在 .nodian/flashcards.json 中，按问题文字区分卡片，笔记本身不被修改
+
+// flashcardTag 是包含卡片的笔记的标签，子标签如 #flashcards/english 也会匹配
+const flashcardTag = "flashcards"
+
+// SM-2 算法的参数
+const (
+	initialEase = 2.5
+	minEase     = 1.3
+	easyBonus   = 1.3
+	hardFactor  = 1.2
+	matureDays  = 21 // 间隔达到该天数的卡片视为已掌握
+)
+
+// flashcard 是笔记中的一张卡片
+type flashcard struct {
+	Path     string
+	Line     int // 问题所在的第一行
+	Question string
+	Answer   string
+}
+
+// id 返回卡片的标识，使用问题文字的哈希，笔记改名或移动后复习记录仍然有效
+func (c flashcard) id() string {
+	sum := sha256.Sum256([]byte(c.Question))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cardState 是一张卡片的复习记录
+type cardState struct {
+	Due      string  `json:"due"` // 下次复习的日期，格式为 2006-01-02
+	Interval int     `json:"interval"`
+	Ease     float64 `json:"ease"`
+	Reps     int     `json:"reps"` // 连续答对的次数
+	Lapses   int     `json:"lapses"`
+	Reviewed string  `json:"reviewed"`
+}
+
+// cardGrade 是复习时的评分
+type cardGrade int
+
+const (
+	gradeAgain cardGrade = iota
+	gradeHard
+	gradeGood
+	gradeEasy
+)
+
+var gradeLabels = []string{"Again", "Hard", "Good", "Easy"}
+
+// review 按 SM-2 算法根据评分计算新的复习记录。Again 重新开始学习并降低难度系数，
+// Hard、Good、Easy 分别对应 SM-2 中的 3、4、5 分
+func (s cardState) review(grade cardGrade, today time.Time) cardState {
+	if s.Ease == 0 {
+		s.Ease = initialEase
+	}
+	if grade == gradeAgain {
+		s.Reps = 0
+		s.Interval = 1
+		s.Lapses++
+		s.Ease = math.Max(minEase, s.Ease-0.2)
+	} else {
+		q := float64(grade) + 2
+		switch {
+		case s.Reps == 0:
+			s.Interval = 1
+		case s.Reps == 1:
+			s.Interval = 6
+		case grade == gradeHard:
+			s.Interval = int(math.Round(float64(s.Interval) * hardFactor))
+		default:
+			s.Interval = int(math.Round(float64(s.Interval) * s.Ease))
+		}
+		if grade == gradeEasy {
+			s.Interval = int(math.Round(float64(s.Interval) * easyBonus))
+		}
+		s.Interval = max(s.Interval, 1)
+		s.Reps++
+		s.Ease = math.Max(minEase, s.Ease+0.1-(5-q)*(0.08+(5-q)*0.02))
+	}
+	s.Ease = math.Round(s.Ease*100) / 100
+	s.Due = today.AddDate(0, 0, s.Interval).Format("2006-01-02")
+	s.Reviewed = today.Format("2006-01-02")
+	return s
+}
+
+// isDue 判断卡片今天是否需要复习，没有复习记录的新卡片总是需要复习
+func (s cardState) isDue(today time.Time) bool {
+	return s.Due == "" || s.Due <= today.Format("2006-01-02")
+}
+
+// parseFlashcards 解析笔记中的卡片，跳过 front matter 和代码块
+func parseFlashcards(path, content string) []flashcard {
+	lines := strings.Split(content, "\n")
+	text := make([]bool, len(lines))
+	forEachTextLine(content, func(i int, _ string) { text[i] = true })
+	blank := func(i int) bool {
+		return !text[i] || strings.TrimSpace(lines[i]) == ""
+	}
+
+	var cards []flashcard
+	for i := range lines {
+		if !text[i] {
+			continue
+		}
+		line := strings.TrimSpace(lines[i])
+		if q, a, ok := strings.Cut(line, "::"); ok {
+			// 去掉列表标记，如 "- 问题 :: 答案"
+			if m := mdListPattern.FindStringSubmatchIndex(q); m != nil {
+				q = q[m[1]:]
+			}
+			q, a = strings.TrimSpace(q), strings.TrimSpace(a)
+			if q != "" && a != "" {
+				cards = append(cards, flashcard{Path: path, Line: i, Question: q, Answer: a})
+			}
+			continue
+		}
+		if line != "?" {
+			continue
+		}
+
+		// 问题是 ? 之前到空行或标题为止的各行，答案是 ? 之后到空行为止的各行
+		start := i
+		for start > 0 && !blank(start-1) {
+			if _, _, ok := parseHeading(lines[start-1]); ok {
+				break
+			}
+			start--
+		}
+		end := i + 1
+		for end < len(lines) && !blank(end) {
+			end++
+		}
+		q := strings.TrimSpace(strings.Join(lines[start:i], "\n"))
+		a := strings.TrimSpace(strings.Join(lines[i+1:end], "\n"))
+		if q != "" && a != "" {
+			cards = append(cards, flashcard{Path: path, Line: start, Question: q, Answer: a})
+		}
+	}
+	return cards
+}
+
+// flashcardDeck 是一篇笔记中的所有卡片
+type flashcardDeck struct {
+	name  string
+	cards []flashcard
+}
+
+// loadFlashcards 读取所有带 #flashcards 标签的笔记中的卡片和复习记录
+func (m *MarkdownEditor) loadFlashcards() ([]flashcardDeck, map[string]cardState, error) {
+	idx, err := m.buildIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	var decks []flashcardDeck
+	for _, path := range idx.paths {
+		info := idx.notes[path]
+		if !info.hasTag(flashcardTag) {
+			continue
+		}
+		if cards := parseFlashcards(path, info.Content); len(cards) > 0 {
+			decks = append(decks, flashcardDeck{name: strings.TrimSuffix(idx.relPath(path), noteExt), cards: cards})
+		}
+	}
+	states := map[string]cardState{}
+	if err := m.readMeta("flashcards.json", &states); err != nil {
+		return nil, nil, err
+	}
+	return decks, states, nil
+}
+
+// deckSummary 返回卡组的统计：卡片总数、新卡片、今天待复习和已掌握的数量
+func deckSummary(cards []flashcard, states map[string]cardState, today time.Time) string {
+	var fresh, due, mature int
+	for _, c := range cards {
+		s, ok := states[c.id()]
+		switch {
+		case !ok:
+			fresh++
+		case s.isDue(today):
+			due++
+		}
+		if ok && s.Interval >= matureDays {
+			mature++
+		}
+	}
+	return fmt.Sprintf("%d cards · %d new · %d due · %d mature", len(cards), fresh, due, mature)
+}
+
+// showFlashcards 在标签页中显示卡组列表和每个卡组的统计
+func (m *MarkdownEditor) showFlashcards() {
+	decks, states, err := m.loadFlashcards()
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	today := time.Now()
+
+	list := widget.NewList(
+		func() int { return len(decks) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabel("")
+			title.TextStyle = fyne.TextStyle{Bold: true}
+			return container.NewBorder(nil, nil, nil, widget.NewButton("Review", nil), container.NewVBox(title, widget.NewLabel("")))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			deck := decks[id]
+			c := item.(*fyne.Container)
+			labels := c.Objects[0].(*fyne.Container).Objects
+			labels[0].(*widget.Label).SetText(deck.name)
+			labels[1].(*widget.Label).SetText(deckSummary(deck.cards, states, today))
+			c.Objects[1].(*widget.Button).OnTapped = func() { m.reviewFlashcards(deck.cards, states) }
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		list.UnselectAll()
+		m.openFileAt(decks[id].cards[0].Path, decks[id].cards[0].Line)
+	}
+
+	var all []flashcard
+	for _, deck := range decks {
+		all = append(all, deck.cards...)
+	}
+	summary := widget.NewLabel("All decks: " + deckSummary(all, states, today))
+	if len(decks) == 0 {
+		summary.SetText("没有找到卡片。在笔记中添加 #flashcards 标签，并用 \"问题 :: 答案\" 或单独一行的 ? 分隔问题和答案")
+		summary.Wrapping = fyne.TextWrapWord
+	}
+	toolbar := container.NewHBox(
+		widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), m.showFlashcards),
+		widget.NewButton("Review All", func() { m.reviewFlashcards(all, states) }),
+		summary,
+	)
+	m.showViewTab("Flashcards", container.NewBorder(toolbar, nil, nil, nil, list))
+}
+
+// reviewFlashcards 依次复习今天到期的卡片。评分为 Again 的卡片在本次复习的最后再出现一次
+func (m *MarkdownEditor) reviewFlashcards(cards []flashcard, states map[string]cardState) {
+	today := time.Now()
+	var queue []flashcard
+	for _, c := range cards {
+		if states[c.id()].isDue(today) {
+			queue = append(queue, c)
+		}
+	}
+	if len(queue) == 0 {
+		dialog.ShowInformation("Flashcards", "今天没有需要复习的卡片", m.window)
+		return
+	}
+	// 先复习到期较早的卡片，新卡片放在最后
+	sort.SliceStable(queue, func(i, j int) bool {
+		a, b := states[queue[i].id()].Due, states[queue[j].id()].Due
+		return a != "" && (b == "" || a < b)
+	})
+
+	reviewed := 0
+	progress := widget.NewLabel("")
+	source := widget.NewLabel("")
+	source.TextStyle = fyne.TextStyle{Italic: true}
+	question := widget.NewRichText()
+	question.Wrapping = fyne.TextWrapWord
+	answer := widget.NewRichText()
+	answer.Wrapping = fyne.TextWrapWord
+	showButton := widget.NewButton("Show Answer", nil)
+	showButton.Importance = widget.HighImportance
+	gradeButtons := make([]*widget.Button, len(gradeLabels))
+	grades := container.NewGridWithColumns(len(gradeLabels))
+	for i := range gradeButtons {
+		gradeButtons[i] = widget.NewButton(gradeLabels[i], nil)
+		grades.Add(gradeButtons[i])
+	}
+
+	var show func()
+	show = func() {
+		if len(queue) == 0 {
+			dialog.ShowInformation("Flashcards", fmt.Sprintf("本次复习完成，共复习 %d 张卡片", reviewed), m.window)
+			m.showFlashcards()
+			return
+		}
+		card := queue[0]
+		progress.SetText(fmt.Sprintf("%d left · %d reviewed", len(queue), reviewed))
+		source.SetText(strings.TrimSuffix(m.vaultRelPath(card.Path), noteExt))
+		question.Segments = newPreviewRenderer(card.Question, card.Path).render()
+		question.Refresh()
+		answer.Segments = newPreviewRenderer(card.Answer, card.Path).render()
+		answer.Refresh()
+		answer.Hide()
+		grades.Hide()
+		showButton.Show()
+
+		state := states[card.id()]
+		for i, button := range gradeButtons {
+			grade := cardGrade(i)
+			next := state.review(grade, today)
+			button.SetText(fmt.Sprintf("%s (%dd)", gradeLabels[i], next.Interval))
+			button.OnTapped = func() {
+				states[card.id()] = next
+				if err := m.writeMeta("flashcards.json", states); err != nil {
+					dialog.ShowError(err, m.window)
+					return
+				}
+				queue = queue[1:]
+				if grade == gradeAgain {
+					queue = append(queue, card)
+				} else {
+					reviewed++
+				}
+				show()
+			}
+		}
+	}
+	showButton.OnTapped = func() {
+		answer.Show()
+		showButton.Hide()
+		grades.Show()
+	}
+
+	edit := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+		if len(queue) > 0 {
+			m.openFileAt(queue[0].Path, queue[0].Line)
+		}
+	})
+	header := container.NewHBox(widget.NewButtonWithIcon("", theme.NavigateBackIcon(), m.showFlashcards), progress, layout.NewSpacer(), source, edit)
+	body := container.NewVScroll(container.NewVBox(question, widget.NewSeparator(), answer))
+	footer := container.NewStack(showButton, grades)
+	show()
+	m.showViewTab("Flashcards", container.NewBorder(header, footer, nil, nil, body))
+}
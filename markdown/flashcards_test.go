@@ -0,0 +1,99 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCardReview(t *testing.T) {
+	today := time.Date(2026, 1, 10, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		name  string
+		state cardState
+		grade cardGrade
+		want  cardState
+	}{
+		{"new good", cardState{}, gradeGood,
+			cardState{Due: "2026-01-11", Interval: 1, Ease: 2.5, Reps: 1, Reviewed: "2026-01-10"}},
+		{"new easy", cardState{}, gradeEasy,
+			cardState{Due: "2026-01-11", Interval: 1, Ease: 2.6, Reps: 1, Reviewed: "2026-01-10"}},
+		{"new hard", cardState{}, gradeHard,
+			cardState{Due: "2026-01-11", Interval: 1, Ease: 2.36, Reps: 1, Reviewed: "2026-01-10"}},
+		{"second review", cardState{Interval: 1, Ease: 2.5, Reps: 1}, gradeGood,
+			cardState{Due: "2026-01-16", Interval: 6, Ease: 2.5, Reps: 2, Reviewed: "2026-01-10"}},
+		{"good multiplies by ease", cardState{Interval: 6, Ease: 2.5, Reps: 2}, gradeGood,
+			cardState{Due: "2026-01-25", Interval: 15, Ease: 2.5, Reps: 3, Reviewed: "2026-01-10"}},
+		{"hard grows slowly", cardState{Interval: 6, Ease: 2.5, Reps: 2}, gradeHard,
+			cardState{Due: "2026-01-17", Interval: 7, Ease: 2.36, Reps: 3, Reviewed: "2026-01-10"}},
+		{"easy bonus", cardState{Interval: 6, Ease: 2.5, Reps: 2}, gradeEasy,
+			cardState{Due: "2026-01-30", Interval: 20, Ease: 2.6, Reps: 3, Reviewed: "2026-01-10"}},
+		{"again resets", cardState{Interval: 30, Ease: 2.5, Reps: 4, Lapses: 1}, gradeAgain,
+			cardState{Due: "2026-01-11", Interval: 1, Ease: 2.3, Reps: 0, Lapses: 2, Reviewed: "2026-01-10"}},
+		{"ease floor", cardState{Interval: 3, Ease: 1.3, Reps: 3}, gradeAgain,
+			cardState{Due: "2026-01-11", Interval: 1, Ease: 1.3, Lapses: 1, Reviewed: "2026-01-10"}},
+	}
+	for _, tt := range tests {
+		if got := tt.state.review(tt.grade, today); got != tt.want {
+			t.Errorf("%s: review() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCardIsDue(t *testing.T) {
+	today := time.Date(2026, 1, 10, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		due  string
+		want bool
+	}{
+		{"", true},
+		{"2026-01-09", true},
+		{"2026-01-10", true},
+		{"2026-01-11", false},
+	}
+	for _, tt := range tests {
+		if got := (cardState{Due: tt.due}).isDue(today); got != tt.want {
+			t.Errorf("isDue(%q) = %v, want %v", tt.due, got, tt.want)
+		}
+	}
+}
+
+func TestParseFlashcards(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []flashcard
+	}{
+		{
+			name:    "inline cards",
+			content: "capital of France :: Paris\n- 2 + 2 :: 4\nno answer ::",
+			want: []flashcard{
+				{Path: "n.md", Line: 0, Question: "capital of France", Answer: "Paris"},
+				{Path: "n.md", Line: 1, Question: "2 + 2", Answer: "4"},
+			},
+		},
+		{
+			name:    "multi-line card",
+			content: "# Deck\nfirst line\nsecond line\n?\nanswer one\nanswer two\n\nnot part",
+			want: []flashcard{
+				{Path: "n.md", Line: 1, Question: "first line\nsecond line", Answer: "answer one\nanswer two"},
+			},
+		},
+		{
+			name:    "skips front matter and code",
+			content: "---\ntags: [flashcards]\nq :: a\n---\n```\nx :: y\n```\nreal :: card",
+			want: []flashcard{
+				{Path: "n.md", Line: 7, Question: "real", Answer: "card"},
+			},
+		},
+		{
+			name:    "lone question mark",
+			content: "text\n\n?\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := parseFlashcards("n.md", tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseFlashcards() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
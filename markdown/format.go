@@ -0,0 +1,279 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	atxHeadingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextPattern     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+)
+
+// fmtKind 是格式化时每一行所属的块类型，用于决定块之间是否需要空行
+type fmtKind int
+
+const (
+	fmtBlank fmtKind = iota
+	fmtFront
+	fmtText
+	fmtHeading
+	fmtRule
+	fmtCode
+	fmtTable
+	fmtQuote
+	fmtList     // 列表项
+	fmtListText // 列表项中的后续内容
+)
+
+type fmtLine struct {
+	text  string
+	kind  fmtKind
+	block int // 代码块和表格的编号，同一个块的行之间不插入空行
+}
+
+// fmtListLevel 是格式化过程中一层列表项的缩进：orig 为原文中内容开始的列，indent 和 content 为格式化后的
+type fmtListLevel struct {
+	orig    int
+	indent  int
+	content int
+	ordered bool
+	number  int
+}
+
+// parseHeading 解析 ATX 标题，返回级别和去掉首尾 # 的标题文字
+func parseHeading(line string) (level int, text string, ok bool) {
+	m := atxHeadingPattern.FindStringSubmatch(line)
+	if m == nil {
+		return 0, "", false
+	}
+	return len(m[1]), strings.TrimSpace(m[2]), true
+}
+
+func headingLine(level int, text string) string {
+	if text == "" {
+		return strings.Repeat("#", level)
+	}
+	return strings.Repeat("#", level) + " " + text
+}
+
+// indentWidth 返回行首空白的列数，制表符按 4 列计算
+func indentWidth(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4 - n%4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// formatMarkdown 统一笔记的格式：标题使用 # 的写法，无序列表使用 -，有序列表连续编号，
+// 嵌套列表按上一级内容的位置缩进，块之间保留一个空行，去掉行尾空白，对齐表格，文件以一个换行结尾。
+// front matter 和代码块中的内容保持不变
+func formatMarkdown(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	front := frontMatterEnd(lines)
+	var out []fmtLine
+	for _, line := range lines[:front] {
+		out = append(out, fmtLine{text: line, kind: fmtFront})
+	}
+
+	var stack []fmtListLevel
+	block := 0
+	blank := true // 上一行是否为空行
+	for i := front; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t\r")
+		if line == "" {
+			out = append(out, fmtLine{kind: fmtBlank})
+			blank = true
+			continue
+		}
+		indent := indentWidth(line)
+		trimmed := strings.TrimLeft(line, " \t")
+		m := mdListPattern.FindStringSubmatchIndex(line)
+		if m != nil && hlRulePattern.MatchString(line) {
+			m = nil
+		}
+
+		// 找出这一行所属的列表项。空行之后缩进不足的行结束列表项，没有空行时是段落的延续
+		var sibling *fmtListLevel
+		for len(stack) > 0 && indent < stack[len(stack)-1].orig && (m != nil || blank) {
+			sibling = &stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+		nested := len(stack) > 0
+		reindent := func(s string) string {
+			if !nested || indentWidth(s) < stack[len(stack)-1].orig {
+				return s
+			}
+			top := stack[len(stack)-1]
+			return strings.Repeat(" ", indentWidth(s)-top.orig+top.content) + strings.TrimLeft(s, " \t")
+		}
+		blank = false
+
+		switch {
+		case fenceMarker(trimmed) != "" && (indent <= 3 || nested):
+			// 围栏代码块原样保留，只随所在的列表项调整缩进
+			block++
+			marker := fenceMarker(trimmed)
+			kind := fmtCode
+			if nested {
+				kind = fmtListText
+			}
+			out = append(out, fmtLine{text: reindent(line), kind: kind, block: block})
+			for i+1 < len(lines) {
+				i++
+				code := strings.TrimRight(lines[i], "\r")
+				if nested {
+					code = reindent(code)
+				}
+				out = append(out, fmtLine{text: code, kind: kind, block: block})
+				if closing := fenceMarker(strings.TrimLeft(code, " \t")); strings.HasPrefix(closing, marker) &&
+					strings.TrimSpace(strings.TrimLeft(code, " \t")[len(closing):]) == "" {
+					break
+				}
+			}
+		case !nested && indent >= 4 && (len(out) == 0 || out[len(out)-1].kind == fmtBlank || out[len(out)-1].kind == fmtCode):
+			// 缩进代码块
+			if len(out) == 0 || out[len(out)-1].kind != fmtCode {
+				block++
+			}
+			out = append(out, fmtLine{text: line, kind: fmtCode, block: block})
+		case m != nil:
+			out = append(out, fmtLine{text: formatListItem(line, m, &stack, sibling), kind: fmtList})
+		case nested:
+			out = append(out, fmtLine{text: reindent(line), kind: fmtListText})
+		case setextPattern.MatchString(line) && len(out) > 0 && out[len(out)-1].kind == fmtText &&
+			(len(out) == 1 || out[len(out)-2].kind != fmtText):
+			// 单行段落下的 === 或 --- 是 Setext 标题
+			level := 1
+			if strings.Contains(line, "-") {
+				level = 2
+			}
+			out[len(out)-1] = fmtLine{text: headingLine(level, strings.TrimSpace(out[len(out)-1].text)), kind: fmtHeading}
+		case atxHeadingPattern.MatchString(line):
+			level, text, _ := parseHeading(line)
+			out = append(out, fmtLine{text: headingLine(level, text), kind: fmtHeading})
+		case hlRulePattern.MatchString(line):
+			out = append(out, fmtLine{text: "---", kind: fmtRule})
+		case strings.Contains(line, "|") && i+1 < len(lines) && tableDelimiterPattern.MatchString(lines[i+1]):
+			t := parseTable(lines, i)
+			if t == nil || t.start != i {
+				out = append(out, fmtLine{text: line, kind: fmtText})
+				break
+			}
+			block++
+			for _, row := range t.format() {
+				out = append(out, fmtLine{text: row, kind: fmtTable, block: block})
+			}
+			i = t.end
+		case strings.HasPrefix(trimmed, ">"):
+			out = append(out, fmtLine{text: line, kind: fmtQuote})
+		default:
+			out = append(out, fmtLine{text: line, kind: fmtText})
+		}
+
+		// 保留段落中用两个空格表示的换行
+		last := &out[len(out)-1]
+		if strings.HasSuffix(strings.TrimRight(lines[i], "\r"), "  ") && i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			switch last.kind {
+			case fmtText, fmtQuote, fmtList, fmtListText:
+				last.text += "  "
+			}
+		}
+	}
+	return joinFormatted(out)
+}
+
+// formatListItem 统一列表标记并按所在层级缩进，m 是 mdListPattern 的匹配位置
+func formatListItem(line string, m []int, stack *[]fmtListLevel, sibling *fmtListLevel) string {
+	level := fmtListLevel{orig: indentWidth(line[:m[3]]) + utf8.RuneCountInString(line[m[3]:m[11]])}
+	if len(*stack) > 0 {
+		level.indent = (*stack)[len(*stack)-1].content
+	}
+
+	marker := "-"
+	if m[6] >= 0 {
+		level.ordered = true
+		level.number, _ = strconv.Atoi(line[m[6]:m[7]])
+		if sibling != nil && sibling.ordered && sibling.indent == level.indent {
+			level.number = sibling.number + 1
+		}
+		marker = strconv.Itoa(level.number) + "."
+	}
+	level.content = level.indent + len(marker) + 1
+	*stack = append(*stack, level)
+
+	rest := line[m[1]:]
+	if m[12] >= 0 {
+		task := "[ ] "
+		if strings.ContainsAny(line[m[12]:m[13]], "xX") {
+			task = "[x] "
+		}
+		rest = task + rest
+	}
+	return strings.Repeat(" ", level.indent) + marker + " " + rest
+}
+
+// needsBlankLine 判断相邻的两行之间是否需要插入空行
+func needsBlankLine(a, b fmtLine) bool {
+	switch {
+	case a.kind == fmtFront || b.kind == fmtFront:
+		return false
+	case a.kind == fmtHeading || b.kind == fmtHeading, a.kind == fmtRule || b.kind == fmtRule:
+		return true
+	case (a.kind == fmtCode || b.kind == fmtCode || a.kind == fmtTable || b.kind == fmtTable) && a.block != b.block:
+		return true
+	case a.kind == fmtText && (b.kind == fmtList || b.kind == fmtQuote):
+		return true
+	}
+	return false
+}
+
+// joinFormatted 在块之间插入空行，合并连续的空行，去掉开头和结尾的空行
+func joinFormatted(lines []fmtLine) string {
+	var b strings.Builder
+	pending := false // 是否需要在下一行之前输出空行
+	started := false
+	var prev fmtLine
+	for _, line := range lines {
+		if line.kind == fmtBlank {
+			pending = started
+			continue
+		}
+		if started && (pending || needsBlankLine(prev, line)) {
+			b.WriteString("\n")
+		}
+		b.WriteString(line.text)
+		b.WriteString("\n")
+		prev, pending, started = line, false, true
+	}
+	return b.String()
+}
+
+// formatDocument 格式化编辑器中的笔记，光标尽量留在原来的行
+func (e *noteEntry) formatDocument() {
+	formatted := formatMarkdown(e.Text)
+	if formatted == e.Text {
+		return
+	}
+	row, col := e.CursorRow, e.CursorColumn
+	e.replaceRange(0, 0, utf8.RuneCountInString(e.Text), formatted)
+	row = min(row, strings.Count(e.Text, "\n"))
+	e.setCursor(row, min(col, utf8.RuneCountInString(lineAt(e.Text, row))))
+}
+
+// formatCurrentFile 格式化当前编辑的笔记
+func (m *MarkdownEditor) formatCurrentFile() {
+	if path, editor := m.currentFile(); editor != nil && isNote(path) {
+		editor.formatDocument()
+	}
+}
@@ -0,0 +1,63 @@
+package markdown
+
+import "testing"
+
+func TestFormatMarkdown(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"empty", "", ""},
+		{"trailing whitespace and final newline", "text  \t\n\n\n", "text\n"},
+		{"crlf", "a\r\nb\r\n", "a\nb\n"},
+		{"atx heading", "#Title\n##  Sub ##\ntext", "#Title\n\n## Sub\n\ntext\n"},
+		{"setext heading", "Title\n=====\nSub\n---\nbody", "# Title\n\n## Sub\n\nbody\n"},
+		{"collapse blank lines", "a\n\n\n\nb", "a\n\nb\n"},
+		{"bullets", "* a\n+ b\n- c", "- a\n- b\n- c\n"},
+		{"renumber ordered list", "1. a\n1. b\n5) c", "1. a\n2. b\n3. c\n"},
+		{"nested list indent", "- a\n    - b\n        - c", "- a\n  - b\n    - c\n"},
+		{"task markers", "* [X] done\n- [ ] todo", "- [x] done\n- [ ] todo\n"},
+		{"blank line before list", "para\n- item", "para\n\n- item\n"},
+		{"list continuation", "- item\n  more text", "- item\n  more text\n"},
+		{"rule", "a\n***\nb", "a\n\n---\n\nb\n"},
+		{"hard line break kept", "one  \ntwo", "one  \ntwo\n"},
+		{"fenced code unchanged", "text\n```go\n*  x  \n\n\n```\nafter", "text\n\n```go\n*  x  \n\n\n```\n\nafter\n"},
+		{"indented code", "para\n\n    code\n    more", "para\n\n    code\n    more\n"},
+		{"table aligned", "|a|bb|\n|-|-|\n|ccc|d|", "| a   | bb  |\n| --- | --- |\n| ccc | d   |\n"},
+		{"front matter unchanged", "---\ntitle:  x  \n---\n#  H", "---\ntitle:  x  \n---\n# H\n"},
+		{"quote", "text\n> quoted", "text\n\n> quoted\n"},
+	}
+	for _, tt := range tests {
+		got := formatMarkdown(tt.in)
+		if got != tt.want {
+			t.Errorf("%s: formatMarkdown(%q) =\n%q\nwant\n%q", tt.name, tt.in, got, tt.want)
+		}
+		// 格式化的结果再次格式化不应变化
+		if again := formatMarkdown(got); again != got {
+			t.Errorf("%s: formatting is not idempotent:\n%q\nthen\n%q", tt.name, got, again)
+		}
+	}
+}
+
+func TestParseHeading(t *testing.T) {
+	tests := []struct {
+		line  string
+		level int
+		text  string
+		ok    bool
+	}{
+		{"# Title", 1, "Title", true},
+		{"###### Six", 6, "Six", true},
+		{"## Closed ##", 2, "Closed", true},
+		{"   # Indented", 1, "Indented", true},
+		{"#", 1, "", true},
+		{"#Tag", 0, "", false},
+		{"####### Seven", 0, "", false},
+		{"    # Code", 0, "", false},
+	}
+	for _, tt := range tests {
+		level, text, ok := parseHeading(tt.line)
+		if level != tt.level || text != tt.text || ok != tt.ok {
+			t.Errorf("parseHeading(%q) = %d, %q, %v; want %d, %q, %v", tt.line, level, text, ok, tt.level, tt.text, tt.ok)
+		}
+	}
+}
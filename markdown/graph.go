@@ -0,0 +1,541 @@
+package markdown
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	graphAllFolders = "All folders"
+	graphAllTags    = "All tags"
+	graphLayoutSize = 1000.0
+	graphNodeRadius = 5
+)
+
+// graphNode 是关系图中的一篇笔记
+type graphNode struct {
+	path   string
+	label  string
+	degree int
+	x, y   float64 // 布局坐标
+}
+
+// graphFilter 是关系图的筛选条件
+type graphFilter struct {
+	folder string // 相对路径，为空表示全部
+	tag    string // 为空表示全部
+	center string // 局部图的中心笔记，为空表示显示全部笔记
+	depth  int
+}
+
+// buildGraph 根据索引和筛选条件生成关系图的节点和边，节点位置由 forceLayout 另外计算
+func buildGraph(idx *vaultIndex, filter graphFilter) ([]graphNode, [][2]int) {
+	include := map[string]bool{}
+	for _, path := range idx.paths {
+		info := idx.notes[path]
+		if filter.folder != "" && !idx.inFolder(path, filter.folder) {
+			continue
+		}
+		if filter.tag != "" && !info.hasTag(filter.tag) {
+			continue
+		}
+		include[path] = true
+	}
+
+	if filter.center != "" {
+		// 局部图：从中心笔记出发，沿链接（不区分方向）扩展 depth 层
+		neighbours := map[string][]string{}
+		for _, path := range idx.paths {
+			for _, target := range idx.notes[path].Targets {
+				neighbours[path] = append(neighbours[path], target)
+				neighbours[target] = append(neighbours[target], path)
+			}
+		}
+		local := map[string]bool{filter.center: true}
+		frontier := []string{filter.center}
+		for d := 0; d < filter.depth; d++ {
+			var next []string
+			for _, path := range frontier {
+				for _, n := range neighbours[path] {
+					if !local[n] && include[n] {
+						local[n] = true
+						next = append(next, n)
+					}
+				}
+			}
+			frontier = next
+		}
+		include = local
+	}
+
+	var nodes []graphNode
+	index := map[string]int{}
+	for _, path := range idx.paths {
+		if include[path] {
+			index[path] = len(nodes)
+			nodes = append(nodes, graphNode{path: path, label: idx.notes[path].Name})
+		}
+	}
+
+	var edges [][2]int
+	for _, path := range idx.paths {
+		from, ok := index[path]
+		if !ok {
+			continue
+		}
+		for _, target := range idx.notes[path].Targets {
+			if to, ok := index[target]; ok {
+				edges = append(edges, [2]int{from, to})
+				nodes[from].degree++
+				nodes[to].degree++
+			}
+		}
+	}
+
+	return nodes, edges
+}
+
+// forceLayout 使用 Fruchterman-Reingold 力导向算法计算节点位置。
+// 初始位置按顺序排列在圆上，因此同样的图每次得到同样的布局
+func forceLayout(nodes []graphNode, edges [][2]int) {
+	n := len(nodes)
+	if n == 0 {
+		return
+	}
+	for i := range nodes {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		nodes[i].x = graphLayoutSize / 4 * math.Cos(angle)
+		nodes[i].y = graphLayoutSize / 4 * math.Sin(angle)
+	}
+	if n == 1 {
+		nodes[0].x, nodes[0].y = 0, 0
+		return
+	}
+
+	k := math.Sqrt(graphLayoutSize * graphLayoutSize / float64(n))
+	temperature := graphLayoutSize / 10
+	iterations := 300
+	if n > 300 {
+		iterations = int(math.Max(50, 90000/float64(n)))
+	}
+
+	dx := make([]float64, n)
+	dy := make([]float64, n)
+	for iter := 0; iter < iterations; iter++ {
+		for i := range dx {
+			dx[i], dy[i] = 0, 0
+		}
+
+		// 节点之间相互排斥
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				ddx, ddy := nodes[i].x-nodes[j].x, nodes[i].y-nodes[j].y
+				d := math.Max(math.Hypot(ddx, ddy), 0.01)
+				f := k * k / d
+				dx[i] += ddx / d * f
+				dy[i] += ddy / d * f
+				dx[j] -= ddx / d * f
+				dy[j] -= ddy / d * f
+			}
+		}
+
+		// 有链接的节点相互吸引
+		for _, e := range edges {
+			a, b := e[0], e[1]
+			if a == b {
+				continue
+			}
+			ddx, ddy := nodes[a].x-nodes[b].x, nodes[a].y-nodes[b].y
+			d := math.Max(math.Hypot(ddx, ddy), 0.01)
+			f := d * d / k
+			dx[a] -= ddx / d * f
+			dy[a] -= ddy / d * f
+			dx[b] += ddx / d * f
+			dy[b] += ddy / d * f
+		}
+
+		for i := range nodes {
+			// 向中心的引力，避免不相连的部分越飘越远
+			dx[i] -= nodes[i].x * 0.05
+			dy[i] -= nodes[i].y * 0.05
+
+			d := math.Hypot(dx[i], dy[i])
+			if d > 0 {
+				step := math.Min(d, temperature)
+				nodes[i].x += dx[i] / d * step
+				nodes[i].y += dy[i] / d * step
+			}
+		}
+		temperature = math.Max(temperature*0.97, 1)
+	}
+}
+
+// graphView 在画布上绘制关系图，支持滚轮缩放、拖动平移和点击打开笔记
+type graphView struct {
+	widget.BaseWidget
+
+	// mu 保护以下字段：布局在后台 goroutine 中计算，完成后由该 goroutine 交给控件并刷新
+	mu       sync.Mutex
+	nodes    []graphNode
+	edges    [][2]int
+	current  string // 高亮显示的笔记
+	layoutID int    // 最近一次开始的布局，旧的布局结果会被丢弃
+	scale    float32
+	offset   fyne.Position
+
+	onOpen func(path string)
+}
+
+func newGraphView(onOpen func(string)) *graphView {
+	g := &graphView{scale: 0.5, onOpen: onOpen}
+	g.ExtendBaseWidget(g)
+	return g
+}
+
+func (g *graphView) setGraph(nodes []graphNode, edges [][2]int, current string) {
+	g.mu.Lock()
+	g.layoutID++
+	g.nodes, g.edges, g.current = nodes, edges, current
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+// layout 在后台计算节点位置，完成后显示并调用 done。计算期间再次调用时，前一次的结果被丢弃。
+// fyne 2.5 没有切回 UI 线程的接口（fyne.Do 在 2.6 才加入），但允许在其他 goroutine 中 Refresh 控件，
+// 所以计算结果在 mu 的保护下交给控件，UI 线程读取节点时同样加锁
+func (g *graphView) layout(nodes []graphNode, edges [][2]int, current string, done func()) {
+	g.mu.Lock()
+	g.layoutID++
+	id := g.layoutID
+	g.mu.Unlock()
+
+	go func() {
+		forceLayout(nodes, edges)
+
+		g.mu.Lock()
+		if id != g.layoutID {
+			g.mu.Unlock()
+			return
+		}
+		g.nodes, g.edges, g.current = nodes, edges, current
+		g.mu.Unlock()
+		g.Refresh()
+		done()
+	}()
+}
+
+// setCurrent 修改高亮显示的笔记
+func (g *graphView) setCurrent(current string) {
+	g.mu.Lock()
+	g.current = current
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+// resetView 恢复默认的缩放和位置
+func (g *graphView) resetView() {
+	g.mu.Lock()
+	g.scale = 0.5
+	g.offset = fyne.NewPos(0, 0)
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+func (g *graphView) zoom(factor float32, around fyne.Position) {
+	g.mu.Lock()
+	scale := g.scale * factor
+	if scale < 0.05 || scale > 10 {
+		g.mu.Unlock()
+		return
+	}
+	// 以 around 为中心缩放：保持该点下的布局坐标不变
+	center := fyne.NewPos(g.Size().Width/2, g.Size().Height/2)
+	rel := around.Subtract(center).Subtract(g.offset)
+	g.offset = g.offset.Add(rel).Subtract(fyne.NewPos(rel.X*factor, rel.Y*factor))
+	g.scale = scale
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+// toScreen 将布局坐标转换为控件内的坐标
+func (g *graphView) toScreen(n graphNode) fyne.Position {
+	return fyne.NewPos(
+		g.Size().Width/2+g.offset.X+float32(n.x)*g.scale,
+		g.Size().Height/2+g.offset.Y+float32(n.y)*g.scale,
+	)
+}
+
+// nodeRadius 返回节点在屏幕上的半径，绘制和点击判断都使用它
+func (g *graphView) nodeRadius(n graphNode) float32 {
+	size := graphNodeRadius + float32(math.Min(float64(n.degree), 10))
+	return size * float32(math.Max(float64(g.scale), 0.5))
+}
+
+func (g *graphView) Scrolled(ev *fyne.ScrollEvent) {
+	factor := float32(1.1)
+	if ev.Scrolled.DY < 0 {
+		factor = 1 / factor
+	}
+	g.zoom(factor, ev.Position)
+}
+
+func (g *graphView) Dragged(ev *fyne.DragEvent) {
+	g.mu.Lock()
+	g.offset = g.offset.Add(ev.Dragged)
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+func (g *graphView) DragEnd() {}
+
+func (g *graphView) Tapped(ev *fyne.PointEvent) {
+	if path := g.nodeAt(ev.Position); path != "" && g.onOpen != nil {
+		g.onOpen(path)
+	}
+}
+
+// nodeAt 返回 pos 处的笔记，重叠时取后绘制（在上层）的节点
+func (g *graphView) nodeAt(pos fyne.Position) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := len(g.nodes) - 1; i >= 0; i-- {
+		n := g.nodes[i]
+		p := g.toScreen(n)
+		r := g.nodeRadius(n)
+		if dx, dy := pos.X-p.X, pos.Y-p.Y; dx*dx+dy*dy <= (r+3)*(r+3) {
+			return n.path
+		}
+	}
+	return ""
+}
+
+func (g *graphView) CreateRenderer() fyne.WidgetRenderer {
+	bg := canvas.NewRectangle(color.Transparent)
+	r := &graphRenderer{graph: g, background: bg}
+	g.mu.Lock()
+	r.rebuild()
+	g.mu.Unlock()
+	return r
+}
+
+type graphRenderer struct {
+	graph      *graphView
+	background *canvas.Rectangle
+	lines      []*canvas.Line
+	circles    []*canvas.Circle
+	labels     []*canvas.Text
+	objects    []fyne.CanvasObject
+}
+
+// rebuild 根据节点和边重新创建绘制对象，调用时需持有 graph.mu
+func (r *graphRenderer) rebuild() {
+	g := r.graph
+	r.lines = r.lines[:0]
+	r.circles = r.circles[:0]
+	r.labels = r.labels[:0]
+	r.objects = []fyne.CanvasObject{r.background}
+
+	edgeColor := theme.Color(theme.ColorNameDisabled)
+	for range g.edges {
+		line := canvas.NewLine(edgeColor)
+		line.StrokeWidth = 1
+		r.lines = append(r.lines, line)
+		r.objects = append(r.objects, line)
+	}
+	for _, n := range g.nodes {
+		fill := theme.Color(theme.ColorNameForeground)
+		if n.path == g.current {
+			fill = theme.Color(theme.ColorNamePrimary)
+		}
+		circle := canvas.NewCircle(fill)
+		r.circles = append(r.circles, circle)
+		r.objects = append(r.objects, circle)
+	}
+	for _, n := range g.nodes {
+		label := canvas.NewText(n.label, theme.Color(theme.ColorNameForeground))
+		label.TextSize = theme.TextSize() * 0.85
+		r.labels = append(r.labels, label)
+		r.objects = append(r.objects, label)
+	}
+}
+
+func (r *graphRenderer) Layout(size fyne.Size) {
+	r.graph.mu.Lock()
+	defer r.graph.mu.Unlock()
+	r.layout(size)
+}
+
+// layout 按当前的缩放和位置摆放绘制对象，调用时需持有 graph.mu
+func (r *graphRenderer) layout(size fyne.Size) {
+	g := r.graph
+	r.background.Resize(size)
+
+	for i, e := range g.edges {
+		r.lines[i].Position1 = g.toScreen(g.nodes[e[0]])
+		r.lines[i].Position2 = g.toScreen(g.nodes[e[1]])
+	}
+
+	// 缩小时隐藏标签，避免文字挤在一起
+	showLabels := g.scale >= 0.35
+	for i, n := range g.nodes {
+		p := g.toScreen(n)
+		radius := g.nodeRadius(n)
+		r.circles[i].Resize(fyne.NewSize(radius*2, radius*2))
+		r.circles[i].Move(fyne.NewPos(p.X-radius, p.Y-radius))
+
+		label := r.labels[i]
+		label.Hidden = !showLabels && n.path != g.current
+		min := label.MinSize()
+		label.Resize(min)
+		label.Move(fyne.NewPos(p.X-min.Width/2, p.Y+radius+2))
+	}
+}
+
+func (r *graphRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(200, 200)
+}
+
+func (r *graphRenderer) Refresh() {
+	r.graph.mu.Lock()
+	if len(r.lines) != len(r.graph.edges) || len(r.circles) != len(r.graph.nodes) {
+		r.rebuild()
+	} else {
+		for i, n := range r.graph.nodes {
+			r.labels[i].Text = n.label
+			if n.path == r.graph.current {
+				r.circles[i].FillColor = theme.Color(theme.ColorNamePrimary)
+			} else {
+				r.circles[i].FillColor = theme.Color(theme.ColorNameForeground)
+			}
+		}
+	}
+	r.layout(r.graph.Size())
+	r.graph.mu.Unlock()
+	canvas.Refresh(r.graph)
+}
+
+func (r *graphRenderer) Objects() []fyne.CanvasObject {
+	r.graph.mu.Lock()
+	defer r.graph.mu.Unlock()
+	return r.objects
+}
+
+func (r *graphRenderer) Destroy() {}
+
+// showGraph 打开笔记关系图标签页
+func (m *MarkdownEditor) showGraph() {
+	graph := newGraphView(func(path string) { m.openFile(path) })
+	filter := graphFilter{depth: 2}
+	local := false
+
+	folderSelect := widget.NewSelect([]string{graphAllFolders}, nil)
+	folderSelect.SetSelected(graphAllFolders)
+	tagSelect := widget.NewSelect([]string{graphAllTags}, nil)
+	tagSelect.SetSelected(graphAllTags)
+	depthSelect := widget.NewSelect([]string{"1", "2", "3", "4", "5"}, nil)
+	depthSelect.SetSelected(strconv.Itoa(filter.depth))
+	status := widget.NewLabel("")
+	shown := "" // 上次加载时的当前笔记
+
+	reload := func() {
+		idx, err := m.buildIndex()
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+
+		// 根目录 "." 包含全部笔记，与 All folders 相同，不单独列出
+		folderSelect.Options = []string{graphAllFolders}
+		for _, folder := range idx.folders() {
+			if folder != "." {
+				folderSelect.Options = append(folderSelect.Options, folder)
+			}
+		}
+		tagSelect.Options = append([]string{graphAllTags}, idx.allTags()...)
+		folderSelect.Refresh()
+		tagSelect.Refresh()
+
+		shown = m.activeNote
+		f := filter
+		f.center = ""
+		if local {
+			f.center = m.activeNote
+			if _, ok := idx.notes[f.center]; !ok {
+				status.SetText("没有打开的笔记")
+				graph.setGraph(nil, nil, "")
+				return
+			}
+		}
+		nodes, edges := buildGraph(idx, f)
+		status.SetText("Laying out " + strconv.Itoa(len(nodes)) + " notes…")
+		graph.layout(nodes, edges, m.activeNote, func() {
+			status.SetText(strconv.Itoa(len(nodes)) + " notes, " + strconv.Itoa(len(edges)) + " links")
+		})
+	}
+
+	folderSelect.OnChanged = func(s string) {
+		filter.folder = ""
+		if s != graphAllFolders {
+			filter.folder = s
+		}
+		reload()
+	}
+	tagSelect.OnChanged = func(s string) {
+		filter.tag = ""
+		if s != graphAllTags {
+			filter.tag = s
+		}
+		reload()
+	}
+	depthSelect.OnChanged = func(s string) {
+		filter.depth, _ = strconv.Atoi(s)
+		reload()
+	}
+	localCheck := widget.NewCheck("Local graph", func(b bool) {
+		local = b
+		reload()
+	})
+
+	toolbar := container.NewHBox(
+		folderSelect, tagSelect, localCheck, widget.NewLabel("Depth"), depthSelect,
+		widget.NewButtonWithIcon("", theme.ZoomInIcon(), func() { graph.zoom(1.25, fyne.NewPos(graph.Size().Width/2, graph.Size().Height/2)) }),
+		widget.NewButtonWithIcon("", theme.ZoomOutIcon(), func() { graph.zoom(0.8, fyne.NewPos(graph.Size().Width/2, graph.Size().Height/2)) }),
+		widget.NewButtonWithIcon("", theme.ZoomFitIcon(), graph.resetView),
+		widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), reload),
+		status,
+	)
+
+	content := container.NewBorder(toolbar, nil, nil, nil, graph)
+	// 切换笔记后，局部图重新以新笔记为中心，全局图只更新高亮
+	m.refreshGraph = func() {
+		open := false
+		for _, tab := range m.tabs.Items {
+			open = open || tab.Content == content
+		}
+		if !open {
+			m.refreshGraph = nil
+			return
+		}
+		if m.activeNote == shown {
+			return
+		}
+		if local {
+			reload()
+			return
+		}
+		shown = m.activeNote
+		graph.setCurrent(m.activeNote)
+	}
+
+	reload()
+	m.showViewTab("Graph", content)
+}
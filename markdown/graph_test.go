@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBuildGraph(t *testing.T) {
+	m := newTestEditor(t)
+	files := map[string]string{
+		"a.md":     "[[b]] [[c]] [c](c.md) [[missing]]",
+		"b.md":     "[[c]]",
+		"c.md":     "none",
+		"sub/d.md": "#x [[a]]",
+		"e.md":     "#x",
+	}
+	for name, content := range files {
+		path := filepath.Join(m.rootPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx, err := m.buildIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(m.rootPath, "a.md")
+
+	tests := []struct {
+		name   string
+		filter graphFilter
+		nodes  string // 标签:连接数
+		edges  string
+	}{
+		{"all", graphFilter{}, "a:3 b:2 c:2 d:1 e:0", "a>b a>c b>c d>a"},
+		{"folder", graphFilter{folder: "sub"}, "d:0", ""},
+		{"tag", graphFilter{tag: "x"}, "d:0 e:0", ""},
+		{"local depth 1", graphFilter{center: a, depth: 1}, "a:3 b:2 c:2 d:1", "a>b a>c b>c d>a"},
+		{"local depth 0", graphFilter{center: a}, "a:0", ""},
+		{"local with tag", graphFilter{center: a, depth: 2, tag: "x"}, "a:1 d:1", "d>a"},
+		{"local from leaf", graphFilter{center: filepath.Join(m.rootPath, "c.md"), depth: 1}, "a:2 b:2 c:2", "a>b a>c b>c"},
+	}
+	for _, tt := range tests {
+		nodes, edges := buildGraph(idx, tt.filter)
+		var gotNodes, gotEdges []string
+		for _, n := range nodes {
+			gotNodes = append(gotNodes, n.label+":"+strconv.Itoa(n.degree))
+		}
+		for _, e := range edges {
+			gotEdges = append(gotEdges, nodes[e[0]].label+">"+nodes[e[1]].label)
+		}
+		sort.Strings(gotNodes)
+		sort.Strings(gotEdges)
+		if got := strings.Join(gotNodes, " "); got != tt.nodes {
+			t.Errorf("%s: nodes = %q, want %q", tt.name, got, tt.nodes)
+		}
+		if got := strings.Join(gotEdges, " "); got != tt.edges {
+			t.Errorf("%s: edges = %q, want %q", tt.name, got, tt.edges)
+		}
+	}
+
+	// 同样的图每次得到同样的布局；节点互不重叠，有链接的节点比孤立的节点离得更近
+	nodes, edges := buildGraph(idx, graphFilter{})
+	again := append([]graphNode(nil), nodes...)
+	forceLayout(nodes, edges)
+	forceLayout(again, edges)
+	if !reflect.DeepEqual(nodes, again) {
+		t.Error("forceLayout is not deterministic")
+	}
+	pos := map[string]graphNode{}
+	for _, n := range nodes {
+		pos[n.label] = n
+	}
+	dist := func(a, b string) float64 {
+		return math.Hypot(pos[a].x-pos[b].x, pos[a].y-pos[b].y)
+	}
+	for i, a := range nodes {
+		for _, b := range nodes[i+1:] {
+			if d := dist(a.label, b.label); d < 2*graphNodeRadius {
+				t.Errorf("nodes %s and %s overlap (%.1f apart)", a.label, b.label, d)
+			}
+		}
+	}
+	if dist("a", "b") >= dist("a", "e") {
+		t.Errorf("linked a-b %.0f apart, unlinked a-e %.0f", dist("a", "b"), dist("a", "e"))
+	}
+}
@@ -0,0 +1,252 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hlKind 是语法高亮中一段文字的类别
+type hlKind uint8
+
+const (
+	hlText        hlKind = iota
+	hlMarker             // #、>、``` 等语法符号
+	hlHeading            // 标题文字
+	hlEmphasis           // 粗体、斜体和删除线
+	hlCode               // 行内代码和代码块
+	hlLink               // 链接文字和 wiki 链接
+	hlURL                // 链接地址
+	hlList               // 列表标记和任务复选框
+	hlQuote              // 引用中的文字
+	hlFrontMatter        // front matter
+)
+
+var (
+	hlHeadingPattern  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]|$)`)
+	hlRulePattern     = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	hlQuotePattern    = regexp.MustCompile(`^ {0,3}(?:>[ \t]?)+`)
+	hlListPattern     = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])(?:[ \t]+\[[ xX]\])?(?:[ \t]+|$)`)
+	hlLinkPattern     = regexp.MustCompile(`!?(\[[^\]\n]*\])(\([^)\n]*\))`)
+	hlURLPattern      = regexp.MustCompile(`<[a-zA-Z][a-zA-Z0-9+.-]*:[^>\s]*>|https?://[^\s<>()]+`)
+	hlEmphasisPattern = regexp.MustCompile(`\*\*[^*\n]+\*\*|__[^_\n]+__|~~[^~\n]+~~|\*[^*\s][^*\n]*\*|\b_[^_\s][^_\n]*_\b`)
+)
+
+// hlSpan 是一行中 [start, end) 字节范围内的一段同类文字
+type hlSpan struct {
+	start, end int
+	kind       hlKind
+}
+
+// hlState 是跨行的语法状态：是否在 front matter 中，以及所在代码块的围栏
+type hlState struct {
+	front bool
+	fence string
+}
+
+// hlLine 是一行的高亮结果
+type hlLine struct {
+	text  string
+	state hlState // 行首的状态
+	next  hlState // 行尾的状态，即下一行的行首状态
+	spans []hlSpan
+}
+
+// highlighter 保存整篇笔记每一行的高亮结果。文本修改后只重新分析变化的行，
+// 以及因代码块或 front matter 状态改变而受影响的后续行
+type highlighter struct {
+	lines []hlLine
+}
+
+// update 用新的文本更新高亮结果
+func (h *highlighter) update(text string) {
+	lines := strings.Split(text, "\n")
+	old := h.lines
+
+	prefix := 0
+	for prefix < len(old) && prefix < len(lines) && old[prefix].text == lines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(lines)-prefix && old[len(old)-1-suffix].text == lines[len(lines)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]hlLine, len(lines))
+	copy(result, old[:prefix])
+	state := hlState{}
+	if prefix > 0 {
+		state = old[prefix-1].next
+	}
+	shift := len(old) - len(lines)
+	for i := prefix; i < len(lines); i++ {
+		// 进入未修改的尾部后，行首状态与原来相同时其余各行的结果都不会变化；
+		// 第一行的 --- 才是 front matter 的开头，所以移入或移出第一行的行需要重新分析
+		if i >= len(lines)-suffix && old[i+shift].state == state && (i == 0) == (i+shift == 0) {
+			copy(result[i:], old[i+shift:])
+			break
+		}
+		result[i] = highlightLine(lines[i], i, state)
+		state = result[i].next
+	}
+	h.lines = result
+}
+
+// highlightLine 分析一行文字
+func highlightLine(text string, index int, state hlState) hlLine {
+	line := hlLine{text: text, state: state, next: state}
+	trimmed := strings.TrimRight(text, "\r")
+	whole := func(kind hlKind) []hlSpan {
+		if text == "" {
+			return nil
+		}
+		return []hlSpan{{0, len(text), kind}}
+	}
+
+	switch {
+	case state.front:
+		line.spans = whole(hlFrontMatter)
+		if trimmed == "---" || trimmed == "..." {
+			line.spans = whole(hlMarker)
+			line.next = hlState{}
+		}
+	case state.fence != "":
+		line.spans = whole(hlCode)
+		if marker := fenceMarker(trimmed); strings.HasPrefix(marker, state.fence) &&
+			strings.TrimSpace(strings.TrimLeft(trimmed, " ")[len(marker):]) == "" {
+			line.spans = whole(hlMarker)
+			line.next = hlState{}
+		}
+	case index == 0 && trimmed == "---":
+		line.spans = whole(hlMarker)
+		line.next = hlState{front: true}
+	case fenceMarker(trimmed) != "":
+		line.spans = whole(hlMarker)
+		line.next = hlState{fence: fenceMarker(trimmed)}
+	default:
+		line.spans = blockSpans(text)
+	}
+	return line
+}
+
+// blockSpans 分析普通文本行：先识别行首的标题、引用和列表标记，再分析行内语法
+func blockSpans(text string) []hlSpan {
+	if m := hlHeadingPattern.FindStringSubmatchIndex(text); m != nil {
+		return append([]hlSpan{{0, m[3], hlMarker}}, inlineSpans(text, m[3], hlHeading)...)
+	}
+	if hlRulePattern.MatchString(text) {
+		return []hlSpan{{0, len(text), hlMarker}}
+	}
+
+	var spans []hlSpan
+	pos := 0
+	base := hlText
+	if m := hlQuotePattern.FindStringIndex(text); m != nil {
+		spans = append(spans, hlSpan{0, m[1], hlMarker})
+		pos = m[1]
+		base = hlQuote
+	}
+	if m := hlListPattern.FindStringIndex(text[pos:]); m != nil && m[1] > 0 {
+		spans = append(spans, hlSpan{pos, pos + m[1], hlList})
+		pos += m[1]
+	}
+	return append(spans, inlineSpans(text, pos, base)...)
+}
+
+// inlineSpans 分析 text[from:] 中的行内代码、链接和强调，其余文字为 base 类别
+func inlineSpans(text string, from int, base hlKind) []hlSpan {
+	if from >= len(text) {
+		return nil
+	}
+	kinds := make([]hlKind, len(text))
+	taken := make([]bool, len(text))
+	for i := from; i < len(text); i++ {
+		kinds[i] = base
+	}
+	mark := func(start, end int, kind hlKind) {
+		for i := start; i < end; i++ {
+			kinds[i] = kind
+			taken[i] = true
+		}
+	}
+	free := func(start, end int) bool {
+		for i := start; i < end; i++ {
+			if taken[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// 行内代码优先，其中的内容不再分析
+	for i := from; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := countByte(text[i:], '`')
+		end := closingTicks(text, i+n, n)
+		if end < 0 {
+			i += n
+			continue
+		}
+		mark(i, end, hlCode)
+		i = end
+	}
+
+	for _, m := range wikiLinkPattern.FindAllStringIndex(text[from:], -1) {
+		if start, end := from+m[0], from+m[1]; free(start, end) {
+			mark(start, end, hlLink)
+		}
+	}
+	for _, m := range hlLinkPattern.FindAllStringSubmatchIndex(text[from:], -1) {
+		if start, end := from+m[0], from+m[1]; free(start, end) {
+			mark(start, from+m[3], hlLink)
+			mark(from+m[4], end, hlURL)
+		}
+	}
+	for _, m := range hlURLPattern.FindAllStringIndex(text[from:], -1) {
+		if start, end := from+m[0], from+m[1]; free(start, end) {
+			mark(start, end, hlURL)
+		}
+	}
+	for _, m := range hlEmphasisPattern.FindAllStringIndex(text[from:], -1) {
+		if start, end := from+m[0], from+m[1]; free(start, end) {
+			mark(start, end, hlEmphasis)
+		}
+	}
+
+	var spans []hlSpan
+	for i := from; i < len(text); {
+		j := i + 1
+		for j < len(text) && kinds[j] == kinds[i] {
+			j++
+		}
+		spans = append(spans, hlSpan{i, j, kinds[i]})
+		i = j
+	}
+	return spans
+}
+
+func countByte(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// closingTicks 查找长度恰好为 n 的反引号串，返回其结束位置，找不到时返回 -1
+func closingTicks(text string, from, n int) int {
+	for i := from; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		run := countByte(text[i:], '`')
+		if run == n {
+			return i + run
+		}
+		i += run
+	}
+	return -1
+}
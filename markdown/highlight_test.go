@@ -0,0 +1,82 @@
+package markdown
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// checkHighlight 比较增量更新的结果和重新分析整篇文本的结果
+func checkHighlight(t *testing.T, h *highlighter, text string) {
+	t.Helper()
+	h.update(text)
+	var full highlighter
+	full.update(text)
+	if !reflect.DeepEqual(h.lines, full.lines) {
+		for i := range full.lines {
+			if i >= len(h.lines) || !reflect.DeepEqual(h.lines[i], full.lines[i]) {
+				t.Fatalf("after update to %q: line %d = %+v, want %+v", text, i, h.lines[min(i, len(h.lines)-1)], full.lines[i])
+			}
+		}
+		t.Fatalf("after update to %q: %d lines, want %d", text, len(h.lines), len(full.lines))
+	}
+}
+
+func TestHighlightIncremental(t *testing.T) {
+	steps := [][]string{
+		// 在中间打开和关闭代码块，后面各行的状态都会变化
+		{"a\n*b*\nc\nd", "a\n```\n*b*\nc\nd", "a\n```\n*b*\n```\nd", "a\n*b*\n```\nd"},
+		// 第一行变成 front matter 的开头
+		{"x\ntitle: a\n---\n# h", "---\ntitle: a\n---\n# h", "---\ntitle: a\n--\n# h"},
+		// 重复的行让相同的前缀和后缀重叠
+		{"a\na\na", "a\na", "a", "a\na\na\na", ""},
+		// 不同长度的围栏和 ~~~
+		{"````\n```\n````\nx", "````\n```\n```\nx", "~~~\n```\n~~~\n`y`"},
+		{"# t\n", "# t\n\n", "\n# t\n", "# t\r\n\r\n"},
+		// --- 移出和移入第一行
+		{"---\na\n---", "x\n---\na\n---", "---\na\n---"},
+	}
+	for _, texts := range steps {
+		var h highlighter
+		for _, text := range texts {
+			checkHighlight(t, &h, text)
+		}
+	}
+
+	// 随机的插入、删除和修改
+	lines := []string{"```", "~~~", "---", "# h", "text *em* `c`", "", "> q", "- [ ] t", "...", "````"}
+	r := rand.New(rand.NewSource(1))
+	var h highlighter
+	doc := []string{"text"}
+	for i := 0; i < 2000; i++ {
+		pos := r.Intn(len(doc) + 1)
+		switch r.Intn(3) {
+		case 0:
+			doc = append(doc[:pos], append([]string{lines[r.Intn(len(lines))]}, doc[pos:]...)...)
+		case 1:
+			if pos < len(doc) && len(doc) > 1 {
+				doc = append(doc[:pos], doc[pos+1:]...)
+			}
+		default:
+			if pos < len(doc) {
+				doc[pos] = lines[r.Intn(len(lines))]
+			}
+		}
+		checkHighlight(t, &h, strings.Join(doc, "\n"))
+	}
+}
+
+func TestHighlightState(t *testing.T) {
+	var h highlighter
+	h.update("---\ntitle: x\n---\n```go\n# not heading\n```\n# heading")
+	want := []hlState{{}, {front: true}, {front: true}, {}, {fence: "```"}, {fence: "```"}, {}}
+	for i, line := range h.lines {
+		if line.state != want[i] {
+			t.Errorf("line %d %q: state %+v, want %+v", i, line.text, line.state, want[i])
+		}
+	}
+	if kind := h.lines[4].spans[0].kind; kind != hlCode {
+		t.Errorf("heading inside code block highlighted as %v", kind)
+	}
+}
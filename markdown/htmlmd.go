@@ -0,0 +1,608 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 任务复选框在转换过程中的占位符，最后统一替换为 [ ] 和 [x]
+const (
+	taskOpen = "\x00o"
+	taskDone = "\x00x"
+)
+
+var (
+	spacePattern     = regexp.MustCompile(`[ \t\r\n\f\x{00a0}]+`)
+	lineStartPattern = regexp.MustCompile(`^(?:[#>]|[-+](?:\s|$)|\d+[.)](?:\s|$)|[-=]+\s*$)`)
+	listLinePattern  = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s`)
+	backtickPattern  = regexp.MustCompile("`+")
+	markdownEscaper  = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`)
+)
+
+// 按块转换的 HTML 元素，其余元素按行内内容处理
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true,
+	"center": true, "dd": true, "details": true, "div": true, "dl": true, "dt": true,
+	"en-note": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "html": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "summary": true, "table": true, "ul": true,
+}
+
+// 转换时直接忽略的元素
+var htmlSkipTags = map[string]bool{
+	"base": true, "head": true, "link": true, "meta": true, "noscript": true,
+	"script": true, "style": true, "template": true, "title": true,
+}
+
+// 无法转换为 Markdown 的元素，忽略后写入导入报告
+var htmlUnsupportedTags = map[string]bool{
+	"applet": true, "audio": true, "button": true, "canvas": true, "embed": true,
+	"form": true, "frame": true, "frameset": true, "iframe": true, "map": true,
+	"math": true, "object": true, "select": true, "svg": true, "textarea": true, "video": true,
+}
+
+// htmlConverter 把 HTML 文档转换为 Markdown
+type htmlConverter struct {
+	// image 返回图片在笔记中使用的链接，返回空字符串时只保留替代文字
+	image func(src string) string
+	// link 改写链接目标
+	link func(href string) string
+	// media 转换 ENEX 中的 <en-media>
+	media func(hash, mime string) string
+	// problem 记录无法转换的内容
+	problem  func(msg string)
+	reported map[string]bool
+}
+
+// convert 转换整个文档
+func (c *htmlConverter) convert(doc *html.Node) string {
+	text := strings.TrimSpace(strings.Join(c.blocks(doc), "\n\n"))
+	if text == "" {
+		return ""
+	}
+	return finishTasks(text) + "\n"
+}
+
+// blocks 转换 n 的子节点，连续的行内内容合并为段落
+func (c *htmlConverter) blocks(n *html.Node) []string {
+	var blocks []string
+	var inline strings.Builder
+	flush := func() {
+		if text := paragraph(inline.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		inline.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type != html.ElementNode:
+				inline.WriteString(c.inline(child))
+			case c.ignored(child):
+			case htmlBlockTags[child.Data]:
+				flush()
+				blocks = append(blocks, c.block(child)...)
+			case hasBlock(child):
+				// 行内元素中包含块元素（如 <span><div>…</div></span>）时展开其内容
+				walk(child)
+			default:
+				inline.WriteString(c.inline(child))
+			}
+		}
+	}
+	walk(n)
+	flush()
+	return blocks
+}
+
+func (c *htmlConverter) block(n *html.Node) []string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := c.inlineText(n)
+		if text == "" {
+			return nil
+		}
+		return []string{strings.Repeat("#", int(n.Data[1]-'0')) + " " + text}
+	case "dt":
+		if text := c.inlineText(n); text != "" {
+			return []string{"**" + text + "**"}
+		}
+		return nil
+	case "pre":
+		return []string{c.codeBlock(n)}
+	case "blockquote":
+		if inner := c.blocks(n); len(inner) > 0 {
+			return []string{quote(strings.Join(inner, "\n\n"))}
+		}
+		return nil
+	case "ul", "ol":
+		if list := c.list(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case "li":
+		// 不在列表中的 <li>
+		return []string{listItem("- ", c.blocks(n))}
+	case "hr":
+		return []string{"---"}
+	case "table":
+		return c.table(n)
+	}
+	return c.blocks(n)
+}
+
+// list 转换 <ul> 和 <ol>，嵌套列表按列表标记的宽度缩进
+func (c *htmlConverter) list(n *html.Node) string {
+	ordered := n.Data == "ol"
+	num := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		num = start
+	}
+
+	var items []string
+	marker := "- "
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || c.ignored(child) {
+			continue
+		}
+		if child.Data == "ul" || child.Data == "ol" {
+			// 直接放在列表中的子列表归入上一项
+			nested := c.list(child)
+			if nested == "" {
+				continue
+			}
+			if len(items) == 0 {
+				items = append(items, nested)
+			} else {
+				pad := strings.Repeat(" ", len(marker))
+				items[len(items)-1] += "\n" + pad + indent(nested, pad)
+			}
+			continue
+		}
+
+		marker = "- "
+		if ordered {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+		items = append(items, listItem(marker, c.blocks(child)))
+	}
+	return strings.Join(items, "\n")
+}
+
+// listItem 用列表标记拼接列表项的内容，后续行按标记宽度缩进
+func listItem(marker string, blocks []string) string {
+	var b strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			// 项目文字后面紧跟子列表时不空行，保持列表紧凑
+			if i == 1 && listLinePattern.MatchString(block) {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(block)
+	}
+	text := indent(b.String(), strings.Repeat(" ", len(marker)))
+	return strings.TrimRight(marker+strings.TrimLeft(text, " "), " ")
+}
+
+// table 转换为 GFM 表格，第一行作为表头
+func (c *htmlConverter) table(n *html.Node) []string {
+	var rows [][]string
+	var caption string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			case "caption":
+				caption = c.inlineText(child)
+			case "tr":
+				rows = append(rows, c.tableRow(child))
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	if cols == 0 {
+		return nil
+	}
+
+	var lines []string
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", cols))
+		}
+	}
+	var blocks []string
+	if caption != "" {
+		blocks = append(blocks, caption)
+	}
+	return append(blocks, strings.Join(lines, "\n"))
+}
+
+func (c *htmlConverter) tableRow(tr *html.Node) []string {
+	var cells []string
+	for cell := tr.FirstChild; cell != nil; cell = cell.NextSibling {
+		if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+			continue
+		}
+		if hasElement(cell, "table") {
+			c.report("nested-table", "嵌套的表格已展开为单元格中的文字")
+		}
+		// 单元格中的段落和换行使用 <br> 表示
+		text := strings.Join(c.blocks(cell), "<br>")
+		text = strings.ReplaceAll(text, "\n", "<br>")
+		cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+
+		span, _ := strconv.Atoi(attr(cell, "colspan"))
+		for i := 1; i < span; i++ {
+			cells = append(cells, "")
+		}
+		if rows, _ := strconv.Atoi(attr(cell, "rowspan")); span > 1 || rows > 1 {
+			c.report("merged-cells", "表格中的合并单元格已拆开")
+		}
+	}
+	return cells
+}
+
+// codeBlock 转换 <pre>，语言取自 class 中的 language-xxx 或 lang-xxx
+func (c *htmlConverter) codeBlock(n *html.Node) string {
+	lang := codeLanguage(n)
+	if code := firstElement(n, "code"); code != nil && lang == "" {
+		lang = codeLanguage(code)
+	}
+	text := strings.TrimSuffix(rawText(n), "\n")
+
+	fence := "```"
+	for _, run := range backtickPattern.FindAllString(text, -1) {
+		if len(run) >= len(fence) {
+			fence = strings.Repeat("`", len(run)+1)
+		}
+	}
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) {
+				return strings.TrimPrefix(class, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// inline 转换行内节点，<br> 输出为换行，由 paragraph 处理为硬换行
+func (c *htmlConverter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return markdownEscaper.Replace(spacePattern.ReplaceAllString(n.Data, " "))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if c.ignored(n) {
+		return ""
+	}
+
+	switch n.Data {
+	case "br":
+		return "\n"
+	case "strong", "b":
+		return emphasis(c.inlines(n), "**")
+	case "em", "i", "cite", "dfn", "var":
+		return emphasis(c.inlines(n), "*")
+	case "del", "s", "strike":
+		return emphasis(c.inlines(n), "~~")
+	case "code", "kbd", "samp", "tt":
+		return codeSpan(spacePattern.ReplaceAllString(rawText(n), " "))
+	case "q":
+		return `"` + c.inlines(n) + `"`
+	case "a":
+		return c.anchor(n)
+	case "img":
+		return c.img(n)
+	case "input":
+		if strings.EqualFold(attr(n, "type"), "checkbox") {
+			return checkbox(hasAttr(n, "checked")) + " "
+		}
+		return ""
+	case "en-todo":
+		return checkbox(attr(n, "checked") == "true") + " " + c.inlines(n)
+	case "en-media":
+		text := ""
+		if c.media != nil {
+			text = c.media(attr(n, "hash"), attr(n, "type"))
+		}
+		return text + c.inlines(n)
+	case "en-crypt":
+		c.report("en-crypt", "加密的内容无法导入，已忽略")
+		return ""
+	}
+	return c.inlines(n)
+}
+
+func (c *htmlConverter) inlines(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(c.inline(child))
+	}
+	return b.String()
+}
+
+// inlineText 转换为单行文字，用于标题和表格标题
+func (c *htmlConverter) inlineText(n *html.Node) string {
+	var parts []string
+	for _, block := range c.blocks(n) {
+		parts = append(parts, strings.ReplaceAll(strings.ReplaceAll(block, "\\\n", " "), "\n", " "))
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+func (c *htmlConverter) anchor(n *html.Node) string {
+	text := c.inlines(n)
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return text
+	}
+	if strings.TrimSpace(rawText(n)) == href && isExternalLink(href) && !hasElement(n, "img") {
+		return "<" + href + ">"
+	}
+	if c.link != nil {
+		href = c.link(href)
+	}
+
+	lead, body, trail := splitSpace(strings.ReplaceAll(text, "\n", " "))
+	if body == "" {
+		body = markdownEscaper.Replace(href)
+	}
+	return lead + "[" + body + "](" + linkDest(href) + ")" + trail
+}
+
+func (c *htmlConverter) img(n *html.Node) string {
+	src := strings.TrimSpace(attr(n, "src"))
+	alt := markdownEscaper.Replace(strings.TrimSpace(spacePattern.ReplaceAllString(attr(n, "alt"), " ")))
+	if src == "" {
+		return alt
+	}
+	if c.image != nil {
+		src = c.image(src)
+	}
+	if src == "" {
+		return alt
+	}
+	return "![" + alt + "](" + linkDest(src) + ")"
+}
+
+// ignored 判断元素是否需要跳过，不支持的元素在报告中每种只记录一次
+func (c *htmlConverter) ignored(n *html.Node) bool {
+	if htmlSkipTags[n.Data] {
+		return true
+	}
+	if htmlUnsupportedTags[n.Data] {
+		c.report(n.Data, fmt.Sprintf("<%s> 无法转换为 Markdown，已忽略", n.Data))
+		return true
+	}
+	return false
+}
+
+func (c *htmlConverter) report(key, msg string) {
+	if c.reported == nil {
+		c.reported = map[string]bool{}
+	}
+	if c.reported[key] || c.problem == nil {
+		return
+	}
+	c.reported[key] = true
+	c.problem(msg)
+}
+
+// paragraph 整理行内内容：合并多余空格，换行转为硬换行，空行分隔为多个段落
+func paragraph(text string) string {
+	var paras, lines []string
+	flush := func() {
+		if len(lines) > 0 {
+			paras = append(paras, strings.Join(lines, "\\\n"))
+			lines = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.Join(strings.Fields(line), " "))
+		if line == "" {
+			flush()
+			continue
+		}
+		if lineStartPattern.MatchString(line) {
+			// 行首的 #、> 和列表标记会被当作 Markdown 语法，需要转义
+			if i := strings.IndexAny(line, "#>-+=.)"); i >= 0 {
+				line = line[:i] + `\` + line[i:]
+			}
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return strings.Join(paras, "\n\n")
+}
+
+// finishTasks 把占位符替换为任务复选框，位于行首的复选框变为任务列表项
+func finishTasks(text string) string {
+	if !strings.Contains(text, "\x00") {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		rest := strings.TrimLeft(line, " >")
+		if strings.HasPrefix(rest, "\x00") {
+			lines[i] = line[:len(line)-len(rest)] + "- " + rest
+		}
+	}
+	return strings.NewReplacer(taskOpen, "[ ]", taskDone, "[x]").Replace(strings.Join(lines, "\n"))
+}
+
+func checkbox(checked bool) string {
+	if checked {
+		return taskDone
+	}
+	return taskOpen
+}
+
+// emphasis 用 mark 包裹文字，首尾空白放在标记之外
+func emphasis(text, mark string) string {
+	lead, body, trail := splitSpace(text)
+	if body == "" {
+		return text
+	}
+	return lead + mark + body + mark + trail
+}
+
+func splitSpace(text string) (string, string, string) {
+	body := strings.TrimSpace(text)
+	if body == "" {
+		return text, "", ""
+	}
+	start := strings.Index(text, body)
+	return text[:start], body, text[start+len(body):]
+}
+
+// codeSpan 生成行内代码，内容中有反引号时使用更长的反引号包裹
+func codeSpan(text string) string {
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+	fence := "`"
+	for _, run := range backtickPattern.FindAllString(text, -1) {
+		if len(run) >= len(fence) {
+			fence = strings.Repeat("`", len(run)+1)
+		}
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// linkDest 生成链接目标，包含空格或括号时使用 <> 包裹
+func linkDest(dest string) string {
+	if strings.HasPrefix(dest, "<") && strings.HasSuffix(dest, ">") {
+		return dest
+	}
+	if strings.ContainsAny(dest, " ()") {
+		return "<" + dest + ">"
+	}
+	return dest
+}
+
+func quote(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indent 缩进除第一行以外的非空行
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rawText 返回节点中的文字，<br> 转为换行，用于代码
+func rawText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			b.WriteString("\n")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBlock 判断节点中是否包含块元素
+func hasBlock(n *html.Node) bool {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (htmlBlockTags[child.Data] || hasBlock(child)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasElement(n *html.Node, tag string) bool {
+	return firstElement(n, tag) != nil
+}
+
+// firstElement 返回 n 中第一个标签为 tag 的子孙元素
+func firstElement(n *html.Node, tag string) *html.Node {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == tag {
+			return child
+		}
+		if found := firstElement(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
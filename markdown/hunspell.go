@@ -0,0 +1,544 @@
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// hunspellDict 是由 Hunspell 的 .aff 和 .dic 文件加载的字典。
+// 加载时按词缀规则展开词根得到所有词形，查询时直接查表。
+// 支持 PFX、SFX（包括交叉组合和一层后缀的后续词缀）、FLAG、TRY、REP、
+// FORBIDDENWORD、NEEDAFFIX、ONLYINCOMPOUND 和 NOSUGGEST，不支持复合词
+type hunspellDict struct {
+	words     map[string]bool
+	noSuggest map[string]bool
+	forbidden map[string]bool
+	try       string
+	rep       [][2]string // 常见错误的替换表，用于生成建议
+}
+
+// affixRule 是一条 PFX 或 SFX 规则
+type affixRule struct {
+	strip string
+	add   string
+	cont  []string // 后续词缀标志
+	cond  *regexp.Regexp
+}
+
+// affixClass 是同一标志下的一组词缀规则
+type affixClass struct {
+	suffix bool
+	cross  bool // 是否可以与另一类词缀组合
+	rules  []affixRule
+}
+
+// affFile 是解析后的 .aff 文件
+type affFile struct {
+	encoding       string
+	flagMode       string // 空为单字符，long 为两个字符，num 为逗号分隔的数字，UTF-8 为单个 Unicode 字符
+	affixes        map[string]*affixClass
+	forbidden      string
+	needAffix      string
+	onlyCompound   string
+	noSuggest      string
+	try            string
+	rep            [][2]string
+	conditionCache map[string]*regexp.Regexp
+}
+
+// loadHunspell 加载一对 .aff 和 .dic 文件
+func loadHunspell(affPath, dicPath string) (*hunspellDict, error) {
+	affData, err := os.ReadFile(affPath)
+	if err != nil {
+		return nil, err
+	}
+	aff, err := parseAff(affData)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", affPath, err)
+	}
+	dicData, err := os.ReadFile(dicPath)
+	if err != nil {
+		return nil, err
+	}
+	dicText, err := decodeDictionary(dicData, aff.encoding)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dicPath, err)
+	}
+
+	d := &hunspellDict{
+		words:     map[string]bool{},
+		noSuggest: map[string]bool{},
+		forbidden: map[string]bool{},
+		try:       aff.try,
+		rep:       aff.rep,
+	}
+	lines := strings.Split(dicText, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		// 第一行是词条数；以制表符开头的行是注释
+		if i == 0 && isNumber(strings.TrimSpace(line)) || line == "" || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		word, flags := splitDicEntry(line)
+		if word == "" {
+			continue
+		}
+		d.addEntry(aff, word, aff.parseFlags(flags))
+	}
+	return d, nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// splitDicEntry 把 "word/flags 形态信息" 拆分为词和标志
+func splitDicEntry(line string) (word, flags string) {
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		line = line[:i]
+	}
+	// "\/" 表示词中的斜杠
+	for i := 0; i < len(line); i++ {
+		if line[i] == '/' && (i == 0 || line[i-1] != '\\') {
+			return strings.ReplaceAll(line[:i], `\/`, "/"), line[i+1:]
+		}
+	}
+	return strings.ReplaceAll(line, `\/`, "/"), ""
+}
+
+// addEntry 展开一个词根的所有词形
+func (d *hunspellDict) addEntry(aff *affFile, root string, flags []string) {
+	has := func(flag string) bool {
+		if flag == "" {
+			return false
+		}
+		for _, f := range flags {
+			if f == flag {
+				return true
+			}
+		}
+		return false
+	}
+	if has(aff.forbidden) {
+		d.forbidden[root] = true
+		return
+	}
+	if has(aff.onlyCompound) {
+		return
+	}
+	noSuggest := has(aff.noSuggest)
+	add := func(word string) {
+		d.words[word] = true
+		if noSuggest {
+			d.noSuggest[word] = true
+		}
+	}
+	if !has(aff.needAffix) {
+		add(root)
+	}
+
+	for _, flag := range flags {
+		sfx := aff.affixes[flag]
+		if sfx == nil || !sfx.suffix {
+			continue
+		}
+		for _, rule := range sfx.rules {
+			word, ok := rule.apply(root, true)
+			if !ok {
+				continue
+			}
+			add(word)
+			// 后续词缀，如 SFX A 0 s/B 中 B 可以接在加了 s 的词后面
+			for _, c := range rule.cont {
+				if next := aff.affixes[c]; next != nil && next.suffix {
+					for _, r := range next.rules {
+						if w, ok := r.apply(word, true); ok {
+							add(w)
+						}
+					}
+				}
+			}
+			if !sfx.cross {
+				continue
+			}
+			for _, pflag := range flags {
+				pfx := aff.affixes[pflag]
+				if pfx == nil || pfx.suffix || !pfx.cross {
+					continue
+				}
+				for _, r := range pfx.rules {
+					if w, ok := r.apply(word, false); ok {
+						add(w)
+					}
+				}
+			}
+		}
+	}
+	for _, flag := range flags {
+		pfx := aff.affixes[flag]
+		if pfx == nil || pfx.suffix {
+			continue
+		}
+		for _, rule := range pfx.rules {
+			if word, ok := rule.apply(root, false); ok {
+				add(word)
+			}
+		}
+	}
+}
+
+// apply 对词应用词缀规则，条件不满足时返回 false
+func (r affixRule) apply(word string, suffix bool) (string, bool) {
+	if suffix {
+		if !strings.HasSuffix(word, r.strip) || len(word) == len(r.strip) && r.add == "" {
+			return "", false
+		}
+		if r.cond != nil && !r.cond.MatchString(word) {
+			return "", false
+		}
+		return word[:len(word)-len(r.strip)] + r.add, true
+	}
+	if !strings.HasPrefix(word, r.strip) || len(word) == len(r.strip) && r.add == "" {
+		return "", false
+	}
+	if r.cond != nil && !r.cond.MatchString(word) {
+		return "", false
+	}
+	return r.add + word[len(r.strip):], true
+}
+
+// parseAff 解析 .aff 文件
+func parseAff(data []byte) (*affFile, error) {
+	aff := &affFile{affixes: map[string]*affixClass{}, conditionCache: map[string]*regexp.Regexp{}}
+	// 先找到 SET 确定编码
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 2 && fields[0] == "SET" {
+			aff.encoding = fields[1]
+			break
+		}
+	}
+	text, err := decodeDictionary(data, aff.encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	for n, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		arg := func(i int) string {
+			if i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+		switch fields[0] {
+		case "FLAG":
+			aff.flagMode = arg(1)
+		case "TRY":
+			aff.try = arg(1)
+		case "FORBIDDENWORD":
+			aff.forbidden = arg(1)
+		case "NEEDAFFIX", "PSEUDOROOT":
+			aff.needAffix = arg(1)
+		case "ONLYINCOMPOUND":
+			aff.onlyCompound = arg(1)
+		case "NOSUGGEST":
+			aff.noSuggest = arg(1)
+		case "REP":
+			// 第一行 REP 后是条目数
+			if len(fields) >= 3 {
+				aff.rep = append(aff.rep, [2]string{strings.ReplaceAll(fields[1], "_", " "), strings.ReplaceAll(fields[2], "_", " ")})
+			}
+		case "PFX", "SFX":
+			if err := aff.parseAffix(fields); err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", n+1, err)
+			}
+		}
+	}
+	return aff, nil
+}
+
+// parseAffix 解析 PFX 或 SFX 的标题行或规则行
+func (aff *affFile) parseAffix(fields []string) error {
+	if len(fields) < 4 {
+		return errors.New("词缀规则格式错误")
+	}
+	flag := fields[1]
+	class := aff.affixes[flag]
+	if class == nil {
+		// 标题行：PFX 标志 交叉组合 条目数
+		aff.affixes[flag] = &affixClass{suffix: fields[0] == "SFX", cross: fields[2] == "Y"}
+		return nil
+	}
+
+	rule := affixRule{strip: fields[2], add: fields[3]}
+	if rule.strip == "0" {
+		rule.strip = ""
+	}
+	if i := strings.Index(rule.add, "/"); i >= 0 {
+		rule.cont = aff.parseFlags(rule.add[i+1:])
+		rule.add = rule.add[:i]
+	}
+	if rule.add == "0" {
+		rule.add = ""
+	}
+	cond := "."
+	if len(fields) >= 5 {
+		cond = fields[4]
+	}
+	if cond != "." {
+		re, err := aff.condition(cond, class.suffix)
+		if err != nil {
+			return err
+		}
+		rule.cond = re
+	}
+	class.rules = append(class.rules, rule)
+	return nil
+}
+
+// condition 把词缀条件（如 [^aeiou]y）转换为正则表达式，后缀条件匹配词尾，前缀条件匹配词首
+func (aff *affFile) condition(cond string, suffix bool) (*regexp.Regexp, error) {
+	key := cond
+	if suffix {
+		key = "$" + cond
+	}
+	if re, ok := aff.conditionCache[key]; ok {
+		return re, nil
+	}
+	var b strings.Builder
+	inClass := false
+	for _, r := range cond {
+		switch {
+		case r == '[':
+			inClass = true
+			b.WriteRune(r)
+		case r == ']':
+			inClass = false
+			b.WriteRune(r)
+		case r == '.' && !inClass:
+			b.WriteRune(r)
+		case r == '^' && inClass:
+			b.WriteRune(r)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	pattern := "^" + b.String()
+	if suffix {
+		pattern = b.String() + "$"
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("词缀条件格式错误: %s", cond)
+	}
+	aff.conditionCache[key] = re
+	return re, nil
+}
+
+// parseFlags 按 FLAG 设置拆分标志串
+func (aff *affFile) parseFlags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var flags []string
+	switch aff.flagMode {
+	case "long":
+		runes := []rune(s)
+		for i := 0; i+1 < len(runes); i += 2 {
+			flags = append(flags, string(runes[i:i+2]))
+		}
+	case "num":
+		for _, f := range strings.Split(s, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				flags = append(flags, f)
+			}
+		}
+	default:
+		for _, r := range s {
+			flags = append(flags, string(r))
+		}
+	}
+	return flags
+}
+
+// decodeDictionary 把字典文件转换为 UTF-8，支持 UTF-8 和 ISO-8859-1 编码
+func decodeDictionary(data []byte, encoding string) (string, error) {
+	switch strings.ToUpper(encoding) {
+	case "", "UTF-8", "UTF8":
+		return string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), nil
+	case "ISO8859-1", "ISO-8859-1", "ISO8859-15", "ISO-8859-15":
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return string(runes), nil
+	}
+	return "", fmt.Errorf("不支持的字典编码: %s", encoding)
+}
+
+// check 判断词是否拼写正确。首字母大写或全部大写的词也接受其小写形式，
+// 全部大写的词还接受首字母大写的形式，如 PARIS 对应 Paris
+func (d *hunspellDict) check(word string) bool {
+	if d.forbidden[word] {
+		return false
+	}
+	if d.words[word] {
+		return true
+	}
+	lower := strings.ToLower(word)
+	first, size := utf8.DecodeRuneInString(lower)
+	title := string(unicode.ToUpper(first)) + lower[size:]
+	switch word {
+	case title:
+		return !d.forbidden[lower] && d.words[lower]
+	case strings.ToUpper(word):
+		return !d.forbidden[lower] && (d.words[lower] || d.words[title])
+	}
+	return false
+}
+
+// suggest 返回拼写建议，按与原词的相似程度排序
+func (d *hunspellDict) suggest(word string, limit int) []string {
+	var result []string
+	seen := map[string]bool{word: true}
+	add := func(candidate string) {
+		if seen[candidate] || len(result) >= limit {
+			return
+		}
+		seen[candidate] = true
+		if d.suggestable(candidate) {
+			result = append(result, candidate)
+		}
+	}
+
+	// 先使用替换表中的常见错误
+	for _, rep := range d.rep {
+		for i := 0; i < len(word); i++ {
+			if strings.HasPrefix(word[i:], rep[0]) {
+				add(word[:i] + rep[1] + word[i+len(rep[0]):])
+			}
+		}
+	}
+
+	try := d.try
+	if try == "" {
+		try = "esianrtolcdugmphbyfvkwzESIANRTOLCDUGMPHBYFVKWZ'"
+	}
+	edits := editCandidates(word, try)
+	var found []string
+	for _, c := range edits {
+		if !seen[c] && d.suggestable(c) {
+			found = append(found, c)
+		}
+	}
+	// 拆成两个词，如 "thecat" -> "the cat"
+	for i := 1; i < len(word); i++ {
+		if utf8.RuneStart(word[i]) && d.check(word[:i]) && d.check(word[i:]) && len(word[:i]) > 1 && len(word[i:]) > 1 {
+			found = append(found, word[:i]+" "+word[i:])
+		}
+	}
+	if len(found) == 0 && utf8.RuneCountInString(word) <= 10 {
+		// 一次编辑找不到时再尝试两次编辑。组合数很多，第二次编辑只使用 TRY 中最常见的小写字母
+		common := strings.Map(func(r rune) rune {
+			if unicode.IsLower(r) {
+				return r
+			}
+			return -1
+		}, try)
+		common = string([]rune(common)[:min(len([]rune(common)), 16)])
+		tried := map[string]bool{}
+		for _, e := range edits {
+			if tried[e] {
+				continue
+			}
+			tried[e] = true
+			for _, c := range editCandidates(e, common) {
+				if !seen[c] && d.suggestable(c) {
+					seen[c] = true
+					found = append(found, c)
+				}
+			}
+		}
+	}
+	rankSuggestions(word, found)
+	for _, c := range found {
+		add(c)
+	}
+	return result
+}
+
+// suggestable 判断词可以作为建议，带有 NOSUGGEST 标志的词不作为建议
+func (d *hunspellDict) suggestable(word string) bool {
+	if strings.Contains(word, " ") {
+		for _, w := range strings.Fields(word) {
+			if !d.check(w) {
+				return false
+			}
+		}
+		return true
+	}
+	return d.check(word) && !d.noSuggest[word] && !d.noSuggest[strings.ToLower(word)]
+}
+
+// editCandidates 返回与 word 相差一次编辑（删除、交换、替换、插入）的所有词
+func editCandidates(word, try string) []string {
+	runes := []rune(word)
+	letters := []rune(try)
+	var result []string
+	for i := range runes {
+		result = append(result, string(runes[:i])+string(runes[i+1:]))
+		if i+1 < len(runes) {
+			swapped := append([]rune(nil), runes...)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			result = append(result, string(swapped))
+		}
+		for _, c := range letters {
+			if c != runes[i] {
+				result = append(result, string(runes[:i])+string(c)+string(runes[i+1:]))
+			}
+		}
+	}
+	for i := 0; i <= len(runes); i++ {
+		for _, c := range letters {
+			result = append(result, string(runes[:i])+string(c)+string(runes[i:]))
+		}
+	}
+	return result
+}
+
+// rankSuggestions 按相似程度排序：大小写形式一致、相同前缀较长、长度相差较小的词在前
+func rankSuggestions(word string, candidates []string) {
+	lower := strings.ToLower(word)
+	score := func(c string) int {
+		s := 0
+		if strings.ToLower(c) == lower {
+			s += 100
+		}
+		cl := strings.ToLower(c)
+		for i := 0; i < len(cl) && i < len(lower) && cl[i] == lower[i]; i++ {
+			s += 2
+		}
+		diff := len(c) - len(word)
+		if diff < 0 {
+			diff = -diff
+		}
+		return s - diff
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return score(candidates[i]) > score(candidates[j])
+	})
+}
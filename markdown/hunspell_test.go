@@ -0,0 +1,175 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAff = `# 测试用的词缀文件
+SET UTF-8
+TRY esianrtolcdugmphbyfvkwz
+FORBIDDENWORD !
+NEEDAFFIX *
+REP 1
+REP f ph
+
+PFX U Y 1
+PFX U 0 un .
+
+SFX S Y 2
+SFX S y ies [^aeiou]y
+SFX S 0 s [aeiou]y
+
+SFX G Y 1
+SFX G e ing e
+
+SFX X Y 1
+SFX X 0 s/Y .
+
+SFX Y Y 1
+SFX Y 0 '
+`
+
+const testDic = `5
+try/SU
+play/S
+make/G
+walk/*X
+colour/!
+`
+
+func TestHunspellCheck(t *testing.T) {
+	dir := t.TempDir()
+	affPath, dicPath := filepath.Join(dir, "test.aff"), filepath.Join(dir, "test.dic")
+	if err := os.WriteFile(affPath, []byte(testAff), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dicPath, []byte(testDic), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := loadHunspell(affPath, dicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		word string
+		want bool
+	}{
+		{"try", true},
+		{"tries", true},
+		{"trys", false},
+		{"untry", true},
+		{"untries", true},
+		{"play", true},
+		{"plays", true},
+		{"plaies", false},
+		{"unplay", false},
+		{"make", true},
+		{"making", true},
+		{"makeing", false},
+		{"walk", false}, // NEEDAFFIX
+		{"walks", true},
+		{"walks'", true}, // 后续词缀
+		{"colour", false},
+		{"Colour", false},
+		{"Play", true},
+		{"PLAYS", true},
+		{"pLay", false},
+	}
+	for _, tt := range tests {
+		if got := d.check(tt.word); got != tt.want {
+			t.Errorf("check(%q) = %v, want %v", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestParseAff(t *testing.T) {
+	tests := []struct {
+		name string
+		aff  string
+		err  string
+	}{
+		{"short affix line", "SFX A Y\n", "第 1 行"},
+		{"bad condition", "SFX A Y 1\nSFX A 0 s [a\n", "第 2 行: 词缀条件格式错误"},
+		{"unknown encoding", "SET KOI8-R\n", "不支持的字典编码"},
+	}
+	for _, tt := range tests {
+		_, err := parseAff([]byte(tt.aff))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: parseAff() error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+
+	aff, err := parseAff([]byte(testAff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][2]string{{"f", "ph"}}; !reflect.DeepEqual(aff.rep, want) {
+		t.Errorf("rep = %q, want %q", aff.rep, want)
+	}
+	if s := aff.affixes["S"]; s == nil || !s.suffix || !s.cross || len(s.rules) != 2 {
+		t.Errorf("SFX S = %+v", s)
+	}
+	if x := aff.affixes["X"]; x == nil || !reflect.DeepEqual(x.rules[0].cont, []string{"Y"}) {
+		t.Errorf("SFX X = %+v", x)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		mode, flags string
+		want        []string
+	}{
+		{"", "", nil},
+		{"", "AbC", []string{"A", "b", "C"}},
+		{"UTF-8", "äß", []string{"ä", "ß"}},
+		{"long", "AaBbC", []string{"Aa", "Bb"}},
+		{"num", "1, 23,456", []string{"1", "23", "456"}},
+	}
+	for _, tt := range tests {
+		aff := &affFile{flagMode: tt.mode}
+		if got := aff.parseFlags(tt.flags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFlags(%q, %q) = %q, want %q", tt.mode, tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestSplitDicEntry(t *testing.T) {
+	tests := []struct {
+		line, word, flags string
+	}{
+		{"word", "word", ""},
+		{"word/AB", "word", "AB"},
+		{"word/AB po:noun", "word", "AB"},
+		{"and\\/or/X", "and/or", "X"},
+		{"tab\tcomment", "tab", ""},
+	}
+	for _, tt := range tests {
+		word, flags := splitDicEntry(tt.line)
+		if word != tt.word || flags != tt.flags {
+			t.Errorf("splitDicEntry(%q) = %q, %q; want %q, %q", tt.line, word, flags, tt.word, tt.flags)
+		}
+	}
+}
+
+func TestDecodeDictionary(t *testing.T) {
+	tests := []struct {
+		data     string
+		encoding string
+		want     string
+	}{
+		{"\xef\xbb\xbfcafé", "UTF-8", "café"},
+		{"caf\xe9", "ISO8859-1", "café"},
+		{"plain", "", "plain"},
+	}
+	for _, tt := range tests {
+		got, err := decodeDictionary([]byte(tt.data), tt.encoding)
+		if err != nil || got != tt.want {
+			t.Errorf("decodeDictionary(%q, %q) = %q, %v; want %q", tt.data, tt.encoding, got, err, tt.want)
+		}
+	}
+}
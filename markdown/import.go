@@ -0,0 +1,888 @@
+package markdown
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	importHTML  = "HTML files"
+	importENEX  = "Evernote (.enex)"
+	importVault = "Markdown vault"
+)
+
+var (
+	errImportOverlap = errors.New("要导入的目录不能与当前笔记库重叠")
+	errNoENEXNotes   = errors.New("文件中没有 Evernote 笔记")
+
+	// ENML 中自闭合的 <en-media/> 和 <en-todo/> 在 HTML 解析器中不会闭合，需要先展开
+	enmlSelfClosing = regexp.MustCompile(`<(en-media|en-todo)\b([^>]*?)/>`)
+)
+
+// 常见图片类型的扩展名，其余类型使用 mime 包查找
+var mimeExts = map[string]string{
+	"image/gif":       ".gif",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/svg+xml":   ".svg",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// importReport 记录一次导入的结果
+type importReport struct {
+	Source      string
+	Notes       []string
+	Attachments int
+	Problems    []string
+}
+
+func (r *importReport) add(name, msg string) {
+	r.Problems = append(r.Problems, fmt.Sprintf("`%s`：%s", name, msg))
+}
+
+// markdown 生成导入报告笔记的内容
+func (r *importReport) markdown(root, path string) string {
+	var b strings.Builder
+	b.WriteString("# 导入报告\n\n")
+	fmt.Fprintf(&b, "- 来源：`%s`\n", r.Source)
+	fmt.Fprintf(&b, "- 时间：%s\n", time.Now().Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "- 笔记：%d 篇\n", len(r.Notes))
+	fmt.Fprintf(&b, "- 附件：%d 个\n", r.Attachments)
+
+	b.WriteString("\n## 无法转换的内容\n\n")
+	if len(r.Problems) == 0 {
+		b.WriteString("全部内容均已转换。\n")
+	}
+	for _, problem := range r.Problems {
+		b.WriteString("- " + problem + "\n")
+	}
+
+	if len(r.Notes) > 0 {
+		b.WriteString("\n## 导入的笔记\n\n")
+		for _, note := range r.Notes {
+			rel, _ := filepath.Rel(root, note)
+			fmt.Fprintf(&b, "- [%s](%s)\n", markdownEscaper.Replace(filepath.ToSlash(rel)), relativeLink(path, note))
+		}
+	}
+	return b.String()
+}
+
+// importer 保存一次导入过程中的状态
+type importer struct {
+	m      *MarkdownEditor
+	dest   string
+	report *importReport
+	copied map[string]string // 已作为附件导入的源文件 -> 附件路径
+	taken  map[string]bool   // 本次导入已占用的笔记路径
+}
+
+func (m *MarkdownEditor) newImporter(src, dest string) *importer {
+	return &importer{
+		m:      m,
+		dest:   dest,
+		report: &importReport{Source: src},
+		copied: map[string]string{},
+		taken:  map[string]bool{},
+	}
+}
+
+// notePath 返回不与已有文件和本次导入的其他笔记冲突的路径
+func (imp *importer) notePath(path string) string {
+	path = uniquePath(path)
+	for i := 1; imp.taken[strings.ToLower(path)]; i++ {
+		ext := filepath.Ext(path)
+		path = uniquePath(fmt.Sprintf("%s %d%s", strings.TrimSuffix(path, ext), i, ext))
+	}
+	imp.taken[strings.ToLower(path)] = true
+	return path
+}
+
+func (imp *importer) writeNote(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	imp.report.Notes = append(imp.report.Notes, path)
+	return nil
+}
+
+// writeReport 把导入报告写入导入目录，返回报告的路径
+func (imp *importer) writeReport() (string, error) {
+	if err := os.MkdirAll(imp.dest, 0755); err != nil {
+		return "", err
+	}
+	path := uniquePath(filepath.Join(imp.dest, "Import report"+noteExt))
+	return path, os.WriteFile(path, []byte(imp.report.markdown(imp.m.rootPath, path)), 0644)
+}
+
+// attach 把数据写为 note 的附件，返回附件路径
+func (imp *importer) attach(note, name string, r io.Reader) (string, error) {
+	dest, err := imp.m.writeAttachment(note, name, r)
+	if err == nil {
+		imp.report.Attachments++
+	}
+	return dest, err
+}
+
+// resource 导入 HTML 中引用的本地文件或 data URI，返回笔记中使用的链接；
+// 外部链接保持不变，无法导入时返回空字符串
+func (imp *importer) resource(name, source, note, src string) string {
+	if strings.HasPrefix(src, "data:") {
+		data, mimeType, err := decodeDataURI(src)
+		if err != nil {
+			imp.report.add(name, "无法解码 data URI 图片："+err.Error())
+			return ""
+		}
+		dest, err := imp.attach(note, "image"+mimeExt(mimeType), bytes.NewReader(data))
+		if err != nil {
+			imp.report.add(name, err.Error())
+			return ""
+		}
+		return relativeLink(note, dest)
+	}
+
+	path, ok := localFile(source, src)
+	if !ok {
+		return src
+	}
+	if dest, ok := imp.copied[path]; ok {
+		return relativeLink(note, dest)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		imp.report.add(name, "找不到引用的文件 "+src)
+		return ""
+	}
+	defer f.Close()
+	dest, err := imp.attach(note, filepath.Base(path), f)
+	if err != nil {
+		imp.report.add(name, err.Error())
+		return ""
+	}
+	imp.copied[path] = dest
+	return relativeLink(note, dest)
+}
+
+// localFile 把 HTML 中的 src 或 href 解析为本地文件路径，外部链接返回 false
+func localFile(source, src string) (string, bool) {
+	if strings.HasPrefix(strings.ToLower(src), "file:") {
+		u, err := url.Parse(src)
+		if err != nil {
+			return "", false
+		}
+		return filepath.FromSlash(u.Path), true
+	}
+	if isExternalLink(src) || strings.HasPrefix(src, "#") || strings.HasPrefix(src, "//") {
+		return "", false
+	}
+	if i := strings.IndexAny(src, "?#"); i >= 0 {
+		src = src[:i]
+	}
+	if unescaped, err := url.PathUnescape(src); err == nil {
+		src = unescaped
+	}
+	return resolveRelative(source, filepath.FromSlash(src)), true
+}
+
+// decodeDataURI 解码 data:[<mime>][;base64],<data>
+func decodeDataURI(uri string) ([]byte, string, error) {
+	meta, data, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, "", errors.New("格式错误")
+	}
+	params := strings.Split(meta, ";")
+	if params[len(params)-1] == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+		return decoded, params[0], err
+	}
+	decoded, err := url.PathUnescape(data)
+	return []byte(decoded), params[0], err
+}
+
+func mimeExt(mimeType string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if ext, ok := mimeExts[mimeType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+func isHTMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".html" || ext == ".htm"
+}
+
+// importHTML 导入一个 HTML 文件，或目录中的所有 HTML 文件并保留目录结构
+func (m *MarkdownEditor) importHTML(src, dest string) (*importer, error) {
+	src = mustAbs(src)
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Dir(src)
+	files := []string{src}
+	if info.IsDir() {
+		root = src
+		dest = uniquePath(filepath.Join(dest, filepath.Base(mustAbs(src))))
+		files = nil
+		err := walkFiles(src, func(path string) error {
+			if isHTMLFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	imp := m.newImporter(src, dest)
+
+	// 先确定每个 HTML 文件对应的笔记，以便把文件之间的链接改为指向笔记
+	notes := map[string]string{}
+	for _, path := range files {
+		rel, _ := filepath.Rel(root, path)
+		notes[path] = imp.notePath(filepath.Join(dest, strings.TrimSuffix(rel, filepath.Ext(rel))+noteExt))
+	}
+	for _, path := range files {
+		name, _ := filepath.Rel(root, path)
+		if err := imp.convertHTML(filepath.ToSlash(name), path, notes[path], notes); err != nil {
+			imp.report.add(filepath.ToSlash(name), err.Error())
+		}
+	}
+	return imp, nil
+}
+
+func (imp *importer) convertHTML(name, source, note string, notes map[string]string) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	// 按 <meta charset> 等信息转换旧网页常用的 GBK 等编码
+	enc, _, _ := charset.DetermineEncoding(data, "")
+	if decoded, err := enc.NewDecoder().Bytes(data); err == nil {
+		data = decoded
+	}
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	c := &htmlConverter{
+		image:   func(src string) string { return imp.resource(name, source, note, src) },
+		problem: func(msg string) { imp.report.add(name, msg) },
+	}
+	c.link = func(href string) string {
+		path, ok := localFile(source, href)
+		if !ok {
+			return href
+		}
+		if target, ok := notes[path]; ok {
+			link := relativeLink(note, target)
+			if _, anchor := splitAnchor(href); anchor != "" {
+				link = linkDest(strings.Trim(link, "<>") + "#" + anchor)
+			}
+			return link
+		}
+		if isFile(path) && !isHTMLFile(path) {
+			if link := imp.resource(name, source, note, href); link != "" {
+				return link
+			}
+		}
+		imp.report.add(name, "链接指向的文件不存在："+href)
+		return href
+	}
+	return imp.writeNote(note, c.convert(doc))
+}
+
+// enexNote 是 ENEX 文件中的一篇笔记
+type enexNote struct {
+	Title      string   `xml:"title"`
+	Content    string   `xml:"content"`
+	Created    string   `xml:"created"`
+	Updated    string   `xml:"updated"`
+	Tags       []string `xml:"tag"`
+	Attributes struct {
+		Author    string `xml:"author"`
+		SourceURL string `xml:"source-url"`
+	} `xml:"note-attributes"`
+	Resources []enexResource `xml:"resource"`
+}
+
+type enexResource struct {
+	Data struct {
+		Encoding string `xml:"encoding,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"data"`
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+
+	data []byte
+	path string // 写入后的附件路径
+}
+
+// enexMeta 是导入的 Evernote 笔记的 front matter
+type enexMeta struct {
+	Created string   `yaml:"created,omitempty"`
+	Updated string   `yaml:"updated,omitempty"`
+	Author  string   `yaml:"author,omitempty"`
+	Source  string   `yaml:"source,omitempty"`
+	Tags    []string `yaml:"tags,omitempty"`
+}
+
+// importENEX 导入 Evernote 导出的 .enex 文件，笔记放在与文件同名的目录中
+func (m *MarkdownEditor) importENEX(src, dest string) (*importer, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	base := filepath.Base(src)
+	dest = uniquePath(filepath.Join(dest, strings.TrimSuffix(base, filepath.Ext(base))))
+	imp := m.newImporter(src, dest)
+
+	// 逐篇解码，避免把带有大量附件的整个文件读入内存
+	d := xml.NewDecoder(f)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(imp.report.Notes) == 0 {
+				return nil, err
+			}
+			imp.report.add(base, "文件在第 "+fmt.Sprint(d.InputOffset())+" 字节处损坏，之后的笔记没有导入："+err.Error())
+			break
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+		var note enexNote
+		if err := d.DecodeElement(&note, &start); err != nil {
+			imp.report.add(base, err.Error())
+			break
+		}
+		if err := imp.importENEXNote(&note); err != nil {
+			imp.report.add(note.Title, err.Error())
+		}
+	}
+	if len(imp.report.Notes) == 0 && len(imp.report.Problems) == 0 {
+		return nil, errNoENEXNotes
+	}
+	return imp, nil
+}
+
+func (imp *importer) importENEXNote(n *enexNote) error {
+	title := strings.TrimSpace(n.Title)
+	if title == "" {
+		title = "Untitled"
+	}
+	name, err := sanitizeName(strings.NewReplacer("/", "-", `\`, "-").Replace(title))
+	if err != nil {
+		name = "Untitled"
+	}
+	note := imp.notePath(filepath.Join(imp.dest, name+noteExt))
+
+	// 资源按内容的 MD5 与正文中的 <en-media hash> 对应
+	resources := map[string]*enexResource{}
+	var order []string
+	for i := range n.Resources {
+		res := &n.Resources[i]
+		if res.Data.Encoding != "" && res.Data.Encoding != "base64" {
+			imp.report.add(title, "不支持的资源编码 "+res.Data.Encoding)
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(res.Data.Value), ""))
+		if err != nil {
+			imp.report.add(title, "无法解码附件 "+res.FileName)
+			continue
+		}
+		sum := md5.Sum(data)
+		hash := hex.EncodeToString(sum[:])
+		if _, ok := resources[hash]; !ok {
+			res.data = data
+			resources[hash] = res
+			order = append(order, hash)
+		}
+	}
+	write := func(res *enexResource) string {
+		if res.path == "" {
+			fileName := res.FileName
+			if _, err := sanitizeName(fileName); err != nil {
+				fileName = "attachment" + mimeExt(res.Mime)
+			}
+			path, err := imp.attach(note, fileName, bytes.NewReader(res.data))
+			if err != nil {
+				imp.report.add(title, err.Error())
+				return ""
+			}
+			res.path = path
+		}
+		return attachmentLink(note, res.path)
+	}
+
+	c := &htmlConverter{
+		problem: func(msg string) { imp.report.add(title, msg) },
+		media: func(hash, mimeType string) string {
+			res, ok := resources[strings.ToLower(hash)]
+			if !ok {
+				imp.report.add(title, "找不到附件 "+hash)
+				return ""
+			}
+			return write(res)
+		},
+	}
+	doc, err := html.Parse(strings.NewReader(enmlSelfClosing.ReplaceAllString(n.Content, "<$1$2></$1>")))
+	if err != nil {
+		return err
+	}
+	body := c.convert(doc)
+
+	// 正文中没有引用的附件附在笔记末尾
+	var extra []string
+	for _, hash := range order {
+		if res := resources[hash]; res.path == "" {
+			if link := write(res); link != "" {
+				extra = append(extra, link)
+			}
+		}
+	}
+	if len(extra) > 0 {
+		body = strings.TrimRight(body, "\n") + "\n\n" + strings.Join(extra, "\n\n") + "\n"
+	}
+
+	created, updated := enexTime(n.Created), enexTime(n.Updated)
+	meta := enexMeta{
+		Author: strings.TrimSpace(n.Attributes.Author),
+		Source: strings.TrimSpace(n.Attributes.SourceURL),
+		Tags:   n.Tags,
+	}
+	if !created.IsZero() {
+		meta.Created = created.Local().Format("2006-01-02 15:04")
+	}
+	if !updated.IsZero() {
+		meta.Updated = updated.Local().Format("2006-01-02 15:04")
+	}
+	front, err := yaml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	content := body
+	if len(bytes.TrimSpace(front)) > 2 { // 空的 front matter 为 "{}"
+		content = "---\n" + string(front) + "---\n\n" + body
+	}
+	if err := imp.writeNote(note, content); err != nil {
+		return err
+	}
+
+	// 保留笔记在 Evernote 中的修改时间
+	if updated.IsZero() {
+		updated = created
+	}
+	if !updated.IsZero() {
+		os.Chtimes(note, updated, updated)
+	}
+	return nil
+}
+
+// enexTime 解析 ENEX 中的 20060102T150405Z 格式时间
+func enexTime(s string) time.Time {
+	t, err := time.Parse("20060102T150405Z", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// importVault 复制另一个 Markdown 笔记库，并把其中的 wiki 链接、省略扩展名的链接和
+// 从库根目录开始的链接转换为本笔记库使用的相对链接
+func (m *MarkdownEditor) importVault(src, dest string) (*importer, error) {
+	src = mustAbs(src)
+	root := mustAbs(m.rootPath)
+	if _, ok := relUnder(src, root); ok {
+		return nil, errImportOverlap
+	}
+	if _, ok := relUnder(root, src); ok {
+		return nil, errImportOverlap
+	}
+
+	dest = uniquePath(filepath.Join(dest, filepath.Base(src)))
+	imp := m.newImporter(src, dest)
+
+	// 复制所有文件，跳过 .obsidian、.git 等隐藏目录
+	files := map[string][]string{} // 小写文件名 -> 附件路径
+	err := walkFiles(src, func(path string) error {
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dest, rel)
+		if err := copyFile(path, target); err != nil {
+			return err
+		}
+		if isNote(path) {
+			imp.report.Notes = append(imp.report.Notes, target)
+		} else {
+			imp.report.Attachments++
+			key := strings.ToLower(filepath.Base(path))
+			files[key] = append(files[key], target)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := indexNotes(dest, func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		return string(data), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	conv := &linkConverter{imp: imp, idx: idx, files: files}
+	for _, path := range idx.paths {
+		content := idx.notes[path].Content
+		if converted := conv.convert(path, content); converted != content {
+			if err := os.WriteFile(path, []byte(converted), 0644); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return imp, nil
+}
+
+// copyFile 复制文件并保留修改时间
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if info, err := in.Stat(); err == nil {
+		os.Chtimes(dest, info.ModTime(), info.ModTime())
+	}
+	return nil
+}
+
+// linkConverter 转换导入的笔记库中的链接
+type linkConverter struct {
+	imp   *importer
+	idx   *vaultIndex
+	files map[string][]string
+}
+
+// convert 转换笔记中的链接，跳过 front matter、代码块和行内代码
+func (lc *linkConverter) convert(notePath, content string) string {
+	lines := strings.Split(content, "\n")
+	forEachTextLine(content, func(i int, line string) {
+		var b strings.Builder
+		last := 0
+		for _, span := range codeSpanPattern.FindAllStringIndex(line, -1) {
+			b.WriteString(lc.convertText(notePath, line[last:span[0]]))
+			b.WriteString(line[span[0]:span[1]])
+			last = span[1]
+		}
+		b.WriteString(lc.convertText(notePath, line[last:]))
+		if strings.HasSuffix(lines[i], "\r") {
+			b.WriteString("\r")
+		}
+		lines[i] = b.String()
+	})
+	return strings.Join(lines, "\n")
+}
+
+func (lc *linkConverter) convertText(notePath, text string) string {
+	text = mdLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return lc.markdownLink(notePath, match)
+	})
+	return wikiLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return lc.wikiLink(notePath, match)
+	})
+}
+
+// markdownLink 修正目标需要按文件名查找、省略了 .md 或从库根目录开始的 Markdown 链接
+func (lc *linkConverter) markdownLink(notePath, match string) string {
+	loc := mdLinkPattern.FindStringSubmatchIndex(match)
+	raw := match[loc[6]:loc[7]]
+	dest := strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">")
+	if dest == "" || isExternalLink(dest) || strings.HasPrefix(dest, "#") {
+		return match
+	}
+	target, anchor := splitAnchor(dest)
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	var path string
+	if strings.HasPrefix(target, "/") {
+		path = filepath.Join(lc.idx.root, filepath.FromSlash(target))
+	} else {
+		path = resolveRelative(notePath, filepath.FromSlash(target))
+		if isFile(path) {
+			return match
+		}
+	}
+	switch {
+	case isFile(path):
+	case isFile(path + noteExt):
+		path += noteExt
+	default:
+		if path = lc.find(notePath, target); path == "" {
+			lc.imp.report.add(lc.idx.relPath(notePath), "找不到链接目标 "+dest)
+			return match
+		}
+	}
+	return match[:loc[6]] + noteHref(notePath, path, anchor) + match[loc[7]:]
+}
+
+// wikiLink 把 [[笔记]] 转换为 [笔记](笔记.md)，![[图片]] 转换为 ![](图片)。
+// 嵌入其他笔记和找不到目标的链接保持不变并写入报告
+func (lc *linkConverter) wikiLink(notePath, match string) string {
+	sub := wikiLinkPattern.FindStringSubmatch(match)
+	embed := sub[1] == "!"
+	inner, alias, _ := strings.Cut(sub[2], "|")
+	target, anchor := splitAnchor(inner)
+	target, anchor = strings.TrimSpace(target), strings.TrimSpace(anchor)
+	name := lc.idx.relPath(notePath)
+
+	if target == "" {
+		if anchor == "" || embed {
+			return match
+		}
+		text := alias
+		if text == "" {
+			text = anchor
+		}
+		return "[" + markdownEscaper.Replace(text) + "](#" + anchorID(anchor) + ")"
+	}
+
+	path := lc.find(notePath, target)
+	switch {
+	case path == "":
+		lc.imp.report.add(name, "找不到链接目标 "+match)
+		return match
+	case embed && isNote(path):
+		lc.imp.report.add(name, "嵌入笔记 "+match+" 保持原样")
+		return match
+	case embed && isImage(path):
+		// 图片的别名在其他笔记软件中通常表示尺寸
+		if strings.Trim(alias, "0123456789x ") == "" {
+			alias = ""
+		}
+		return "![" + markdownEscaper.Replace(alias) + "](" + noteHref(notePath, path, "") + ")"
+	}
+
+	text := alias
+	if text == "" {
+		text = strings.TrimSpace(inner)
+		if !isNote(path) {
+			text = filepath.Base(target)
+		}
+	}
+	return "[" + markdownEscaper.Replace(text) + "](" + noteHref(notePath, path, anchor) + ")"
+}
+
+// find 按 wiki 链接的写法查找笔记或附件：可以只写文件名，也可以带上相对于笔记库的路径
+func (lc *linkConverter) find(notePath, target string) string {
+	if path := lc.idx.resolveName(notePath, target); path != "" {
+		return path
+	}
+
+	target = filepath.FromSlash(target)
+	for _, path := range []string{resolveRelative(notePath, target), filepath.Join(lc.idx.root, target)} {
+		if isFile(path) {
+			return path
+		}
+	}
+	candidates := append([]string(nil), lc.files[strings.ToLower(filepath.Base(target))]...)
+	sort.Strings(candidates)
+	for _, path := range candidates {
+		if strings.ContainsRune(target, filepath.Separator) {
+			if strings.HasSuffix(strings.ToLower(path), strings.ToLower(string(filepath.Separator)+target)) {
+				return path
+			}
+			continue
+		}
+		if filepath.Dir(path) == filepath.Dir(notePath) {
+			return path
+		}
+	}
+	if len(candidates) > 0 && !strings.ContainsRune(target, filepath.Separator) {
+		return candidates[0]
+	}
+	return ""
+}
+
+// noteHref 生成从 from 指向 to 的相对链接目标，anchor 是标题或块 ID
+func noteHref(from, to, anchor string) string {
+	link := strings.TrimSuffix(strings.TrimPrefix(relativeLink(from, to), "<"), ">")
+	if anchor != "" {
+		link += "#" + anchorID(anchor)
+	}
+	return linkDest(link)
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// vaultFolders 返回笔记库中的所有目录（相对路径，根目录为 "."），跳过隐藏目录
+func (m *MarkdownEditor) vaultFolders() []string {
+	var folders []string
+	filepath.WalkDir(m.rootPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != m.rootPath && isHidden(d.Name()) {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(m.rootPath, path)
+		folders = append(folders, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(folders)
+	return folders
+}
+
+// showImport 显示导入对话框，导入完成后打开导入报告
+func (m *MarkdownEditor) showImport() {
+	kind := widget.NewSelect([]string{importHTML, importENEX, importVault}, nil)
+	srcEntry := widget.NewEntry()
+	srcEntry.SetPlaceHolder("文件或目录")
+
+	setPath := func(uri fyne.URI) {
+		if uri != nil {
+			srcEntry.SetText(uri.Path())
+		}
+	}
+	fileButton := widget.NewButton("File", func() {
+		open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, m.window)
+				return
+			}
+			if reader != nil {
+				reader.Close()
+				setPath(reader.URI())
+			}
+		}, m.window)
+		if kind.Selected == importENEX {
+			open.SetFilter(storage.NewExtensionFileFilter([]string{".enex"}))
+		} else {
+			open.SetFilter(storage.NewExtensionFileFilter([]string{".html", ".htm"}))
+		}
+		open.Show()
+	})
+	folderButton := widget.NewButton("Folder", func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, m.window)
+				return
+			}
+			if dir != nil {
+				setPath(dir)
+			}
+		}, m.window)
+	})
+	kind.OnChanged = func(selected string) {
+		// ENEX 只能选择文件，笔记库只能选择目录，HTML 两者均可
+		fileButton.Enable()
+		folderButton.Enable()
+		switch selected {
+		case importENEX:
+			folderButton.Disable()
+		case importVault:
+			fileButton.Disable()
+		}
+	}
+	kind.SetSelected(importHTML)
+
+	destSelect := widget.NewSelect(m.vaultFolders(), nil)
+	destSelect.SetSelected(".")
+	if m.selectedNode != "" && m.isBranch(m.selectedNode) {
+		if rel, err := filepath.Rel(m.rootPath, m.uidToPath(m.selectedNode)); err == nil {
+			destSelect.SetSelected(filepath.ToSlash(rel))
+		}
+	}
+
+	m.showCustomFormDialog("Import", "Import", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Type", kind),
+		widget.NewFormItem("Source", container.NewBorder(nil, nil, nil, container.NewHBox(fileButton, folderButton), srcEntry)),
+		widget.NewFormItem("Into", destSelect),
+	}, func(ok bool) {
+		src := strings.TrimSpace(srcEntry.Text)
+		if !ok || src == "" || destSelect.Selected == "" {
+			return
+		}
+		dest := filepath.Join(m.rootPath, filepath.FromSlash(destSelect.Selected))
+
+		var imp *importer
+		var err error
+		switch kind.Selected {
+		case importHTML:
+			imp, err = m.importHTML(src, dest)
+		case importENEX:
+			imp, err = m.importENEX(src, dest)
+		case importVault:
+			imp, err = m.importVault(src, dest)
+		}
+		if err == nil {
+			var report string
+			if report, err = imp.writeReport(); err == nil {
+				m.refreshTree()
+				m.openFile(report)
+			}
+		}
+		if err != nil {
+			m.refreshTree()
+			dialog.ShowError(err, m.window)
+		}
+	}, m.window)
+}
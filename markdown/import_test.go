@@ -0,0 +1,119 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+		problems         int
+	}{
+		{"headings", `<h1>Title</h1><h3>Sub <em>x</em></h3><h2></h2>`, "# Title\n\n### Sub *x*\n", 0},
+		{"nested lists", `<ul><li>a<ul><li>b<ol><li>c</li></ol></li></ul></li><li>d</li></ul>`,
+			"- a\n  - b\n    1. c\n- d\n", 0},
+		{"ordered start and paragraphs", `<ol start="3"><li>x</li><li><p>y</p><p>z</p></li></ol>`,
+			"3. x\n4. y\n\n   z\n", 0},
+		{"list directly in list", `<ul><li>a</li><ul><li>b</li><li>c</li></ul></ul>`, "- a\n  - b\n  - c\n", 0},
+		{"list directly in ordered list", `<ol><li>a</li><ul><li>b</li></ul><li>c</li></ol>`, "1. a\n   - b\n2. c\n", 0},
+		{"list before any item", `<ul><ul><li>b</li></ul></ul>`, "- b\n", 0},
+		{"tasks", `<ul><li><input type="checkbox" checked> done</li><li><input type="checkbox"> todo</li></ul>`,
+			"- [x] done\n- [ ] todo\n", 0},
+		{"table", `<table><caption>Cap</caption><tr><th>A</th><th>B</th></tr><tr><td>1|2</td><td><b>3</b></td></tr></table>`,
+			"Cap\n\n| A | B |\n| --- | --- |\n| 1\\|2 | **3** |\n", 0},
+		{"single row table", `<table><tr><td>only</td></tr></table>`, "| only |\n| --- |\n", 0},
+		{"code block", "<pre><code class=\"language-go\">func f() {\n\treturn 1\n}</code></pre>",
+			"```go\nfunc f() {\n\treturn 1\n}\n```\n", 0},
+		{"code block with fence", "<pre>a\n```\nb</pre>", "````\na\n```\nb\n````\n", 0},
+		{"links and images", `<p>see <a href="https://x.com/a b">the site</a> and <img src="img/a.png" alt="A"> <a href="#top">top</a></p>`,
+			"see [the site](<https://x.com/a b>) and ![A](img/a.png) [top](#top)\n", 0},
+		{"escaping", "<p>*not em* and <code>a ` b</code></p><p># not heading</p>",
+			"\\*not em\\* and ``a ` b``\n\n\\# not heading\n", 0},
+		{"blockquote", `<blockquote><p>q1</p><p>q2</p></blockquote>`, "> q1\n>\n> q2\n", 0},
+		{"line break and rule", `<p>a<br>b</p><hr>`, "a\\\nb\n\n---\n", 0},
+		{"emphasis spaces", `<p><strong> bold </strong>text</p>`, "**bold** text\n", 0},
+		{"unsupported", `<script>x</script><iframe src="y"></iframe><video></video><p>kept</p>`, "kept\n", 2},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var problems []string
+		c := &htmlConverter{
+			image:   func(src string) string { return src },
+			link:    func(href string) string { return href },
+			problem: func(msg string) { problems = append(problems, msg) },
+		}
+		if got := c.convert(doc); got != tt.want || len(problems) != tt.problems {
+			t.Errorf("%s: convert() = %q, %d problems; want %q, %d", tt.name, got, len(problems), tt.want, tt.problems)
+		}
+	}
+}
+
+func TestImportHTML(t *testing.T) {
+	m := newTestEditor(t)
+	m.config.AttachmentFolder = "attachments"
+	src := filepath.Join(t.TempDir(), "site")
+	files := map[string]string{
+		"index.html": `<p><img src="a/img.png"><img src="b/img.png"><img src="a/img.png">` +
+			`<img src="data:image/png;base64,iVBORw0KGgo="><a href="page.html#sec">page</a><img src="missing.png" alt="gone"></p>`,
+		"page.html": `<h1>Page</h1><img src="a/img.png">`,
+		"a/img.png": "A",
+		"b/img.png": "B",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 笔记库中已有同名目录时导入到新的目录
+	if err := os.Mkdir(filepath.Join(m.rootPath, "site"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	imp, err := m.importHTML(src, m.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(m.rootPath, "site 1")
+	if want := []string{filepath.Join(dest, "index.md"), filepath.Join(dest, "page.md")}; !reflect.DeepEqual(imp.report.Notes, want) {
+		t.Errorf("notes = %q, want %q", imp.report.Notes, want)
+	}
+	// 同一个文件只复制一次，同名的不同文件自动编号
+	if imp.report.Attachments != 3 {
+		t.Errorf("attachments = %d, want 3", imp.report.Attachments)
+	}
+	if len(imp.report.Problems) != 1 || !strings.Contains(imp.report.Problems[0], "missing.png") {
+		t.Errorf("problems = %q, want the missing image", imp.report.Problems)
+	}
+
+	want := map[string]string{
+		"site 1/index.md": "![](../attachments/img.png)![](<../attachments/img 1.png>)![](../attachments/img.png)" +
+			"![](../attachments/image.png)[page](page.md#sec)gone\n",
+		"site 1/page.md":        "# Page\n\n![](../attachments/img.png)\n",
+		"attachments/img.png":   "A",
+		"attachments/img 1.png": "B",
+		"attachments/image.png": "\x89PNG\r\n\x1a\n",
+	}
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(m.rootPath, filepath.FromSlash(name)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+	}
+}
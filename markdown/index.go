@@ -0,0 +1,276 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// noteInfo 是索引中一篇笔记的信息
+type noteInfo struct {
+	Path    string
+	Name    string // 不含扩展名的文件名
+	Content string
+	Meta    map[string]interface{} // front matter
+	Tags    []string
+	Links   []noteLink
+	Targets []string // 链接到的笔记路径，已去重
+	ModTime time.Time
+	// Encrypted 为 true 时是加密笔记，索引中只有文件名，没有内容
+	Encrypted bool
+}
+
+// vaultIndex 是笔记库的索引，记录笔记之间的链接和标签
+type vaultIndex struct {
+	root   string
+	notes  map[string]*noteInfo
+	paths  []string            // 按路径排序的笔记列表
+	byName map[string][]string // 小写文件名 -> 笔记路径
+}
+
+// buildIndex 扫描笔记库生成索引，已打开的笔记使用编辑器中的内容
+func (m *MarkdownEditor) buildIndex() (*vaultIndex, error) {
+	return indexNotes(m.rootPath, m.noteContent)
+}
+
+// indexNotes 扫描 root 下的笔记生成索引，read 用于读取笔记内容
+func indexNotes(root string, read func(path string) (string, error)) (*vaultIndex, error) {
+	idx := &vaultIndex{
+		root:   root,
+		notes:  map[string]*noteInfo{},
+		byName: map[string][]string{},
+	}
+
+	err := walkNotes(root, func(path string) error {
+		content, err := read(path)
+		encrypted := errors.Is(err, errEncryptedNote)
+		if err != nil && !encrypted {
+			return err
+		}
+		info := &noteInfo{
+			Path:      path,
+			Name:      strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+			Content:   content,
+			Meta:      parseFrontMatter(content),
+			Links:     extractLinks(content),
+			Encrypted: encrypted,
+		}
+		if stat, err := os.Stat(path); err == nil {
+			info.ModTime = stat.ModTime()
+		}
+		info.Tags = noteTags(content, info.Meta)
+
+		idx.notes[path] = info
+		idx.paths = append(idx.paths, path)
+		key := strings.ToLower(info.Name)
+		idx.byName[key] = append(idx.byName[key], path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(idx.paths)
+
+	for _, info := range idx.notes {
+		seen := map[string]bool{}
+		for _, link := range info.Links {
+			target := idx.resolve(info.Path, link)
+			if target != "" && target != info.Path && !seen[target] {
+				seen[target] = true
+				info.Targets = append(info.Targets, target)
+			}
+		}
+		sort.Strings(info.Targets)
+	}
+	return idx, nil
+}
+
+// resolve 将链接解析为笔记路径，链接不指向笔记库中的笔记时返回空字符串
+func (idx *vaultIndex) resolve(from string, link noteLink) string {
+	if link.Target == "" || isExternalLink(link.Target) {
+		return ""
+	}
+	if !link.Wiki {
+		path := resolveRelative(from, link.Target)
+		if _, ok := idx.notes[path]; ok {
+			return path
+		}
+		if _, ok := idx.notes[path+noteExt]; ok {
+			return path + noteExt
+		}
+		return ""
+	}
+	return idx.resolveName(from, link.Target)
+}
+
+// resolveName 按 wiki 链接的写法查找笔记：可以只写文件名，也可以带上相对于笔记库的路径。
+// 有多篇同名笔记时，优先选择与当前笔记同目录的那篇
+func (idx *vaultIndex) resolveName(from, target string) string {
+	target = strings.TrimSuffix(filepath.FromSlash(target), noteExt)
+	candidates := idx.byName[strings.ToLower(filepath.Base(target))]
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	if strings.ContainsRune(target, filepath.Separator) {
+		want := strings.ToLower(target + noteExt)
+		for _, path := range candidates {
+			rel, _ := filepath.Rel(idx.root, path)
+			if strings.HasSuffix(strings.ToLower(rel), want) {
+				return path
+			}
+		}
+		return ""
+	}
+
+	for _, path := range candidates {
+		if filepath.Dir(path) == filepath.Dir(from) {
+			return path
+		}
+	}
+	return candidates[0]
+}
+
+// backlinks 返回链接到 path 的笔记
+func (idx *vaultIndex) backlinks(path string) []string {
+	var result []string
+	for _, p := range idx.paths {
+		for _, target := range idx.notes[p].Targets {
+			if target == path {
+				result = append(result, p)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// relPath 返回笔记相对于笔记库根目录的路径，使用 / 分隔
+func (idx *vaultIndex) relPath(path string) string {
+	rel, err := filepath.Rel(idx.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// allTags 返回笔记库中出现的所有标签
+func (idx *vaultIndex) allTags() []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, info := range idx.notes {
+		for _, tag := range info.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// folders 返回包含笔记的所有目录（相对路径，根目录为 "."）
+func (idx *vaultIndex) folders() []string {
+	seen := map[string]bool{}
+	var folders []string
+	for _, path := range idx.paths {
+		dir := filepath.ToSlash(filepath.Dir(idx.relPath(path)))
+		for dir != "" && !seen[dir] {
+			seen[dir] = true
+			folders = append(folders, dir)
+			if dir == "." {
+				break
+			}
+			dir = filepath.ToSlash(filepath.Dir(dir))
+		}
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+// inFolder 判断笔记是否位于 folder（相对路径）或其子目录中
+func (idx *vaultIndex) inFolder(path, folder string) bool {
+	if folder == "" || folder == "." {
+		return true
+	}
+	rel := idx.relPath(path)
+	return strings.HasPrefix(rel, strings.TrimSuffix(folder, "/")+"/")
+}
+
+// hasTag 判断笔记是否带有 tag 标签，父标签也会匹配其子标签（#a 匹配 #a/b）
+func (info *noteInfo) hasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	for _, t := range info.Tags {
+		t = strings.ToLower(t)
+		if t == tag || strings.HasPrefix(t, tag+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFrontMatter 解析笔记开头 --- 之间的 YAML，没有或解析失败时返回 nil
+func parseFrontMatter(content string) map[string]interface{} {
+	lines := strings.Split(content, "\n")
+	end := frontMatterEnd(lines)
+	if end == 0 {
+		return nil
+	}
+	var meta map[string]interface{}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end-1], "\n")), &meta); err != nil {
+		return nil
+	}
+	return meta
+}
+
+// metaStrings 将 front matter 中的值转换为字符串列表，支持列表和逗号或空格分隔的字符串
+func metaStrings(v interface{}) []string {
+	var result []string
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			if item != nil {
+				result = append(result, fmt.Sprint(item))
+			}
+		}
+	case string:
+		result = strings.FieldsFunc(t, func(r rune) bool { return r == ',' || r == ' ' })
+	case nil:
+	default:
+		result = append(result, fmt.Sprint(t))
+	}
+	return result
+}
+
+// noteTags 合并 front matter 中的 tags 和正文中的 #标签
+func noteTags(content string, meta map[string]interface{}) []string {
+	seen := map[string]bool{}
+	var tags []string
+	add := func(tag string) {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, key := range []string{"tags", "tag"} {
+		for _, tag := range metaStrings(meta[key]) {
+			add(tag)
+		}
+	}
+	forEachTextLine(content, func(_ int, line string) {
+		line = codeSpanPattern.ReplaceAllString(line, "")
+		for _, tag := range extractTags(line) {
+			add(tag)
+		}
+	})
+	return tags
+}
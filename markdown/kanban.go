@@ -0,0 +1,496 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// front matter 中有 kanban: true 的笔记打开为看板：二级标题是列，列中顶层的列表项是卡片，
+// 卡片下缩进的行也属于这张卡片。标题为 Archive 的列保存归档的卡片，默认不显示。
+// 看板上的操作只移动、插入或替换卡片所在的行，笔记中的其它内容保持不变
+
+// kanbanArchive 是保存归档卡片的列的标题
+const kanbanArchive = "Archive"
+
+// kanbanColumnWidth 是看板中每一列的宽度
+const kanbanColumnWidth = 260
+
+// kanbanCard 是看板中的一张卡片
+type kanbanCard struct {
+	start, end int    // 卡片占用的行 [start, end)
+	prefix     string // 第一行的列表标记，如 "- " 或 "- [ ] "
+	indent     int    // 后续各行的缩进
+	text       string // 去掉列表标记和缩进后的内容
+}
+
+// kanbanColumn 是看板中的一列
+type kanbanColumn struct {
+	title string
+	line  int // 标题所在的行
+	end   int // 下一个标题所在的行，最后一列为总行数
+	cards []kanbanCard
+}
+
+func (c *kanbanColumn) isArchive() bool {
+	return strings.EqualFold(c.title, kanbanArchive)
+}
+
+// isKanban 判断笔记是否应该打开为看板
+func isKanban(content string) bool {
+	kanban, _ := parseFrontMatter(content)["kanban"].(bool)
+	return kanban
+}
+
+// parseKanban 解析看板的各列和卡片，跳过 front matter 和代码块中的标题
+func parseKanban(content string) []*kanbanColumn {
+	lines := strings.Split(content, "\n")
+	text := make([]bool, len(lines))
+	forEachTextLine(content, func(i int, _ string) { text[i] = true })
+
+	var columns []*kanbanColumn
+	var column *kanbanColumn
+	for i := frontMatterEnd(lines); i < len(lines); i++ {
+		if !text[i] {
+			continue
+		}
+		line := strings.TrimRight(lines[i], "\r")
+		if level, title, ok := parseHeading(line); ok {
+			if column != nil {
+				column.end = i
+			}
+			column = nil
+			if level == 2 {
+				column = &kanbanColumn{title: title, line: i, end: len(lines)}
+				columns = append(columns, column)
+			}
+			continue
+		}
+		m := mdListPattern.FindStringSubmatchIndex(line)
+		if column == nil || m == nil || m[3] > m[2] {
+			continue
+		}
+
+		// 卡片到下一个没有缩进的非空行或标题为止，不包括末尾的空行
+		card := kanbanCard{start: i, prefix: line[:m[1]], indent: m[11]}
+		card.end = i + 1
+		for j := i + 1; j < len(lines); j++ {
+			next := strings.TrimRight(lines[j], "\r")
+			if strings.TrimSpace(next) == "" {
+				continue
+			}
+			if next[0] != ' ' && next[0] != '\t' {
+				break
+			}
+			if _, _, ok := parseHeading(next); ok && text[j] {
+				break
+			}
+			card.end = j + 1
+		}
+		body := []string{line[m[1]:]}
+		for _, next := range lines[i+1 : card.end] {
+			next = strings.TrimRight(next, "\r")
+			n := 0
+			for n < len(next) && n < card.indent && (next[n] == ' ' || next[n] == '\t') {
+				n++
+			}
+			body = append(body, next[n:])
+		}
+		card.text = strings.Join(body, "\n")
+		column.cards = append(column.cards, card)
+		i = card.end - 1
+	}
+	return columns
+}
+
+// cardLines 把卡片内容转换为笔记中的行，第一行加上列表标记，后续各行缩进
+func cardLines(prefix string, indent int, text string) []string {
+	var lines []string
+	for i, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case i == 0:
+			lines = append(lines, prefix+line)
+		case strings.TrimSpace(line) == "":
+			lines = append(lines, "")
+		default:
+			lines = append(lines, strings.Repeat(" ", indent)+line)
+		}
+	}
+	return lines
+}
+
+// spliceLines 删除 [start, end) 的行并在第 at 行之前插入 block，at 是删除之前的行号
+func spliceLines(lines []string, start, end, at int, block []string) []string {
+	result := make([]string, 0, len(lines)+len(block))
+	for i := 0; i <= len(lines); i++ {
+		if i == at {
+			result = append(result, block...)
+		}
+		if i < len(lines) && (i < start || i >= end) {
+			result = append(result, lines[i])
+		}
+	}
+	return result
+}
+
+// insertPosition 返回把 block 插入到列中第 index 张卡片之前的行号；
+// 列中没有卡片时插入到这一列的末尾，与前面的标题或内容之间保留一个空行，与后面的内容在需要时用空行隔开
+func (c *kanbanColumn) insertPosition(lines []string, index int, block []string) (int, []string) {
+	if index < len(c.cards) {
+		return c.cards[index].start, block
+	}
+	if len(c.cards) > 0 {
+		return c.cards[len(c.cards)-1].end, block
+	}
+	at := c.end
+	for at > c.line+1 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	if at+1 < min(c.end, len(lines)) && strings.TrimSpace(lines[at]) == "" {
+		at++ // 已经有空行，插入到空行之后
+	} else {
+		block = append([]string{""}, block...)
+	}
+	if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		block = append(block, "")
+	}
+	return at, block
+}
+
+// kanbanView 是看板模式的标签页内容，可以在看板和源码之间切换。
+// 源码模式显示普通的编辑和预览分栏，看板上的修改都写入同一个编辑器
+type kanbanView struct {
+	widget.BaseWidget
+
+	m      *MarkdownEditor
+	editor *noteEntry
+	split  *container.Split
+
+	toggle      *widget.Button
+	tools       *fyne.Container
+	board       *fyne.Container
+	boardArea   fyne.CanvasObject
+	ghost       *canvas.Rectangle
+	showArchive bool
+	sourceMode  bool
+
+	columns []*kanbanColumn
+	// targets 是看板上显示的各列和放置卡片的容器，用于计算拖放的位置
+	targets []kanbanTarget
+	dropAt  fyne.Position
+}
+
+type kanbanTarget struct {
+	column *kanbanColumn
+	box    *fyne.Container
+}
+
+func (m *MarkdownEditor) newKanbanView(editor *noteEntry, split *container.Split) *kanbanView {
+	v := &kanbanView{m: m, editor: editor, split: split}
+	v.toggle = widget.NewButtonWithIcon("Source", theme.DocumentIcon(), func() { v.setSourceMode(!v.sourceMode) })
+	archive := widget.NewCheck("Show Archive", func(show bool) {
+		v.showArchive = show
+		v.refresh()
+	})
+	v.tools = container.NewHBox(widget.NewButtonWithIcon("Add Column", theme.ContentAddIcon(), v.addColumn), archive)
+
+	v.board = container.NewHBox()
+	v.ghost = canvas.NewRectangle(theme.Color(theme.ColorNameSelection))
+	v.ghost.CornerRadius = theme.InputRadiusSize()
+	v.ghost.Hide()
+	v.boardArea = container.NewStack(container.NewScroll(v.board), container.NewWithoutLayout(v.ghost))
+	split.Hide()
+	v.refresh()
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *kanbanView) CreateRenderer() fyne.WidgetRenderer {
+	toolbar := container.NewHBox(v.toggle, v.tools)
+	return widget.NewSimpleRenderer(container.NewBorder(toolbar, nil, nil, nil, container.NewStack(v.split, v.boardArea)))
+}
+
+// setSourceMode 在看板和源码之间切换
+func (v *kanbanView) setSourceMode(source bool) {
+	v.sourceMode = source
+	if source {
+		v.toggle.SetText("Board")
+		v.toggle.SetIcon(theme.GridIcon())
+		v.tools.Hide()
+		v.boardArea.Hide()
+		v.split.Show()
+		v.m.window.Canvas().Focus(v.editor)
+		return
+	}
+	v.toggle.SetText("Source")
+	v.toggle.SetIcon(theme.DocumentIcon())
+	v.tools.Show()
+	v.split.Hide()
+	v.boardArea.Show()
+	v.refresh()
+}
+
+// textChanged 在编辑器内容变化后更新看板，源码模式下切换回看板时再更新
+func (v *kanbanView) textChanged() {
+	if !v.sourceMode {
+		v.refresh()
+	}
+}
+
+// refresh 按编辑器中的内容重新生成看板
+func (v *kanbanView) refresh() {
+	v.columns = parseKanban(v.editor.Text)
+	v.targets = nil
+	v.board.RemoveAll()
+	for _, column := range v.columns {
+		if column.isArchive() && !v.showArchive {
+			continue
+		}
+		column := column
+		title := widget.NewLabel(fmt.Sprintf("%s (%d)", column.title, len(column.cards)))
+		title.TextStyle = fyne.TextStyle{Bold: true}
+		title.Truncation = fyne.TextTruncateEllipsis
+		cards := container.NewVBox()
+		for _, card := range column.cards {
+			cards.Add(newKanbanCardView(v, column, card))
+		}
+		add := widget.NewButtonWithIcon("Add Card", theme.ContentAddIcon(), func() { v.addCard(column) })
+		add.Importance = widget.LowImportance
+
+		// 背景的最小宽度决定列宽
+		background := canvas.NewRectangle(theme.Color(theme.ColorNameHover))
+		background.CornerRadius = theme.InputRadiusSize()
+		background.SetMinSize(fyne.NewSize(kanbanColumnWidth, 0))
+		v.board.Add(container.NewStack(background, container.NewPadded(container.NewVBox(title, cards, add))))
+		v.targets = append(v.targets, kanbanTarget{column: column, box: cards})
+	}
+	if len(v.columns) == 0 {
+		v.board.Add(widget.NewLabel("没有找到列。用二级标题（## 标题）添加列，标题下的列表项是卡片"))
+	}
+	v.board.Refresh()
+}
+
+// apply 用修改后的各行替换编辑器的内容。修改前没有未保存的内容时直接保存
+func (v *kanbanView) apply(lines []string) {
+	dirty := v.m.isDirty(v.editor)
+	v.editor.replaceText(strings.Join(lines, "\n"))
+	if !dirty {
+		if err := v.m.saveFile(v.m.pathOf(v.editor), v.editor); err != nil {
+			dialog.ShowError(err, v.m.window)
+		}
+	}
+}
+
+func (v *kanbanView) lines() []string {
+	return strings.Split(v.editor.Text, "\n")
+}
+
+// removeEnd 返回移走卡片时删除的最后一行之后的行号。卡片前后都是空行时一起删除后面的空行，
+// 这样卡片在列之间来回移动不会留下多余的空行
+func (c kanbanCard) removeEnd(lines []string) int {
+	if c.start > 0 && strings.TrimSpace(lines[c.start-1]) == "" && c.end < len(lines) && strings.TrimSpace(lines[c.end]) == "" {
+		return c.end + 1
+	}
+	return c.end
+}
+
+// moveCard 把卡片移动到列中第 index 张卡片之前，index 按移动之前的位置计算
+func (v *kanbanView) moveCard(card kanbanCard, column *kanbanColumn, index int) {
+	lines := v.lines()
+	end := card.removeEnd(lines)
+	at, block := column.insertPosition(lines, index, append([]string(nil), lines[card.start:card.end]...))
+	if at >= card.start && at <= end {
+		return // 位置没有变化
+	}
+	v.apply(spliceLines(lines, card.start, end, at, block))
+}
+
+// addCard 在列的末尾添加卡片
+func (v *kanbanView) addCard(column *kanbanColumn) {
+	v.editCardText("Add Card", "Add", "", func(text string) {
+		prefix, indent := "- ", 2
+		if len(column.cards) > 0 {
+			// 与这一列中最后一张卡片使用相同的列表标记，已完成的任务改为未完成
+			last := column.cards[len(column.cards)-1]
+			prefix = strings.NewReplacer("[x]", "[ ]", "[X]", "[ ]").Replace(last.prefix)
+			indent = last.indent
+		}
+		lines := v.lines()
+		at, block := column.insertPosition(lines, len(column.cards), cardLines(prefix, indent, text))
+		v.apply(spliceLines(lines, 0, 0, at, block))
+	})
+}
+
+// editCard 修改卡片的内容，列表标记保持不变
+func (v *kanbanView) editCard(card kanbanCard) {
+	v.editCardText("Edit Card", "Save", card.text, func(text string) {
+		v.apply(spliceLines(v.lines(), card.start, card.end, card.start, cardLines(card.prefix, card.indent, text)))
+	})
+}
+
+// archiveCard 把卡片移动到 Archive 列的末尾，没有这一列时在笔记末尾添加
+func (v *kanbanView) archiveCard(card kanbanCard) {
+	for _, column := range v.columns {
+		if column.isArchive() {
+			v.moveCard(card, column, len(column.cards))
+			return
+		}
+	}
+	lines := v.lines()
+	at := len(lines)
+	if at > 0 && lines[at-1] == "" {
+		at-- // 保留文件末尾的换行
+	}
+	block := append([]string{"", "## " + kanbanArchive, ""}, lines[card.start:card.end]...)
+	if at > 0 && strings.TrimSpace(lines[at-1]) == "" {
+		block = block[1:]
+	}
+	v.apply(spliceLines(lines, card.start, card.removeEnd(lines), at, block))
+}
+
+// addColumn 添加一列，有 Archive 列时添加在它之前
+func (v *kanbanView) addColumn() {
+	entry := widget.NewEntry()
+	v.m.showCustomFormDialog("Add Column", "Add", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Title", entry),
+	}, func(ok bool) {
+		title := strings.TrimSpace(entry.Text)
+		if !ok || title == "" {
+			return
+		}
+		lines := v.lines()
+		for _, column := range v.columns {
+			if column.isArchive() {
+				v.apply(spliceLines(lines, 0, 0, column.line, []string{"## " + title, ""}))
+				return
+			}
+		}
+		at := len(lines)
+		if at > 0 && lines[at-1] == "" {
+			at--
+		}
+		block := []string{"## " + title}
+		if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+			block = append([]string{""}, block...)
+		}
+		v.apply(spliceLines(lines, 0, 0, at, block))
+	}, v.m.window)
+}
+
+// editCardText 弹出编辑卡片内容的对话框，内容不为空时调用 done
+func (v *kanbanView) editCardText(title, confirm, text string, done func(string)) {
+	entry := widget.NewMultiLineEntry()
+	entry.Wrapping = fyne.TextWrapWord
+	entry.SetMinRowsVisible(4)
+	entry.SetText(text)
+	v.m.showCustomFormDialog(title, confirm, "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Card", entry),
+	}, func(ok bool) {
+		if ok && strings.TrimSpace(entry.Text) != "" {
+			done(entry.Text)
+		}
+	}, v.m.window)
+	v.m.window.Canvas().Focus(entry)
+}
+
+// dragCard 在拖动卡片时显示卡片将要放置的位置
+func (v *kanbanView) dragCard(pos fyne.Position, size fyne.Size) {
+	v.dropAt = pos
+	origin := fyne.CurrentApp().Driver().AbsolutePositionForObject(v.boardArea)
+	v.ghost.Resize(size)
+	v.ghost.Move(pos.Subtract(origin).Subtract(fyne.NewPos(size.Width/2, size.Height/2)))
+	v.ghost.Show()
+	v.ghost.Refresh()
+}
+
+// dropCard 把卡片放到拖动结束时指针所在的列中
+func (v *kanbanView) dropCard(card kanbanCard) {
+	v.ghost.Hide()
+	driver := fyne.CurrentApp().Driver()
+	for _, target := range v.targets {
+		pos := driver.AbsolutePositionForObject(target.box)
+		if v.dropAt.X < pos.X || v.dropAt.X > pos.X+target.box.Size().Width {
+			continue
+		}
+		index := len(target.box.Objects)
+		for i, o := range target.box.Objects {
+			if v.dropAt.Y < driver.AbsolutePositionForObject(o).Y+o.Size().Height/2 {
+				index = i
+				break
+			}
+		}
+		v.moveCard(card, target.column, index)
+		return
+	}
+}
+
+// kanbanCardView 是看板上的一张卡片，可以拖动到其它位置，双击编辑，右键或菜单按钮打开菜单
+type kanbanCardView struct {
+	widget.BaseWidget
+
+	view   *kanbanView
+	column *kanbanColumn
+	card   kanbanCard
+	menu   *widget.Button
+}
+
+func newKanbanCardView(view *kanbanView, column *kanbanColumn, card kanbanCard) *kanbanCardView {
+	c := &kanbanCardView{view: view, column: column, card: card}
+	c.menu = widget.NewButtonWithIcon("", theme.MoreHorizontalIcon(), func() {
+		driver := fyne.CurrentApp().Driver()
+		pos := driver.AbsolutePositionForObject(c.menu).Add(fyne.NewPos(0, c.menu.Size().Height))
+		widget.ShowPopUpMenuAtPosition(c.cardMenu(), driver.CanvasForObject(c), pos)
+	})
+	c.menu.Importance = widget.LowImportance
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+func (c *kanbanCardView) CreateRenderer() fyne.WidgetRenderer {
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	background.CornerRadius = theme.InputRadiusSize()
+	background.StrokeColor = theme.Color(theme.ColorNameInputBorder)
+	background.StrokeWidth = 1
+
+	text := widget.NewRichText(newPreviewRenderer(c.card.text, c.view.m.pathOf(c.view.editor)).render()...)
+	text.Wrapping = fyne.TextWrapWord
+	return widget.NewSimpleRenderer(container.NewStack(background, container.NewBorder(nil, nil, nil, container.NewVBox(c.menu, layout.NewSpacer()), text)))
+}
+
+func (c *kanbanCardView) cardMenu() *fyne.Menu {
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem("Edit", func() { c.view.editCard(c.card) }),
+		fyne.NewMenuItem("Show in Source", func() {
+			c.view.setSourceMode(true)
+			c.view.editor.setCursor(c.card.start, 0)
+		}),
+	}
+	if !c.column.isArchive() {
+		items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Archive", func() { c.view.archiveCard(c.card) }))
+	}
+	return fyne.NewMenu("", items...)
+}
+
+func (c *kanbanCardView) Dragged(ev *fyne.DragEvent) {
+	c.view.dragCard(ev.AbsolutePosition, c.Size())
+}
+
+func (c *kanbanCardView) DragEnd() {
+	c.view.dropCard(c.card)
+}
+
+func (c *kanbanCardView) DoubleTapped(*fyne.PointEvent) {
+	c.view.editCard(c.card)
+}
+
+func (c *kanbanCardView) TappedSecondary(ev *fyne.PointEvent) {
+	widget.ShowPopUpMenuAtPosition(c.cardMenu(), fyne.CurrentApp().Driver().CanvasForObject(c), ev.AbsolutePosition)
+}
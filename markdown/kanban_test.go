@@ -0,0 +1,198 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKanban(t *testing.T) {
+	type column struct {
+		title string
+		line  int
+		end   int
+		cards []kanbanCard
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    []column
+	}{
+		{
+			name:    "columns and cards",
+			content: "---\nkanban: true\n---\n\n## Todo\n\n- a\n- [ ] b\n\n## Done\n- [x] c",
+			want: []column{
+				{"Todo", 4, 9, []kanbanCard{
+					{start: 6, end: 7, prefix: "- ", indent: 2, text: "a"},
+					{start: 7, end: 8, prefix: "- [ ] ", indent: 2, text: "b"},
+				}},
+				{"Done", 9, 11, []kanbanCard{
+					{start: 10, end: 11, prefix: "- [x] ", indent: 2, text: "c"},
+				}},
+			},
+		},
+		{
+			name:    "multi-line card",
+			content: "## Doing\n- first\n  second\n\n  third\nafter",
+			want: []column{
+				{"Doing", 0, 6, []kanbanCard{
+					{start: 1, end: 5, prefix: "- ", indent: 2, text: "first\nsecond\n\nthird"},
+				}},
+			},
+		},
+		{
+			name:    "ordered and crlf",
+			content: "## A\r\n1. one\r\n2. two\r\n",
+			want: []column{
+				{"A", 0, 4, []kanbanCard{
+					{start: 1, end: 2, prefix: "1. ", indent: 3, text: "one"},
+					{start: 2, end: 3, prefix: "2. ", indent: 3, text: "two"},
+				}},
+			},
+		},
+		{
+			name:    "ignores other headings and code",
+			content: "# Board\n- loose\n## Col\n```\n## not a column\n- not a card\n```\n### Sub\n- hidden",
+			want: []column{
+				{"Col", 2, 7, nil},
+			},
+		},
+		{
+			name:    "nested items belong to the card",
+			content: "## Col\n- parent\n  - child\n- next",
+			want: []column{
+				{"Col", 0, 4, []kanbanCard{
+					{start: 1, end: 3, prefix: "- ", indent: 2, text: "parent\n- child"},
+					{start: 3, end: 4, prefix: "- ", indent: 2, text: "next"},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var got []column
+		for _, c := range parseKanban(tt.content) {
+			got = append(got, column{c.title, c.line, c.end, c.cards})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseKanban() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCardLines(t *testing.T) {
+	tests := []struct {
+		prefix string
+		indent int
+		text   string
+		want   []string
+	}{
+		{"- ", 2, "task", []string{"- task"}},
+		{"- [ ] ", 2, "  trimmed  \n", []string{"- [ ] trimmed"}},
+		{"1. ", 3, "first\nsecond\r\n\nthird", []string{"1. first", "   second", "", "   third"}},
+	}
+	for _, tt := range tests {
+		if got := cardLines(tt.prefix, tt.indent, tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cardLines(%q, %d, %q) = %q, want %q", tt.prefix, tt.indent, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSpliceLines(t *testing.T) {
+	lines := []string{"a", "b", "c", "d"}
+	tests := []struct {
+		name           string
+		start, end, at int
+		block          []string
+		want           []string
+	}{
+		{"insert only", 0, 0, 2, []string{"x"}, []string{"a", "b", "x", "c", "d"}},
+		{"insert at end", 0, 0, 4, []string{"x"}, []string{"a", "b", "c", "d", "x"}},
+		{"delete only", 1, 3, 1, nil, []string{"a", "d"}},
+		{"replace", 1, 2, 1, []string{"x", "y"}, []string{"a", "x", "y", "c", "d"}},
+		{"move down", 0, 1, 3, []string{"a"}, []string{"b", "c", "a", "d"}},
+		{"move up", 3, 4, 1, []string{"d"}, []string{"a", "d", "b", "c"}},
+	}
+	for _, tt := range tests {
+		got := spliceLines(lines, tt.start, tt.end, tt.at, tt.block)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: spliceLines() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if !reflect.DeepEqual(lines, []string{"a", "b", "c", "d"}) {
+		t.Errorf("spliceLines modified its input: %q", lines)
+	}
+}
+
+func TestKanbanInsert(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		column  int
+		index   int
+		want    string
+	}{
+		{
+			name:    "empty column keeps a blank line after the heading",
+			content: "## Todo\n## Done",
+			want:    "## Todo\n\n- new\n\n## Done",
+		},
+		{
+			name:    "empty column with blank lines",
+			content: "## Todo\n\n\n## Done",
+			want:    "## Todo\n\n- new\n\n## Done",
+		},
+		{
+			name:    "single blank line before the next column",
+			content: "## Todo\n\n## Done",
+			want:    "## Todo\n\n- new\n\n## Done",
+		},
+		{
+			name:    "empty last column",
+			content: "## Todo\n",
+			want:    "## Todo\n\n- new\n",
+		},
+		{
+			name:    "empty column with text",
+			content: "## Todo\nSome notes.\n## Done",
+			want:    "## Todo\nSome notes.\n\n- new\n\n## Done",
+		},
+		{
+			name:    "after the last card",
+			content: "## Todo\n\n- a\n\n## Done",
+			index:   1,
+			want:    "## Todo\n\n- a\n- new\n\n## Done",
+		},
+		{
+			name:    "before a card",
+			content: "## Todo\n- a\n- b",
+			index:   1,
+			want:    "## Todo\n- a\n- new\n- b",
+		},
+	}
+	for _, tt := range tests {
+		lines := strings.Split(tt.content, "\n")
+		column := parseKanban(tt.content)[tt.column]
+		at, block := column.insertPosition(lines, tt.index, []string{"- new"})
+		if got := strings.Join(spliceLines(lines, 0, 0, at, block), "\n"); got != tt.want {
+			t.Errorf("%s: got\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCardRemoveEnd(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"## A\n\n- a\n\n## B", 4},
+		{"## A\n- a\n\n## B", 2},
+		{"## A\n\n- a\n## B", 3},
+		{"## A\n\n- a", 3},
+	}
+	for _, tt := range tests {
+		card := parseKanban(tt.content)[0].cards[0]
+		if got := card.removeEnd(strings.Split(tt.content, "\n")); got != tt.want {
+			t.Errorf("removeEnd(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package markdown
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]\n]+)\]\]`)
+	mdLinkPattern   = regexp.MustCompile(`(!?)\[([^\]\n]*)\]\(\s*(<[^>\n]*>|[^)\s]+)(?:\s+"[^"\n]*")?\s*\)`)
+	codeSpanPattern = regexp.MustCompile("`+[^`\n]*`+")
+	schemePattern   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// noteLink 表示笔记中的一个链接或嵌入
+type noteLink struct {
+	Target string // 链接目标，不含 # 之后的部分
+	Anchor string // # 之后的标题或块 ID
+	Text   string // 显示文本
+	Line   int    // 所在行，从 0 开始
+	Wiki   bool   // 是否为 [[wiki]] 形式
+	Embed  bool   // 是否以 ! 开头
+}
+
+// extractLinks 提取笔记中的 Markdown 链接和 wiki 链接，跳过 front matter、代码块和行内代码
+func extractLinks(content string) []noteLink {
+	var links []noteLink
+	forEachTextLine(content, func(i int, line string) {
+		// 用空格替换行内代码，保留列位置
+		line = codeSpanPattern.ReplaceAllStringFunc(line, func(s string) string {
+			return strings.Repeat(" ", len(s))
+		})
+
+		for _, match := range wikiLinkPattern.FindAllStringSubmatch(line, -1) {
+			inner := match[2]
+			text := ""
+			if bar := strings.Index(inner, "|"); bar >= 0 {
+				inner, text = inner[:bar], inner[bar+1:]
+			}
+			target, anchor := splitAnchor(inner)
+			if text == "" {
+				text = inner
+			}
+			links = append(links, noteLink{
+				Target: strings.TrimSpace(target),
+				Anchor: strings.TrimSpace(anchor),
+				Text:   text,
+				Line:   i,
+				Wiki:   true,
+				Embed:  match[1] == "!",
+			})
+		}
+
+		for _, match := range mdLinkPattern.FindAllStringSubmatch(line, -1) {
+			dest := strings.TrimSuffix(strings.TrimPrefix(match[3], "<"), ">")
+			if unescaped, err := url.PathUnescape(dest); err == nil {
+				dest = unescaped
+			}
+			target, anchor := splitAnchor(dest)
+			links = append(links, noteLink{
+				Target: target,
+				Anchor: anchor,
+				Text:   match[2],
+				Line:   i,
+				Embed:  match[1] == "!",
+			})
+		}
+	})
+	return links
+}
+
+// forEachTextLine 依次回调正文中的每一行，跳过 front matter 和围栏代码块
+func forEachTextLine(content string, fn func(i int, line string)) {
+	lines := strings.Split(content, "\n")
+	start := frontMatterEnd(lines)
+	fence := ""
+	for i := start; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if marker := fenceMarker(line); marker != "" {
+			if fence == "" {
+				fence = marker
+				continue
+			}
+			if strings.HasPrefix(marker, fence) {
+				fence = ""
+				continue
+			}
+		}
+		if fence != "" {
+			continue
+		}
+		fn(i, line)
+	}
+}
+
+// frontMatterEnd 返回 front matter 之后第一行的行号，没有 front matter 时返回 0
+func frontMatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimRight(lines[0], "\r ") != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r ")
+		if line == "---" || line == "..." {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// fenceMarker 返回围栏代码块的标记（``` 或 ~~~），不是围栏行时返回空字符串
+func fenceMarker(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return ""
+	}
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == c {
+			n++
+		}
+		if n >= 3 {
+			return trimmed[:n]
+		}
+	}
+	return ""
+}
+
+func splitAnchor(target string) (string, string) {
+	if i := strings.Index(target, "#"); i >= 0 {
+		return target[:i], target[i+1:]
+	}
+	return target, ""
+}
+
+// isExternalLink 判断链接是否指向外部资源（http、mailto 等）
+func isExternalLink(target string) bool {
+	return schemePattern.MatchString(target) && !filepath.IsAbs(target)
+}
+
+// resolveRelative 将 Markdown 链接目标解析为基于笔记所在目录的绝对路径
+func resolveRelative(notePath, target string) string {
+	if filepath.IsAbs(target) {
+		return filepath.Clean(target)
+	}
+	return filepath.Join(filepath.Dir(notePath), filepath.FromSlash(target))
+}
+
+// relativeLink 生成从笔记指向 target 的相对链接，路径中有空格时使用 <> 包裹
+func relativeLink(notePath, target string) string {
+	rel, err := filepath.Rel(filepath.Dir(notePath), target)
+	if err != nil {
+		rel = target
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.ContainsAny(rel, " ()") {
+		return "<" + rel + ">"
+	}
+	return rel
+}
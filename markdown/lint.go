@@ -0,0 +1,344 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+var emptyLinkPattern = regexp.MustCompile(`(!?)\[([^\]\n]*)\]\(\s*\)`)
+
+// lintIssue 是检查笔记时发现的一个问题
+type lintIssue struct {
+	Line    int // 所在行，从 0 开始
+	Message string
+	Fix     string                           // 快速修复的说明，为空时没有快速修复
+	fix     func(line string) (string, bool) // 修改所在行，行内容已变化时返回 false
+}
+
+// lintNote 检查笔记中的失效链接、重复标题、跳级的标题和空链接
+func (m *MarkdownEditor) lintNote(idx *vaultIndex, path, content string) []lintIssue {
+	var issues []lintIssue
+	headings := map[string]bool{}
+	prevLevel := 0
+	forEachTextLine(content, func(i int, line string) {
+		if level, text, ok := parseHeading(line); ok && text != "" {
+			issues = append(issues, lintHeading(i, level, text, prevLevel, headings)...)
+			prevLevel = level
+		}
+
+		// 用空格替换行内代码，保留列位置
+		masked := codeSpanPattern.ReplaceAllStringFunc(line, func(s string) string {
+			return strings.Repeat(" ", len(s))
+		})
+		for _, match := range emptyLinkPattern.FindAllStringSubmatch(masked, -1) {
+			issues = append(issues, lintEmptyLink(i, match))
+		}
+		for _, match := range mdLinkPattern.FindAllStringSubmatch(masked, -1) {
+			if match[1] == "" && strings.TrimSpace(match[2]) == "" {
+				issues = append(issues, lintEmptyText(i, match))
+			}
+		}
+	})
+
+	for _, link := range extractLinks(content) {
+		if issue, ok := m.lintLink(idx, path, content, link); ok {
+			issues = append(issues, issue)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// lintHeading 检查标题是否与前面的标题重复、是否比上一个标题深了不止一级
+func lintHeading(i, level int, text string, prevLevel int, seen map[string]bool) []lintIssue {
+	var issues []lintIssue
+	if prevLevel > 0 && level > prevLevel+1 {
+		want := prevLevel + 1
+		issues = append(issues, lintIssue{
+			Line:    i,
+			Message: fmt.Sprintf("标题从 %d 级跳到了 %d 级", prevLevel, level),
+			Fix:     fmt.Sprintf("改为 %d 级标题", want),
+			fix: func(line string) (string, bool) {
+				l, t, ok := parseHeading(line)
+				if !ok || l != level || t != text {
+					return line, false
+				}
+				return headingLine(want, t), true
+			},
+		})
+	}
+
+	key := strings.ToLower(text)
+	if !seen[key] {
+		seen[key] = true
+		return issues
+	}
+	renamed := text
+	for n := 2; seen[strings.ToLower(renamed)]; n++ {
+		renamed = text + " (" + strconv.Itoa(n) + ")"
+	}
+	seen[strings.ToLower(renamed)] = true
+	return append(issues, lintIssue{
+		Line:    i,
+		Message: "重复的标题：" + text,
+		Fix:     "重命名为 " + renamed,
+		fix: func(line string) (string, bool) {
+			l, t, ok := parseHeading(line)
+			if !ok || t != text {
+				return line, false
+			}
+			return headingLine(l, renamed), true
+		},
+	})
+}
+
+// lintEmptyLink 处理没有链接地址的链接，修复时保留链接文字，图片则整个删除
+func lintEmptyLink(i int, match []string) lintIssue {
+	issue := lintIssue{Line: i, Message: "链接地址为空：" + match[0], Fix: "改为纯文本"}
+	replacement := match[2]
+	if match[1] == "!" {
+		issue.Message = "图片地址为空：" + match[0]
+		issue.Fix = "删除图片"
+		replacement = ""
+	}
+	issue.fix = replaceOnce(match[0], replacement)
+	return issue
+}
+
+// lintEmptyText 处理没有链接文字的链接，修复时外部链接改为 <地址>，其他链接以文件名作为文字
+func lintEmptyText(i int, match []string) lintIssue {
+	dest := strings.TrimSuffix(strings.TrimPrefix(match[3], "<"), ">")
+	issue := lintIssue{Line: i, Message: "链接文字为空：" + match[0]}
+	if isExternalLink(dest) {
+		issue.Fix = "改为 <" + dest + ">"
+		issue.fix = replaceOnce(match[0], "<"+dest+">")
+		return issue
+	}
+	target, anchor := splitAnchor(dest)
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	text := strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+	if target == "" {
+		text = anchor
+	}
+	if text == "" || text == "." {
+		return issue
+	}
+	issue.Fix = "使用 " + text + " 作为链接文字"
+	issue.fix = replaceOnce(match[0], "["+text+"]"+match[0][strings.Index(match[0], "]")+1:])
+	return issue
+}
+
+// lintLink 检查链接指向的笔记、附件和标题是否存在
+func (m *MarkdownEditor) lintLink(idx *vaultIndex, path, content string, link noteLink) (lintIssue, bool) {
+	if isExternalLink(link.Target) {
+		return lintIssue{}, false
+	}
+	issue := lintIssue{Line: link.Line}
+
+	target := path
+	switch {
+	case link.Target == "":
+		if link.Wiki && link.Anchor == "" {
+			return lintIssue{}, false
+		}
+	case link.Wiki:
+		ext := strings.ToLower(filepath.Ext(link.Target))
+		if ext == "" || ext == noteExt {
+			target = idx.resolveName(path, link.Target)
+		} else {
+			target = m.findAttachment(path, link.Target)
+		}
+		if target == "" {
+			issue.Message = "找不到链接的笔记或附件：" + link.Target
+			return issue, true
+		}
+	default:
+		target = resolveRelative(path, link.Target)
+		if !isFile(target) && isFile(target+noteExt) {
+			target += noteExt
+		}
+		if !isFile(target) {
+			if _, err := os.Stat(target); err == nil {
+				return lintIssue{}, false // 指向目录的链接
+			}
+			issue.Message = "链接的文件不存在：" + link.Target
+			if found := m.findLinkTarget(idx, path, link.Target); found != "" {
+				dest := relativeLink(path, found)
+				issue.Fix = "改为 " + dest
+				issue.fix = func(line string) (string, bool) {
+					return replaceLinkTarget(line, link.Target, dest)
+				}
+			}
+			return issue, true
+		}
+	}
+
+	if link.Anchor == "" || strings.HasPrefix(link.Anchor, "^") || !isNote(target) {
+		return lintIssue{}, false
+	}
+	targetContent := content
+	if note, ok := idx.notes[target]; ok {
+		if note.Encrypted {
+			return lintIssue{}, false // 加密笔记的标题无法检查
+		}
+		targetContent = note.Content
+	}
+	if !hasHeading(targetContent, link.Anchor) {
+		issue.Message = "找不到链接的标题：#" + link.Anchor
+		return issue, true
+	}
+	return lintIssue{}, false
+}
+
+// findLinkTarget 按文件名查找失效链接可能指向的文件，只有唯一的候选时才返回
+func (m *MarkdownEditor) findLinkTarget(idx *vaultIndex, path, target string) string {
+	name := filepath.Base(filepath.FromSlash(target))
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != "" && ext != noteExt {
+		return m.findAttachment(path, name)
+	}
+	candidates := idx.byName[strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))]
+	if len(candidates) != 1 {
+		return ""
+	}
+	return candidates[0]
+}
+
+// hasHeading 判断笔记中是否有与 anchor 对应的标题，anchor 可以是标题文字或 HTML 中的 id
+func hasHeading(content, anchor string) bool {
+	want := headingID(anchor)
+	found := false
+	ids := map[string]int{}
+	forEachTextLine(content, func(_ int, line string) {
+		if _, text, ok := parseHeading(line); ok && uniqueID(ids, headingID(text)) == want {
+			found = true
+		}
+	})
+	return found
+}
+
+// replaceLinkTarget 把行内指向 target 的第一个 Markdown 链接改为指向 dest，保留 # 之后的部分
+func replaceLinkTarget(line, target, dest string) (string, bool) {
+	for _, m := range mdLinkPattern.FindAllStringSubmatchIndex(line, -1) {
+		raw := line[m[6]:m[7]]
+		old := strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">")
+		if unescaped, err := url.PathUnescape(old); err == nil {
+			old = unescaped
+		}
+		t, anchor := splitAnchor(old)
+		if t != target {
+			continue
+		}
+		if anchor != "" {
+			if strings.HasSuffix(dest, ">") {
+				dest = strings.TrimSuffix(dest, ">") + "#" + anchor + ">"
+			} else {
+				dest += "#" + anchor
+			}
+		}
+		return line[:m[6]] + dest + line[m[7]:], true
+	}
+	return line, false
+}
+
+// replaceOnce 返回把行内第一个 old 替换为 replacement 的修复函数
+func replaceOnce(old, replacement string) func(string) (string, bool) {
+	return func(line string) (string, bool) {
+		if !strings.Contains(line, old) {
+			return line, false
+		}
+		return strings.Replace(line, old, replacement, 1), true
+	}
+}
+
+// showLint 打开检查当前笔记的标签页，列出发现的问题，有快速修复的问题可以一键修复
+func (m *MarkdownEditor) showLint() {
+	path := m.activeNote
+	if path == "" {
+		dialog.ShowError(errors.New("请先打开一篇笔记"), m.window)
+		return
+	}
+
+	var issues []lintIssue
+	list := widget.NewList(
+		func() int { return len(issues) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil, widget.NewButton("Fix", nil), widget.NewLabel(""))
+		},
+		nil,
+	)
+	status := widget.NewLabel("")
+
+	var reload func()
+	apply := func(fixes []lintIssue) {
+		for _, issue := range fixes {
+			if issue.fix == nil {
+				continue
+			}
+			if err := m.updateNoteLine(path, issue.Line, issue.fix); err != nil {
+				dialog.ShowError(err, m.window)
+				break
+			}
+		}
+		reload()
+	}
+	list.UpdateItem = func(id widget.ListItemID, item fyne.CanvasObject) {
+		issue := issues[id]
+		c := item.(*fyne.Container)
+		label := c.Objects[0].(*widget.Label)
+		button := c.Objects[1].(*widget.Button)
+		label.SetText(fmt.Sprintf("第 %d 行：%s", issue.Line+1, issue.Message))
+		if issue.fix == nil {
+			button.Hide()
+			return
+		}
+		button.Show()
+		button.SetText(issue.Fix)
+		button.OnTapped = func() { apply([]lintIssue{issue}) }
+	}
+	list.OnSelected = func(id widget.ListItemID) {
+		list.UnselectAll()
+		m.openFileAt(path, issues[id].Line)
+	}
+
+	reload = func() {
+		issues = nil
+		idx, err := m.buildIndex()
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		content, err := m.noteContent(path)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		issues = m.lintNote(idx, path, content)
+		status.SetText(fmt.Sprintf("%d problems in %s", len(issues), filepath.Base(path)))
+		list.Refresh()
+	}
+
+	toolbar := container.NewHBox(
+		widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() { reload() }),
+		widget.NewButton("Fix All", func() { apply(issues) }),
+		status,
+	)
+
+	reload()
+	m.showViewTab("Lint", container.NewBorder(toolbar, nil, nil, nil, list))
+}
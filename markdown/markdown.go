@@ -7,9 +7,9 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/driver/desktop"
@@ -26,15 +26,33 @@ type MarkdownEditor struct {
 	rootPath      string
 	window        fyne.Window
 	selectedNode  widget.TreeNodeID
-	openFiles     map[string]*widget.Entry // 新增：用于跟踪打开的文件
+	openFiles     map[string]*noteEntry // 新增：用于跟踪打开的文件
 	isCreatingNew bool
 	newItemEntry  *widget.Entry
+	config        vaultConfig
+	activeNote    string // 最近一次选中的笔记
+	menuButton    *widget.Button
+	shortcuts     map[fyne.KeyName]func() // Ctrl/Cmd 加按键对应的操作
+	statusBar     *fyne.Container
+	modeLabel     *widget.Label // 状态栏中显示 Vim 模式
+	statsLabel    *widget.Label // 状态栏中显示光标位置和字数
+	lockTimer     *time.Timer   // 加密笔记的空闲锁定计时
+	lastActivity  time.Time     // 最近一次操作已解密笔记的时间，用于空闲锁定
+	bookmarks     []bookmark
+	recent        []string // 最近打开的文件，相对于笔记库
+	bookmarkList  *widget.List
+	recentButton  *widget.Button
+	// queryCache 是查询块和嵌入使用的索引，保存笔记或刷新目录树时失效
+	queryCache     *vaultIndex
+	queryCacheTime time.Time
+	spell          *spellChecker // 未启用拼写检查时为 nil
+	refreshGraph   func()        // 关系图标签页打开时，切换笔记后更新关系图
 }
 
 func NewMarkdownEditor(window fyne.Window) *MarkdownEditor {
 	m := &MarkdownEditor{
 		window:    window,
-		openFiles: make(map[string]*widget.Entry), // 初始化 openFiles
+		openFiles: make(map[string]*noteEntry), // 初始化 openFiles
 	}
 	m.initUI()
 	return m
@@ -60,28 +78,73 @@ func (m *MarkdownEditor) initUI() {
 		widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), m.saveCurrentFile),
 		widget.NewButtonWithIcon("", theme.ContentCutIcon(), m.renameSelected), // 新增重命名按钮
 		widget.NewButtonWithIcon("", theme.DeleteIcon(), m.deleteSelected),     // 新增���除按钮
+		widget.NewButtonWithIcon("", starIcon, m.toggleBookmark),
 	)
+	m.recentButton = widget.NewButtonWithIcon("", theme.HistoryIcon(), m.showRecentMenu)
+	toolbar.Add(m.recentButton)
+	m.menuButton = widget.NewButtonWithIcon("", theme.MoreHorizontalIcon(), m.showToolsMenu)
+	toolbar.Add(m.menuButton)
 
 	// 创建文件标签和内容区
 	m.tabs = container.NewDocTabs()
+	m.modeLabel = widget.NewLabel("")
+	m.modeLabel.TextStyle.Monospace = true
+	m.statsLabel = widget.NewLabel("")
+	m.statusBar = container.NewHBox(m.modeLabel, layout.NewSpacer(), m.statsLabel)
+	m.statusBar.Hide()
+	// 收藏列表固定在目录树上方
+	treeSplit := container.NewVSplit(m.newBookmarkList(), m.treeView)
+	treeSplit.Offset = 0.25
 	m.contentSplit = container.NewHSplit(
-		container.NewBorder(toolbar, nil, nil, nil, m.treeView),
-		m.tabs,
+		container.NewBorder(toolbar, nil, nil, nil, treeSplit),
+		container.NewBorder(nil, m.statusBar, nil, nil, m.tabs),
 	)
 	m.contentSplit.Offset = 0.2 // 将目录树的宽度设置为内容区域的 20%
 
 	m.container = container.NewStack(m.contentSplit)
 
-	// 修改快捷键支持
-	ctrlS := &desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierControl}
-	superS := &desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierSuper}
+	// 修改快捷键支持，Ctrl 和 macOS 的 Cmd 都可以使用
+	m.shortcuts = map[fyne.KeyName]func(){
+		fyne.KeyS:        m.saveCurrentFile,
+		fyne.KeyB:        func() { m.formatEditor(func(e *noteEntry) { e.wrapSelection("**", "**") }) },
+		fyne.KeyI:        func() { m.formatEditor(func(e *noteEntry) { e.wrapSelection("*", "*") }) },
+		fyne.KeyK:        func() { m.formatEditor((*noteEntry).insertLink) },
+		fyne.KeyBackTick: func() { m.formatEditor((*noteEntry).insertCode) },
+		fyne.KeyPeriod:   m.showSpellingSuggestions,
+	}
+	for key, action := range m.shortcuts {
+		action := action
+		for _, modifier := range []fyne.KeyModifier{fyne.KeyModifierControl, fyne.KeyModifierSuper} {
+			m.window.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: key, Modifier: modifier}, func(fyne.Shortcut) {
+				action()
+			})
+		}
+	}
 
-	m.window.Canvas().AddShortcut(ctrlS, func(shortcut fyne.Shortcut) {
-		m.saveCurrentFile()
-	})
-	m.window.Canvas().AddShortcut(superS, func(shortcut fyne.Shortcut) {
-		m.saveCurrentFile()
-	})
+	// 拖入文件作为附件
+	m.window.SetOnDropped(m.onDropped)
+
+	// 回到窗口时检查已解密的笔记是否超过了空闲时间
+	fyne.CurrentApp().Lifecycle().SetOnEnteredForeground(m.resetLockTimer)
+
+	m.tabs.OnSelected = func(item *container.TabItem) {
+		if editor := tabEditor(item); editor != nil {
+			m.activeNote = m.pathOf(editor)
+		}
+		if m.refreshGraph != nil {
+			m.refreshGraph()
+		}
+		m.updateStatus()
+		m.resetLockTimer()
+	}
+
+	// 监听标签页关闭事件
+	m.tabs.OnClosed = func(item *container.TabItem) {
+		if editor := tabEditor(item); editor != nil {
+			delete(m.openFiles, m.pathOf(editor))
+		}
+		m.updateStatus()
+	}
 
 	// 添加右键菜单
 	// m.treeView.OnTapped = func(e *fyne.PointEvent) {
@@ -104,6 +167,12 @@ func (m *MarkdownEditor) LoadDirectory(path string) error {
 			return err
 		}
 	}
+	m.loadConfig()
+	m.loadBookmarks()
+	m.queryCache = nil
+	if err := m.applySpellCheck(); err != nil {
+		fyne.LogError("Failed to load spelling dictionaries", err)
+	}
 
 	m.treeView.Root = "" // 将根设置为空字符串
 	m.treeView.OpenAllBranches()
@@ -126,6 +195,9 @@ func (m *MarkdownEditor) childUIDs(uid widget.TreeNodeID) []widget.TreeNodeID {
 
 	var children []widget.TreeNodeID
 	for _, file := range files {
+		if isHidden(file.Name()) {
+			continue
+		}
 		childUID := file.Name()
 		if uid != "" {
 			childUID = filepath.Join(uid, file.Name())
@@ -222,8 +294,8 @@ func (m *MarkdownEditor) onNodeSelected(uid widget.TreeNodeID) {
 
 func (m *MarkdownEditor) openFile(path string) {
 	// 检查文件是否已经打开
-	for _, tab := range m.tabs.Items {
-		if tab.Text == filepath.Base(path) {
+	if editor, ok := m.openFiles[path]; ok {
+		if tab := m.tabOf(editor); tab != nil {
 			m.tabs.Select(tab)
 			return
 		}
@@ -234,43 +306,72 @@ func (m *MarkdownEditor) openFile(path string) {
 		dialog.ShowError(err, m.window)
 		return
 	}
+	if isEncrypted(content) {
+		m.unlockFile(path, content)
+		return
+	}
+	m.openEditor(path, string(content), nil)
+}
 
-	editor := widget.NewMultiLineEntry()
-	editor.SetText(string(content))
+// openEditor 在新标签页中编辑笔记，key 不为 nil 时 content 是加密笔记解密后的内容
+func (m *MarkdownEditor) openEditor(path, content string, key *noteKey) {
+	editor := newNoteEntry()
+	editor.SetText(content)
+	editor.key = key
+	m.addRecent(path)
+	editor.onPaste = func() bool { return m.pasteAttachment(editor) || m.pasteTSV(editor) }
+	editor.onShortcut = m.editorShortcut
+	m.setVimMode(editor, m.config.VimMode)
 
 	preview := NewCustomRichText() // 使用自定义的 RichText
 	preview.Wrapping = fyne.TextWrapWord
 
-	split := container.NewHSplit(editor, container.NewScroll(preview))
+	source := newSourceEditor(editor)
+	source.setLineNumbers(m.config.LineNumbers)
+	source.setMonospace(m.config.MonospaceFont)
+	source.setSpellChecker(m.spell)
+	source.onCursorChanged = m.updateStatus
+
+	split := container.NewHSplit(source, container.NewScroll(preview))
 	split.Offset = 0.5
 
-	tab := container.NewTabItem(filepath.Base(path), split)
+	m.openFiles[path] = editor // 将打开的文件添加到 map 中
+	if key != nil {
+		m.lastActivity = time.Now() // 刚解密的笔记从现在开始计算空闲时间
+	}
+
+	// 看板笔记默认显示看板，可以切换到源码
+	var view fyne.CanvasObject = split
+	var board *kanbanView
+	if isNote(path) && isKanban(content) {
+		board = m.newKanbanView(editor, split)
+		view = board
+	}
+
+	tab := container.NewTabItem(filepath.Base(path), view)
 	m.tabs.Append(tab)
 	m.tabs.Select(tab)
-
-	m.openFiles[path] = editor // 将打开的文件添加到 map 中
+	m.updateStatus()
 
 	// 立即更新预览
-	m.updatePreview(preview, editor.Text)
+	m.updatePreview(preview, m.renderEditorPreview(editor, path))
 
 	// 强制重新布局整个分割视图
 	split.Refresh()
 
 	editor.OnChanged = func(content string) {
+		source.textChanged(content)
 		if !strings.HasPrefix(tab.Text, "*") {
 			tab.Text = "*" + tab.Text
 			m.tabs.Refresh()
 		}
-		m.updatePreview(preview, content)
-	}
-
-	// 监听标签页闭事件
-	m.tabs.OnClosed = func(item *container.TabItem) {
-		for p, e := range m.openFiles {
-			if e == editor {
-				delete(m.openFiles, p)
-				break
-			}
+		m.updatePreview(preview, m.renderEditorPreview(editor, m.pathOf(editor)))
+		if board != nil {
+			board.textChanged()
+		}
+		m.updateStatus()
+		if editor.key != nil {
+			m.resetLockTimer()
 		}
 	}
 }
@@ -285,7 +386,7 @@ type CustomRichText struct {
 func NewCustomRichText() *CustomRichText {
 	rt := &CustomRichText{}
 	rt.ExtendBaseWidget(rt)
-	rt.lineSpacing = 5.5 // 设置行间距为 1.5 倍
+	rt.lineSpacing = 1.5 // 设置行间距为 1.5 倍
 	return rt
 }
 
@@ -333,22 +434,32 @@ func (r *customRichTextRenderer) Refresh() {
 	r.applyLineSpacing()
 }
 
+// applyLineSpacing 在基础布局之上按行增加间距。同一行内的文字和图片等对象一起下移，
+// 这样图片、复选框等非文字对象不会与文字重叠
 func (r *customRichTextRenderer) applyLineSpacing() {
-	y := float32(0)
+	extra := float32(0)
+	rowTop, rowBottom := float32(0), float32(0)
 	for _, o := range r.Objects() {
-		if text, ok := o.(*canvas.Text); ok {
-			text.Move(fyne.NewPos(text.Position().X, y))
-			y += text.MinSize().Height * r.richText.lineSpacing
+		pos := o.Position()
+		if pos.Y >= rowBottom {
+			// 新的一行开始，累加上一行的额外间距
+			extra += (rowBottom - rowTop) * (r.richText.lineSpacing - 1)
+			rowTop = pos.Y
+			rowBottom = pos.Y
+		}
+		if bottom := pos.Y + o.Size().Height; bottom > rowBottom {
+			rowBottom = bottom
 		}
+		o.Move(fyne.NewPos(pos.X, pos.Y+extra))
 	}
 }
 
-func (m *MarkdownEditor) updatePreview(preview *CustomRichText, content string) {
-	preview.ParseMarkdown(content)
+func (m *MarkdownEditor) updatePreview(preview *CustomRichText, segments []widget.RichTextSegment) {
+	preview.Segments = segments
 	preview.Refresh()
 
 	// 强制重新布局
-	if split, ok := m.tabs.Selected().Content.(*container.Split); ok {
+	if split := tabSplit(m.tabs.Selected()); split != nil {
 		if scroll, ok := split.Trailing.(*container.Scroll); ok {
 			scroll.Refresh()
 		}
@@ -364,46 +475,236 @@ func (m *MarkdownEditor) saveCurrentFile() {
 		return // 没有选中的标签页
 	}
 
-	var editor *widget.Entry
-	var path string
-
 	// 查找当前正在编辑文件
-	if split, ok := currentTab.Content.(*container.Split); ok {
-		if entry, ok := split.Leading.(*widget.Entry); ok {
-			editor = entry
-			for p, e := range m.openFiles {
-				if e == entry {
-					path = p
-					break
-				}
-			}
-		}
-	}
-
+	path, editor := m.currentFile()
 	if editor == nil || path == "" {
 		dialog.ShowError(errors.New("无法找到当前编辑的文件"), m.window)
 		return
 	}
 
+	if m.config.FormatOnSave && isNote(path) {
+		editor.formatDocument()
+	}
+	if err := m.saveFile(path, editor); err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+
+	// 移除成功保存的弹窗
+	// dialog.ShowInformation("保存成功", "文件已成功保存", m.window)
+}
+
+// saveFile 将编辑器内容写入 path，并清除标签页上的未保存标记
+func (m *MarkdownEditor) saveFile(path string, editor *noteEntry) error {
 	// 获取当前编辑器中的文本内容
 	content := editor.Text
 
-	// 保存文件
-	err := ioutil.WriteFile(path, []byte(content), 0644)
+	// 保存文件，加密笔记写入加密后的内容
+	err := writeNote(path, content, editor.key)
 	if err != nil {
-		dialog.ShowError(err, m.window)
-		return
+		return err
 	}
 
 	// 更新标签页标题（移星号）
-	currentTab.Text = strings.TrimPrefix(currentTab.Text, "*")
-	m.tabs.Refresh()
+	if tab := m.tabOf(editor); tab != nil {
+		tab.Text = strings.TrimPrefix(tab.Text, "*")
+		m.tabs.Refresh()
+	}
+	m.refreshDependentPreviews(path)
+	return nil
+}
 
-	// 移除成功保存的弹窗
-	// dialog.ShowInformation("保存成功", "文件已成功保存", m.window)
+// currentFile 返回当前标签页对应的文件路径和编辑器
+func (m *MarkdownEditor) currentFile() (string, *noteEntry) {
+	currentTab := m.tabs.Selected()
+	if currentTab == nil {
+		return "", nil
+	}
+	if editor := tabEditor(currentTab); editor != nil {
+		return m.pathOf(editor), editor
+	}
+	return "", nil
+}
+
+// editorShortcut 执行编辑器获得焦点时收到的 Ctrl/Cmd 快捷键
+func (m *MarkdownEditor) editorShortcut(shortcut *desktop.CustomShortcut) bool {
+	if shortcut.Modifier != fyne.KeyModifierControl && shortcut.Modifier != fyne.KeyModifierSuper {
+		return false
+	}
+	action, ok := m.shortcuts[shortcut.KeyName]
+	if ok {
+		action()
+	}
+	return ok
+}
+
+// formatEditor 对当前标签页的编辑器执行格式化操作
+func (m *MarkdownEditor) formatEditor(format func(e *noteEntry)) {
+	if _, editor := m.currentFile(); editor != nil {
+		format(editor)
+	}
+}
+
+// updateStatus 在状态栏中显示当前编辑器的 Vim 模式、光标位置和字数，没有打开笔记时隐藏状态栏
+func (m *MarkdownEditor) updateStatus() {
+	_, editor := m.currentFile()
+	if editor == nil {
+		m.statusBar.Hide()
+		return
+	}
+	if editor.vim != nil {
+		m.modeLabel.SetText(editor.vim.status())
+		m.modeLabel.Show()
+	} else {
+		m.modeLabel.Hide()
+	}
+	m.statsLabel.SetText(editorStatus(editor))
+	m.statusBar.Show()
+}
+
+// tabEditor 返回笔记标签页中的编辑器，其它视图的标签页返回 nil
+func tabEditor(tab *container.TabItem) *noteEntry {
+	if source := tabSource(tab); source != nil {
+		return source.entry
+	}
+	return nil
+}
+
+// tabSource 返回笔记标签页中的源码编辑区
+func tabSource(tab *container.TabItem) *sourceEditor {
+	if split := tabSplit(tab); split != nil {
+		if source, ok := split.Leading.(*sourceEditor); ok {
+			return source
+		}
+	}
+	return nil
+}
+
+// tabSplit 返回笔记标签页中源码和预览的分栏，看板的标签页返回其源码模式的分栏
+func tabSplit(tab *container.TabItem) *container.Split {
+	switch content := tab.Content.(type) {
+	case *container.Split:
+		return content
+	case *kanbanView:
+		return content.split
+	}
+	return nil
+}
+
+// viewMode 是笔记标签页的显示方式
+type viewMode string
+
+const (
+	viewSplit   viewMode = "split"   // 源码和预览并排
+	viewSource  viewMode = "source"  // 只显示源码
+	viewPreview viewMode = "preview" // 只显示预览
+	viewBoard   viewMode = "board"   // 看板笔记显示看板
+)
+
+// tabViewMode 返回笔记标签页当前的显示方式
+func tabViewMode(tab *container.TabItem) viewMode {
+	if board, ok := tab.Content.(*kanbanView); ok && !board.sourceMode {
+		return viewBoard
+	}
+	split := tabSplit(tab)
+	switch {
+	case split == nil:
+		return viewSplit
+	case !split.Trailing.Visible():
+		return viewSource
+	case !split.Leading.Visible():
+		return viewPreview
+	}
+	return viewSplit
+}
+
+// setTabViewMode 切换笔记标签页的显示方式，看板只对看板笔记有效。
+// 只显示一侧时分割位置移到边上，回到并排时恢复为一半
+func setTabViewMode(tab *container.TabItem, mode viewMode) {
+	split := tabSplit(tab)
+	if split == nil {
+		return
+	}
+	board, _ := tab.Content.(*kanbanView)
+	if mode == viewBoard {
+		if board != nil && board.sourceMode {
+			board.setSourceMode(false)
+		}
+		return
+	}
+
+	previous := tabViewMode(tab)
+	split.Leading.Show()
+	split.Trailing.Show()
+	switch mode {
+	case viewSource:
+		split.Trailing.Hide()
+		split.Offset = 1
+	case viewPreview:
+		split.Leading.Hide()
+		split.Offset = 0
+	default:
+		if previous == viewSource || previous == viewPreview {
+			split.Offset = 0.5
+		}
+	}
+	split.Refresh()
+	if board != nil && !board.sourceMode {
+		board.setSourceMode(true)
+	}
+}
+
+// viewMenu 返回切换当前标签页显示方式的菜单
+func (m *MarkdownEditor) viewMenu() *fyne.Menu {
+	tab := m.tabs.Selected()
+	var current viewMode
+	isBoard := false
+	if tab != nil && tabSplit(tab) != nil {
+		current = tabViewMode(tab)
+		_, isBoard = tab.Content.(*kanbanView)
+	}
+	item := func(label string, mode viewMode) *fyne.MenuItem {
+		it := fyne.NewMenuItem(label, func() { setTabViewMode(tab, mode) })
+		it.Checked = current == mode
+		it.Disabled = current == "" || (mode == viewBoard && !isBoard)
+		return it
+	}
+	return fyne.NewMenu("",
+		item("Split", viewSplit),
+		item("Source Only", viewSource),
+		item("Preview Only", viewPreview),
+		item("Board", viewBoard),
+	)
+}
+
+// tabOf 返回编辑器所在的标签页
+func (m *MarkdownEditor) tabOf(editor *noteEntry) *container.TabItem {
+	for _, tab := range m.tabs.Items {
+		if tabEditor(tab) == editor {
+			return tab
+		}
+	}
+	return nil
+}
+
+// isDirty 判断编辑器中是否有未保存的修改
+func (m *MarkdownEditor) isDirty(editor *noteEntry) bool {
+	tab := m.tabOf(editor)
+	return tab != nil && strings.HasPrefix(tab.Text, "*")
+}
+
+// pathOf 返回编辑器对应的文件路径
+func (m *MarkdownEditor) pathOf(editor *noteEntry) string {
+	for p, e := range m.openFiles {
+		if e == editor {
+			return p
+		}
+	}
+	return ""
 }
 
 func (m *MarkdownEditor) refreshTree() {
+	m.queryCache = nil
 	m.treeView.Refresh()
 }
 
@@ -425,12 +726,53 @@ func (m *MarkdownEditor) openAllBranches(uid widget.TreeNodeID) {
 	}
 }
 
+// showToolsMenu 在工具栏的更多按钮下方弹出功能菜单
+func (m *MarkdownEditor) showToolsMenu() {
+	table := fyne.NewMenuItem("Table", nil)
+	table.ChildMenu = m.tableMenu()
+	view := fyne.NewMenuItem("View", nil)
+	view.ChildMenu = m.viewMenu()
+	menu := fyne.NewMenu("",
+		view,
+		table,
+		fyne.NewMenuItem("Format Document", m.formatCurrentFile),
+		fyne.NewMenuItem("Lint", m.showLint),
+		fyne.NewMenuItem("Bookmark Heading", m.bookmarkHeading),
+		fyne.NewMenuItem("Spelling Suggestions", m.showSpellingSuggestions),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Encrypt Note", m.encryptCurrentFile),
+		fyne.NewMenuItem("Remove Encryption", m.decryptCurrentFile),
+		fyne.NewMenuItem("Lock All", m.lockAll),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Present", m.startPresentation),
+		fyne.NewMenuItem("Tasks", m.showTasks),
+		fyne.NewMenuItem("Graph", m.showGraph),
+		fyne.NewMenuItem("Flashcards", m.showFlashcards),
+		fyne.NewMenuItem("Vault Statistics", m.showStatistics),
+		fyne.NewMenuItem("Unused Attachments", m.showUnusedAttachments),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Import", m.showImport),
+		fyne.NewMenuItem("Export as HTML", m.showExportHTML),
+		fyne.NewMenuItem("Export as Slides", m.showExportSlides),
+		fyne.NewMenuItem("Export as PDF", m.showExportPDF),
+		fyne.NewMenuItem("Export as EPUB", m.showExportEPUB),
+		fyne.NewMenuItem("Publish Site", m.showPublishSite),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Settings", m.showSettings),
+	)
+
+	c := fyne.CurrentApp().Driver().CanvasForObject(m.menuButton)
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(m.menuButton)
+	widget.ShowPopUpMenuAtPosition(menu, c, pos.Add(fyne.NewPos(0, m.menuButton.Size().Height)))
+}
+
 func (m *MarkdownEditor) createContextMenu(uid widget.TreeNodeID) *fyne.Menu {
 	return fyne.NewMenu("",
 		fyne.NewMenuItem("New File", func() { m.newFile(uid) }),
 		fyne.NewMenuItem("New Folder", func() { m.newFolder(uid) }),
 		fyne.NewMenuItem("Rename", func() { m.rename(uid) }),
 		fyne.NewMenuItem("Delete", func() { m.delete(uid) }),
+		fyne.NewMenuItem("Bookmark", func() { m.toggleNodeBookmark(uid) }),
 	)
 }
 
@@ -470,32 +812,7 @@ func (m *MarkdownEditor) finishCreatingNew(name string, isFolder bool) {
 		parentNode = m.treeView.Root
 	}
 
-	parentPath := m.uidToPath(parentNode)
-	newPath := filepath.Join(parentPath, name)
-	var err error
-
-	if isFolder {
-		err = os.Mkdir(newPath, 0755)
-	} else {
-		if !strings.HasSuffix(name, ".md") {
-			name += ".md"
-			newPath += ".md"
-		}
-		err = os.WriteFile(newPath, []byte(""), 0644)
-	}
-
-	if err != nil {
-		dialog.ShowError(err, m.window)
-		return
-	}
-
-	// 更新树形视图
-	m.treeView.OpenBranch(parentNode)
-	m.treeView.Refresh()
-
-	if !isFolder {
-		m.openFile(newPath)
-	}
+	m.createItem(m.uidToPath(parentNode), name, isFolder)
 }
 
 func (m *MarkdownEditor) cancelCreatingNew() {
@@ -565,38 +882,7 @@ func (m *MarkdownEditor) rename(uid widget.TreeNodeID) {
 		widget.NewFormItem("New Name", entryContainer),
 	}, func(ok bool) {
 		if ok {
-			newName := entry.Text
-			newPath := filepath.Join(filepath.Dir(oldPath), newName)
-			err := os.Rename(oldPath, newPath)
-			if err != nil {
-				dialog.ShowError(err, m.window)
-				return
-			}
-
-			// 更新树形视图
-			m.treeView.Refresh()
-
-			// 更新选中的节点
-			m.selectedNode = widget.TreeNodeID(filepath.Join(filepath.Dir(string(uid)), newName))
-
-			// 更新编辑区域的文件标签
-			for i, tab := range m.tabs.Items {
-				if strings.TrimPrefix(tab.Text, "*") == filepath.Base(oldPath) {
-					m.tabs.Items[i].Text = newName
-					if strings.HasPrefix(tab.Text, "*") {
-						m.tabs.Items[i].Text = "*" + newName
-					}
-
-					// 更新 openFiles 映射
-					if entry, ok := m.openFiles[oldPath]; ok {
-						delete(m.openFiles, oldPath)
-						m.openFiles[newPath] = entry
-					}
-
-					break
-				}
-			}
-			m.tabs.Refresh()
+			m.renameItem(uid, entry.Text)
 		}
 	}, m.window)
 }
@@ -615,18 +901,7 @@ func (m *MarkdownEditor) newFile(uid widget.TreeNodeID) {
 		widget.NewFormItem("Name", entry),
 	}, func(ok bool) {
 		if ok {
-			fileName := entry.Text
-			if !strings.HasSuffix(fileName, ".md") {
-				fileName += ".md"
-			}
-			newPath := filepath.Join(selectedPath, fileName)
-			err := ioutil.WriteFile(newPath, []byte(""), 0644)
-			if err != nil {
-				dialog.ShowError(err, m.window)
-				return
-			}
-			m.treeView.Refresh()
-			m.openFile(newPath)
+			m.createItem(selectedPath, entry.Text, false)
 		}
 	}, m.window)
 }
@@ -645,13 +920,7 @@ func (m *MarkdownEditor) newFolder(uid widget.TreeNodeID) {
 		widget.NewFormItem("Name", entry),
 	}, func(ok bool) {
 		if ok {
-			newPath := filepath.Join(selectedPath, entry.Text)
-			err := os.Mkdir(newPath, 0755)
-			if err != nil {
-				dialog.ShowError(err, m.window)
-				return
-			}
-			m.treeView.Refresh()
+			m.createItem(selectedPath, entry.Text, true)
 		}
 	}, m.window)
 }
@@ -660,15 +929,8 @@ func (m *MarkdownEditor) delete(uid widget.TreeNodeID) {
 	path := m.uidToPath(uid)
 	dialog.ShowConfirm("Delete", "Are you sure you want to delete this item?", func(ok bool) {
 		if ok {
-			// 首先关闭文件（如果它在编辑区域中打开）
-			fileName := filepath.Base(path)
-			for _, tab := range m.tabs.Items {
-				if strings.TrimPrefix(tab.Text, "*") == fileName {
-					m.tabs.Remove(tab)
-					delete(m.openFiles, path)
-					break
-				}
-			}
+			// 首先关闭该路径及其子路径下已打开的文件
+			m.closeFilesUnder(path)
 
 			// 然后删除文件
 			err := os.RemoveAll(path)
@@ -678,7 +940,8 @@ func (m *MarkdownEditor) delete(uid widget.TreeNodeID) {
 			}
 
 			// 更新树形视图
-			m.treeView.Refresh()
+			m.refreshTree()
+			m.pruneBookmarks()
 
 			// 清除选中的节点
 			m.selectedNode = ""
@@ -0,0 +1,367 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+const noteExt = ".md"
+
+var (
+	errEmptyName    = errors.New("名称不能为空")
+	errInvalidName  = errors.New("名称不能包含路径分隔符或为 . / ..")
+	errReservedName = errors.New("名称是系统保留名称")
+	errHiddenName   = errors.New("名称不能以 . 开头，否则不会显示在文件列表中")
+	errOutsideRoot  = errors.New("目标路径超出了笔记目录")
+)
+
+// collisionChoice 表示目标已存在时用户的选择
+type collisionChoice int
+
+const (
+	collisionCancel collisionChoice = iota
+	collisionAutoNumber
+	collisionReplace
+)
+
+// Windows 下不能作为文件名的设备名
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// sanitizeName 校验用户输入的名称，并把保留字符替换为下划线
+func sanitizeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errEmptyName
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", errInvalidName
+	}
+	if isHidden(name) {
+		return "", errHiddenName
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	// Windows 会忽略结尾的点和空格，这里统一去掉以免出现同名冲突
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "", errInvalidName
+	}
+
+	base := strings.ToUpper(strings.TrimSuffix(name, filepath.Ext(name)))
+	if reservedNames[base] {
+		return "", errReservedName
+	}
+	return name, nil
+}
+
+// noteName 确保笔记文件名带有 .md 扩展名
+func noteName(name string) string {
+	if strings.EqualFold(filepath.Ext(name), noteExt) {
+		return name
+	}
+	return name + noteExt
+}
+
+// resolveInRoot 将名称拼接到父目录下，并确认结果仍在 rootPath 之内
+func (m *MarkdownEditor) resolveInRoot(parentPath, name string) (string, error) {
+	path := filepath.Join(parentPath, name)
+	if !m.insideRoot(path) {
+		return "", errOutsideRoot
+	}
+	return path, nil
+}
+
+func (m *MarkdownEditor) insideRoot(path string) bool {
+	root, err := filepath.Abs(m.rootPath)
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// uniquePath 为已存在的路径生成 "name 1.md"、"name 2.md" 这样的可用路径
+func uniquePath(path string) string {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return path
+	}
+
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	for i := 1; ; i++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s %d%s", base, i, ext))
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// resolveCollision 在目标已存在时询问用户如何处理，并返回最终使用的路径。
+// 文件与文件夹之间不允许替换，替换文件夹或有未保存修改的笔记前需要再次确认
+func (m *MarkdownEditor) resolveCollision(path string, isFolder bool, callback func(path string, replace bool)) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		callback(path, false)
+		return
+	}
+	canReplace := err == nil && info.IsDir() == isFolder
+
+	m.showCollisionDialog(filepath.Base(path), canReplace, func(choice collisionChoice) {
+		switch choice {
+		case collisionAutoNumber:
+			callback(uniquePath(path), false)
+		case collisionReplace:
+			dirty := m.dirtyFilesUnder(path)
+			if !info.IsDir() && len(dirty) == 0 {
+				callback(path, true)
+				return
+			}
+			title := "Replace"
+			message := fmt.Sprintf("将永久删除“%s”，确定要替换吗？", filepath.Base(path))
+			if info.IsDir() {
+				title = "Replace Folder"
+				message = fmt.Sprintf("将永久删除文件夹“%s”及其中的所有内容，确定要替换吗？", filepath.Base(path))
+			}
+			if len(dirty) > 0 {
+				message += fmt.Sprintf("\n\n以下已打开的笔记有未保存的修改，替换后将丢失：\n%s", strings.Join(dirty, "\n"))
+			}
+			confirm := dialog.NewConfirm(title, message,
+				func(ok bool) {
+					if ok {
+						callback(path, true)
+					}
+				}, m.window)
+			confirm.SetConfirmText("Replace")
+			confirm.SetConfirmImportance(widget.DangerImportance)
+			confirm.Show()
+		}
+	})
+}
+
+func (m *MarkdownEditor) showCollisionDialog(name string, canReplace bool, callback func(collisionChoice)) {
+	autoButton := widget.NewButton("Auto-number", nil)
+	replaceButton := widget.NewButton("Replace", nil)
+	cancelButton := widget.NewButton("Cancel", nil)
+
+	message := fmt.Sprintf("“%s” 已存在，要如何处理？", name)
+	if !canReplace {
+		replaceButton.Disable()
+		message = fmt.Sprintf("“%s” 已存在且类型不同，无法替换，要如何处理？", name)
+	}
+
+	content := container.NewVBox(
+		widget.NewLabel(message),
+		container.NewHBox(layout.NewSpacer(), cancelButton, replaceButton, autoButton),
+	)
+
+	d := dialog.NewCustomWithoutButtons("Name Collision", content, m.window)
+	choose := func(choice collisionChoice) func() {
+		return func() {
+			d.Hide()
+			callback(choice)
+		}
+	}
+	autoButton.OnTapped = choose(collisionAutoNumber)
+	replaceButton.OnTapped = choose(collisionReplace)
+	cancelButton.OnTapped = choose(collisionCancel)
+	d.Show()
+}
+
+// createItem 在 parentPath 下创建笔记或文件夹，所有创建入口都经过这里
+func (m *MarkdownEditor) createItem(parentPath, name string, isFolder bool) {
+	name, err := sanitizeName(name)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	if !isFolder {
+		name = noteName(name)
+	}
+
+	newPath, err := m.resolveInRoot(parentPath, name)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+
+	m.resolveCollision(newPath, isFolder, func(path string, replace bool) {
+		if replace {
+			m.closeFilesUnder(path)
+			if err := os.RemoveAll(path); err != nil {
+				dialog.ShowError(err, m.window)
+				return
+			}
+		}
+
+		if isFolder {
+			err = os.Mkdir(path, 0755)
+		} else {
+			err = os.WriteFile(path, []byte(""), 0644)
+		}
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+
+		m.treeView.OpenBranch(m.pathToUID(parentPath))
+		m.refreshTree()
+
+		if !isFolder {
+			m.openFile(path)
+		}
+	})
+}
+
+// renameItem 重命名 uid 对应的文件或文件夹，笔记与 createItem 一样保证以 .md 结尾
+func (m *MarkdownEditor) renameItem(uid widget.TreeNodeID, newName string) {
+	oldPath := m.uidToPath(uid)
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+
+	newName, err = sanitizeName(newName)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	if !info.IsDir() && strings.EqualFold(filepath.Ext(oldPath), noteExt) {
+		newName = noteName(newName)
+	}
+
+	newPath, err := m.resolveInRoot(filepath.Dir(oldPath), newName)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	if newPath == oldPath {
+		return
+	}
+
+	// 仅大小写不同的重命名在不区分大小写的文件系统上指向同一个文件，不算冲突
+	if newInfo, err := os.Stat(newPath); err == nil && os.SameFile(info, newInfo) {
+		m.moveItem(oldPath, newPath)
+		return
+	}
+
+	m.resolveCollision(newPath, info.IsDir(), func(path string, replace bool) {
+		if replace {
+			m.closeFilesUnder(path)
+			if err := os.RemoveAll(path); err != nil {
+				dialog.ShowError(err, m.window)
+				return
+			}
+		}
+		m.moveItem(oldPath, path)
+	})
+}
+
+// moveItem 执行重命名，并让已打开的标签页跟随新路径
+func (m *MarkdownEditor) moveItem(oldPath, newPath string) {
+	if err := os.Rename(oldPath, newPath); err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+
+	for p, entry := range m.openFiles {
+		rel, ok := relUnder(oldPath, p)
+		if !ok {
+			continue
+		}
+		moved := filepath.Join(newPath, rel)
+		delete(m.openFiles, p)
+		m.openFiles[moved] = entry
+
+		if tab := m.tabOf(entry); tab != nil {
+			dirty := strings.HasPrefix(tab.Text, "*")
+			tab.Text = filepath.Base(moved)
+			if dirty {
+				tab.Text = "*" + tab.Text
+			}
+		}
+	}
+
+	m.followMove(oldPath, newPath)
+	m.selectedNode = m.pathToUID(newPath)
+	m.refreshTree()
+	m.tabs.Refresh()
+}
+
+// closeFilesUnder 关闭 path 本身或其子路径对应的标签页
+func (m *MarkdownEditor) closeFilesUnder(path string) {
+	for p, entry := range m.openFiles {
+		if _, ok := relUnder(path, p); !ok {
+			continue
+		}
+		if tab := m.tabOf(entry); tab != nil {
+			m.tabs.Remove(tab)
+		}
+		delete(m.openFiles, p)
+	}
+}
+
+// dirtyFilesUnder 返回 path 本身或其子路径中有未保存修改的已打开笔记，相对于笔记库并排序
+func (m *MarkdownEditor) dirtyFilesUnder(path string) []string {
+	var dirty []string
+	for p, entry := range m.openFiles {
+		if _, ok := relUnder(path, p); ok && m.isDirty(entry) {
+			rel, err := filepath.Rel(m.rootPath, p)
+			if err != nil {
+				rel = p
+			}
+			dirty = append(dirty, filepath.ToSlash(rel))
+		}
+	}
+	sort.Strings(dirty)
+	return dirty
+}
+
+// relUnder 判断 path 是否为 parent 本身或位于 parent 之下，并返回相对路径
+func relUnder(parent, path string) (string, bool) {
+	if path == parent {
+		return "", true
+	}
+	prefix := parent + string(filepath.Separator)
+	if strings.HasPrefix(path, prefix) {
+		return strings.TrimPrefix(path, prefix), true
+	}
+	return "", false
+}
+
+func (m *MarkdownEditor) pathToUID(path string) widget.TreeNodeID {
+	rel, err := filepath.Rel(m.rootPath, path)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return rel
+}
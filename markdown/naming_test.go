@@ -0,0 +1,183 @@
+package markdown
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		err  error
+	}{
+		{"note", "note", nil},
+		{"  note.md  ", "note.md", nil},
+		{"a:b?c", "a_b_c", nil},
+		{"tab\there", "tab_here", nil},
+		{"trailing. . ", "trailing", nil},
+		{"", "", errEmptyName},
+		{"   ", "", errEmptyName},
+		{"a/b", "", errInvalidName},
+		{`a\b`, "", errInvalidName},
+		{".", "", errInvalidName},
+		{"..", "", errInvalidName},
+		{"...", "", errHiddenName},
+		{".hidden", "", errHiddenName},
+		{" .md", "", errHiddenName},
+		{"a.b", "a.b", nil},
+		{"con", "", errReservedName},
+		{"LPT1.md", "", errReservedName},
+		{"console", "console", nil},
+	}
+	for _, tt := range tests {
+		got, err := sanitizeName(tt.name)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("sanitizeName(%q) = %q, %v; want %q, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestNoteName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"note", "note.md"},
+		{"note.md", "note.md"},
+		{"NOTE.MD", "NOTE.MD"},
+		{"v1.2", "v1.2.md"},
+		{"draft.txt", "draft.txt.md"},
+	}
+	for _, tt := range tests {
+		if got := noteName(tt.name); got != tt.want {
+			t.Errorf("noteName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "a 1.md", "folder"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name, want string
+	}{
+		{"b.md", "b.md"},
+		{"a.md", "a 2.md"},
+		{"folder", "folder 1"},
+	}
+	for _, tt := range tests {
+		got := uniquePath(filepath.Join(dir, tt.name))
+		if want := filepath.Join(dir, tt.want); got != want {
+			t.Errorf("uniquePath(%q) = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestRelUnder(t *testing.T) {
+	sep := string(filepath.Separator)
+	tests := []struct {
+		parent, path string
+		rel          string
+		ok           bool
+	}{
+		{"a", "a", "", true},
+		{"a", "a" + sep + "b.md", "b.md", true},
+		{"a", "a" + sep + "b" + sep + "c.md", "b" + sep + "c.md", true},
+		{"a", "ab.md", "", false},
+		{"a" + sep + "b", "a", "", false},
+	}
+	for _, tt := range tests {
+		rel, ok := relUnder(tt.parent, tt.path)
+		if rel != tt.rel || ok != tt.ok {
+			t.Errorf("relUnder(%q, %q) = %q, %v; want %q, %v", tt.parent, tt.path, rel, ok, tt.rel, tt.ok)
+		}
+	}
+}
+
+func TestRenameItem(t *testing.T) {
+	tests := []struct {
+		old, name, want string
+	}{
+		{"a.md", "b", "b.md"},
+		{"a.md", "b.md", "b.md"},
+		{"a.md", "B.MD", "B.MD"},
+		{"a.md", "v1.2", "v1.2.md"},
+		{"a.md", "b.txt", "b.txt.md"},
+		{"image.png", "photo", "photo"},
+		{"folder", "archive.2024", "archive.2024"},
+	}
+	for _, tt := range tests {
+		m := newTestEditor(t)
+		old := filepath.Join(m.rootPath, tt.old)
+		var err error
+		if filepath.Ext(tt.old) == "" {
+			err = os.Mkdir(old, 0755)
+		} else {
+			err = os.WriteFile(old, nil, 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m.renameItem(tt.old, tt.name)
+		if _, err := os.Stat(filepath.Join(m.rootPath, tt.want)); err != nil {
+			t.Errorf("renameItem(%q, %q): %v", tt.old, tt.name, err)
+		}
+	}
+}
+
+func TestDirtyFilesUnder(t *testing.T) {
+	m := newTestEditor(t)
+	names := []string{"a/b.md", "a/c.md", "ab.md", "d.md"}
+	for _, name := range names {
+		path := filepath.Join(m.rootPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("text"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m.openFile(path)
+	}
+	for _, name := range []string{"a/b.md", "ab.md", "d.md"} {
+		m.openFiles[filepath.Join(m.rootPath, filepath.FromSlash(name))].SetText("changed")
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"a", []string{"a/b.md"}},
+		{"a/b.md", []string{"a/b.md"}},
+		{"a/c.md", nil},
+		{"d.md", []string{"d.md"}},
+		{"", []string{"a/b.md", "ab.md", "d.md"}},
+	}
+	for _, tt := range tests {
+		got := m.dirtyFilesUnder(filepath.Join(m.rootPath, filepath.FromSlash(tt.path)))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("dirtyFilesUnder(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// newTestEditor 创建一个使用临时笔记库的编辑器
+func newTestEditor(t *testing.T) *MarkdownEditor {
+	t.Helper()
+	test.NewApp()
+	m := NewMarkdownEditor(test.NewWindow(nil))
+	if err := m.LoadDirectory(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
@@ -0,0 +1,544 @@
+package markdown
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// 一个最小的 PDF 生成器：西文使用 PDF 标准字体，中文等其它字符使用不嵌入的 STSong-Light CID 字体，
+// 阅读器会用系统中的宋体替代，因此不需要任何外部工具或字体文件
+
+// A4 纸的尺寸，单位为点（1/72 英寸）
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+)
+
+type pdfFont int
+
+const (
+	fontRegular pdfFont = iota
+	fontBold
+	fontItalic
+	fontBoldItalic
+	fontMono
+	fontMonoBold
+	fontCJK
+	fontCount
+)
+
+var pdfFontNames = [fontCount]string{
+	"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique",
+	"Courier", "Courier-Bold", "STSong-Light",
+}
+
+// Helvetica 和 Helvetica-Bold 中 ASCII 32~126 的字宽（千分之一字号）
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// latin1Base 是 0xC0~0xFF 中带重音的字母对应的基本字母，用来估算字宽
+const latin1Base = "AAAAAAACEEEEIIIIDNOOOOO*OUUUUYPsaaaaaaaceeeeiiiidnooooo/ouuuuypy"
+
+// winAnsiExtra 是 WinAnsiEncoding 中 0x80~0x9F 的常用字符及其字宽
+var winAnsiExtra = map[rune]struct {
+	code  byte
+	width int
+}{
+	'€': {0x80, 556}, '…': {0x85, 1000}, '‘': {0x91, 222}, '’': {0x92, 222},
+	'“': {0x93, 333}, '”': {0x94, 333}, '•': {0x95, 350}, '–': {0x96, 556}, '—': {0x97, 1000},
+}
+
+// winAnsi 返回字符在标准字体中的编码，不能用标准字体显示时返回 false
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	}
+	if extra, ok := winAnsiExtra[r]; ok {
+		return extra.code, true
+	}
+	return 0, false
+}
+
+// runeWidth 返回字符在给定字体和字号下的宽度
+func runeWidth(r rune, font pdfFont, size float64) float64 {
+	code, ok := winAnsi(r)
+	if !ok {
+		if isWide(r) {
+			return size
+		}
+		return size / 2
+	}
+	if font == fontMono || font == fontMonoBold {
+		return size * 0.6
+	}
+
+	widths := &helveticaWidths
+	if font == fontBold || font == fontBoldItalic {
+		widths = &helveticaBoldWidths
+	}
+	w := 556
+	switch {
+	case code >= 32 && code <= 126:
+		w = widths[code-32]
+	case code == 0xA0:
+		w = 278
+	case code >= 0xC0:
+		if base := latin1Base[code-0xC0]; base >= 32 && base <= 126 {
+			w = widths[base-32]
+		}
+	case code < 0xA0:
+		for _, extra := range winAnsiExtra {
+			if extra.code == code {
+				w = extra.width
+			}
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+func textWidth(s string, font pdfFont, size float64) float64 {
+	w := 0.0
+	for _, r := range s {
+		w += runeWidth(r, font, size)
+	}
+	return w
+}
+
+type pdfColor struct{ r, g, b float64 }
+
+var (
+	pdfBlack     = pdfColor{0, 0, 0}
+	pdfGray      = pdfColor{0.4, 0.4, 0.4}
+	pdfLightGray = pdfColor{0.85, 0.85, 0.85}
+	pdfCodeBg    = pdfColor{0.95, 0.96, 0.97}
+	pdfLinkColor = pdfColor{0.04, 0.41, 0.85}
+)
+
+// pdfLink 是页面中的一个可点击区域，uri 为空时跳转到文档中的 page 页
+type pdfLink struct {
+	x, y, w, h float64
+	uri        string
+	page       int
+	destY      float64
+}
+
+// pdfOutline 是书签中的一项
+type pdfOutline struct {
+	title    string
+	page     int
+	y        float64
+	children []*pdfOutline
+}
+
+// pdfPage 是一页的内容，坐标以页面左上角为原点，写入内容流时转换为 PDF 的坐标系
+type pdfPage struct {
+	content bytes.Buffer
+	links   []pdfLink
+	images  map[int]bool
+	header  string
+}
+
+// pdfImage 是一张图片，JPEG 直接写入，其它格式解码后压缩写入
+type pdfImage struct {
+	width, height int
+	colorSpace    string
+	filter        string
+	data          []byte
+	smask         []byte
+}
+
+type pdfDoc struct {
+	title    string
+	pages    []*pdfPage
+	images   []*pdfImage
+	imageIDs map[string]int
+	outlines []*pdfOutline
+}
+
+func newPDFDoc(title string) *pdfDoc {
+	return &pdfDoc{title: title, imageIDs: map[string]int{}}
+}
+
+func (d *pdfDoc) newPage(header string) *pdfPage {
+	p := &pdfPage{images: map[int]bool{}, header: header}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+func pdfNum(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// fillColor 设置填充颜色（文字和矩形）
+func (p *pdfPage) fillColor(c pdfColor) {
+	fmt.Fprintf(&p.content, "%s %s %s rg\n", pdfNum(c.r), pdfNum(c.g), pdfNum(c.b))
+}
+
+func (p *pdfPage) strokeColor(c pdfColor) {
+	fmt.Fprintf(&p.content, "%s %s %s RG\n", pdfNum(c.r), pdfNum(c.g), pdfNum(c.b))
+}
+
+// rect 绘制填充的矩形，(x, y) 为左上角
+func (p *pdfPage) rect(x, y, w, h float64, c pdfColor) {
+	p.fillColor(c)
+	fmt.Fprintf(&p.content, "%s %s %s %s re f\n", pdfNum(x), pdfNum(pdfPageHeight-y-h), pdfNum(w), pdfNum(h))
+}
+
+// strokeRect 绘制矩形边框
+func (p *pdfPage) strokeRect(x, y, w, h, width float64, c pdfColor) {
+	p.strokeColor(c)
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n", pdfNum(width), pdfNum(x), pdfNum(pdfPageHeight-y-h), pdfNum(w), pdfNum(h))
+}
+
+func (p *pdfPage) line(x1, y1, x2, y2, width float64, c pdfColor) {
+	p.strokeColor(c)
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", pdfNum(width),
+		pdfNum(x1), pdfNum(pdfPageHeight-y1), pdfNum(x2), pdfNum(pdfPageHeight-y2))
+}
+
+// text 在基线 y 处从 x 开始绘制文字，按字符自动切换标准字体和中文字体，返回文字宽度
+func (p *pdfPage) text(x, y float64, s string, font pdfFont, size float64, c pdfColor) float64 {
+	p.fillColor(c)
+	start := x
+	for len(s) > 0 {
+		// 取出一段使用同一种字体的文字
+		cjk := false
+		end := 0
+		for i, r := range s {
+			_, latin := winAnsi(r)
+			if i == 0 {
+				cjk = !latin
+			} else if latin == cjk {
+				break
+			}
+			end = i + len(string(r))
+		}
+		run := s[:end]
+		s = s[end:]
+
+		f := font
+		if cjk {
+			f = fontCJK
+		}
+		width := textWidth(run, f, size)
+		skew := 0.0
+		if cjk && (font == fontItalic || font == fontBoldItalic) {
+			skew = 0.2 // 中文字体没有斜体，用倾斜模拟
+		}
+		fakeBold := cjk && (font == fontBold || font == fontBoldItalic || font == fontMonoBold)
+
+		p.content.WriteString("BT\n")
+		if fakeBold {
+			// 中文字体没有粗体，用描边模拟
+			fmt.Fprintf(&p.content, "2 Tr %s w %s %s %s RG\n", pdfNum(size/30), pdfNum(c.r), pdfNum(c.g), pdfNum(c.b))
+		}
+		fmt.Fprintf(&p.content, "/F%d %s Tf 1 0 %s 1 %s %s Tm ", f, pdfNum(size), pdfNum(skew), pdfNum(x), pdfNum(pdfPageHeight-y))
+		if cjk {
+			p.content.WriteString(pdfCJKString(run))
+		} else {
+			p.content.WriteString(pdfLatinString(run))
+		}
+		p.content.WriteString(" Tj\n")
+		if fakeBold {
+			p.content.WriteString("0 Tr\n")
+		}
+		p.content.WriteString("ET\n")
+		x += width
+	}
+	return x - start
+}
+
+// image 在 (x, y) 处绘制图片，(x, y) 为左上角
+func (p *pdfPage) image(id int, x, y, w, h float64) {
+	p.images[id] = true
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", pdfNum(w), pdfNum(h), pdfNum(x), pdfNum(pdfPageHeight-y-h), id)
+}
+
+func (p *pdfPage) link(l pdfLink) {
+	p.links = append(p.links, l)
+}
+
+// pdfLatinString 把文字编码为 WinAnsiEncoding 的 PDF 字符串
+func pdfLatinString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		code, _ := winAnsi(r)
+		switch {
+		case code == '(' || code == ')' || code == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(code)
+		case code < 32 || code > 126:
+			fmt.Fprintf(&b, "\\%03o", code)
+		default:
+			b.WriteByte(code)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// pdfCJKString 把文字编码为 UCS-2 的十六进制字符串，超出基本平面的字符（如 emoji）替换为 □
+func pdfCJKString(s string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range s {
+		if r > 0xFFFF {
+			r = '□'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// pdfTextString 把文字编码为文档信息和书签中使用的 UTF-16BE 字符串
+func pdfTextString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", c)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// addImage 读取图片并加入文档，同一个文件只写入一次
+func (d *pdfDoc) addImage(path string) (int, *pdfImage, error) {
+	if id, ok := d.imageIDs[path]; ok {
+		return id, d.images[id], nil
+	}
+	img, err := loadPDFImage(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	id := len(d.images)
+	d.images = append(d.images, img)
+	d.imageIDs[path] = id
+	return id, img, nil
+}
+
+func loadPDFImage(path string) (*pdfImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if format == "jpeg" {
+		switch cfg.ColorModel {
+		case color.YCbCrModel:
+			return &pdfImage{width: cfg.Width, height: cfg.Height, colorSpace: "DeviceRGB", filter: "DCTDecode", data: data}, nil
+		case color.GrayModel:
+			return &pdfImage{width: cfg.Width, height: cfg.Height, colorSpace: "DeviceGray", filter: "DCTDecode", data: data}, nil
+		}
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			if c.A != 0xFF {
+				opaque = false
+			}
+		}
+	}
+	img := &pdfImage{width: bounds.Dx(), height: bounds.Dy(), colorSpace: "DeviceRGB", filter: "FlateDecode", data: deflate(rgb)}
+	if !opaque {
+		img.smask = deflate(alpha)
+	}
+	return img, nil
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// pdfWriter 负责对象编号和交叉引用表
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// reserve 预留一个对象编号
+func (w *pdfWriter) reserve() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+func (w *pdfWriter) object(id int, body string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (w *pdfWriter) stream(id int, dict string, data []byte) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// bytes 生成 PDF 文件，不写入创建时间，同样的内容每次得到相同的输出
+func (d *pdfDoc) bytes() []byte {
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	catalogID := w.reserve()
+	pagesID := w.reserve()
+	infoID := w.reserve()
+
+	var fonts strings.Builder
+	for f := pdfFont(0); f < fontCount; f++ {
+		id := w.reserve()
+		fmt.Fprintf(&fonts, "/F%d %d 0 R ", f, id)
+		if f != fontCJK {
+			w.object(id, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", pdfFontNames[f]))
+			continue
+		}
+		cidID := w.reserve()
+		descID := w.reserve()
+		w.object(id, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /UniGB-UCS2-H /DescendantFonts [%d 0 R] >>", pdfFontNames[f], cidID))
+		w.object(cidID, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor %d 0 R /DW 1000 /W [1 95 500 814 939 500] >>", pdfFontNames[f], descID))
+		w.object(descID, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>", pdfFontNames[f]))
+	}
+
+	imageIDs := make([]int, len(d.images))
+	for i, img := range d.images {
+		imageIDs[i] = w.reserve()
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s",
+			img.width, img.height, img.colorSpace, img.filter)
+		if img.smask != nil {
+			maskID := w.reserve()
+			w.stream(maskID, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode",
+				img.width, img.height), img.smask)
+			dict += fmt.Sprintf(" /SMask %d 0 R", maskID)
+		}
+		w.stream(imageIDs[i], dict, img.data)
+	}
+
+	pageIDs := make([]int, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = w.reserve()
+	}
+	for i, p := range d.pages {
+		contentID := w.reserve()
+		w.stream(contentID, "/Filter /FlateDecode", deflate(p.content.Bytes()))
+
+		var xobjects strings.Builder
+		for id := range d.images {
+			if p.images[id] {
+				fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", id, imageIDs[id])
+			}
+		}
+
+		var annots []string
+		for _, l := range p.links {
+			id := w.reserve()
+			rect := fmt.Sprintf("[%s %s %s %s]", pdfNum(l.x), pdfNum(pdfPageHeight-l.y-l.h), pdfNum(l.x+l.w), pdfNum(pdfPageHeight-l.y))
+			action := ""
+			if l.uri != "" {
+				action = "/A << /S /URI /URI " + pdfLatinString(l.uri) + " >>"
+			} else {
+				action = fmt.Sprintf("/Dest [%d 0 R /XYZ 0 %s null]", pageIDs[l.page], pdfNum(pdfPageHeight-l.destY))
+			}
+			w.object(id, fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect %s /Border [0 0 0] %s >>", rect, action))
+			annots = append(annots, fmt.Sprintf("%d 0 R", id))
+		}
+
+		page := fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R /Resources << /Font << %s>> /XObject << %s>> >>",
+			pagesID, pdfNum(pdfPageWidth), pdfNum(pdfPageHeight), contentID, fonts.String(), xobjects.String())
+		if len(annots) > 0 {
+			page += " /Annots [" + strings.Join(annots, " ") + "]"
+		}
+		w.object(pageIDs[i], page+" >>")
+	}
+
+	var kids []string
+	for _, id := range pageIDs {
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+	w.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageIDs)))
+
+	catalog := fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R", pagesID)
+	if len(d.outlines) > 0 {
+		outlinesID := w.reserve()
+		first, last, count := d.writeOutlines(w, d.outlines, outlinesID, pageIDs)
+		w.object(outlinesID, fmt.Sprintf("<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>", first, last, count))
+		catalog += fmt.Sprintf(" /Outlines %d 0 R /PageMode /UseOutlines", outlinesID)
+	}
+	w.object(catalogID, catalog+" >>")
+	w.object(infoID, fmt.Sprintf("<< /Title %s /Producer (Nodian) >>", pdfTextString(d.title)))
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, catalogID, infoID, xref)
+	return w.buf.Bytes()
+}
+
+// writeOutlines 写入一层书签，返回第一项和最后一项的对象编号以及书签总数
+func (d *pdfDoc) writeOutlines(w *pdfWriter, items []*pdfOutline, parent int, pageIDs []int) (int, int, int) {
+	ids := make([]int, len(items))
+	for i := range items {
+		ids[i] = w.reserve()
+	}
+	count := len(items)
+	for i, item := range items {
+		dict := fmt.Sprintf("<< /Title %s /Parent %d 0 R /Dest [%d 0 R /XYZ 0 %s null]",
+			pdfTextString(item.title), parent, pageIDs[item.page], pdfNum(pdfPageHeight-item.y))
+		if i > 0 {
+			dict += fmt.Sprintf(" /Prev %d 0 R", ids[i-1])
+		}
+		if i < len(items)-1 {
+			dict += fmt.Sprintf(" /Next %d 0 R", ids[i+1])
+		}
+		if len(item.children) > 0 {
+			first, last, n := d.writeOutlines(w, item.children, ids[i], pageIDs)
+			// 子书签默认折叠
+			dict += fmt.Sprintf(" /First %d 0 R /Last %d 0 R /Count -%d", first, last, n)
+		}
+		w.object(ids[i], dict+" >>")
+	}
+	return ids[0], ids[len(ids)-1], count
+}
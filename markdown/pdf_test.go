@@ -0,0 +1,290 @@
+package markdown
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+var (
+	pdfRefPattern  = regexp.MustCompile(`(\d+) 0 R`)
+	pdfShowPattern = regexp.MustCompile(`/F(\d) [\d.]+ Tf 1 0 [\d.]+ 1 ([\d.]+) ([\d.]+) Tm (\((?:\\.|[^\\)])*\)|<[0-9A-F]*>) Tj`)
+)
+
+// parsePDF 按交叉引用表读取所有对象，同时检查表中的偏移量和对象编号
+func parsePDF(t *testing.T, data []byte) map[int]string {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	i := bytes.LastIndex(data, []byte("startxref\n"))
+	if i < 0 {
+		t.Fatal("missing startxref")
+	}
+	var xref, size int
+	if _, err := fmt.Sscanf(string(data[i+len("startxref\n"):]), "%d", &xref); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fmt.Sscanf(string(data[xref:]), "xref\n0 %d\n", &size); err != nil {
+		t.Fatalf("startxref %d does not point to the xref table: %v", xref, err)
+	}
+	table := data[xref+len(fmt.Sprintf("xref\n0 %d\n", size)):]
+	if !bytes.HasPrefix(table, []byte("0000000000 65535 f \n")) {
+		t.Fatal("xref table does not start with the free entry")
+	}
+	if trailer := string(table[size*20:]); !strings.Contains(trailer, fmt.Sprintf("/Size %d ", size)) {
+		t.Errorf("trailer %q does not match xref size %d", trailer, size)
+	}
+
+	objects := map[int]string{}
+	for id := 1; id < size; id++ {
+		entry := string(table[id*20 : id*20+20])
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil || !strings.HasSuffix(entry, " 00000 n \n") {
+			t.Fatalf("bad xref entry %q", entry)
+		}
+		head := fmt.Sprintf("%d 0 obj\n", id)
+		if !bytes.HasPrefix(data[offset:], []byte(head)) {
+			t.Fatalf("xref offset %d of object %d points to %q", offset, id, data[offset:min(offset+20, len(data))])
+		}
+		body := data[offset+len(head):]
+		if n := bytes.Index(body, []byte("\nstream\n")); n >= 0 && n < bytes.Index(body, []byte("\nendobj\n")) {
+			// 按 /Length 读取流，流中可能出现任意字节
+			var length int
+			fmt.Sscanf(string(body[bytes.Index(body, []byte("/Length "))+len("/Length "):]), "%d", &length)
+			end := n + len("\nstream\n") + length
+			if !bytes.HasPrefix(body[end:], []byte("\nendstream\nendobj\n")) {
+				t.Fatalf("object %d: stream length %d does not end at endstream", id, length)
+			}
+			body = body[:end]
+		} else {
+			body = body[:bytes.Index(body, []byte("\nendobj\n"))]
+		}
+		objects[id] = string(body)
+	}
+	if n := bytes.Count(data, []byte(" 0 obj\n")); n != size-1 {
+		t.Errorf("file has %d objects, xref lists %d", n, size-1)
+	}
+	for id, body := range objects {
+		for _, ref := range pdfRefPattern.FindAllStringSubmatch(body, -1) {
+			if n, _ := strconv.Atoi(ref[1]); n >= size {
+				t.Errorf("object %d refers to missing object %d", id, n)
+			}
+		}
+	}
+	return objects
+}
+
+// pdfText 是页面中绘制的一段文字
+type pdfText struct {
+	font int
+	y    float64 // 距页面顶部的距离
+	text string
+}
+
+// pdfPages 按页面顺序返回每一页中绘制的文字
+func pdfPages(t *testing.T, objects map[int]string) [][]pdfText {
+	t.Helper()
+	var root string
+	for _, body := range objects {
+		if strings.Contains(body, "/Type /Pages") {
+			root = body
+		}
+	}
+	kids := root[strings.Index(root, "/Kids [")+len("/Kids [") : strings.Index(root, "]")]
+	var pages [][]pdfText
+	for _, ref := range pdfRefPattern.FindAllStringSubmatch(kids, -1) {
+		id, _ := strconv.Atoi(ref[1])
+		page := objects[id]
+		content := pdfRefPattern.FindStringSubmatch(page[strings.Index(page, "/Contents"):])
+		cid, _ := strconv.Atoi(content[1])
+		stream := objects[cid]
+		r, err := zlib.NewReader(strings.NewReader(stream[strings.Index(stream, "\nstream\n")+len("\nstream\n"):]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var texts []pdfText
+		for _, m := range pdfShowPattern.FindAllStringSubmatch(string(data), -1) {
+			font, _ := strconv.Atoi(m[1])
+			y, _ := strconv.ParseFloat(m[3], 64)
+			texts = append(texts, pdfText{font: font, y: pdfPageHeight - y, text: decodePDFString(m[4])})
+		}
+		pages = append(pages, texts)
+	}
+	if want := fmt.Sprintf("/Count %d ", len(pages)); !strings.Contains(root, want) {
+		t.Errorf("pages object %q does not contain %q", root, want)
+	}
+	return pages
+}
+
+func decodePDFString(s string) string {
+	if s[0] == '<' {
+		data, _ := hex.DecodeString(s[1 : len(s)-1])
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		}
+		return string(utf16.Decode(units))
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func pageText(texts []pdfText) string {
+	var b strings.Builder
+	for _, t := range texts {
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+func exportTestPDF(t *testing.T, toc bool, notes ...string) [][]pdfText {
+	t.Helper()
+	m := newTestEditor(t)
+	var paths []string
+	for i, content := range notes {
+		path := filepath.Join(m.rootPath, fmt.Sprintf("note%d.md", i+1))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	data, err := m.exportPDF("Export", paths, toc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := m.exportPDF("Export", paths, toc)
+	if !bytes.Equal(data, again) {
+		t.Error("exporting twice gave different output")
+	}
+	return pdfPages(t, parsePDF(t, data))
+}
+
+func TestPDFFonts(t *testing.T) {
+	m := newTestEditor(t)
+	path := filepath.Join(m.rootPath, "note.md")
+	if err := os.WriteFile(path, []byte("# Title\n\nHello (world) 中文**粗体** end\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.exportPDF("", []string{path}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := parsePDF(t, data)
+
+	// 西文使用 14 种标准字体，中文使用不嵌入的 CID 字体，阅读器用系统字体替代
+	var type1, type0, cid []string
+	for _, body := range objects {
+		switch {
+		case strings.Contains(body, "/Subtype /Type1"):
+			type1 = append(type1, body)
+		case strings.Contains(body, "/Subtype /Type0"):
+			type0 = append(type0, body)
+		case strings.Contains(body, "/Subtype /CIDFontType0"):
+			cid = append(cid, body)
+		}
+		if strings.Contains(body, "/FontFile") {
+			t.Errorf("font program embedded: %s", body)
+		}
+	}
+	if len(type1) != 6 || len(type0) != 1 || len(cid) != 1 {
+		t.Fatalf("fonts: %d Type1, %d Type0, %d CID; want 6, 1, 1", len(type1), len(type0), len(cid))
+	}
+	if !strings.Contains(type0[0], "/BaseFont /STSong-Light /Encoding /UniGB-UCS2-H") {
+		t.Errorf("CJK font = %s", type0[0])
+	}
+	if !strings.Contains(cid[0], "/Ordering (GB1)") || !strings.Contains(cid[0], "/FontDescriptor") {
+		t.Errorf("CID font = %s", cid[0])
+	}
+
+	pages := pdfPages(t, objects)
+	if len(pages) != 1 {
+		t.Fatalf("got %d pages, want 1", len(pages))
+	}
+	// 单词之间的空格可能只体现为位置的间隔
+	if got := pageText(pages[0]); !strings.Contains(got, "Hello (world) 中文粗体") || !strings.Contains(got, "end") {
+		t.Errorf("page text = %q", got)
+	}
+	for _, text := range pages[0] {
+		cjk := strings.ContainsAny(text.text, "中文粗体")
+		if cjk != (text.font == int(fontCJK)) {
+			t.Errorf("%q drawn with font F%d", text.text, text.font)
+		}
+	}
+	if !strings.Contains(string(data), "/Title <FEFF005400690074006C0065>") {
+		t.Error("document title is not taken from the first heading")
+	}
+}
+
+func TestPDFPageBreaks(t *testing.T) {
+	var note strings.Builder
+	for i := 1; i <= 120; i++ {
+		fmt.Fprintf(&note, "para %d\n\n", i)
+	}
+	pages := exportTestPDF(t, false, note.String())
+	if len(pages) < 2 {
+		t.Fatalf("120 paragraphs fit on %d page", len(pages))
+	}
+
+	next := 1
+	for i, page := range pages {
+		for _, text := range page {
+			if !strings.HasPrefix(text.text, "para ") {
+				continue
+			}
+			if want := fmt.Sprintf("para %d", next); text.text != want {
+				t.Fatalf("page %d: got %q, want %q", i+1, text.text, want)
+			}
+			next++
+			if text.y < pdfMarginTop || text.y > pdfPageHeight-pdfMarginBottom {
+				t.Errorf("page %d: %q drawn at %.1f, outside the margins", i+1, text.text, text.y)
+			}
+		}
+		if want := fmt.Sprintf("%d / %d", i+1, len(pages)); !strings.Contains(pageText(page), want) {
+			t.Errorf("page %d has no page number %q", i+1, want)
+		}
+	}
+	if next != 121 {
+		t.Errorf("found %d paragraphs, want 120", next-1)
+	}
+
+	pages = exportTestPDF(t, false, "first\n\n<div style=\"page-break-after: always\"></div>\n\nsecond\n")
+	if len(pages) != 2 || !strings.Contains(pageText(pages[0]), "first") || !strings.Contains(pageText(pages[1]), "second") {
+		t.Errorf("manual page break: pages = %v", pages)
+	}
+}
+
+func TestPDFTableOfContents(t *testing.T) {
+	pages := exportTestPDF(t, true, "# 第一篇\n\none", "# Second\n\ntwo")
+	if len(pages) != 3 {
+		t.Fatalf("got %d pages, want a contents page and one page per note", len(pages))
+	}
+	contents := pageText(pages[0])
+	for _, want := range []string{"Export", "第一篇", "Second"} {
+		if !strings.Contains(contents, want) {
+			t.Errorf("contents page %q does not contain %q", contents, want)
+		}
+	}
+	if !strings.Contains(pageText(pages[1]), "one") || !strings.Contains(pageText(pages[2]), "two") {
+		t.Error("each note should start on its own page")
+	}
+}
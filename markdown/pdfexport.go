@@ -0,0 +1,899 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// PDF 页面布局，单位为点
+const (
+	pdfMarginX      = 56.0
+	pdfMarginTop    = 64.0
+	pdfMarginBottom = 64.0
+	pdfBodySize     = 10.5
+	pdfCodeSize     = 9.0
+	pdfLineFactor   = 1.5
+	pdfListIndent   = 18.0
+	pdfQuoteIndent  = 14.0
+	pdfCellPadding  = 4.0
+	pdfTOCLine      = 20.0
+
+	// 手动分页：在笔记中插入含有 page-break 的 HTML，如 <div style="page-break-after: always"></div>
+	pdfPageBreak = "page-break"
+)
+
+var errMissingImage = errors.New("图片不存在")
+
+var pdfHeadingSizes = [...]float64{20, 16, 13.5, 12, 11, 10.5}
+
+var pdfMarkdown = goldmark.New(goldmark.WithExtensions(extension.GFM, wikiLinks{}))
+
+// pdfStyle 是一段文字的样式
+type pdfStyle struct {
+	bold, italic, mono, strike bool
+	size                       float64
+	color                      pdfColor
+	link                       string // 外部链接地址
+}
+
+func (s pdfStyle) font() pdfFont {
+	switch {
+	case s.mono && s.bold:
+		return fontMonoBold
+	case s.mono:
+		return fontMono
+	case s.bold && s.italic:
+		return fontBoldItalic
+	case s.bold:
+		return fontBold
+	case s.italic:
+		return fontItalic
+	}
+	return fontRegular
+}
+
+type pdfRun struct {
+	text  string
+	style pdfStyle
+}
+
+// pdfAtom 是换行时不可再分的一段文字：一个西文单词、一个中文字符或一个空格
+type pdfAtom struct {
+	text  string
+	style pdfStyle
+	width float64
+	space bool
+	br    bool // 强制换行
+}
+
+// pdfImageRef 是段落中引用的图片
+type pdfImageRef struct {
+	dest string
+	alt  string
+	wiki bool
+}
+
+// pdfLayout 把笔记的 Markdown 语法树排版到 PDF 页面中
+type pdfLayout struct {
+	m   *MarkdownEditor
+	doc *pdfDoc
+
+	page  *pdfPage
+	y     float64 // 当前位置，从页面顶部算起
+	left  float64
+	width float64
+	color pdfColor
+
+	notePath string
+	source   []byte
+	header   string
+	outlines *[]*pdfOutline // 标题书签加入的位置
+}
+
+func (l *pdfLayout) top() float64 {
+	return pdfMarginTop
+}
+
+func (l *pdfLayout) bottom() float64 {
+	return pdfPageHeight - pdfMarginBottom
+}
+
+func (l *pdfLayout) atTop() bool {
+	return l.y <= l.top()
+}
+
+func (l *pdfLayout) newPage() {
+	l.page = l.doc.newPage(l.header)
+	l.y = l.top()
+}
+
+// ensure 确保当前页还能放下高度为 h 的内容，否则换到下一页
+func (l *pdfLayout) ensure(h float64) {
+	if l.y+h > l.bottom() && !l.atTop() {
+		l.newPage()
+	}
+}
+
+func (l *pdfLayout) pageIndex() int {
+	return len(l.doc.pages) - 1
+}
+
+func (l *pdfLayout) baseStyle() pdfStyle {
+	return pdfStyle{size: pdfBodySize, color: l.color}
+}
+
+// renderNote 从新的一页开始排版一篇笔记，返回标题和起始页
+func (l *pdfLayout) renderNote(notePath, content string) (string, int) {
+	l.notePath = notePath
+	l.source = []byte(stripFrontMatter(content))
+	doc := pdfMarkdown.Parser().Parse(text.NewReader(l.source))
+	title := noteTitle(notePath, content, l.source, doc)
+
+	l.header = title
+	l.left, l.width = pdfMarginX, pdfPageWidth-2*pdfMarginX
+	l.color = pdfBlack
+	l.newPage()
+	start := l.pageIndex()
+	l.blocks(doc)
+	return title, start
+}
+
+func (l *pdfLayout) blocks(n ast.Node) {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		l.block(child)
+	}
+}
+
+func (l *pdfLayout) block(n ast.Node) {
+	switch t := n.(type) {
+	case *ast.Heading:
+		l.heading(t)
+	case *ast.Paragraph:
+		l.paragraph(t, pdfBodySize*0.6)
+	case *ast.TextBlock:
+		l.paragraph(t, 2)
+	case *ast.List:
+		l.list(t)
+	case *ast.Blockquote:
+		l.blockquote(t)
+	case *ast.CodeBlock, *ast.FencedCodeBlock:
+		l.codeBlock(strings.TrimSuffix(blockText(l.source, n), "\n"))
+	case *extast.Table:
+		l.table(t)
+	case *ast.ThematicBreak:
+		l.ensure(pdfBodySize)
+		l.page.line(l.left, l.y+pdfBodySize/2, l.left+l.width, l.y+pdfBodySize/2, 0.5, pdfLightGray)
+		l.y += pdfBodySize * 1.5
+	case *ast.HTMLBlock:
+		if strings.Contains(blockText(l.source, n), pdfPageBreak) && !l.atTop() {
+			l.newPage()
+		}
+	default:
+		l.blocks(n)
+	}
+}
+
+func (l *pdfLayout) heading(h *ast.Heading) {
+	size := pdfHeadingSizes[h.Level-1]
+	if !l.atTop() {
+		l.y += size * 0.8
+	}
+	// 标题和下面至少两行正文放在同一页
+	l.ensure(size*pdfLineFactor + pdfBodySize*pdfLineFactor*2)
+	if l.outlines != nil && h.Level <= 3 {
+		*l.outlines = append(*l.outlines, &pdfOutline{title: headingText(l.source, h), page: l.pageIndex(), y: l.y})
+	}
+
+	style := l.baseStyle()
+	style.bold, style.size = true, size
+	l.flow(l.inlines(h, style, nil))
+	if h.Level <= 2 {
+		l.page.line(l.left, l.y+2, l.left+l.width, l.y+2, 0.5, pdfLightGray)
+		l.y += 4
+	}
+	l.y += size * 0.4
+}
+
+func (l *pdfLayout) paragraph(n ast.Node, after float64) {
+	var images []pdfImageRef
+	runs := l.inlines(n, l.baseStyle(), &images)
+	if strings.TrimSpace(runsText(runs)) != "" {
+		l.flow(runs)
+	}
+	for _, img := range images {
+		l.image(img)
+	}
+	l.y += after
+}
+
+func runsText(runs []pdfRun) string {
+	var b strings.Builder
+	for _, run := range runs {
+		b.WriteString(run.text)
+	}
+	return b.String()
+}
+
+// inlines 把行内节点转换为带样式的文字。images 不为 nil 时收集其中的图片，否则图片以替代文本显示
+func (l *pdfLayout) inlines(n ast.Node, style pdfStyle, images *[]pdfImageRef) []pdfRun {
+	var runs []pdfRun
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch t := c.(type) {
+		case *ast.Text:
+			s := string(t.Segment.Value(l.source))
+			runs = append(runs, pdfRun{s, style})
+			if t.HardLineBreak() {
+				runs = append(runs, pdfRun{"\n", style})
+			} else if t.SoftLineBreak() {
+				// 中文换行处不加空格
+				if r, _ := utf8.DecodeLastRuneInString(s); !isWide(r) {
+					runs = append(runs, pdfRun{" ", style})
+				}
+			}
+		case *ast.String:
+			runs = append(runs, pdfRun{string(t.Value), style})
+		case *ast.CodeSpan:
+			s := style
+			s.mono, s.color = true, pdfColor{0.75, 0.2, 0.3}
+			runs = append(runs, pdfRun{headingText(l.source, t), s})
+		case *ast.Emphasis:
+			s := style
+			if t.Level == 2 {
+				s.bold = true
+			} else {
+				s.italic = true
+			}
+			runs = append(runs, l.inlines(t, s, images)...)
+		case *extast.Strikethrough:
+			s := style
+			s.strike = true
+			runs = append(runs, l.inlines(t, s, images)...)
+		case *ast.Link:
+			s := style
+			if dest := string(t.Destination); isExternalLink(dest) {
+				s.color, s.link = pdfLinkColor, dest
+			}
+			runs = append(runs, l.inlines(t, s, images)...)
+		case *ast.AutoLink:
+			s := style
+			s.color, s.link = pdfLinkColor, string(t.URL(l.source))
+			runs = append(runs, pdfRun{string(t.Label(l.source)), s})
+		case *ast.Image:
+			alt := headingText(l.source, t)
+			if images != nil {
+				*images = append(*images, pdfImageRef{dest: string(t.Destination), alt: alt})
+			} else {
+				runs = append(runs, pdfRun{alt, style})
+			}
+		case *wikiLinkNode:
+			if t.Embed && isImage(t.Target) && images != nil {
+				*images = append(*images, pdfImageRef{dest: t.Target, alt: t.Label, wiki: true})
+			} else {
+				runs = append(runs, pdfRun{t.Label, style})
+			}
+		case *extast.TaskCheckBox:
+			// 复选框由列表绘制
+		case *ast.RawHTML:
+			if t.Segments.Len() == 0 {
+				continue
+			}
+			if segment := t.Segments.At(0); strings.HasPrefix(strings.ToLower(string(segment.Value(l.source))), "<br") {
+				runs = append(runs, pdfRun{"\n", style})
+			}
+		default:
+			runs = append(runs, l.inlines(c, style, images)...)
+		}
+	}
+	return runs
+}
+
+// atoms 把文字拆分为换行的最小单位
+func atoms(runs []pdfRun) []pdfAtom {
+	var result []pdfAtom
+	for _, run := range runs {
+		font, size := run.style.font(), run.style.size
+		var word strings.Builder
+		flush := func() {
+			if word.Len() > 0 {
+				s := word.String()
+				result = append(result, pdfAtom{text: s, style: run.style, width: textWidth(s, font, size)})
+				word.Reset()
+			}
+		}
+		for _, r := range run.text {
+			switch {
+			case r == '\n':
+				flush()
+				result = append(result, pdfAtom{br: true, style: run.style})
+			case r == ' ' || r == '\t':
+				flush()
+				result = append(result, pdfAtom{text: " ", style: run.style, width: runeWidth(' ', font, size), space: true})
+			case isWide(r):
+				flush()
+				result = append(result, pdfAtom{text: string(r), style: run.style, width: runeWidth(r, font, size)})
+			default:
+				word.WriteRune(r)
+			}
+		}
+		flush()
+	}
+	return result
+}
+
+// wrap 按宽度把文字分成多行，过长的单词按字符拆开
+func wrap(atoms []pdfAtom, width float64) [][]pdfAtom {
+	var lines [][]pdfAtom
+	var line []pdfAtom
+	w := 0.0
+	end := func() {
+		for len(line) > 0 && line[len(line)-1].space {
+			line = line[:len(line)-1]
+		}
+		lines = append(lines, line)
+		line, w = nil, 0
+	}
+
+	for _, a := range atoms {
+		switch {
+		case a.br:
+			end()
+			continue
+		case a.space:
+			if len(line) > 0 {
+				line = append(line, a)
+				w += a.width
+			}
+			continue
+		}
+		if w+a.width > width && len(line) > 0 {
+			end()
+		}
+		for a.width > width {
+			font, size := a.style.font(), a.style.size
+			cut, cw := 0, 0.0
+			for i, r := range a.text {
+				rw := runeWidth(r, font, size)
+				if cw+rw > width && i > 0 {
+					break
+				}
+				cut, cw = i+utf8.RuneLen(r), cw+rw
+			}
+			line = append(line, pdfAtom{text: a.text[:cut], style: a.style, width: cw})
+			end()
+			a.text = a.text[cut:]
+			a.width = textWidth(a.text, font, size)
+		}
+		line = append(line, a)
+		w += a.width
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		end()
+	}
+	return lines
+}
+
+func lineHeight(line []pdfAtom, base float64) float64 {
+	size := base
+	for _, a := range line {
+		size = math.Max(size, a.style.size)
+	}
+	return size * pdfLineFactor
+}
+
+func lineWidth(line []pdfAtom) float64 {
+	w := 0.0
+	for _, a := range line {
+		w += a.width
+	}
+	return w
+}
+
+// drawLine 在 (x, y) 处绘制一行文字，y 为行的顶部。相同样式的连续文字合并为一次绘制
+func (l *pdfLayout) drawLine(line []pdfAtom, x, y, lh float64) {
+	for len(line) > 0 {
+		n, width := 0, 0.0
+		var b strings.Builder
+		for n < len(line) && line[n].style == line[0].style {
+			b.WriteString(line[n].text)
+			width += line[n].width
+			n++
+		}
+		style := line[0].style
+		line = line[n:]
+
+		baseline := y + lh/2 + style.size*0.35
+		if text := strings.TrimLeft(b.String(), " "); text != "" {
+			l.page.text(x+textWidth(b.String()[:b.Len()-len(text)], style.font(), style.size), baseline, text, style.font(), style.size, style.color)
+		}
+		if style.strike {
+			l.page.line(x, baseline-style.size*0.3, x+width, baseline-style.size*0.3, 0.6, style.color)
+		}
+		if style.link != "" {
+			l.page.link(pdfLink{x: x, y: y, w: width, h: lh, uri: style.link})
+		}
+		x += width
+	}
+}
+
+// flow 在当前位置排版一段文字，需要时自动换页
+func (l *pdfLayout) flow(runs []pdfRun) {
+	base := pdfBodySize
+	if len(runs) > 0 {
+		base = runs[0].style.size
+	}
+	for _, line := range wrap(atoms(runs), l.width) {
+		lh := lineHeight(line, base)
+		l.ensure(lh)
+		l.drawLine(line, l.left, l.y, lh)
+		l.y += lh
+	}
+}
+
+func (l *pdfLayout) list(list *ast.List) {
+	number := list.Start
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		lh := pdfBodySize * pdfLineFactor
+		l.ensure(lh)
+		markerX := l.left + 4
+
+		if box := taskCheckBox(item); box != nil {
+			size := pdfBodySize * 0.75
+			top := l.y + (lh-size)/2
+			l.page.strokeRect(markerX, top, size, size, 0.7, l.color)
+			if box.IsChecked {
+				l.page.line(markerX+size*0.2, top+size*0.5, markerX+size*0.42, top+size*0.78, 1, l.color)
+				l.page.line(markerX+size*0.42, top+size*0.78, markerX+size*0.85, top+size*0.2, 1, l.color)
+			}
+		} else {
+			marker := "•"
+			if list.IsOrdered() {
+				marker = strconv.Itoa(number) + "."
+				number++
+			}
+			l.page.text(markerX, l.y+lh/2+pdfBodySize*0.35, marker, fontRegular, pdfBodySize, l.color)
+		}
+
+		start := l.y
+		l.left += pdfListIndent
+		l.width -= pdfListIndent
+		l.blocks(item)
+		l.left -= pdfListIndent
+		l.width += pdfListIndent
+		if l.y == start {
+			l.y += lh
+		}
+	}
+	if !list.IsTight {
+		l.y += pdfBodySize * 0.3
+	}
+	l.y += pdfBodySize * 0.3
+}
+
+// taskCheckBox 返回任务项开头的复选框
+func taskCheckBox(item ast.Node) *extast.TaskCheckBox {
+	if first := item.FirstChild(); first != nil {
+		if box, ok := first.FirstChild().(*extast.TaskCheckBox); ok {
+			return box
+		}
+	}
+	return nil
+}
+
+func (l *pdfLayout) blockquote(n *ast.Blockquote) {
+	startPage, startY := l.pageIndex(), l.y
+	color := l.color
+	l.left += pdfQuoteIndent
+	l.width -= pdfQuoteIndent
+	l.color = pdfGray
+	l.blocks(n)
+	l.left -= pdfQuoteIndent
+	l.width += pdfQuoteIndent
+	l.color = color
+
+	// 在引用跨越的每一页左侧画竖线
+	x := l.left + 3
+	for i := startPage; i <= l.pageIndex(); i++ {
+		from, to := l.top(), l.bottom()
+		if i == startPage {
+			from = startY
+		}
+		if i == l.pageIndex() {
+			to = l.y - pdfBodySize*0.3
+		}
+		if to > from {
+			l.doc.pages[i].line(x, from, x, to, 2.5, pdfLightGray)
+		}
+	}
+	l.y += pdfBodySize * 0.3
+}
+
+func (l *pdfLayout) codeBlock(code string) {
+	const pad = 6.0
+	lh := pdfCodeSize * 1.4
+	style := pdfStyle{mono: true, size: pdfCodeSize, color: pdfBlack}
+
+	l.ensure(pad + lh)
+	l.page.rect(l.left, l.y, l.width, pad, pdfCodeBg)
+	l.y += pad
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		for _, wrapped := range wrap(atoms([]pdfRun{{line, style}}), l.width-2*pad) {
+			if l.y+lh > l.bottom() {
+				l.newPage()
+			}
+			l.page.rect(l.left, l.y, l.width, lh, pdfCodeBg)
+			l.drawLine(wrapped, l.left+pad, l.y, lh)
+			l.y += lh
+		}
+	}
+	if l.y+pad > l.bottom() {
+		l.newPage()
+	}
+	l.page.rect(l.left, l.y, l.width, pad, pdfCodeBg)
+	l.y += pad + pdfBodySize*0.6
+}
+
+type pdfCell struct {
+	atoms []pdfAtom
+	align extast.Alignment
+}
+
+func (l *pdfLayout) table(t *extast.Table) {
+	var rows [][]pdfCell
+	cols := 0
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		_, header := row.(*extast.TableHeader)
+		style := l.baseStyle()
+		style.bold = header
+		var cells []pdfCell
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			c := pdfCell{atoms: atoms(l.inlines(cell, style, nil))}
+			if tc, ok := cell.(*extast.TableCell); ok {
+				c.align = tc.Alignment
+			}
+			cells = append(cells, c)
+		}
+		rows = append(rows, cells)
+		cols = int(math.Max(float64(cols), float64(len(cells))))
+	}
+	if cols == 0 {
+		return
+	}
+
+	// 列宽按内容的自然宽度分配，总宽度超出时按比例缩小
+	widths := make([]float64, cols)
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = math.Max(widths[i], lineWidth(cell.atoms)+2*pdfCellPadding)
+		}
+	}
+	total := 0.0
+	for i := range widths {
+		widths[i] = math.Max(widths[i], 24)
+		total += widths[i]
+	}
+	if total > l.width {
+		for i := range widths {
+			widths[i] = widths[i] * l.width / total
+		}
+	}
+
+	drawRow := func(row []pdfCell, header bool) {
+		lines := make([][][]pdfAtom, cols)
+		height := 0.0
+		for i := 0; i < cols; i++ {
+			var cellAtoms []pdfAtom
+			if i < len(row) {
+				cellAtoms = row[i].atoms
+			}
+			lines[i] = wrap(cellAtoms, widths[i]-2*pdfCellPadding)
+			h := 0.0
+			for _, line := range lines[i] {
+				h += lineHeight(line, pdfBodySize)
+			}
+			height = math.Max(height, h)
+		}
+		height += 2 * pdfCellPadding
+
+		x := l.left
+		for i := 0; i < cols; i++ {
+			if header {
+				l.page.rect(x, l.y, widths[i], height, pdfCodeBg)
+			}
+			y := l.y + pdfCellPadding
+			for _, line := range lines[i] {
+				lh := lineHeight(line, pdfBodySize)
+				offset := 0.0
+				if i < len(row) {
+					switch row[i].align {
+					case extast.AlignRight:
+						offset = widths[i] - 2*pdfCellPadding - lineWidth(line)
+					case extast.AlignCenter:
+						offset = (widths[i] - 2*pdfCellPadding - lineWidth(line)) / 2
+					}
+				}
+				l.drawLine(line, x+pdfCellPadding+offset, y, lh)
+				y += lh
+			}
+			l.page.strokeRect(x, l.y, widths[i], height, 0.5, pdfLightGray)
+			x += widths[i]
+		}
+		l.y += height
+	}
+
+	rowHeight := func(row []pdfCell) float64 {
+		h := 0.0
+		for i, cell := range row {
+			lines := wrap(cell.atoms, widths[i]-2*pdfCellPadding)
+			cellHeight := 0.0
+			for _, line := range lines {
+				cellHeight += lineHeight(line, pdfBodySize)
+			}
+			h = math.Max(h, cellHeight)
+		}
+		return h + 2*pdfCellPadding
+	}
+
+	_, hasHeader := t.FirstChild().(*extast.TableHeader)
+	for i, row := range rows {
+		h := rowHeight(row)
+		if i == 0 && hasHeader && len(rows) > 1 {
+			// 表头和第一行放在同一页
+			h += rowHeight(rows[1])
+		}
+		if l.y+h > l.bottom() && !l.atTop() {
+			l.newPage()
+			if i > 0 && hasHeader {
+				// 换页后重复表头
+				drawRow(rows[0], true)
+			}
+		}
+		drawRow(row, i == 0 && hasHeader)
+	}
+	l.y += pdfBodySize * 0.6
+}
+
+// image 排版一张图片，缩放到不超过版心；替代文本以 "|宽度" 结尾时按该宽度显示
+func (l *pdfLayout) image(ref pdfImageRef) {
+	path := ""
+	if ref.wiki {
+		path = l.m.findAttachment(l.notePath, ref.dest)
+	} else if !isExternalLink(ref.dest) {
+		dest := ref.dest
+		if unescaped, err := url.PathUnescape(dest); err == nil {
+			dest = unescaped
+		}
+		path = resolveRelative(l.notePath, dest)
+	}
+
+	var id int
+	var img *pdfImage
+	err := errMissingImage
+	if path != "" {
+		id, img, err = l.doc.addImage(path)
+	}
+	if err != nil {
+		style := l.baseStyle()
+		style.italic, style.color = true, pdfGray
+		if isExternalLink(ref.dest) {
+			style.color, style.link = pdfLinkColor, ref.dest
+		}
+		l.flow([]pdfRun{{"[图片: " + ref.dest + "]", style}})
+		return
+	}
+
+	// 按 96 DPI 换算像素和点
+	w, h := float64(img.width)*0.75, float64(img.height)*0.75
+	alt := ref.alt
+	if ref.wiki {
+		alt = "|" + alt
+	}
+	if i := strings.LastIndex(alt, "|"); i >= 0 {
+		if hint, err := strconv.Atoi(strings.TrimSpace(alt[i+1:])); err == nil && hint > 0 {
+			w, h = float64(hint)*0.75, h*float64(hint)*0.75/w
+		}
+	}
+	if w > l.width {
+		w, h = l.width, h*l.width/w
+	}
+	if maxHeight := (l.bottom() - l.top()) * 0.9; h > maxHeight {
+		w, h = w*maxHeight/h, maxHeight
+	}
+
+	l.ensure(h)
+	l.page.image(id, l.left, l.y, w, h)
+	l.y += h + pdfBodySize*0.6
+}
+
+// decorate 为每一页加上页眉和页码
+func (d *pdfDoc) decorate() {
+	total := len(d.pages)
+	for i, p := range d.pages {
+		if p.header != "" {
+			header := truncateText(p.header, fontRegular, 8, pdfPageWidth-2*pdfMarginX)
+			p.text(pdfMarginX, 38, header, fontRegular, 8, pdfGray)
+			p.line(pdfMarginX, 44, pdfPageWidth-pdfMarginX, 44, 0.5, pdfLightGray)
+		}
+		number := fmt.Sprintf("%d / %d", i+1, total)
+		p.text((pdfPageWidth-textWidth(number, fontRegular, 8))/2, pdfPageHeight-30, number, fontRegular, 8, pdfGray)
+	}
+}
+
+// truncateText 截断文字使其不超过 width，截断时以省略号结尾
+func truncateText(s string, font pdfFont, size, width float64) string {
+	if textWidth(s, font, size) <= width {
+		return s
+	}
+	limit := width - runeWidth('…', font, size)
+	w := 0.0
+	for i, r := range s {
+		w += runeWidth(r, font, size)
+		if w > limit {
+			return s[:i] + "…"
+		}
+	}
+	return s
+}
+
+// exportPDF 把笔记排版为一个 PDF 文件；toc 为 true 时在开头生成目录，每篇笔记都是一个书签。
+// title 为空时使用第一篇笔记的标题
+func (m *MarkdownEditor) exportPDF(title string, paths []string, toc bool) ([]byte, error) {
+	doc := newPDFDoc(title)
+	l := &pdfLayout{m: m, doc: doc}
+
+	// 目录的页数只取决于笔记数量，先留出目录页，正文的页码就不需要再调整
+	var tocPages []*pdfPage
+	contentHeight := pdfPageHeight - pdfMarginTop - pdfMarginBottom
+	titleHeight := pdfHeadingSizes[0] * 3
+	perPage := int(math.Floor(contentHeight / pdfTOCLine))
+	if toc {
+		first := int(math.Floor((contentHeight - titleHeight) / pdfTOCLine))
+		pages := 1
+		if len(paths) > first {
+			pages += (len(paths) - first + perPage - 1) / perPage
+		}
+		for i := 0; i < pages; i++ {
+			tocPages = append(tocPages, doc.newPage(title))
+		}
+	}
+
+	type tocEntry struct {
+		title string
+		page  int
+	}
+	var entries []tocEntry
+	for _, path := range paths {
+		content, err := m.noteContent(path)
+		if errors.Is(err, errEncryptedNote) && len(paths) > 1 {
+			continue // 导出目录时跳过加密笔记
+		}
+		if err != nil {
+			return nil, err
+		}
+		if toc {
+			outline := &pdfOutline{page: len(doc.pages), y: pdfMarginTop}
+			doc.outlines = append(doc.outlines, outline)
+			l.outlines = &outline.children
+			outline.title, _ = l.renderNote(path, content)
+			entries = append(entries, tocEntry{outline.title, outline.page})
+		} else {
+			l.outlines = &doc.outlines
+			if noteTitle, _ := l.renderNote(path, content); doc.title == "" {
+				doc.title = noteTitle
+			}
+		}
+	}
+
+	if toc {
+		page, y := 0, pdfMarginTop
+		tocPages[0].text(pdfMarginX, y+pdfHeadingSizes[0], title, fontBold, pdfHeadingSizes[0], pdfBlack)
+		y += titleHeight
+		width := pdfPageWidth - 2*pdfMarginX
+		for _, entry := range entries {
+			if y+pdfTOCLine > pdfPageHeight-pdfMarginBottom {
+				page, y = page+1, pdfMarginTop
+			}
+			p := tocPages[page]
+			number := strconv.Itoa(entry.page + 1)
+			numberWidth := textWidth(number, fontRegular, pdfBodySize)
+			name := truncateText(entry.title, fontRegular, pdfBodySize, width-numberWidth-40)
+			baseline := y + pdfTOCLine/2 + pdfBodySize*0.35
+			nameWidth := p.text(pdfMarginX, baseline, name, fontRegular, pdfBodySize, pdfBlack)
+
+			// 标题和页码之间用点连接
+			dotWidth := runeWidth('.', fontRegular, pdfBodySize)
+			dots := int((width - nameWidth - numberWidth - 12) / dotWidth)
+			if dots > 0 {
+				p.text(pdfMarginX+nameWidth+6, baseline, strings.Repeat(".", dots), fontRegular, pdfBodySize, pdfLightGray)
+			}
+			p.text(pdfPageWidth-pdfMarginX-numberWidth, baseline, number, fontRegular, pdfBodySize, pdfBlack)
+			p.link(pdfLink{x: pdfMarginX, y: y, w: width, h: pdfTOCLine, page: entry.page, destY: pdfMarginTop})
+			y += pdfTOCLine
+		}
+	}
+
+	doc.decorate()
+	return doc.bytes(), nil
+}
+
+// folderNotes 按目录树中的顺序返回 folder 下的所有笔记
+func (m *MarkdownEditor) folderNotes(folder string) []string {
+	var notes []string
+	var walk func(uid widget.TreeNodeID)
+	walk = func(uid widget.TreeNodeID) {
+		for _, child := range m.childUIDs(uid) {
+			if m.isBranch(child) {
+				walk(child)
+			} else if isNote(child) {
+				notes = append(notes, m.uidToPath(child))
+			}
+		}
+	}
+	walk(m.pathToUID(folder))
+	return notes
+}
+
+// showExportPDF 导出当前笔记或一个目录为 PDF
+func (m *MarkdownEditor) showExportPDF() {
+	idx, err := m.buildIndex()
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+
+	const currentNote = "Current note"
+	notePath, _ := m.currentFile()
+	var options []string
+	if notePath != "" {
+		options = append(options, currentNote)
+	}
+	options = append(options, idx.folders()...)
+	source := widget.NewSelect(options, nil)
+	source.SetSelectedIndex(0)
+
+	m.showCustomFormDialog("Export as PDF", "Export", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Export", source),
+	}, func(ok bool) {
+		if !ok || source.Selected == "" {
+			return
+		}
+
+		if source.Selected == currentNote {
+			data, err := m.exportPDF("", []string{notePath}, false)
+			if err != nil {
+				dialog.ShowError(err, m.window)
+				return
+			}
+			m.saveExport(notePath, ".pdf", data)
+			return
+		}
+
+		folder := filepath.Join(m.rootPath, filepath.FromSlash(source.Selected))
+		notes := m.folderNotes(folder)
+		if len(notes) == 0 {
+			dialog.ShowInformation("Export as PDF", "目录中没有笔记", m.window)
+			return
+		}
+		data, err := m.exportPDF(filepath.Base(mustAbs(folder)), notes, true)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		m.saveExport(folder, ".pdf", data)
+	}, m.window)
+}
@@ -0,0 +1,456 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"image/color"
+	"strings"
+
+	"github.com/yuin/goldmark/text"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 演示模式把笔记按单独一行的 --- 分成幻灯片（front matter 和代码块中的除外）。
+// 幻灯片中以 Note: 开头的一行及其后的内容是演讲者备注，只在备注面板和演讲者视图中显示。
+//
+// 快捷键：→ ↓ 空格 PageDown 下一张，← ↑ PageUp Backspace 上一张，Home/End 第一张和最后一张，
+// O 或 Tab 显示全部幻灯片，N 显示备注，F 切换全屏，Esc 退出
+
+// slideTextScale 是幻灯片文字相对于普通文字的倍数
+const slideTextScale = 2
+
+// maxExcerpt 是概览中每张幻灯片最多显示的字数
+const maxExcerpt = 120
+
+// slide 是演示中的一张幻灯片
+type slide struct {
+	Content string
+	Notes   string
+}
+
+// title 返回幻灯片的标题：第一个标题，没有标题时使用第一行文字
+func (s slide) title() string {
+	first := ""
+	for _, line := range strings.Split(s.Content, "\n") {
+		if _, heading, ok := parseHeading(line); ok {
+			return heading
+		}
+		if line = strings.TrimSpace(line); first == "" && line != "" {
+			first = line
+		}
+	}
+	return first
+}
+
+// excerpt 返回概览中显示的幻灯片正文开头，不含作为标题的一行
+func (s slide) excerpt() string {
+	title := s.title()
+	var parts []string
+	for _, line := range strings.Split(s.Content, "\n") {
+		if _, heading, ok := parseHeading(line); ok && heading == title {
+			title = ""
+			continue
+		}
+		if line = strings.TrimSpace(line); line == title {
+			title = ""
+		} else if line != "" {
+			parts = append(parts, line)
+		}
+	}
+	excerpt := []rune(strings.Join(parts, " "))
+	if len(excerpt) > maxExcerpt {
+		return string(excerpt[:maxExcerpt]) + "…"
+	}
+	return string(excerpt)
+}
+
+// splitSlides 把笔记分成幻灯片，没有内容的幻灯片被忽略
+func splitSlides(content string) []slide {
+	lines := strings.Split(content, "\n")
+	var separators []int
+	forEachTextLine(content, func(i int, line string) {
+		if strings.TrimSpace(line) == "---" {
+			separators = append(separators, i)
+		}
+	})
+	separators = append(separators, len(lines))
+
+	var slides []slide
+	start := frontMatterEnd(lines)
+	for _, end := range separators {
+		s := slideFrom(lines[start:end])
+		if strings.TrimSpace(s.Content) != "" || strings.TrimSpace(s.Notes) != "" {
+			slides = append(slides, s)
+		}
+		start = end + 1
+	}
+	return slides
+}
+
+// slideFrom 把幻灯片的各行分为正文和备注，备注从 Note: 开头的一行开始，代码块中的除外
+func slideFrom(lines []string) slide {
+	body := strings.Join(lines, "\n")
+	notes := -1
+	forEachTextLine(body, func(i int, line string) {
+		if notes < 0 && len(line) >= 5 && strings.EqualFold(line[:5], "note:") {
+			notes = i
+		}
+	})
+	if notes < 0 {
+		return slide{Content: strings.TrimSpace(body)}
+	}
+	return slide{
+		Content: strings.TrimSpace(strings.Join(lines[:notes], "\n")),
+		Notes:   strings.TrimSpace(lines[notes][5:] + "\n" + strings.Join(lines[notes+1:], "\n")),
+	}
+}
+
+// slideTheme 放大幻灯片中的文字
+type slideTheme struct {
+	scale float32
+}
+
+func (t slideTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	return theme.Current().Color(name, variant)
+}
+
+func (t slideTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return theme.Current().Font(style)
+}
+
+func (t slideTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return theme.Current().Icon(name)
+}
+
+func (t slideTheme) Size(name fyne.ThemeSizeName) float32 {
+	switch name {
+	case theme.SizeNameText, theme.SizeNameHeadingText, theme.SizeNameSubHeadingText,
+		theme.SizeNameCaptionText, theme.SizeNameInlineIcon, theme.SizeNameLineSpacing:
+		return theme.Current().Size(name) * t.scale
+	}
+	return theme.Current().Size(name)
+}
+
+// presentation 是一次演示：全屏的演示窗口和主窗口中的演讲者视图
+type presentation struct {
+	m        *MarkdownEditor
+	notePath string
+	slides   []slide
+	current  int
+
+	window   fyne.Window
+	slide    *widget.RichText
+	counter  *widget.Label
+	notes    *widget.RichText
+	notesBar fyne.CanvasObject
+	overview *fyne.Container
+	grid     *fyne.Container
+
+	// 演讲者视图
+	speakerSlide   *widget.Label
+	speakerNotes   *widget.RichText
+	speakerNext    *widget.RichText
+	speakerCounter *widget.Label
+	speakerView    fyne.CanvasObject
+}
+
+// startPresentation 以当前笔记开始演示
+func (m *MarkdownEditor) startPresentation() {
+	notePath, editor := m.currentFile()
+	if editor == nil {
+		dialog.ShowInformation("提示", "请先打开一篇笔记", m.window)
+		return
+	}
+	slides := splitSlides(editor.Text)
+	if len(slides) == 0 {
+		dialog.ShowInformation("提示", "笔记中没有可以演示的内容", m.window)
+		return
+	}
+
+	p := &presentation{m: m, notePath: notePath, slides: slides}
+	p.window = fyne.CurrentApp().NewWindow(strings.TrimSuffix(m.vaultRelPath(notePath), noteExt))
+
+	p.slide = widget.NewRichText()
+	p.slide.Wrapping = fyne.TextWrapWord
+	p.counter = widget.NewLabel("")
+	p.notes = widget.NewRichText()
+	p.notes.Wrapping = fyne.TextWrapWord
+	p.notesBar = container.NewVBox(widget.NewSeparator(), p.notes)
+	p.notesBar.Hide()
+	view := container.NewBorder(nil, container.NewVBox(p.notesBar, container.NewHBox(layout.NewSpacer(), p.counter)), nil, nil,
+		container.NewThemeOverride(container.NewVScroll(container.NewPadded(p.slide)), slideTheme{scale: slideTextScale}))
+
+	p.grid = container.NewGridWrap(fyne.NewSize(260, 180))
+	p.overview = container.NewBorder(widget.NewLabel("All slides — press Esc to return"), nil, nil, nil, container.NewVScroll(p.grid))
+	p.overview.Hide()
+
+	p.window.SetContent(container.NewStack(view, p.overview))
+	p.window.Canvas().SetOnTypedKey(p.typedKey)
+	p.window.SetOnClosed(p.closeSpeakerView)
+	p.window.Resize(m.window.Canvas().Size())
+	p.window.SetFullScreen(true)
+
+	p.showSpeakerView()
+	p.show(0)
+	p.window.Show()
+}
+
+// typedKey 处理演示窗口中的按键
+func (p *presentation) typedKey(ev *fyne.KeyEvent) {
+	switch ev.Name {
+	case fyne.KeyRight, fyne.KeyDown, fyne.KeySpace, fyne.KeyPageDown, fyne.KeyReturn, fyne.KeyEnter:
+		if p.overview.Visible() {
+			p.toggleOverview()
+			return
+		}
+		p.show(p.current + 1)
+	case fyne.KeyLeft, fyne.KeyUp, fyne.KeyPageUp, fyne.KeyBackspace:
+		p.show(p.current - 1)
+	case fyne.KeyHome:
+		p.show(0)
+	case fyne.KeyEnd:
+		p.show(len(p.slides) - 1)
+	case fyne.KeyO, fyne.KeyTab:
+		p.toggleOverview()
+	case fyne.KeyN:
+		if p.notesBar.Visible() {
+			p.notesBar.Hide()
+		} else {
+			p.notesBar.Show()
+		}
+	case fyne.KeyF:
+		p.window.SetFullScreen(!p.window.FullScreen())
+	case fyne.KeyEscape:
+		if p.overview.Visible() {
+			p.toggleOverview()
+			return
+		}
+		p.window.Close()
+	}
+}
+
+// show 显示第 i 张幻灯片，超出范围时停在第一张或最后一张
+func (p *presentation) show(i int) {
+	p.current = max(0, min(i, len(p.slides)-1))
+	s := p.slides[p.current]
+	p.slide.Segments = p.render(s.Content)
+	p.slide.Refresh()
+	p.notes.Segments = p.render(s.Notes)
+	p.notes.Refresh()
+	p.counter.SetText(fmt.Sprintf("%d / %d", p.current+1, len(p.slides)))
+	p.updateSpeakerView()
+}
+
+func (p *presentation) render(content string) []widget.RichTextSegment {
+	r := newPreviewRenderer(content, p.notePath)
+	r.embeds = &transcluder{m: p.m, stack: []string{p.notePath + "#"}}
+	r.queries = func(query string) []widget.RichTextSegment { return p.m.renderQuery(p.notePath, query) }
+	return r.render()
+}
+
+// toggleOverview 显示或隐藏全部幻灯片的概览，点击缩略图跳到该幻灯片
+func (p *presentation) toggleOverview() {
+	if p.overview.Visible() {
+		p.overview.Hide()
+		return
+	}
+	p.grid.RemoveAll()
+	for i, s := range p.slides {
+		i := i
+		button := widget.NewButton("", func() {
+			p.overview.Hide()
+			p.show(i)
+		})
+		if i == p.current {
+			button.Importance = widget.HighImportance
+		}
+		title := widget.NewLabel(fmt.Sprintf("%d. %s", i+1, s.title()))
+		title.TextStyle = fyne.TextStyle{Bold: true}
+		title.Truncation = fyne.TextTruncateEllipsis
+		excerpt := widget.NewLabel(s.excerpt())
+		excerpt.Wrapping = fyne.TextWrapWord
+		p.grid.Add(container.NewStack(button, container.NewBorder(title, nil, nil, nil, excerpt)))
+	}
+	p.overview.Show()
+}
+
+// showSpeakerView 在主窗口的标签页中显示演讲者视图：当前幻灯片、备注和下一张幻灯片
+func (p *presentation) showSpeakerView() {
+	p.speakerSlide = widget.NewLabel("")
+	p.speakerSlide.TextStyle = fyne.TextStyle{Bold: true}
+	p.speakerCounter = widget.NewLabel("")
+	p.speakerNotes = widget.NewRichText()
+	p.speakerNotes.Wrapping = fyne.TextWrapWord
+	p.speakerNext = widget.NewRichText()
+	p.speakerNext.Wrapping = fyne.TextWrapWord
+
+	nextLabel := widget.NewLabel("Next")
+	nextLabel.TextStyle = fyne.TextStyle{Bold: true}
+	toolbar := container.NewHBox(
+		widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { p.show(p.current - 1) }),
+		widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() { p.show(p.current + 1) }),
+		p.speakerCounter,
+		p.speakerSlide,
+		layout.NewSpacer(),
+		widget.NewButton("End Presentation", p.window.Close),
+	)
+	split := container.NewVSplit(
+		container.NewVScroll(p.speakerNotes),
+		container.NewBorder(nextLabel, nil, nil, nil, container.NewVScroll(p.speakerNext)),
+	)
+	split.Offset = 0.6
+	p.speakerView = container.NewBorder(toolbar, nil, nil, nil, split)
+	p.m.showViewTab("Speaker View", p.speakerView)
+}
+
+// closeSpeakerView 在演示结束后关闭演讲者视图，已经被新的演示替换时不关闭
+func (p *presentation) closeSpeakerView() {
+	for _, tab := range p.m.tabs.Items {
+		if tab.Content == p.speakerView {
+			p.m.tabs.Remove(tab)
+			return
+		}
+	}
+}
+
+func (p *presentation) updateSpeakerView() {
+	s := p.slides[p.current]
+	p.speakerCounter.SetText(fmt.Sprintf("%d / %d", p.current+1, len(p.slides)))
+	p.speakerSlide.SetText(s.title())
+	if strings.TrimSpace(s.Notes) == "" {
+		p.speakerNotes.Segments = []widget.RichTextSegment{&widget.TextSegment{Text: "没有备注", Style: widget.RichTextStyleEmphasis}}
+	} else {
+		p.speakerNotes.Segments = p.render(s.Notes)
+	}
+	p.speakerNotes.Refresh()
+	if p.current+1 < len(p.slides) {
+		p.speakerNext.Segments = p.render(p.slides[p.current+1].Content)
+	} else {
+		p.speakerNext.Segments = []widget.RichTextSegment{&widget.TextSegment{Text: "最后一张幻灯片", Style: widget.RichTextStyleEmphasis}}
+	}
+	p.speakerNext.Refresh()
+}
+
+// slideStyle 是导出的幻灯片的样式表，附加在 exportStyle 之后
+const slideStyle = `
+html, body { height: 100%; margin: 0; max-width: none; padding: 0; overflow: hidden; }
+.slide { display: none; box-sizing: border-box; height: 100vh; padding: 6vh 8vw; overflow: auto; font-size: 2.2vw; }
+.slide.current { display: block; }
+.slide h1 { font-size: 2.4em; border: 0; }
+.slide h2 { font-size: 1.8em; border: 0; }
+.slide img { max-height: 70vh; }
+.notes { display: none; }
+body.show-notes .slide.current .notes { display: block; position: fixed; left: 0; right: 0; bottom: 0; max-height: 30vh; overflow: auto; padding: .5em 2em; font-size: 16px; background: #f6f8fa; border-top: 1px solid #d0d7de; }
+#counter { position: fixed; right: 1.5em; bottom: 1em; color: #8b949e; font-size: 14px; }
+body.overview { overflow: auto; }
+body.overview .slides { display: grid; grid-template-columns: repeat(auto-fill, minmax(280px, 1fr)); gap: 1em; padding: 1em; }
+body.overview .slide { display: block; height: 200px; font-size: 8px; padding: 1em; border: 1px solid #d0d7de; border-radius: 6px; cursor: pointer; overflow: hidden; }
+body.overview .slide.current { border-color: #0969da; }
+body.overview .notes, body.overview #counter { display: none !important; }
+@media (prefers-color-scheme: dark) {
+  body.show-notes .slide.current .notes { background: #161b22; border-color: #30363d; }
+  body.overview .slide { border-color: #30363d; }
+}
+`
+
+// slideScript 实现导出的幻灯片的键盘导航，快捷键与演示模式相同
+const slideScript = `
+(function () {
+  var slides = document.querySelectorAll('.slide');
+  var counter = document.getElementById('counter');
+  var current = 0;
+  function show(i) {
+    current = Math.max(0, Math.min(i, slides.length - 1));
+    slides.forEach(function (s, j) { s.classList.toggle('current', j === current); });
+    counter.textContent = (current + 1) + ' / ' + slides.length;
+    history.replaceState(null, '', '#' + (current + 1));
+  }
+  function overview(on) { document.body.classList.toggle('overview', on); }
+  slides.forEach(function (s, j) {
+    s.addEventListener('click', function () {
+      if (document.body.classList.contains('overview')) { overview(false); show(j); }
+    });
+  });
+  document.addEventListener('keydown', function (e) {
+    if (e.ctrlKey || e.metaKey || e.altKey) return;
+    var inOverview = document.body.classList.contains('overview');
+    switch (e.key) {
+      case 'ArrowRight': case 'ArrowDown': case ' ': case 'PageDown': case 'Enter':
+        if (inOverview) overview(false); else show(current + 1); break;
+      case 'ArrowLeft': case 'ArrowUp': case 'PageUp': case 'Backspace': show(current - 1); break;
+      case 'Home': show(0); break;
+      case 'End': show(slides.length - 1); break;
+      case 'o': case 'O': case 'Tab': overview(!inOverview); break;
+      case 'n': case 'N': document.body.classList.toggle('show-notes'); break;
+      case 'f': case 'F':
+        if (document.fullscreenElement) document.exitFullscreen(); else document.documentElement.requestFullscreen();
+        break;
+      case 'Escape': overview(false); break;
+      default: return;
+    }
+    e.preventDefault();
+  });
+  show((parseInt(location.hash.slice(1), 10) || 1) - 1);
+})();
+`
+
+// exportSlides 把笔记导出为可以在浏览器中演示的单个 HTML 文件，本地图片以 data URI 内联
+func (m *MarkdownEditor) exportSlides(notePath, content string) ([]byte, error) {
+	slides := splitSlides(content)
+	if len(slides) == 0 {
+		return nil, errors.New("笔记中没有可以演示的内容")
+	}
+	source := []byte(slides[0].Content)
+	title := noteTitle(notePath, content, source, markdownParser.Parser().Parse(text.NewReader(source)))
+
+	x := &htmlExporter{m: m}
+	var body strings.Builder
+	body.WriteString("<div class=\"slides\">\n")
+	for _, s := range slides {
+		slideHTML, _, err := x.render(notePath, s.Content)
+		if err != nil {
+			return nil, err
+		}
+		body.WriteString("<section class=\"slide\">\n" + slideHTML)
+		if strings.TrimSpace(s.Notes) != "" {
+			notesHTML, _, err := x.render(notePath, s.Notes)
+			if err != nil {
+				return nil, err
+			}
+			body.WriteString("<aside class=\"notes\">\n" + notesHTML + "</aside>\n")
+		}
+		body.WriteString("</section>\n")
+	}
+	body.WriteString("</div>\n")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&buf, "<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&buf, "<title>%s</title>\n<style>%s%s</style>\n</head>\n<body>\n", html.EscapeString(title), exportStyle, slideStyle)
+	buf.WriteString(body.String())
+	buf.WriteString("<div id=\"counter\"></div>\n<script>" + slideScript + "</script>\n</body>\n</html>\n")
+	return buf.Bytes(), nil
+}
+
+// showExportSlides 将当前笔记导出为 HTML 幻灯片
+func (m *MarkdownEditor) showExportSlides() {
+	notePath, editor := m.currentFile()
+	if editor == nil {
+		dialog.ShowInformation("提示", "请先打开一篇笔记", m.window)
+		return
+	}
+	data, err := m.exportSlides(notePath, editor.Text)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	m.saveExport(notePath, ".slides.html", data)
+}
@@ -0,0 +1,72 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSlides(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []slide
+	}{
+		{"empty", "", nil},
+		{"single slide", "# One\ntext", []slide{{Content: "# One\ntext"}}},
+		{
+			name:    "separators",
+			content: "# One\n\n---\n\n# Two\n  ---  \n# Three",
+			want:    []slide{{Content: "# One"}, {Content: "# Two"}, {Content: "# Three"}},
+		},
+		{
+			name:    "empty slides dropped",
+			content: "---\n---\n# Only\n---\n",
+			want:    []slide{{Content: "# Only"}},
+		},
+		{
+			name:    "front matter is not a separator",
+			content: "---\ntheme: dark\n---\n# Title\n---\nNext",
+			want:    []slide{{Content: "# Title"}, {Content: "Next"}},
+		},
+		{
+			name:    "separator inside code",
+			content: "```yaml\n---\nkey: v\n```\n---\nB",
+			want:    []slide{{Content: "```yaml\n---\nkey: v\n```"}, {Content: "B"}},
+		},
+		{
+			name:    "speaker notes",
+			content: "# A\npoint\nNote: say hello\nand wave\n---\nnote:only notes",
+			want:    []slide{{Content: "# A\npoint", Notes: "say hello\nand wave"}, {Notes: "only notes"}},
+		},
+		{
+			name:    "note inside code",
+			content: "```\nNote: code\n```",
+			want:    []slide{{Content: "```\nNote: code\n```"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := splitSlides(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: splitSlides() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSlideTitle(t *testing.T) {
+	tests := []struct {
+		content, title, excerpt string
+	}{
+		{"# Heading\nbody text\nmore", "Heading", "body text more"},
+		{"intro line\n\n## Later", "Later", "intro line"},
+		{"\n  first  \nsecond", "first", "second"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		s := slide{Content: tt.content}
+		if got := s.title(); got != tt.title {
+			t.Errorf("title(%q) = %q, want %q", tt.content, got, tt.title)
+		}
+		if got := s.excerpt(); got != tt.excerpt {
+			t.Errorf("excerpt(%q) = %q, want %q", tt.content, got, tt.excerpt)
+		}
+	}
+}
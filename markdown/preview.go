@@ -0,0 +1,443 @@
+package markdown
+
+import (
+	"image"
+	_ "image/gif"  // 注册 GIF 解码器，用于获取图片尺寸
+	_ "image/jpeg" // 注册 JPEG 解码器
+	_ "image/png"  // 注册 PNG 解码器
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 预览中图片的最大宽度
+const maxPreviewImageWidth = 600
+
+var markdownParser = goldmark.New(goldmark.WithExtensions(extension.GFM, wikiLinks{}))
+
+// previewRenderer 把 Markdown 转换为 RichText 片段。
+// 相比 RichText.ParseMarkdown，它会按笔记所在目录解析本地图片，并支持 GFM 扩展语法。
+type previewRenderer struct {
+	source     []byte
+	notePath   string
+	lineStarts []int
+
+	// onToggleTask 在预览中勾选任务时调用，参数为任务所在的源码行
+	onToggleTask func(line int)
+	// embeds 展开 ![[笔记]] 嵌入，为 nil 时嵌入按纯文本显示
+	embeds *transcluder
+	// queries 执行 query 代码块，为 nil 时按代码块显示
+	queries func(query string) []widget.RichTextSegment
+}
+
+// renderEditorPreview 渲染编辑器中的笔记，预览中的任务复选框会直接修改编辑器中对应的源码行
+func (m *MarkdownEditor) renderEditorPreview(editor *noteEntry, notePath string) []widget.RichTextSegment {
+	r := newPreviewRenderer(editor.Text, notePath)
+	r.onToggleTask = func(line int) {
+		if err := editNoteLine(editor, line, toggleTaskLine); err != nil {
+			fyne.LogError("Failed to toggle task", err)
+		}
+	}
+	r.embeds = &transcluder{m: m, stack: []string{notePath + "#"}}
+	r.queries = func(query string) []widget.RichTextSegment { return m.renderQuery(notePath, query) }
+	return r.render()
+}
+
+func newPreviewRenderer(content, notePath string) *previewRenderer {
+	r := &previewRenderer{source: []byte(stripFrontMatter(content)), notePath: notePath}
+	r.lineStarts = append(r.lineStarts, 0)
+	for i, c := range r.source {
+		if c == '\n' {
+			r.lineStarts = append(r.lineStarts, i+1)
+		}
+	}
+	return r
+}
+
+func (r *previewRenderer) render() []widget.RichTextSegment {
+	doc := markdownParser.Parser().Parse(text.NewReader(r.source))
+	return r.renderNode(doc, false)
+}
+
+// lineOf 返回源码偏移量所在的行号
+func (r *previewRenderer) lineOf(offset int) int {
+	return sort.Search(len(r.lineStarts), func(i int) bool { return r.lineStarts[i] > offset }) - 1
+}
+
+// blockLine 返回节点所属块的第一行行号，找不到时返回 -1
+func (r *previewRenderer) blockLine(n ast.Node) int {
+	for ; n != nil; n = n.Parent() {
+		if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+			return r.lineOf(n.Lines().At(0).Start)
+		}
+	}
+	return -1
+}
+
+// stripFrontMatter 将 front matter 替换为空行，保持正文的行号不变
+func stripFrontMatter(content string) string {
+	lines := strings.Split(content, "\n")
+	end := frontMatterEnd(lines)
+	if end == 0 {
+		return content
+	}
+	return strings.Repeat("\n", end) + strings.Join(lines[end:], "\n")
+}
+
+func (r *previewRenderer) renderNode(n ast.Node, blockquote bool) []widget.RichTextSegment {
+	switch t := n.(type) {
+	case *ast.Document:
+		return r.renderChildren(n, blockquote)
+	case *ast.Paragraph:
+		children := r.renderChildren(n, blockquote)
+		if !blockquote {
+			children = append(children, &widget.TextSegment{Style: widget.RichTextStyleParagraph})
+		}
+		return children
+	case *ast.List:
+		items := r.renderChildren(n, blockquote)
+		return []widget.RichTextSegment{&widget.ListSegment{Items: items, Ordered: t.IsOrdered()}}
+	case *ast.ListItem:
+		return []widget.RichTextSegment{&widget.ParagraphSegment{Texts: r.renderChildren(n, blockquote)}}
+	case *ast.TextBlock:
+		return r.renderChildren(n, blockquote)
+	case *ast.Heading:
+		text := headingText(r.source, n)
+		switch t.Level {
+		case 1:
+			return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleHeading, Text: text}}
+		case 2:
+			return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleSubHeading, Text: text}}
+		default:
+			seg := &widget.TextSegment{Style: widget.RichTextStyleParagraph, Text: text}
+			seg.Style.TextStyle.Bold = true
+			return []widget.RichTextSegment{seg}
+		}
+	case *ast.ThematicBreak:
+		return []widget.RichTextSegment{&widget.SeparatorSegment{}}
+	case *ast.Link:
+		link, _ := url.Parse(string(t.Destination))
+		return []widget.RichTextSegment{&widget.HyperlinkSegment{Alignment: fyne.TextAlignLeading, Text: plainText(r.source, n), URL: link}}
+	case *ast.AutoLink:
+		label := string(t.Label(r.source))
+		link, _ := url.Parse(string(t.URL(r.source)))
+		return []widget.RichTextSegment{&widget.HyperlinkSegment{Alignment: fyne.TextAlignLeading, Text: label, URL: link}}
+	case *ast.CodeSpan:
+		return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleCodeInline, Text: plainText(r.source, n)}}
+	case *ast.CodeBlock, *ast.FencedCodeBlock:
+		if fenced, ok := n.(*ast.FencedCodeBlock); ok && r.queries != nil && string(fenced.Language(r.source)) == "query" {
+			return r.queries(blockText(r.source, n))
+		}
+		data := strings.TrimSuffix(blockText(r.source, n), "\n")
+		if data == "" {
+			return nil
+		}
+		return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleCodeBlock, Text: data}}
+	case *ast.Emphasis:
+		text := plainText(r.source, n)
+		if t.Level == 2 {
+			return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleStrong, Text: text}}
+		}
+		return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleEmphasis, Text: text}}
+	case *extast.Strikethrough:
+		return r.renderChildren(n, blockquote)
+	case *extast.TaskCheckBox:
+		line := r.blockLine(n)
+		if r.onToggleTask == nil || line < 0 {
+			mark := "[ ] "
+			if t.IsChecked {
+				mark = "[x] "
+			}
+			return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleInline, Text: mark}}
+		}
+		toggle := r.onToggleTask
+		return []widget.RichTextSegment{&checkboxSegment{checked: t.IsChecked, onToggle: func() { toggle(line) }}}
+	case *extast.Table:
+		return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleCodeBlock, Text: tableText(r.source, t)}}
+	case *ast.Text:
+		text := string(t.Text(r.source))
+		if text == "" {
+			// goldmark 中空文本表示非文本元素之后的单个换行
+			return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleInline, Text: " "}}
+		}
+		// 相邻文本节点原样相连，只有换行处补一个空格
+		if t.SoftLineBreak() || t.HardLineBreak() {
+			text += " "
+		}
+		if blockquote {
+			return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleBlockquote, Text: text}}
+		}
+		return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleInline, Text: text}}
+	case *ast.Blockquote:
+		return r.renderChildren(n, true)
+	case *ast.Image:
+		return []widget.RichTextSegment{r.image(string(t.Destination), string(t.Title), plainText(r.source, n))}
+	case *wikiLinkNode:
+		if t.Embed && r.embeds != nil {
+			return r.embeds.renderEmbed(r.notePath, t)
+		}
+		return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleInline, Text: t.Label}}
+	}
+	return nil
+}
+
+func (r *previewRenderer) renderChildren(n ast.Node, blockquote bool) []widget.RichTextSegment {
+	children := make([]widget.RichTextSegment, 0, n.ChildCount())
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		children = append(children, r.renderNode(child, blockquote)...)
+	}
+	return children
+}
+
+// image 创建图片片段。替代文本以 "|宽度" 结尾时（如 ![截图|300](a.png)）按该宽度显示
+func (r *previewRenderer) image(dest, title, alt string) widget.RichTextSegment {
+	width := float32(0)
+	if i := strings.LastIndex(alt, "|"); i >= 0 {
+		if w, err := strconv.Atoi(strings.TrimSpace(alt[i+1:])); err == nil && w > 0 {
+			width = float32(w)
+		}
+	}
+
+	if isExternalLink(dest) {
+		u, err := storage.ParseURI(dest)
+		if err == nil {
+			return &widget.ImageSegment{Source: u, Title: title, Alignment: fyne.TextAlignCenter}
+		}
+	}
+
+	if unescaped, err := url.PathUnescape(dest); err == nil {
+		dest = unescaped
+	}
+	path := resolveRelative(r.notePath, dest)
+	if _, err := os.Stat(path); err != nil {
+		missing := &widget.TextSegment{Style: widget.RichTextStyleParagraph, Text: "[图片不存在: " + dest + "]"}
+		missing.Style.TextStyle.Italic = true
+		return missing
+	}
+	return &imageSegment{path: path, title: title, width: width}
+}
+
+// checkboxSegment 在预览中把任务项显示为可以点击的复选框
+type checkboxSegment struct {
+	checked  bool
+	onToggle func()
+}
+
+func (s *checkboxSegment) Inline() bool {
+	return true
+}
+
+func (s *checkboxSegment) Textual() string {
+	if s.checked {
+		return "[x] "
+	}
+	return "[ ] "
+}
+
+func (s *checkboxSegment) Visual() fyne.CanvasObject {
+	check := widget.NewCheck("", nil)
+	s.Update(check)
+	return check
+}
+
+func (s *checkboxSegment) Update(o fyne.CanvasObject) {
+	check := o.(*widget.Check)
+	check.OnChanged = nil
+	check.SetChecked(s.checked)
+	check.OnChanged = func(bool) { s.onToggle() }
+}
+
+func (s *checkboxSegment) Select(_, _ fyne.Position) {}
+
+func (s *checkboxSegment) SelectedText() string {
+	return ""
+}
+
+func (s *checkboxSegment) Unselect() {}
+
+// imageSegment 显示本地图片，并按图片比例缩放到合适的大小
+type imageSegment struct {
+	path  string
+	title string
+	width float32
+}
+
+func (s *imageSegment) Inline() bool {
+	return false
+}
+
+func (s *imageSegment) Textual() string {
+	return "Image " + s.title
+}
+
+func (s *imageSegment) Visual() fyne.CanvasObject {
+	img := canvas.NewImageFromFile(s.path)
+	img.FillMode = canvas.ImageFillContain
+	img.SetMinSize(s.size())
+	return img
+}
+
+func (s *imageSegment) Update(o fyne.CanvasObject) {
+	img := o.(*canvas.Image)
+	img.File = s.path
+	img.SetMinSize(s.size())
+	img.Refresh()
+}
+
+func (s *imageSegment) Select(_, _ fyne.Position) {}
+
+func (s *imageSegment) SelectedText() string {
+	return ""
+}
+
+func (s *imageSegment) Unselect() {}
+
+// size 根据图片原始尺寸计算显示尺寸，宽度不超过 maxPreviewImageWidth
+func (s *imageSegment) size() fyne.Size {
+	w, h := float32(300), float32(200)
+	if f, err := os.Open(s.path); err == nil {
+		if cfg, _, err := image.DecodeConfig(f); err == nil && cfg.Width > 0 {
+			w, h = float32(cfg.Width), float32(cfg.Height)
+		}
+		f.Close()
+	}
+
+	target := w
+	if s.width > 0 {
+		target = s.width
+	}
+	if target > maxPreviewImageWidth {
+		target = maxPreviewImageWidth
+	}
+	return fyne.NewSize(target, h*target/w)
+}
+
+// plainText 拼接节点下的文本，相邻文本节点原样相连，只有软换行处补一个空格
+func plainText(source []byte, n ast.Node) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := n.(*ast.Text); ok && entering {
+			b.Write(t.Text(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+func headingText(source []byte, n ast.Node) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			switch t := n.(type) {
+			case *ast.Text:
+				b.Write(t.Text(source))
+			case *ast.String:
+				b.Write(t.Value)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// blockText 返回块节点的原始文本行
+func blockText(source []byte, n ast.Node) string {
+	var b strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		b.Write(line.Value(source))
+	}
+	return b.String()
+}
+
+// tableText 将表格渲染为对齐的纯文本
+func tableText(source []byte, table *extast.Table) string {
+	var rows [][]string
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, headingText(source, cell))
+		}
+		rows = append(rows, cells)
+	}
+	return alignRows(rows)
+}
+
+// alignRows 将多行单元格排列为按列对齐的纯文本
+func alignRows(rows [][]string) string {
+	widths := map[int]int{}
+	for _, row := range rows {
+		for i, cell := range row {
+			if w := displayWidth(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	var b strings.Builder
+	for r, row := range rows {
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-displayWidth(cell)) + " │ ")
+			}
+		}
+		if r < len(rows)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// displayWidth 计算字符串在等宽字体下的显示宽度，中日韩等全角字符占两列
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if isWide(r) {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return w
+}
+
+func isWide(r rune) bool {
+	return r >= 0x1100 && (r <= 0x115f || r == 0x2329 || r == 0x232a ||
+		(r >= 0x2e80 && r <= 0xa4cf && r != 0x303f) ||
+		(r >= 0xac00 && r <= 0xd7a3) ||
+		(r >= 0xf900 && r <= 0xfaff) ||
+		(r >= 0xfe30 && r <= 0xfe6f) ||
+		(r >= 0xff00 && r <= 0xff60) ||
+		(r >= 0xffe0 && r <= 0xffe6) ||
+		(r >= 0x1f300 && r <= 0x1f64f) ||
+		(r >= 0x1f900 && r <= 0x1f9ff) ||
+		(r >= 0x20000 && r <= 0x3fffd))
+}
+
+// imageExts 是预览和附件功能识别为图片的扩展名
+var imageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".svg": true, ".webp": true,
+}
+
+func isImage(path string) bool {
+	return imageExts[strings.ToLower(filepath.Ext(path))]
+}
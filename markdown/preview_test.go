@@ -0,0 +1,130 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/widget"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestRenderSpacing(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"snake_case and don't", "snake_case and don't\n"},
+		{"foo**bar**baz", "foobarbaz\n"},
+		{"a *b* c", "a b c\n"},
+		{"- [ ] task one", "[ ] task one\n"},
+		{"soft\nbreak", "soft break\n"},
+		{"see (https://example.com) now", "see (https://example.com) now\n"},
+		{"~~old~~new", "oldnew\n"},
+	}
+	for _, tt := range tests {
+		got := segmentText(newPreviewRenderer(tt.source, "note.md").render())
+		if got != tt.want {
+			t.Errorf("render(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"[don't panic](a.md)", "don't panic"},
+		{"[snake_case](a.md)", "snake_case"},
+		{"[two\nlines](a.md)", "two lines"},
+		{"`a\\b`", "a\\b"},
+	}
+	for _, tt := range tests {
+		source := []byte(tt.source)
+		doc := markdownParser.Parser().Parse(text.NewReader(source))
+		// 第一个段落中的第一个行内节点
+		inline := doc.FirstChild().FirstChild()
+		if inline == nil || inline.Kind() != ast.KindLink && inline.Kind() != ast.KindCodeSpan {
+			t.Fatalf("%q: unexpected node %v", tt.source, inline)
+		}
+		if got := plainText(source, inline); got != tt.want {
+			t.Errorf("plainText(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+// segmentText 拼接片段中的文字：行内片段直接相连，块级片段之后换行
+func segmentText(segs []widget.RichTextSegment) string {
+	var b strings.Builder
+	for _, seg := range segs {
+		switch s := seg.(type) {
+		case *widget.ParagraphSegment:
+			b.WriteString(segmentText(s.Texts))
+		case *widget.ListSegment:
+			for _, item := range s.Items {
+				b.WriteString(strings.TrimSuffix(segmentText([]widget.RichTextSegment{item}), "\n") + "\n")
+			}
+		default:
+			b.WriteString(seg.Textual())
+			if !seg.Inline() {
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestPreviewToggleTask(t *testing.T) {
+	m := newTestEditor(t)
+	path := filepath.Join(m.rootPath, "tasks.md")
+	source := "---\ntags: [a]\n---\n- [ ] one\n  - [x] nested\n\n> - [ ] quoted\n\n```\n- [ ] code\n```\n1. [ ] 中文 task\n"
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.openFile(path)
+	editor := m.openFiles[path]
+
+	var boxes []*checkboxSegment
+	var collect func(segs []widget.RichTextSegment)
+	collect = func(segs []widget.RichTextSegment) {
+		for _, seg := range segs {
+			switch s := seg.(type) {
+			case *checkboxSegment:
+				boxes = append(boxes, s)
+			case *widget.ParagraphSegment:
+				collect(s.Texts)
+			case *widget.ListSegment:
+				collect(s.Items)
+			}
+		}
+	}
+	collect(m.renderEditorPreview(editor, path))
+	if len(boxes) != 4 {
+		t.Fatalf("rendered %d checkboxes, want 4", len(boxes))
+	}
+	for i, want := range []bool{false, true, false, false} {
+		if boxes[i].checked != want {
+			t.Errorf("checkbox %d checked = %v, want %v", i, boxes[i].checked, want)
+		}
+	}
+
+	// 勾选只修改对应的源码行，光标保持在原处
+	editor.CursorRow, editor.CursorColumn = 11, 9
+	lines := strings.Split(source, "\n")
+	for i, line := range []int{3, 4, 6, 11} {
+		boxes[i].onToggle()
+		lines[line], _ = toggleTaskLine(lines[line])
+		if want := strings.Join(lines, "\n"); editor.Text != want {
+			t.Fatalf("after toggling checkbox %d: text = %q, want %q", i, editor.Text, want)
+		}
+	}
+	if editor.CursorRow != 11 || editor.CursorColumn != 9 {
+		t.Errorf("cursor = %d:%d, want 11:9", editor.CursorRow, editor.CursorColumn)
+	}
+	if !m.isDirty(editor) {
+		t.Error("tab is not marked dirty after toggling a task")
+	}
+}
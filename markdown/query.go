@@ -0,0 +1,403 @@
+package markdown
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 查询块是语言为 query 的代码块，预览时按笔记库索引列出符合条件的笔记，例如：
+//
+//	```query
+//	table title, due, modified
+//	from projects
+//	tag #active
+//	where status != done
+//	sort modified desc
+//	limit 20
+//	```
+//
+// 语法见 querySyntax，查询出错时会把它显示在预览中。
+// 包含查询块的笔记本身不会出现在结果中。
+
+// querySyntax 是查询块的语法说明
+const querySyntax = `每行一个子句，关键字不区分大小写，以 // 开头的行是注释：
+
+list [字段, ...]      以列表显示，第一个字段（默认 title）作为链接，其余字段附在后面
+table 字段, ...       以表格显示
+from 文件夹           只包含该文件夹（相对于笔记库）及其子文件夹中的笔记
+tag #标签, ...        必须带有所有标签，父标签匹配子标签
+where 字段            字段不为空
+where 字段 运算符 值  运算符为 = != < <= > >= contains，运算符两边的空格可以省略（contains 除外）；
+                      两边都是数字时按数字比较，否则按文字比较，值中有空格时可以加引号
+sort 字段 [asc|desc]  排序，默认按路径排序
+limit 数量            最多显示的笔记数
+
+字段可以是 title、name、path、folder、modified、tags，其余的名称取 front matter 中的属性，
+字段名中不能包含 < > = ! ~；日期按 2006-01-02 格式比较。`
+
+// queryFencePattern 匹配查询块的开始行
+var queryFencePattern = regexp.MustCompile("(?mi)^[ \t]*(```|~~~)[ \t]*query[ \t]*$")
+
+// queryIndexTTL 是查询使用的索引的缓存时间，避免输入时反复扫描笔记库；保存笔记时会立即失效
+const queryIndexTTL = 5 * time.Second
+
+// noteQuery 是解析后的查询块
+type noteQuery struct {
+	table  bool
+	fields []string
+	folder string
+	tags   []string
+	conds  []queryCond
+	sortBy string
+	desc   bool
+	limit  int
+}
+
+// queryCond 是一个 where 条件，op 为空表示字段不为空
+type queryCond struct {
+	field, op, value string
+}
+
+// parseQuery 解析查询块的内容，出错时返回带行号的错误
+func parseQuery(src string) (*noteQuery, error) {
+	q := &noteQuery{}
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("第 %d 行: %s", i+1, fmt.Sprintf(format, args...))
+		}
+
+		switch strings.ToLower(keyword) {
+		case "list", "table":
+			q.table = strings.EqualFold(keyword, "table")
+			q.fields = queryList(rest)
+			if q.table && len(q.fields) == 0 {
+				return nil, fail("table 需要至少一个字段")
+			}
+		case "from":
+			q.folder = strings.Trim(unquote(rest), "/")
+			if q.folder == "" {
+				return nil, fail("from 需要文件夹")
+			}
+		case "tag", "tags":
+			tags := queryList(rest)
+			if len(tags) == 0 {
+				return nil, fail("tag 需要标签")
+			}
+			q.tags = append(q.tags, tags...)
+		case "where":
+			cond, err := parseCond(rest)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			q.conds = append(q.conds, cond)
+		case "sort":
+			fields := strings.Fields(rest)
+			if len(fields) == 0 || len(fields) > 2 {
+				return nil, fail("sort 的格式为 sort 字段 [asc|desc]")
+			}
+			q.sortBy = strings.ToLower(fields[0])
+			if len(fields) == 2 {
+				switch strings.ToLower(fields[1]) {
+				case "asc":
+				case "desc":
+					q.desc = true
+				default:
+					return nil, fail("排序方向只能是 asc 或 desc: %s", fields[1])
+				}
+			}
+		case "limit":
+			n, err := strconv.Atoi(rest)
+			if err != nil || n <= 0 {
+				return nil, fail("limit 需要正整数: %s", rest)
+			}
+			q.limit = n
+		default:
+			return nil, fail("未知的子句: %s", keyword)
+		}
+	}
+	if len(q.fields) == 0 {
+		q.fields = []string{"title"}
+	}
+	return q, nil
+}
+
+// condPattern 匹配 "字段 运算符 值"，符号运算符两边可以没有空格
+var condPattern = regexp.MustCompile(`^([^\s<>=!~]+)(?:\s*(!=|<=|>=|=|<|>)|\s+((?i)contains)(?:\s|$))\s*(.*)$`)
+
+func parseCond(s string) (queryCond, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return queryCond{}, fmt.Errorf("where 需要字段")
+	}
+	match := condPattern.FindStringSubmatch(s)
+	if match == nil {
+		if strings.ContainsAny(s, "<>=!~") {
+			return queryCond{}, fmt.Errorf("无法识别的条件: %s", s)
+		}
+		if strings.ContainsAny(s, " \t") {
+			return queryCond{}, fmt.Errorf("where 的格式为 where 字段 运算符 值")
+		}
+		return queryCond{field: strings.ToLower(s)}, nil
+	}
+
+	op, value := match[2]+strings.ToLower(match[3]), match[4]
+	if value == "" {
+		return queryCond{}, fmt.Errorf("运算符 %s 后面需要值", op)
+	}
+	// 如 "=<"、"==" 这样的写法，需要比较符号本身时请给值加引号
+	if strings.ContainsAny(value[:1], "<>=!~") {
+		return queryCond{}, fmt.Errorf("未知的运算符: %s", op+value[:1])
+	}
+	return queryCond{field: strings.ToLower(match[1]), op: op, value: unquote(value)}, nil
+}
+
+// queryList 拆分逗号分隔的列表
+func queryList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// run 在索引中执行查询，exclude 是包含查询块的笔记
+func (q *noteQuery) run(idx *vaultIndex, exclude string) []*noteInfo {
+	var result []*noteInfo
+	for _, path := range idx.paths {
+		info := idx.notes[path]
+		if path == exclude || info.Encrypted || !idx.inFolder(path, q.folder) {
+			continue
+		}
+		if q.matches(idx, info) {
+			result = append(result, info)
+		}
+	}
+
+	if q.sortBy != "" {
+		sort.SliceStable(result, func(i, j int) bool {
+			a, b := result[i], result[j]
+			if q.sortBy == "modified" {
+				if q.desc {
+					return a.ModTime.After(b.ModTime)
+				}
+				return a.ModTime.Before(b.ModTime)
+			}
+			va, vb := queryValue(idx, a, q.sortBy), queryValue(idx, b, q.sortBy)
+			// 没有该字段的笔记总是排在最后
+			if va == "" || vb == "" {
+				return va != "" && vb == ""
+			}
+			if q.desc {
+				return compareValues(va, vb) > 0
+			}
+			return compareValues(va, vb) < 0
+		})
+	}
+	if q.limit > 0 && len(result) > q.limit {
+		result = result[:q.limit]
+	}
+	return result
+}
+
+func (q *noteQuery) matches(idx *vaultIndex, info *noteInfo) bool {
+	for _, tag := range q.tags {
+		if !info.hasTag(tag) {
+			return false
+		}
+	}
+	for _, c := range q.conds {
+		v := queryValue(idx, info, c.field)
+		var ok bool
+		switch c.op {
+		case "":
+			ok = v != ""
+		case "contains":
+			ok = strings.Contains(strings.ToLower(v), strings.ToLower(c.value))
+		case "=":
+			ok = compareValues(v, c.value) == 0
+		case "!=":
+			ok = compareValues(v, c.value) != 0
+		case "<":
+			ok = v != "" && compareValues(v, c.value) < 0
+		case "<=":
+			ok = v != "" && compareValues(v, c.value) <= 0
+		case ">":
+			ok = v != "" && compareValues(v, c.value) > 0
+		case ">=":
+			ok = v != "" && compareValues(v, c.value) >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// queryValue 返回笔记中字段的值，没有该字段时返回空字符串
+func queryValue(idx *vaultIndex, info *noteInfo, field string) string {
+	switch field {
+	case "title":
+		return queryTitle(info)
+	case "name":
+		return info.Name
+	case "path":
+		return idx.relPath(info.Path)
+	case "folder":
+		return filepath.ToSlash(filepath.Dir(idx.relPath(info.Path)))
+	case "modified":
+		if info.ModTime.IsZero() {
+			return ""
+		}
+		return info.ModTime.Format("2006-01-02 15:04")
+	case "tags":
+		return strings.Join(info.Tags, ", ")
+	}
+	for key, v := range info.Meta {
+		if !strings.EqualFold(key, field) {
+			continue
+		}
+		if t, ok := v.(time.Time); ok {
+			if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+				return t.Format("2006-01-02")
+			}
+			return t.Format("2006-01-02 15:04")
+		}
+		if _, ok := v.([]interface{}); ok {
+			return strings.Join(metaStrings(v), ", ")
+		}
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// queryTitle 返回笔记的标题：依次使用 front matter 中的 title、第一个一级标题和文件名
+func queryTitle(info *noteInfo) string {
+	if t, ok := info.Meta["title"].(string); ok && strings.TrimSpace(t) != "" {
+		return strings.TrimSpace(t)
+	}
+	title := ""
+	forEachTextLine(info.Content, func(_ int, line string) {
+		if level, text, ok := parseHeading(line); ok && level == 1 && title == "" {
+			title = text
+		}
+	})
+	if title != "" {
+		return title
+	}
+	return info.Name
+}
+
+// compareValues 比较两个字段值，都是数字时按数字比较，否则按不区分大小写的文字比较
+func compareValues(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// renderQuery 执行笔记中的查询块并渲染结果，查询有误时显示错误信息
+func (m *MarkdownEditor) renderQuery(notePath, src string) []widget.RichTextSegment {
+	q, err := parseQuery(src)
+	if err != nil {
+		return []widget.RichTextSegment{
+			queryNotice("查询错误: " + err.Error()),
+			&widget.TextSegment{Style: widget.RichTextStyleCodeBlock, Text: querySyntax},
+		}
+	}
+	idx, err := m.queryIndex()
+	if err != nil {
+		return []widget.RichTextSegment{queryNotice("查询错误: " + err.Error())}
+	}
+	notes := q.run(idx, notePath)
+	if len(notes) == 0 {
+		return []widget.RichTextSegment{queryNotice("没有符合条件的笔记")}
+	}
+
+	if q.table {
+		rows := [][]string{q.fields}
+		for _, info := range notes {
+			row := make([]string, len(q.fields))
+			for i, field := range q.fields {
+				row[i] = queryValue(idx, info, field)
+			}
+			rows = append(rows, row)
+		}
+		return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleCodeBlock, Text: alignRows(rows)}}
+	}
+
+	items := make([]widget.RichTextSegment, 0, len(notes))
+	for _, info := range notes {
+		path := info.Path
+		link := &widget.HyperlinkSegment{Alignment: fyne.TextAlignLeading, Text: queryValue(idx, info, q.fields[0])}
+		if link.Text == "" {
+			link.Text = info.Name
+		}
+		link.OnTapped = func() { m.openFile(path) }
+		texts := []widget.RichTextSegment{link}
+
+		var extra []string
+		for _, field := range q.fields[1:] {
+			if v := queryValue(idx, info, field); v != "" {
+				extra = append(extra, v)
+			}
+		}
+		if len(extra) > 0 {
+			texts = append(texts, &widget.TextSegment{Style: widget.RichTextStyleInline, Text: " · " + strings.Join(extra, " · ")})
+		}
+		items = append(items, &widget.ParagraphSegment{Texts: texts})
+	}
+	return []widget.RichTextSegment{&widget.ListSegment{Items: items}}
+}
+
+func queryNotice(text string) widget.RichTextSegment {
+	seg := &widget.TextSegment{Style: widget.RichTextStyleParagraph, Text: "[" + text + "]"}
+	seg.Style.TextStyle.Italic = true
+	return seg
+}
+
+// queryIndex 返回查询使用的索引，缓存 queryIndexTTL 时间
+func (m *MarkdownEditor) queryIndex() (*vaultIndex, error) {
+	if m.queryCache != nil && time.Since(m.queryCacheTime) < queryIndexTTL {
+		return m.queryCache, nil
+	}
+	idx, err := m.buildIndex()
+	if err != nil {
+		return nil, err
+	}
+	m.queryCache, m.queryCacheTime = idx, time.Now()
+	return idx, nil
+}
@@ -0,0 +1,81 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCond(t *testing.T) {
+	tests := []struct {
+		in   string
+		want queryCond
+		err  string
+	}{
+		{"status", queryCond{field: "status"}, ""},
+		{"Status != done", queryCond{field: "status", op: "!=", value: "done"}, ""},
+		{"due<2026-01-01", queryCond{field: "due", op: "<", value: "2026-01-01"}, ""},
+		{"due <= 2026-01-01", queryCond{field: "due", op: "<=", value: "2026-01-01"}, ""},
+		{"size>=10", queryCond{field: "size", op: ">=", value: "10"}, ""},
+		{"title = 'a b'", queryCond{field: "title", op: "=", value: "a b"}, ""},
+		{"tags CONTAINS work", queryCond{field: "tags", op: "contains", value: "work"}, ""},
+		{`x = "="`, queryCond{field: "x", op: "=", value: "="}, ""},
+		{"", queryCond{}, "where 需要字段"},
+		{"due <", queryCond{}, "后面需要值"},
+		{"tags contains", queryCond{}, "后面需要值"},
+		{"due =< 2026", queryCond{}, "未知的运算符: =<"},
+		{"due ~ x", queryCond{}, "无法识别的条件"},
+		{"status done", queryCond{}, "where 的格式"},
+		{"titlecontains x", queryCond{}, "where 的格式"},
+	}
+	for _, tt := range tests {
+		got, err := parseCond(tt.in)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseCond(%q) error = %v, want %q", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseCond(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want *noteQuery
+		err  string
+	}{
+		{
+			name: "defaults",
+			src:  "",
+			want: &noteQuery{fields: []string{"title"}},
+		},
+		{
+			name: "all clauses",
+			src:  "TABLE title, due\n// comment\nfrom /projects/\ntag #a, #b\nwhere due<2026-01-01\nsort due DESC\nlimit 5",
+			want: &noteQuery{table: true, fields: []string{"title", "due"}, folder: "projects", tags: []string{"#a", "#b"},
+				conds: []queryCond{{field: "due", op: "<", value: "2026-01-01"}}, sortBy: "due", desc: true, limit: 5},
+		},
+		{name: "table without fields", src: "table", err: "第 1 行"},
+		{name: "bad limit", src: "list\nlimit 0", err: "第 2 行: limit"},
+		{name: "bad sort", src: "sort a b c", err: "sort 的格式"},
+		{name: "unknown clause", src: "group by x", err: "未知的子句: group"},
+		{name: "bad where", src: "\n\nwhere a ~ b", err: "第 3 行"},
+	}
+	for _, tt := range tests {
+		got, err := parseQuery(tt.src)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseQuery() = %+v, %v; want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
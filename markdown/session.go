@@ -0,0 +1,178 @@
+package markdown
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+)
+
+// vaultSession 是保存在 .nodian/session.json 中的界面状态，下次启动时恢复
+type vaultSession struct {
+	Tabs   []sessionTab `json:"tabs"`
+	Active string       `json:"active"` // 当前标签页的笔记，相对于笔记库
+	// OpenBranches 是目录树中展开的目录；为 nil 时表示没有保存过，展开全部目录
+	OpenBranches []string `json:"openBranches"`
+	TreeSplit    float64  `json:"treeSplit"` // 目录树与编辑区的分割位置
+	Tool         int      `json:"tool"`      // 主菜单中选中的工具
+	Width        float32  `json:"width"`
+	Height       float32  `json:"height"`
+}
+
+// sessionTab 是一个笔记标签页的状态
+type sessionTab struct {
+	Path          string  `json:"path"` // 相对于笔记库
+	CursorRow     int     `json:"cursorRow"`
+	CursorColumn  int     `json:"cursorColumn"`
+	SourceScroll  float32 `json:"sourceScroll"`
+	PreviewScroll float32 `json:"previewScroll"`
+	// View 是标签页的显示方式，Split 是源码与预览并排时的分割位置
+	View  viewMode `json:"view"`
+	Split float64  `json:"split"`
+}
+
+// SaveSession 保存打开的标签页、光标和滚动位置、目录树的展开状态和布局，
+// tool 和 size 是主窗口选中的工具和窗口大小
+func (m *MarkdownEditor) SaveSession(tool int, size fyne.Size) error {
+	if m.rootPath == "" {
+		return nil
+	}
+	s := vaultSession{
+		OpenBranches: []string{},
+		TreeSplit:    m.contentSplit.Offset,
+		Tool:         tool,
+		Width:        size.Width,
+		Height:       size.Height,
+	}
+	for _, tab := range m.tabs.Items {
+		source := tabSource(tab)
+		if source == nil || source.entry.key != nil {
+			continue // 加密笔记不记录，避免启动时要求输入口令
+		}
+		path := m.pathOf(source.entry)
+		if path == "" {
+			continue
+		}
+		t := sessionTab{
+			Path:         m.vaultRelPath(path),
+			CursorRow:    source.entry.CursorRow,
+			CursorColumn: source.entry.CursorColumn,
+			SourceScroll: source.scroll.Offset.Y,
+		}
+		t.View = tabViewMode(tab)
+		if split := tabSplit(tab); split != nil {
+			t.Split = split.Offset
+			if !split.Leading.Visible() || !split.Trailing.Visible() {
+				t.Split = 0.5 // 只显示一侧时分割位置在边上，回到并排时使用默认位置
+			}
+			if preview, ok := split.Trailing.(*container.Scroll); ok {
+				t.PreviewScroll = preview.Offset.Y
+			}
+		}
+		s.Tabs = append(s.Tabs, t)
+		if tab == m.tabs.Selected() {
+			s.Active = t.Path
+		}
+	}
+	for _, folder := range m.vaultFolders() {
+		if uid := m.pathToUID(filepath.Join(m.rootPath, filepath.FromSlash(folder))); uid != "" && m.treeView.IsBranchOpen(uid) {
+			s.OpenBranches = append(s.OpenBranches, folder)
+		}
+	}
+	return m.writeMeta("session.json", s)
+}
+
+// RestoreSession 恢复上次保存的界面状态，返回主窗口应选中的工具和窗口大小；没有保存过时窗口大小为零
+func (m *MarkdownEditor) RestoreSession() (tool int, size fyne.Size) {
+	data, err := os.ReadFile(m.metaPath("session.json"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fyne.LogError("Failed to read session", err)
+		}
+		return 0, fyne.Size{}
+	}
+	var s vaultSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		fyne.LogError("Failed to parse session", err)
+		return 0, fyne.Size{}
+	}
+
+	if s.TreeSplit > 0 && s.TreeSplit < 1 {
+		m.contentSplit.Offset = s.TreeSplit
+		m.contentSplit.Refresh()
+	}
+	if s.OpenBranches != nil {
+		m.treeView.CloseAllBranches()
+		for _, folder := range s.OpenBranches {
+			if uid := m.pathToUID(filepath.Join(m.rootPath, filepath.FromSlash(folder))); uid != "" && m.isBranch(uid) {
+				m.treeView.OpenBranch(uid)
+			}
+		}
+	}
+
+	var active *container.TabItem
+	for _, t := range s.Tabs {
+		path := filepath.Join(m.rootPath, filepath.FromSlash(t.Path))
+		if !isFile(path) {
+			continue
+		}
+		m.openFile(path)
+		editor, ok := m.openFiles[path]
+		if !ok {
+			continue
+		}
+		tab := m.tabOf(editor)
+		m.restoreTab(tab, t)
+		if t.Path == s.Active {
+			active = tab
+		}
+	}
+	if active != nil {
+		m.tabs.Select(active)
+	}
+	return s.Tool, fyne.NewSize(s.Width, s.Height)
+}
+
+// restoreTab 恢复标签页中的光标、滚动位置、分割位置和显示方式。
+// 滚动位置在标签页第一次布局时才会生效，此时会按内容大小修正
+func (m *MarkdownEditor) restoreTab(tab *container.TabItem, t sessionTab) {
+	source := tabSource(tab)
+	if source == nil {
+		return
+	}
+	editor := source.entry
+	row := min(max(t.CursorRow, 0), strings.Count(editor.Text, "\n"))
+	editor.CursorRow = row
+	editor.CursorColumn = min(max(t.CursorColumn, 0), utf8.RuneCountInString(lineAt(editor.Text, row)))
+	editor.Refresh()
+	source.scroll.Offset.Y = t.SourceScroll
+
+	split := tabSplit(tab)
+	if split == nil {
+		return
+	}
+	if t.Split > 0 && t.Split < 1 {
+		split.Offset = t.Split
+	}
+	if preview, ok := split.Trailing.(*container.Scroll); ok {
+		preview.Offset.Y = t.PreviewScroll
+	}
+	split.Refresh()
+	// 没有记录显示方式时保持默认：看板笔记显示看板，其他笔记并排显示
+	if t.View != "" {
+		setTabViewMode(tab, t.View)
+	}
+}
+
+// vaultRelPath 返回文件相对于笔记库的路径，统一使用 / 分隔
+func (m *MarkdownEditor) vaultRelPath(path string) string {
+	rel, err := filepath.Rel(m.rootPath, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
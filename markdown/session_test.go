@@ -0,0 +1,94 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	m := newTestEditor(t)
+	files := map[string]string{
+		"a.md":       "one\ntwo\nthree",
+		"b.md":       "gone",
+		"board.md":   "---\nkanban: true\n---\n## Todo\n- card\n",
+		"sources.md": "---\nkanban: true\n---\n## Todo\n",
+		"preview.md": "# Preview",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(m.rootPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	open := func(m *MarkdownEditor, name string) *noteEntry {
+		path := filepath.Join(m.rootPath, name)
+		m.openFile(path)
+		return m.openFiles[path]
+	}
+	for _, name := range []string{"a.md", "b.md", "board.md", "sources.md", "preview.md"} {
+		open(m, name)
+	}
+	a := m.openFiles[filepath.Join(m.rootPath, "a.md")]
+	a.CursorRow, a.CursorColumn = 2, 3
+	setTabViewMode(m.tabOf(m.openFiles[filepath.Join(m.rootPath, "sources.md")]), viewSource)
+	setTabViewMode(m.tabOf(m.openFiles[filepath.Join(m.rootPath, "preview.md")]), viewPreview)
+	tabSplit(m.tabOf(a)).Offset = 0.3
+	m.tabs.Select(m.tabOf(a))
+	if err := m.SaveSession(2, fyne.NewSize(800, 600)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 保存之后被删除或改短的笔记
+	if err := os.Remove(filepath.Join(m.rootPath, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(m.rootPath, "a.md"), []byte("one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewMarkdownEditor(test.NewWindow(nil))
+	if err := restored.LoadDirectory(filepath.Dir(m.rootPath)); err != nil {
+		t.Fatal(err)
+	}
+	tool, size := restored.RestoreSession()
+	if tool != 2 || size != fyne.NewSize(800, 600) {
+		t.Errorf("RestoreSession() = %d, %v; want 2, 800x600", tool, size)
+	}
+
+	var names []string
+	modes := map[string]viewMode{}
+	for _, tab := range restored.tabs.Items {
+		names = append(names, tab.Text)
+		modes[tab.Text] = tabViewMode(tab)
+	}
+	if want := []string{"a.md", "board.md", "sources.md", "preview.md"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("restored tabs = %q, want %q", names, want)
+	}
+	wantModes := map[string]viewMode{"a.md": viewSplit, "board.md": viewBoard, "sources.md": viewSource, "preview.md": viewPreview}
+	if !reflect.DeepEqual(modes, wantModes) {
+		t.Errorf("restored view modes = %v, want %v", modes, wantModes)
+	}
+
+	selected := restored.tabs.Selected()
+	if selected == nil || selected.Text != "a.md" {
+		t.Fatalf("active tab = %v, want a.md", selected)
+	}
+	editor := tabEditor(selected)
+	if editor.CursorRow != 0 || editor.CursorColumn != 3 {
+		t.Errorf("cursor = %d:%d, want it clamped to 0:3", editor.CursorRow, editor.CursorColumn)
+	}
+	if offset := tabSplit(selected).Offset; offset != 0.3 {
+		t.Errorf("split offset = %v, want 0.3", offset)
+	}
+
+	// 回到并排显示时不沿用只显示一侧时的分割位置
+	sources := restored.tabs.Items[2]
+	setTabViewMode(sources, viewSplit)
+	if mode, offset := tabViewMode(sources), tabSplit(sources).Offset; mode != viewSplit || offset != 0.5 {
+		t.Errorf("after switching back: mode %q, offset %v; want split, 0.5", mode, offset)
+	}
+}
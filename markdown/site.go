@@ -0,0 +1,562 @@
+package markdown
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// siteMarker 标记目录是由发布功能生成的，重新发布时可以安全地清空
+const siteMarker = ".nodian-site"
+
+// 搜索索引中每篇笔记保留的最大字符数
+const maxSearchText = 2000
+
+var (
+	errSiteInVault  = errors.New("输出目录不能位于笔记库中")
+	errSiteNotEmpty = errors.New("输出目录不为空，且不是之前发布的网站")
+	errAssetOutside = errors.New("附件的输出位置超出了网站目录")
+
+	htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+)
+
+// siteStyle 是网站在导出样式之外增加的布局样式
+const siteStyle = `
+body { max-width: none; margin: 0; padding: 0; display: flex; }
+nav.sidebar { width: 260px; flex-shrink: 0; height: 100vh; position: sticky; top: 0; overflow: auto; padding: 1em; box-sizing: border-box; border-right: 1px solid #d0d7de; font-size: 14px; }
+nav.sidebar ul { list-style: none; padding-left: 1em; margin: 0; }
+nav.sidebar > ul { padding-left: 0; }
+nav.sidebar summary { cursor: pointer; }
+nav.sidebar .current { font-weight: bold; }
+nav.sidebar .home { display: block; font-size: 1.2em; font-weight: bold; margin-bottom: .6em; }
+nav.sidebar .tags-link { display: block; margin-top: 1em; }
+#search { width: 100%; box-sizing: border-box; padding: .3em .5em; margin-bottom: .6em; }
+#results { margin-bottom: 1em !important; }
+main { flex: 1; min-width: 0; max-width: 760px; margin: 0 auto; padding: 1em 2em 4em; }
+.page-tags a { margin-right: .6em; }
+.backlinks { margin-top: 3em; padding-top: 1em; border-top: 1px solid #d0d7de; }
+@media (prefers-color-scheme: dark) {
+  nav.sidebar, .backlinks { border-color: #30363d; }
+}
+`
+
+// siteScript 在浏览器中加载搜索索引，按标题、标签和正文过滤
+const siteScript = `(function () {
+  var base = document.currentScript.src.replace(/search\.js$/, "");
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var index = null;
+  function show(q) {
+    results.innerHTML = "";
+    q = q.trim().toLowerCase();
+    if (!q || !index) return;
+    index.filter(function (e) {
+      return e.title.toLowerCase().indexOf(q) >= 0 ||
+        e.text.toLowerCase().indexOf(q) >= 0 ||
+        e.tags.some(function (t) { return t.toLowerCase().indexOf(q) >= 0; });
+    }).slice(0, 20).forEach(function (e) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = base + e.url;
+      a.textContent = e.title;
+      li.appendChild(a);
+      results.appendChild(li);
+    });
+  }
+  input.addEventListener("input", function () {
+    if (index) { show(input.value); return; }
+    fetch(base + "search-index.json").then(function (r) { return r.json(); }).then(function (data) {
+      index = data;
+      show(input.value);
+    });
+  });
+})();
+`
+
+// sitePage 是网站中的一个页面
+type sitePage struct {
+	path  string // 笔记路径
+	url   string // 相对于网站根目录的地址，使用 / 分隔
+	title string
+	body  string
+	info  *noteInfo
+}
+
+// siteNav 是侧边栏目录树中的一项，结构与笔记目录树一致
+type siteNav struct {
+	name     string
+	path     string
+	children []*siteNav
+}
+
+// searchEntry 是 search-index.json 中的一项
+type searchEntry struct {
+	Title string   `json:"title"`
+	URL   string   `json:"url"`
+	Tags  []string `json:"tags"`
+	Text  string   `json:"text"`
+}
+
+// siteBuilder 把一个目录中的笔记生成为静态网站。
+// 页面按路径排序生成，不写入时间等可变内容，同样的笔记每次得到完全相同的输出
+type siteBuilder struct {
+	m      *MarkdownEditor
+	idx    *vaultIndex
+	folder string
+	out    string
+	name   string
+
+	pages  map[string]*sitePage
+	paths  []string
+	nav    []*siteNav
+	assets map[string]string // 图片路径 -> 输出中的相对地址
+	// skipped 是没有发布的加密笔记，相对于笔记库
+	skipped []string
+	// outside 是笔记引用的笔记库之外的图片，不会复制到网站中
+	outside []string
+}
+
+// publishSite 将 folder 中的笔记发布到 out 目录，返回发布的笔记数量、因加密而跳过的笔记和没有复制的库外图片
+func (m *MarkdownEditor) publishSite(folder, out string) (n int, skipped, outside []string, err error) {
+	out, err = filepath.Abs(out)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if m.insideRoot(out) {
+		return 0, nil, nil, errSiteInVault
+	}
+	idx, err := m.buildIndex()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	s := &siteBuilder{
+		m:      m,
+		idx:    idx,
+		folder: folder,
+		out:    out,
+		name:   filepath.Base(mustAbs(folder)),
+		pages:  map[string]*sitePage{},
+		assets: map[string]string{},
+	}
+	if err := prepareSiteDir(out); err != nil {
+		return 0, nil, nil, err
+	}
+	if err := s.build(); err != nil {
+		return 0, nil, nil, err
+	}
+	return len(s.paths), s.skipped, s.outside, nil
+}
+
+func mustAbs(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// prepareSiteDir 确保输出目录为空；之前发布的网站会被清空，保留其中的 .git
+func prepareSiteDir(out string) error {
+	entries, err := os.ReadDir(out)
+	if os.IsNotExist(err) {
+		return os.MkdirAll(out, 0755)
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(out, siteMarker)); err != nil {
+		return errSiteNotEmpty
+	}
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(out, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isPublished 判断笔记是否需要发布，front matter 中 publish: false 的笔记不发布
+func isPublished(info *noteInfo) bool {
+	publish, ok := info.Meta["publish"].(bool)
+	return !ok || publish
+}
+
+func (s *siteBuilder) build() error {
+	for _, path := range s.idx.paths {
+		info := s.idx.notes[path]
+		if _, ok := relUnder(s.folder, path); !ok || !isPublished(info) {
+			continue
+		}
+		// 加密笔记永远不发布，即使已经解密打开
+		if info.Encrypted {
+			s.skipped = append(s.skipped, s.idx.relPath(path))
+			continue
+		}
+		rel, _ := filepath.Rel(s.folder, path)
+		s.paths = append(s.paths, path)
+		s.pages[path] = &sitePage{
+			path: path,
+			url:  strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel)) + ".html",
+			info: info,
+		}
+	}
+
+	published := map[string]bool{}
+	for path := range s.pages {
+		published[path] = true
+	}
+	x := &htmlExporter{m: s.m, idx: s.idx, linkNotes: true, published: published, imageHref: s.asset}
+	for _, path := range s.paths {
+		page := s.pages[path]
+		body, title, err := x.render(path, page.info.Content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.idx.relPath(path), err)
+		}
+		page.body, page.title = body, title
+	}
+
+	s.nav = s.navItems(s.m.pathToUID(s.folder))
+
+	for _, path := range s.paths {
+		page := s.pages[path]
+		if err := s.write(page.url, s.page(page)); err != nil {
+			return err
+		}
+	}
+	if _, ok := s.pageByURL("index.html"); !ok {
+		if err := s.write("index.html", s.home()); err != nil {
+			return err
+		}
+	}
+	if err := s.write("tags.html", s.tagIndex()); err != nil {
+		return err
+	}
+	if err := s.writeSearchIndex(); err != nil {
+		return err
+	}
+	if err := s.write("style.css", []byte(exportStyle+siteStyle)); err != nil {
+		return err
+	}
+	if err := s.write("search.js", []byte(siteScript)); err != nil {
+		return err
+	}
+	return s.write(siteMarker, []byte("由 Nodian 生成，重新发布时会清空此目录\n"))
+}
+
+// asset 把图片复制到输出目录的 assets 中，返回从页面指向它的相对地址。
+// 笔记库之外的图片不复制，记录在 outside 中，页面中保留原来的地址
+func (s *siteBuilder) asset(notePath, path string) string {
+	url, ok := s.assets[path]
+	if !ok {
+		if !s.m.insideRoot(path) {
+			s.outside = append(s.outside, path)
+			s.assets[path] = ""
+			return ""
+		}
+		url = "assets/" + s.idx.relPath(path)
+		if err := s.copyFile(path, url); err != nil {
+			fyne.LogError("Failed to copy "+path, err)
+			return ""
+		}
+		s.assets[path] = url
+	}
+	if url == "" {
+		return ""
+	}
+	return s.relURL(s.pages[notePath].url, url)
+}
+
+func (s *siteBuilder) copyFile(src, url string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dst := filepath.Join(s.out, filepath.FromSlash(url))
+	if rel, err := filepath.Rel(s.out, dst); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errAssetOutside
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (s *siteBuilder) write(url string, data []byte) error {
+	path := filepath.Join(s.out, filepath.FromSlash(url))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (s *siteBuilder) pageByURL(url string) (*sitePage, bool) {
+	for _, page := range s.pages {
+		if page.url == url {
+			return page, true
+		}
+	}
+	return nil, false
+}
+
+// relURL 返回从 from 页面指向 to 的相对地址，两者都是相对于网站根目录的地址
+func (s *siteBuilder) relURL(from, to string) string {
+	return rootPrefix(from) + to
+}
+
+// rootPrefix 返回从页面回到网站根目录的前缀，如 "../../"
+func rootPrefix(url string) string {
+	return strings.Repeat("../", strings.Count(url, "/"))
+}
+
+// navItems 按照目录树的顺序生成侧边栏，只包含发布的笔记和含有发布笔记的目录
+func (s *siteBuilder) navItems(uid widget.TreeNodeID) []*siteNav {
+	var items []*siteNav
+	for _, child := range s.m.childUIDs(uid) {
+		path := s.m.uidToPath(child)
+		if s.m.isBranch(child) {
+			if children := s.navItems(child); len(children) > 0 {
+				items = append(items, &siteNav{name: filepath.Base(path), path: path, children: children})
+			}
+			continue
+		}
+		if _, ok := s.pages[path]; ok {
+			items = append(items, &siteNav{name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), path: path})
+		}
+	}
+	return items
+}
+
+func (s *siteBuilder) writeNav(buf *bytes.Buffer, items []*siteNav, current *sitePage) {
+	buf.WriteString("<ul>\n")
+	for _, item := range items {
+		if item.children != nil {
+			open := ""
+			if current != nil {
+				if _, ok := relUnder(item.path, current.path); ok {
+					open = " open"
+				}
+			}
+			fmt.Fprintf(buf, "<li><details%s><summary>%s</summary>\n", open, html.EscapeString(item.name))
+			s.writeNav(buf, item.children, current)
+			buf.WriteString("</details></li>\n")
+			continue
+		}
+		class := ""
+		if current != nil && item.path == current.path {
+			class = ` class="current"`
+		}
+		from := "index.html"
+		if current != nil {
+			from = current.url
+		}
+		fmt.Fprintf(buf, "<li><a href=\"%s\"%s>%s</a></li>\n",
+			html.EscapeString(s.relURL(from, s.pages[item.path].url)), class, html.EscapeString(item.name))
+	}
+	buf.WriteString("</ul>\n")
+}
+
+// layout 生成带有侧边栏的完整页面，url 是页面自身的地址
+func (s *siteBuilder) layout(url, title string, current *sitePage, main string) []byte {
+	root := rootPrefix(url)
+	var buf bytes.Buffer
+	buf.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	buf.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&buf, "<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintf(&buf, "<link rel=\"stylesheet\" href=\"%sstyle.css\">\n</head>\n<body>\n", root)
+	buf.WriteString("<nav class=\"sidebar\">\n")
+	fmt.Fprintf(&buf, "<a class=\"home\" href=\"%sindex.html\">%s</a>\n", root, html.EscapeString(s.name))
+	buf.WriteString("<input id=\"search\" type=\"search\" placeholder=\"Search\">\n<ul id=\"results\"></ul>\n")
+	s.writeNav(&buf, s.nav, current)
+	fmt.Fprintf(&buf, "<a class=\"tags-link\" href=\"%stags.html\">Tags</a>\n</nav>\n", root)
+	buf.WriteString("<main>\n")
+	buf.WriteString(main)
+	buf.WriteString("</main>\n")
+	fmt.Fprintf(&buf, "<script src=\"%ssearch.js\"></script>\n</body>\n</html>\n", root)
+	return buf.Bytes()
+}
+
+func (s *siteBuilder) page(page *sitePage) []byte {
+	var main bytes.Buffer
+	if len(page.info.Tags) > 0 {
+		main.WriteString("<p class=\"page-tags\">")
+		for _, tag := range page.info.Tags {
+			fmt.Fprintf(&main, "<a href=\"%s\">#%s</a>", html.EscapeString(s.relURL(page.url, "tags.html#"+tagID(tag))), html.EscapeString(tag))
+		}
+		main.WriteString("</p>\n")
+	}
+	main.WriteString("<article>\n")
+	main.WriteString(page.body)
+	main.WriteString("</article>\n")
+
+	var backlinks []*sitePage
+	for _, path := range s.idx.backlinks(page.path) {
+		if from, ok := s.pages[path]; ok {
+			backlinks = append(backlinks, from)
+		}
+	}
+	if len(backlinks) > 0 {
+		main.WriteString("<section class=\"backlinks\">\n<h2>Backlinks</h2>\n<ul>\n")
+		for _, from := range backlinks {
+			fmt.Fprintf(&main, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(s.relURL(page.url, from.url)), html.EscapeString(from.title))
+		}
+		main.WriteString("</ul>\n</section>\n")
+	}
+	return s.layout(page.url, page.title, page, main.String())
+}
+
+// home 在没有 index 笔记时生成首页，列出所有页面
+func (s *siteBuilder) home() []byte {
+	var main bytes.Buffer
+	fmt.Fprintf(&main, "<h1>%s</h1>\n<ul>\n", html.EscapeString(s.name))
+	for _, path := range s.paths {
+		page := s.pages[path]
+		fmt.Fprintf(&main, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(page.url), html.EscapeString(page.title))
+	}
+	main.WriteString("</ul>\n")
+	return s.layout("index.html", s.name, nil, main.String())
+}
+
+// tagIndex 生成标签索引页面
+func (s *siteBuilder) tagIndex() []byte {
+	byTag := map[string][]*sitePage{}
+	var tags []string
+	for _, path := range s.paths {
+		page := s.pages[path]
+		for _, tag := range page.info.Tags {
+			if byTag[tag] == nil {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], page)
+		}
+	}
+	sort.Strings(tags)
+
+	var main bytes.Buffer
+	main.WriteString("<h1>Tags</h1>\n")
+	for _, tag := range tags {
+		fmt.Fprintf(&main, "<h2 id=\"%s\">#%s</h2>\n<ul>\n", tagID(tag), html.EscapeString(tag))
+		for _, page := range byTag[tag] {
+			fmt.Fprintf(&main, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(page.url), html.EscapeString(page.title))
+		}
+		main.WriteString("</ul>\n")
+	}
+	return s.layout("tags.html", "Tags", nil, main.String())
+}
+
+// tagID 返回标签在标签索引页面中的 id
+func tagID(tag string) string {
+	return "tag-" + headingID(strings.ReplaceAll(tag, "/", "-"))
+}
+
+func (s *siteBuilder) writeSearchIndex() error {
+	entries := []searchEntry{}
+	for _, path := range s.paths {
+		page := s.pages[path]
+		text := strings.Join(strings.Fields(html.UnescapeString(htmlTagPattern.ReplaceAllString(page.body, " "))), " ")
+		if runes := []rune(text); len(runes) > maxSearchText {
+			text = string(runes[:maxSearchText])
+		}
+		tags := page.info.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		entries = append(entries, searchEntry{Title: page.title, URL: page.url, Tags: tags, Text: text})
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.write("search-index.json", append(data, '\n'))
+}
+
+// showPublishSite 显示发布网站对话框
+func (m *MarkdownEditor) showPublishSite() {
+	idx, err := m.buildIndex()
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+
+	folderSelect := widget.NewSelect(idx.folders(), nil)
+	folder := m.config.PublishFolder
+	if folder == "" {
+		folder = "."
+	}
+	folderSelect.SetSelected(folder)
+
+	outEntry := widget.NewEntry()
+	outEntry.SetText(m.config.PublishDir)
+	outEntry.SetPlaceHolder("笔记库之外的目录")
+	browse := widget.NewButton("Browse", func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, m.window)
+				return
+			}
+			if dir != nil {
+				outEntry.SetText(dir.Path())
+			}
+		}, m.window)
+	})
+
+	m.showCustomFormDialog("Publish Site", "Publish", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Folder", folderSelect),
+		widget.NewFormItem("Output", container.NewBorder(nil, nil, nil, browse, outEntry)),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		out := strings.TrimSpace(outEntry.Text)
+		if out == "" || folderSelect.Selected == "" {
+			return
+		}
+		m.config.PublishFolder = folderSelect.Selected
+		m.config.PublishDir = out
+		if err := m.saveConfig(); err != nil {
+			fyne.LogError("Failed to save vault config", err)
+		}
+
+		n, skipped, outside, err := m.publishSite(filepath.Join(m.rootPath, filepath.FromSlash(folderSelect.Selected)), out)
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		message := fmt.Sprintf("已发布 %d 篇笔记到 %s", n, out)
+		if len(skipped) > 0 {
+			message += fmt.Sprintf("\n\n跳过了 %d 篇加密笔记：\n%s", len(skipped), strings.Join(skipped, "\n"))
+		}
+		if len(outside) > 0 {
+			message += fmt.Sprintf("\n\n%d 张图片位于笔记库之外，没有复制：\n%s", len(outside), strings.Join(outside, "\n"))
+		}
+		dialog.ShowInformation("Publish Site", message, m.window)
+	}, m.window)
+}
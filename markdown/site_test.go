@@ -0,0 +1,73 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPublishSiteAssets(t *testing.T) {
+	m := newTestEditor(t)
+	base := filepath.Dir(m.rootPath)
+	outsideRel := filepath.Join(base, "secret.png")
+	outsideAbs := filepath.Join(t.TempDir(), "private.png")
+	files := map[string]string{
+		filepath.Join(m.rootPath, "img", "a.png"): "a",
+		outsideRel: "secret",
+		outsideAbs: "private",
+		filepath.Join(m.rootPath, "notes", "page.md"): "# Page\n\n![](../img/a.png)\n![](../../secret.png)\n![](" +
+			filepath.ToSlash(outsideAbs) + ")\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := filepath.Join(base, "site")
+	n, _, outside, err := m.publishSite(m.rootPath, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("published %d notes, want 1", n)
+	}
+	if want := []string{outsideRel, outsideAbs}; !reflect.DeepEqual(outside, want) {
+		t.Errorf("outside = %q, want %q", outside, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(out, "assets", "img", "a.png")); err != nil || string(data) != "a" {
+		t.Errorf("vault image not copied: %q, %v", data, err)
+	}
+	page, err := os.ReadFile(filepath.Join(out, "notes", "page.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `src="../assets/img/a.png"`) {
+		t.Errorf("page does not link the copied image:\n%s", page)
+	}
+
+	// 输出目录之外只能有原来的文件
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"nodian", "secret.png", "site"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("files next to the site = %q, want %q", names, want)
+	}
+
+	s := &siteBuilder{out: out}
+	for _, url := range []string{"assets/../../escape.png", "../escape.png"} {
+		if err := s.copyFile(outsideRel, url); err != errAssetOutside {
+			t.Errorf("copyFile(%q) err = %v, want %v", url, err, errAssetOutside)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var mdListPattern = regexp.MustCompile(`^([ \t]*)(?:([-*+])|(\d{1,9})([.)]))([ \t]+)(\[[ xX]\][ \t]+)?`)
+
+// 自动补全的成对字符；左右相同的是行内代码和强调标记
+var autoPairs = map[rune]rune{
+	'(': ')', '[': ']', '{': '}',
+	'`': '`', '*': '*', '_': '_', '~': '~',
+}
+
+// listMarker 是列表项行首的标记
+type listMarker struct {
+	indent string
+	bullet string // - * +，有序列表为空
+	number int
+	delim  string // 有序列表的 . 或 )
+	space  string
+	task   bool
+	width  int // 整个标记（含缩进和复选框）的字符数
+}
+
+func parseListMarker(line string) (listMarker, bool) {
+	m := mdListPattern.FindStringSubmatch(line)
+	if m == nil {
+		return listMarker{}, false
+	}
+	item := listMarker{indent: m[1], bullet: m[2], delim: m[4], space: m[5], task: m[6] != ""}
+	if item.bullet == "" {
+		item.number, _ = strconv.Atoi(m[3])
+	}
+	item.width = utf8.RuneCountInString(m[0])
+	return item, true
+}
+
+// core 返回不含缩进和复选框的列表标记，如 "- "、"2. "
+func (item listMarker) core() string {
+	if item.bullet != "" {
+		return item.bullet + item.space
+	}
+	return strconv.Itoa(item.number) + item.delim + item.space
+}
+
+// String 返回新列表项的标记，任务列表项使用未完成的复选框
+func (item listMarker) String() string {
+	s := item.indent + item.core()
+	if item.task {
+		s += "[ ] "
+	}
+	return s
+}
+
+// inCodeBlock 判断第 row 行是否为围栏代码块（包括围栏所在的行），代码块中不做列表续行和自动配对
+func inCodeBlock(content string, row int) bool {
+	if row < frontMatterEnd(strings.Split(content, "\n")) {
+		return false
+	}
+	text := false
+	forEachTextLine(content, func(i int, _ string) {
+		text = text || i == row
+	})
+	return !text
+}
+
+// continueList 在列表项中按回车时插入下一项的标记；在空的列表项中按回车时结束列表
+func (e *noteEntry) continueList() bool {
+	if e.SelectedText() != "" || inCodeBlock(e.Text, e.CursorRow) {
+		return false
+	}
+	row, col := e.CursorRow, e.CursorColumn
+	line := lineAt(e.Text, row)
+	item, ok := parseListMarker(line)
+	if !ok || col < item.width {
+		return false
+	}
+
+	if strings.TrimSpace(string([]rune(line)[item.width:])) == "" {
+		e.replaceRange(row, 0, utf8.RuneCountInString(line), "")
+		return true
+	}
+
+	next := item
+	if next.bullet == "" {
+		next.number++
+	}
+	e.insertText("\n" + next.String())
+	if next.bullet == "" {
+		e.renumberList(row + 1)
+	}
+	return true
+}
+
+// renumberList 从第 row 行开始重新编号其后同一层级的有序列表项
+func (e *noteEntry) renumberList(row int) {
+	lines := strings.Split(e.Text, "\n")
+	item, ok := parseListMarker(lines[row])
+	if !ok || item.bullet != "" {
+		return
+	}
+
+	cursorRow, cursorCol := e.CursorRow, e.CursorColumn
+	n := item.number
+	for i := row + 1; i < len(lines); i++ {
+		next, ok := parseListMarker(lines[i])
+		indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " \t"))
+		if !ok || len(next.indent) > len(item.indent) {
+			// 跳过子列表和列表项中缩进的后续段落
+			if strings.TrimSpace(lines[i]) != "" && indent > len(item.indent) {
+				continue
+			}
+			break
+		}
+		if len(next.indent) < len(item.indent) || next.bullet != "" {
+			break
+		}
+		n++
+		if next.number != n {
+			old := strconv.Itoa(next.number)
+			e.replaceRange(i, utf8.RuneCountInString(next.indent), len(old), strconv.Itoa(n))
+		}
+	}
+	e.setCursor(cursorRow, cursorCol)
+}
+
+// indentList 用 Tab 缩进列表项，使其成为上一项的子项；outdent 为 true 时（Shift+Tab）减少一级缩进。
+// 当前行不是列表项时返回 false
+func (e *noteEntry) indentList(outdent bool) bool {
+	if strings.Contains(e.SelectedText(), "\n") {
+		return false
+	}
+	row := e.CursorRow
+	lines := strings.Split(e.Text, "\n")
+	item, ok := parseListMarker(lines[row])
+	if !ok {
+		return false
+	}
+
+	next := item
+	if !outdent {
+		// 缩进到上一个同级项目的内容位置，没有同级项目时按自身标记的宽度缩进
+		width := len(item.core())
+		for i := row - 1; i >= 0; i-- {
+			prev, ok := parseListMarker(lines[i])
+			if !ok || len(prev.indent) < len(item.indent) {
+				break
+			}
+			if len(prev.indent) == len(item.indent) {
+				width = len(prev.core())
+				break
+			}
+		}
+		next.indent = item.indent + strings.Repeat(" ", width)
+		if next.bullet == "" {
+			next.number = 1
+		}
+	} else {
+		if item.indent == "" {
+			return true
+		}
+		// 退到上一个缩进更少的列表项的位置
+		next.indent = ""
+		for i := row - 1; i >= 0; i-- {
+			if prev, ok := parseListMarker(lines[i]); ok && len(prev.indent) < len(item.indent) {
+				next.indent = prev.indent
+				if prev.bullet == "" && next.bullet == "" {
+					next.number = prev.number + 1
+				}
+				break
+			}
+		}
+	}
+
+	old := item.indent + item.core()
+	text := next.indent + next.core()
+	col := e.CursorColumn + utf8.RuneCountInString(text) - utf8.RuneCountInString(old)
+	e.replaceRange(row, 0, utf8.RuneCountInString(old), text)
+	e.setCursor(row, max(col, utf8.RuneCountInString(text)))
+	return true
+}
+
+// autoPair 处理成对字符：有选中文字时用这对字符包裹；光标后正好是要输入的右侧字符时跳过它；
+// 否则插入一对字符并把光标放在中间。返回 false 时按普通字符输入
+func (e *noteEntry) autoPair(r rune) bool {
+	closing, opening := autoPairs[r]
+	if !opening && !strings.ContainsRune(")]}", r) || inCodeBlock(e.Text, e.CursorRow) {
+		return false
+	}
+	if sel := e.SelectedText(); sel != "" {
+		if !opening {
+			return false
+		}
+		e.insertText(string(r) + sel + string(closing))
+		return true
+	}
+
+	line := []rune(lineAt(e.Text, e.CursorRow))
+	col := min(e.CursorColumn, len(line))
+	var prev, prev2, next rune
+	if col > 0 {
+		prev = line[col-1]
+	}
+	if col > 1 {
+		prev2 = line[col-2]
+	}
+	if col < len(line) {
+		next = line[col]
+	}
+	symmetric := opening && closing == r
+
+	switch {
+	case symmetric && r != '`' && prev == r && next == r && prev2 != r:
+		// 在 *|* 中再输入 * 得到 **|**
+	case r == next && (symmetric || strings.ContainsRune(")]}", r)):
+		e.setCursor(e.CursorRow, col+1)
+		return true
+	case !opening:
+		return false
+	case next != 0 && !unicode.IsSpace(next) && !strings.ContainsRune(")]}", next):
+		return false
+	case symmetric && (unicode.IsLetter(prev) || unicode.IsDigit(prev)):
+		// 单词中间的 _ 和 * 不补全，如 snake_case、2*3
+		return false
+	case symmetric && r != '`' && strings.TrimSpace(string(line[:col])) == "":
+		// 行首的 * 和 - 可能是列表标记或分隔线
+		return false
+	case r == '`' && prev == '`' && prev2 == '`':
+		// 第三个反引号组成代码块的围栏
+		return false
+	}
+	e.insertText(string(r) + string(closing))
+	e.setCursor(e.CursorRow, e.CursorColumn-1)
+	return true
+}
+
+// deletePair 在一对刚补全的空字符中间按退格时同时删除两侧的字符
+func (e *noteEntry) deletePair() bool {
+	if e.SelectedText() != "" {
+		return false
+	}
+	line := []rune(lineAt(e.Text, e.CursorRow))
+	col := e.CursorColumn
+	if col == 0 || col >= len(line) {
+		return false
+	}
+	if closing, ok := autoPairs[line[col-1]]; !ok || closing != line[col] {
+		return false
+	}
+	e.replaceRange(e.CursorRow, col-1, 2, "")
+	return true
+}
+
+// wrapSelection 用 before 和 after 包裹选中的文字，已经包裹时去掉这对标记；
+// 没有选中文字时插入一对标记并把光标放在中间
+func (e *noteEntry) wrapSelection(before, after string) {
+	sel := e.SelectedText()
+	if sel == "" {
+		e.insertText(before + after)
+		e.setCursor(e.CursorRow, e.CursorColumn-utf8.RuneCountInString(after))
+		return
+	}
+	if len(sel) >= len(before)+len(after) && strings.HasPrefix(sel, before) && strings.HasSuffix(sel, after) {
+		e.insertText(sel[len(before) : len(sel)-len(after)])
+		return
+	}
+	e.insertText(before + sel + after)
+}
+
+// insertLink 把选中的文字变为链接；选中的是网址时把它作为链接地址，光标放在链接文字处
+func (e *noteEntry) insertLink() {
+	sel := e.SelectedText()
+	if url := strings.TrimSpace(sel); url != "" && isExternalLink(url) && !strings.ContainsAny(url, " \n") {
+		e.insertText("[](" + url + ")")
+		e.setCursor(e.CursorRow, e.CursorColumn-utf8.RuneCountInString(url)-3)
+		return
+	}
+	e.insertText("[" + sel + "]()")
+	e.setCursor(e.CursorRow, e.CursorColumn-1)
+}
+
+// insertCode 把选中的文字变为行内代码，选中多行时变为代码块
+func (e *noteEntry) insertCode() {
+	if sel := e.SelectedText(); strings.Contains(sel, "\n") {
+		e.insertText("```\n" + strings.TrimSuffix(sel, "\n") + "\n```")
+		return
+	}
+	e.wrapSelection("`", "`")
+}
+
+// setCursor 移动光标并取消选区
+func (e *noteEntry) setCursor(row, col int) {
+	e.clearSelection()
+	e.CursorRow, e.CursorColumn = row, max(col, 0)
+	e.Refresh()
+	if e.OnCursorChanged != nil {
+		e.OnCursorChanged()
+	}
+}
+
+// lineAt 返回 text 中的第 row 行，行号超出范围时返回空字符串
+func lineAt(text string, row int) string {
+	for ; row > 0; row-- {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			return ""
+		}
+		text = text[i+1:]
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
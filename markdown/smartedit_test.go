@@ -0,0 +1,75 @@
+package markdown
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestContinueList(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		row, col int
+		want     string
+		ok       bool
+	}{
+		{"bullet", "- a", 0, 3, "- a\n- ", true},
+		{"task", "  - [x] done", 0, 12, "  - [x] done\n  - [ ] ", true},
+		{"ordered renumbers", "1. a\n2. b", 0, 4, "1. a\n2. \n3. b", true},
+		{"empty item ends list", "- a\n- ", 1, 2, "- a\n", true},
+		{"cursor in marker", "- a", 0, 1, "- a", false},
+		{"plain text", "text", 0, 4, "text", false},
+		{"inside fence", "```\n- a\n```", 1, 3, "```\n- a\n```", false},
+		{"after fence", "```\ncode\n```\n- a", 3, 3, "```\ncode\n```\n- a\n- ", true},
+	}
+	test.NewApp()
+	for _, tt := range tests {
+		e := newNoteEntry()
+		e.SetText(tt.text)
+		e.setCursor(tt.row, tt.col)
+		ok := e.continueList()
+		if ok != tt.ok || e.Text != tt.want {
+			t.Errorf("%s: continueList() = %v, text %q; want %v, %q", tt.name, ok, e.Text, tt.ok, tt.want)
+		}
+	}
+}
+
+func TestAutoPair(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		row, col int
+		r        rune
+		want     string
+		ok       bool
+	}{
+		{"paren", "a ", 0, 2, '(', "a ()", true},
+		{"skip closing", "()", 0, 1, ')', "()", true},
+		{"inside word", "snake", 0, 5, '_', "snake", false},
+		{"list marker", "", 0, 0, '*', "", false},
+		{"inside fence", "```\nf \n```", 1, 2, '(', "```\nf \n```", false},
+		{"closing inside fence", "```\n()\n```", 1, 1, ')', "```\n()\n```", false},
+		{"plain rune", "a", 0, 1, 'b', "a", false},
+	}
+	test.NewApp()
+	for _, tt := range tests {
+		e := newNoteEntry()
+		e.SetText(tt.text)
+		e.setCursor(tt.row, tt.col)
+		ok := e.autoPair(tt.r)
+		if ok != tt.ok || e.Text != tt.want {
+			t.Errorf("%s: autoPair(%q) = %v, text %q; want %v, %q", tt.name, tt.r, ok, e.Text, tt.ok, tt.want)
+		}
+	}
+}
+
+func TestInCodeBlock(t *testing.T) {
+	content := "---\ntags:\n  - a\n---\n- x\n~~~\n- y\n~~~\n- z"
+	want := []bool{false, false, false, false, false, true, true, true, false}
+	for row, w := range want {
+		if got := inCodeBlock(content, row); got != w {
+			t.Errorf("inCodeBlock(row %d) = %v, want %v", row, got, w)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package markdown
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// personalDictionary 是笔记库的个人词典，每行一个词
+const personalDictionary = "dictionary.txt"
+
+// maxSuggestions 是拼写建议菜单中最多显示的建议数
+const maxSuggestions = 8
+
+// spellKinds 是需要检查拼写的高亮类别，代码、链接、网址、front matter 等不检查
+var spellKinds = map[hlKind]bool{hlText: true, hlHeading: true, hlEmphasis: true, hlQuote: true}
+
+// spellChecker 合并多个 Hunspell 字典和笔记库的个人词典
+type spellChecker struct {
+	dicts    []*hunspellDict
+	personal map[string]bool
+	// version 在个人词典变化时加一，编辑器据此清除拼写检查的缓存
+	version int
+}
+
+// loadSpellChecker 按设置加载字典目录中的 .aff/.dic 文件和个人词典
+func (m *MarkdownEditor) loadSpellChecker() (*spellChecker, error) {
+	dir := m.dictionaryFolder()
+	affs, err := filepath.Glob(filepath.Join(dir, "*.aff"))
+	if err != nil {
+		return nil, err
+	}
+	languages := map[string]bool{}
+	for _, lang := range strings.FieldsFunc(m.config.SpellLanguages, func(r rune) bool { return r == ',' || r == ' ' }) {
+		languages[lang] = true
+	}
+
+	sc := &spellChecker{personal: map[string]bool{}}
+	for _, aff := range affs {
+		lang := strings.TrimSuffix(filepath.Base(aff), ".aff")
+		if len(languages) > 0 && !languages[lang] {
+			continue
+		}
+		dic := strings.TrimSuffix(aff, ".aff") + ".dic"
+		if !isFile(dic) {
+			continue
+		}
+		d, err := loadHunspell(aff, dic)
+		if err != nil {
+			return nil, err
+		}
+		sc.dicts = append(sc.dicts, d)
+	}
+	if len(sc.dicts) == 0 {
+		return nil, errors.New("字典目录中没有可用的 .aff/.dic 字典: " + dir)
+	}
+
+	data, err := os.ReadFile(m.metaPath(personalDictionary))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, word := range strings.Split(string(data), "\n") {
+		if word = strings.TrimSpace(word); word != "" {
+			sc.personal[word] = true
+		}
+	}
+	return sc, nil
+}
+
+// dictionaryFolder 返回字典目录，未设置时使用笔记库的 .nodian/dictionaries
+func (m *MarkdownEditor) dictionaryFolder() string {
+	if m.config.SpellDictionaries != "" {
+		return m.config.SpellDictionaries
+	}
+	return m.metaPath("dictionaries")
+}
+
+// applySpellCheck 按设置重新加载拼写检查，未启用时关闭拼写检查
+func (m *MarkdownEditor) applySpellCheck() error {
+	m.spell = nil
+	var err error
+	if m.config.SpellCheck {
+		m.spell, err = m.loadSpellChecker()
+	}
+	for _, tab := range m.tabs.Items {
+		if source := tabSource(tab); source != nil {
+			source.setSpellChecker(m.spell)
+		}
+	}
+	return err
+}
+
+// check 判断词是否拼写正确，任何一个字典或个人词典中有这个词即可
+func (sc *spellChecker) check(word string) bool {
+	word = strings.ReplaceAll(word, "’", "'")
+	if sc.personal[word] || sc.personal[strings.ToLower(word)] {
+		return true
+	}
+	for _, d := range sc.dicts {
+		if d.check(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest 合并各字典的拼写建议
+func (sc *spellChecker) suggest(word string) []string {
+	word = strings.ReplaceAll(word, "’", "'")
+	var result []string
+	seen := map[string]bool{}
+	for _, d := range sc.dicts {
+		for _, s := range d.suggest(word, maxSuggestions) {
+			if !seen[s] && len(result) < maxSuggestions {
+				seen[s] = true
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
+
+// misspelled 返回一行中拼写错误的词的字节范围
+func (sc *spellChecker) misspelled(line hlLine) []hlSpan {
+	var result []hlSpan
+	for _, span := range line.spans {
+		if !spellKinds[span.kind] {
+			continue
+		}
+		spellWords(line.text, span.start, span.end, func(start, end int) {
+			if !sc.check(line.text[start:end]) {
+				result = append(result, hlSpan{start, end, span.kind})
+			}
+		})
+	}
+	return result
+}
+
+// spellWords 找出 text[from:to] 中需要检查的词。中日韩文字、含数字的词、标签、
+// 文件名和域名、驼峰式标识符以及全部大写的缩写都不检查
+func spellWords(text string, from, to int, fn func(start, end int)) {
+	isWordRune := func(r rune) bool {
+		return unicode.IsLetter(r) && !isCJK(r)
+	}
+	for i := from; i < to; {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isWordRune(r) {
+			i += size
+			continue
+		}
+		start := i
+		end := i
+		for end < to {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if isWordRune(r) {
+				end += size
+				continue
+			}
+			// 词中的撇号，如 don't
+			if r == '\'' || r == '’' {
+				if next, _ := utf8.DecodeRuneInString(text[end+size:]); end+size < to && isWordRune(next) {
+					end += size
+					continue
+				}
+			}
+			break
+		}
+		i = end
+		if shouldCheckWord(text, start, end) {
+			fn(start, end)
+		}
+	}
+}
+
+func shouldCheckWord(text string, start, end int) bool {
+	word := text[start:end]
+	if utf8.RuneCountInString(word) < 2 {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(text[:start])
+	next, size := utf8.DecodeRuneInString(text[end:])
+	if start > 0 && (unicode.IsDigit(prev) || strings.ContainsRune("#@/\\._", prev)) {
+		return false
+	}
+	if end < len(text) && (unicode.IsDigit(next) || strings.ContainsRune("@/\\_", next) || isCJK(next)) {
+		return false
+	}
+	if start > 0 && isCJK(prev) {
+		return false
+	}
+	// 后面是点和字母时是文件名或域名，如 notes.md、example.com
+	if next == '.' {
+		if after, _ := utf8.DecodeRuneInString(text[end+size:]); end+size < len(text) && unicode.IsLetter(after) {
+			return false
+		}
+	}
+
+	// 全部大写的缩写和首字母以外有大写字母的驼峰式标识符
+	allUpper, innerUpper := true, false
+	for i, r := range word {
+		if unicode.IsLower(r) {
+			allUpper = false
+		} else if i > 0 && unicode.IsUpper(r) {
+			innerUpper = true
+		}
+	}
+	return !allUpper && !innerUpper
+}
+
+// isCJK 判断字符是否为中日韩文字，这些文字不做拼写检查
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Bopomofo)
+}
+
+// addToDictionary 把词加入个人词典
+func (m *MarkdownEditor) addToDictionary(word string) error {
+	if m.spell == nil {
+		return nil
+	}
+	m.spell.personal[word] = true
+	m.spell.version++
+	words := make([]string, 0, len(m.spell.personal))
+	for w := range m.spell.personal {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	if err := os.MkdirAll(filepath.Join(m.rootPath, metaDir), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.metaPath(personalDictionary), []byte(strings.Join(words, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	for _, tab := range m.tabs.Items {
+		if source := tabSource(tab); source != nil {
+			source.redraw()
+		}
+	}
+	return nil
+}
+
+// showSpellingSuggestions 在光标处弹出光标所在词的拼写建议
+func (m *MarkdownEditor) showSpellingSuggestions() {
+	_, editor := m.currentFile()
+	if editor == nil {
+		return
+	}
+	if m.spell == nil {
+		dialog.ShowError(errors.New("请先在设置中启用拼写检查"), m.window)
+		return
+	}
+	source := tabSource(m.tabOf(editor))
+	row := editor.CursorRow
+	line := lineAt(editor.Text, row)
+	col := len(string([]rune(line)[:min(editor.CursorColumn, utf8.RuneCountInString(line))]))
+
+	var start, end int
+	found := false
+	spellWords(line, 0, len(line), func(s, e int) {
+		if s <= col && col <= e && !found {
+			start, end, found = s, e, true
+		}
+	})
+	if !found {
+		dialog.ShowInformation("Spelling", "光标处没有需要检查的单词", m.window)
+		return
+	}
+	word := line[start:end]
+	startCol := utf8.RuneCountInString(line[:start])
+	n := utf8.RuneCountInString(word)
+
+	var items []*fyne.MenuItem
+	if m.spell.check(word) {
+		correct := fyne.NewMenuItem("“"+word+"” is spelled correctly", nil)
+		correct.Disabled = true
+		items = append(items, correct)
+	} else {
+		for _, s := range m.spell.suggest(word) {
+			s := s
+			items = append(items, fyne.NewMenuItem(s, func() { editor.replaceRange(row, startCol, n, s) }))
+		}
+		if len(items) == 0 {
+			none := fyne.NewMenuItem("No suggestions", nil)
+			none.Disabled = true
+			items = append(items, none)
+		}
+		items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Add to Dictionary", func() {
+			if err := m.addToDictionary(word); err != nil {
+				dialog.ShowError(err, m.window)
+			}
+		}))
+	}
+
+	// 菜单显示在单词下方
+	textSize, pad, lineHeight := source.metrics()
+	c := fyne.CurrentApp().Driver().CanvasForObject(source.layer)
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(source.layer)
+	x := pad + fyne.MeasureText(line[:start], textSize, editor.TextStyle).Width
+	y := pad + float32(row+1)*lineHeight
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), c, pos.Add(fyne.NewPos(x, y)))
+}
@@ -0,0 +1,220 @@
+package markdown
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 阅读速度：英文等按词计算，中日韩文字按字计算
+const (
+	wordsPerMinute = 200
+	cjkPerMinute   = 400
+)
+
+// textCount 是一段文字的字数统计
+type textCount struct {
+	words int // 词数，中日韩文字每个字算一个词
+	cjk   int // 中日韩文字的字数
+	chars int // 字符数，不含换行
+}
+
+// countText 统计文字的词数和字符数
+func countText(text string) textCount {
+	var c textCount
+	inWord := false
+	for _, r := range text {
+		if r != '\n' && r != '\r' {
+			c.chars++
+		}
+		switch {
+		case isCJK(r):
+			c.words++
+			c.cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || (inWord && (r == '\'' || r == '’')):
+			if !inWord {
+				c.words++
+				inWord = true
+			}
+		default:
+			inWord = false
+		}
+	}
+	return c
+}
+
+// readingMinutes 估算阅读时间，至少为 1 分钟
+func (c textCount) readingMinutes() int {
+	minutes := float64(c.words-c.cjk)/wordsPerMinute + float64(c.cjk)/cjkPerMinute
+	return max(int(minutes+0.5), 1)
+}
+
+func (c textCount) add(o textCount) textCount {
+	return textCount{c.words + o.words, c.cjk + o.cjk, c.chars + o.chars}
+}
+
+// editorStatus 返回状态栏中显示的光标位置、字数和阅读时间，有选区时还显示选中的字数
+func editorStatus(editor *noteEntry) string {
+	c := countText(stripFrontMatter(editor.Text))
+	parts := []string{
+		fmt.Sprintf("Ln %d, Col %d", editor.CursorRow+1, editor.CursorColumn+1),
+		fmt.Sprintf("%d words", c.words),
+		fmt.Sprintf("%d chars", c.chars),
+		fmt.Sprintf("%d min read", c.readingMinutes()),
+	}
+	if selected := editor.SelectedText(); selected != "" {
+		s := countText(selected)
+		parts = append(parts, fmt.Sprintf("Selected: %d words, %d chars", s.words, s.chars))
+	}
+	return strings.Join(parts, "  ·  ")
+}
+
+// statRow 是统计页中的一行，heading 为 true 时是分组标题；path 不为空时点击打开该笔记
+type statRow struct {
+	text    string
+	heading bool
+	mono    bool
+	path    string
+}
+
+// vaultStats 统计笔记库，返回统计页的各行
+func vaultStats(idx *vaultIndex) []statRow {
+	counts := map[string]textCount{}
+	var total textCount
+	for _, path := range idx.paths {
+		info := idx.notes[path]
+		if info.Encrypted {
+			continue
+		}
+		c := countText(stripFrontMatter(info.Content))
+		counts[path] = c
+		total = total.add(c)
+	}
+
+	rows := []statRow{
+		{text: "Overview", heading: true},
+		{text: fmt.Sprintf("%d notes in %d folders", len(idx.paths), len(idx.folders()))},
+		{text: fmt.Sprintf("%d words, %d characters", total.words, total.chars)},
+		{text: fmt.Sprintf("Reading time: about %d min", total.readingMinutes())},
+	}
+
+	// 每个目录中的笔记数和字数，只计算直接位于该目录中的笔记
+	rows = append(rows, statRow{text: "Notes per folder", heading: true})
+	folderNotes := map[string]int{}
+	folderWords := map[string]int{}
+	for _, path := range idx.paths {
+		folder := filepath.ToSlash(filepath.Dir(idx.relPath(path)))
+		folderNotes[folder]++
+		folderWords[folder] += counts[path].words
+	}
+	folders := make([]string, 0, len(folderNotes))
+	for folder := range folderNotes {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+	for _, folder := range folders {
+		name := folder
+		if name == "." {
+			name = "(root)"
+		}
+		rows = append(rows, statRow{text: fmt.Sprintf("%s — %d notes, %d words", name, folderNotes[folder], folderWords[folder])})
+	}
+
+	rows = append(rows, statRow{text: "Growth by month (last modified)", heading: true})
+	rows = append(rows, growthRows(idx)...)
+
+	rows = append(rows, statRow{text: "Largest notes", heading: true})
+	largest := append([]string(nil), idx.paths...)
+	sort.SliceStable(largest, func(i, j int) bool { return counts[largest[i]].words > counts[largest[j]].words })
+	for _, path := range largest[:min(len(largest), 10)] {
+		rows = append(rows, statRow{text: fmt.Sprintf("%s — %d words", idx.relPath(path), counts[path].words), path: path})
+	}
+
+	// 孤立笔记：没有链接到其它笔记，也没有被其它笔记链接
+	rows = append(rows, statRow{text: "Orphan notes", heading: true})
+	linked := map[string]bool{}
+	for _, info := range idx.notes {
+		for _, target := range info.Targets {
+			linked[target] = true
+		}
+	}
+	orphans := 0
+	for _, path := range idx.paths {
+		if len(idx.notes[path].Targets) == 0 && !linked[path] {
+			rows = append(rows, statRow{text: idx.relPath(path), path: path})
+			orphans++
+		}
+	}
+	if orphans == 0 {
+		rows = append(rows, statRow{text: "没有孤立的笔记"})
+	}
+	return rows
+}
+
+// growthRows 按文件修改时间统计每个月的笔记数，以累计数量的条形图显示
+func growthRows(idx *vaultIndex) []statRow {
+	perMonth := map[string]int{}
+	for _, path := range idx.paths {
+		if t := idx.notes[path].ModTime; !t.IsZero() {
+			perMonth[t.Format("2006-01")]++
+		}
+	}
+	months := make([]string, 0, len(perMonth))
+	for month := range perMonth {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+
+	const barWidth = 30
+	var rows []statRow
+	cumulative := 0
+	for _, month := range months {
+		cumulative += perMonth[month]
+		bar := strings.Repeat("█", max(cumulative*barWidth/len(idx.paths), 1))
+		rows = append(rows, statRow{text: fmt.Sprintf("%s %-*s %d (+%d)", month, barWidth, bar, cumulative, perMonth[month]), mono: true})
+	}
+	return rows
+}
+
+// showStatistics 在标签页中显示笔记库的统计
+func (m *MarkdownEditor) showStatistics() {
+	var rows []statRow
+	list := widget.NewList(
+		func() int { return len(rows) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			label := item.(*widget.Label)
+			label.TextStyle = fyne.TextStyle{Bold: rows[id].heading, Monospace: rows[id].mono}
+			label.SetText(rows[id].text)
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		list.UnselectAll()
+		if path := rows[id].path; path != "" {
+			m.openFile(path)
+		}
+	}
+
+	reload := func() {
+		idx, err := m.buildIndex()
+		if err != nil {
+			dialog.ShowError(err, m.window)
+			return
+		}
+		rows = vaultStats(idx)
+		list.Refresh()
+	}
+	reload()
+
+	toolbar := container.NewHBox(widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), reload))
+	m.showViewTab("Statistics", container.NewBorder(toolbar, nil, nil, nil, list))
+}
@@ -0,0 +1,45 @@
+package markdown
+
+import "testing"
+
+func TestCountText(t *testing.T) {
+	tests := []struct {
+		text string
+		want textCount
+	}{
+		{"", textCount{}},
+		{"hello world", textCount{words: 2, chars: 11}},
+		{"don't stop", textCount{words: 2, chars: 10}},
+		{"it’s 2026", textCount{words: 2, chars: 9}},
+		{"a-b c_d", textCount{words: 4, chars: 7}},
+		{"'quoted'", textCount{words: 1, chars: 8}},
+		{"line\r\nbreak\n", textCount{words: 2, chars: 9}},
+		{"中文字数", textCount{words: 4, cjk: 4, chars: 4}},
+		{"Go语言 test", textCount{words: 4, cjk: 2, chars: 9}},
+		{"日本語とカナ", textCount{words: 6, cjk: 6, chars: 6}},
+		{"# Title\n\n- item", textCount{words: 2, chars: 13}},
+	}
+	for _, tt := range tests {
+		if got := countText(tt.text); got != tt.want {
+			t.Errorf("countText(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReadingMinutes(t *testing.T) {
+	tests := []struct {
+		count textCount
+		want  int
+	}{
+		{textCount{}, 1},
+		{textCount{words: 100}, 1},
+		{textCount{words: 500}, 3},
+		{textCount{words: 800, cjk: 800}, 2},
+		{textCount{words: 1400, cjk: 1200}, 4},
+	}
+	for _, tt := range tests {
+		if got := tt.count.readingMinutes(); got != tt.want {
+			t.Errorf("readingMinutes(%+v) = %d, want %d", tt.count, got, tt.want)
+		}
+	}
+}
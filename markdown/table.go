@@ -0,0 +1,503 @@
+package markdown
+
+import (
+	"encoding/csv"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+var (
+	errNotInTable   = errors.New("光标不在表格中")
+	errNotDelimited = errors.New("剪贴板中没有 CSV 或 TSV 格式的数据")
+
+	tableDelimiterPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// tableAlign 是表格列的对齐方式
+type tableAlign int
+
+const (
+	alignNone tableAlign = iota
+	alignLeft
+	alignCenter
+	alignRight
+)
+
+// mdTable 是笔记中的一个管道表格
+type mdTable struct {
+	start  int // 表格第一行在笔记中的行号
+	end    int // 表格最后一行的行号
+	indent string
+	align  []tableAlign
+	rows   [][]string // 第一行是表头，不包括分隔行
+}
+
+// parseTable 解析包含第 row 行的表格，第 row 行不在表格中时返回 nil
+func parseTable(lines []string, row int) *mdTable {
+	if row >= len(lines) || !strings.Contains(lines[row], "|") {
+		return nil
+	}
+	start, end := row, row
+	for start > 0 && strings.Contains(lines[start-1], "|") {
+		start--
+	}
+	for end+1 < len(lines) && strings.Contains(lines[end+1], "|") {
+		end++
+	}
+	// 表头之后必须是分隔行；笔记中相邻的两个表格之间至少有一行不含 |
+	for start+1 <= end && !tableDelimiterPattern.MatchString(lines[start+1]) {
+		start++
+	}
+	if start >= end || start > row {
+		return nil
+	}
+
+	line := lines[start]
+	t := &mdTable{start: start, end: end, indent: line[:len(line)-len(strings.TrimLeft(line, " \t"))]}
+	for _, cell := range splitTableRow(lines[start+1]) {
+		t.align = append(t.align, parseAlign(cell))
+	}
+	for i := start; i <= end; i++ {
+		if i != start+1 {
+			t.rows = append(t.rows, splitTableRow(lines[i]))
+		}
+	}
+	t.normalize()
+	return t
+}
+
+func parseAlign(cell string) tableAlign {
+	left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+	switch {
+	case left && right:
+		return alignCenter
+	case left:
+		return alignLeft
+	case right:
+		return alignRight
+	}
+	return alignNone
+}
+
+// splitTableRow 按未转义的 | 拆分表格行，去掉首尾的 | 和单元格两端的空白
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '|':
+			cells = append(cells, strings.TrimSpace(line[start:i]))
+			start = i + 1
+		}
+	}
+	return append(cells, strings.TrimSpace(line[start:]))
+}
+
+// normalize 让每一行的单元格数与列数相同
+func (t *mdTable) normalize() {
+	cols := len(t.align)
+	for _, row := range t.rows {
+		cols = max(cols, len(row))
+	}
+	for len(t.align) < cols {
+		t.align = append(t.align, alignNone)
+	}
+	for i, row := range t.rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		t.rows[i] = row
+	}
+}
+
+func (t *mdTable) columns() int {
+	return len(t.align)
+}
+
+// format 返回对齐后的表格各行
+func (t *mdTable) format() []string {
+	widths := make([]int, t.columns())
+	for c := range widths {
+		widths[c] = 3
+		for _, row := range t.rows {
+			widths[c] = max(widths[c], displayWidth(row[c]))
+		}
+	}
+
+	lines := make([]string, 0, len(t.rows)+1)
+	for i, row := range t.rows {
+		cells := make([]string, len(row))
+		for c, cell := range row {
+			cells[c] = padCell(cell, widths[c], t.align[c])
+		}
+		lines = append(lines, t.indent+"| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			for c, w := range widths {
+				cells[c] = delimiterCell(w, t.align[c])
+			}
+			lines = append(lines, t.indent+"| "+strings.Join(cells, " | ")+" |")
+		}
+	}
+	return lines
+}
+
+func padCell(cell string, w int, align tableAlign) string {
+	pad := w - displayWidth(cell)
+	switch align {
+	case alignRight:
+		return strings.Repeat(" ", pad) + cell
+	case alignCenter:
+		return strings.Repeat(" ", pad/2) + cell + strings.Repeat(" ", pad-pad/2)
+	}
+	return cell + strings.Repeat(" ", pad)
+}
+
+func delimiterCell(w int, align tableAlign) string {
+	switch align {
+	case alignLeft:
+		return ":" + strings.Repeat("-", w-1)
+	case alignCenter:
+		return ":" + strings.Repeat("-", w-2) + ":"
+	case alignRight:
+		return strings.Repeat("-", w-1) + ":"
+	}
+	return strings.Repeat("-", w)
+}
+
+// lineRow 把笔记中的行号转换为表格的行号（0 是表头），分隔行返回 0
+func (t *mdTable) lineRow(line int) int {
+	if line <= t.start+1 {
+		return 0
+	}
+	return line - t.start - 1
+}
+
+// rowLine 把表格的行号转换为笔记中的行号
+func (t *mdTable) rowLine(row int) int {
+	if row == 0 {
+		return t.start
+	}
+	return t.start + row + 1
+}
+
+// cellAt 返回表格行中第 col 个字符所在的单元格
+func cellAt(line string, col int) int {
+	runes := []rune(line)
+	cell := 0
+	if strings.HasPrefix(strings.TrimSpace(line), "|") {
+		cell = -1
+	}
+	for i := 0; i < min(col, len(runes)); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case '|':
+			cell++
+		}
+	}
+	return max(cell, 0)
+}
+
+// cellColumn 返回格式化后的表格行中第 cell 个单元格内容开始的列
+func cellColumn(line string, cell int) int {
+	runes := []rune(line)
+	pipes := 0
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case '|':
+			pipes++
+			if pipes == cell+1 {
+				j := i + 1
+				for j < len(runes) && runes[j] == ' ' {
+					j++
+				}
+				if j < len(runes) && runes[j] == '|' {
+					// 空单元格，光标放在 | 之后的第一个空格后
+					return min(i+2, j)
+				}
+				return j
+			}
+		}
+	}
+	return len(runes)
+}
+
+// currentTable 返回光标所在的表格以及光标所在的表格行和单元格
+func (e *noteEntry) currentTable() (t *mdTable, row, cell int) {
+	lines := strings.Split(e.Text, "\n")
+	t = parseTable(lines, e.CursorRow)
+	if t == nil {
+		return nil, 0, 0
+	}
+	cell = min(cellAt(lines[e.CursorRow], e.CursorColumn), t.columns()-1)
+	return t, t.lineRow(e.CursorRow), cell
+}
+
+// writeTable 用格式化后的表格替换原来的表格行，并把光标移到第 row 行的第 cell 个单元格
+func (e *noteEntry) writeTable(t *mdTable, row, cell int) {
+	lines := strings.Split(e.Text, "\n")
+	old := strings.Join(lines[t.start:t.end+1], "\n")
+	formatted := t.format()
+	if text := strings.Join(formatted, "\n"); text != old {
+		e.replaceRange(t.start, 0, utf8.RuneCountInString(old), text)
+	}
+	row = min(max(row, 0), len(t.rows)-1)
+	line := formatted[t.rowLine(row)-t.start]
+	e.setCursor(t.rowLine(row), cellColumn(line, min(max(cell, 0), t.columns()-1)))
+}
+
+// tableTab 在表格中按 Tab 移到下一个单元格，在最后一个单元格中按 Tab 时添加新行；
+// back 为 true 时（Shift+Tab）移到上一个单元格。光标不在表格中时返回 false
+func (e *noteEntry) tableTab(back bool) bool {
+	t, row, cell := e.currentTable()
+	if t == nil {
+		return false
+	}
+	if back {
+		cell--
+		if cell < 0 && row > 0 {
+			row, cell = row-1, t.columns()-1
+		}
+	} else {
+		cell++
+		if cell == t.columns() {
+			row, cell = row+1, 0
+			if row == len(t.rows) {
+				t.rows = append(t.rows, make([]string, t.columns()))
+			}
+		}
+	}
+	e.writeTable(t, row, cell)
+	return true
+}
+
+// editTable 修改光标所在的表格。edit 返回修改后光标所在的行和单元格
+func (e *noteEntry) editTable(edit func(t *mdTable, row, cell int) (int, int)) error {
+	t, row, cell := e.currentTable()
+	if t == nil {
+		return errNotInTable
+	}
+	row, cell = edit(t, row, cell)
+	e.writeTable(t, row, cell)
+	return nil
+}
+
+// insertTableRow 在当前行的上方或下方插入空行，表头上方不能插入
+func insertTableRow(below bool) func(t *mdTable, row, cell int) (int, int) {
+	return func(t *mdTable, row, cell int) (int, int) {
+		at := row
+		if below || row == 0 {
+			at = row + 1
+		}
+		t.rows = append(t.rows[:at], append([][]string{make([]string, t.columns())}, t.rows[at:]...)...)
+		return at, cell
+	}
+}
+
+// deleteTableRow 删除当前行，表头不能删除
+func deleteTableRow(t *mdTable, row, cell int) (int, int) {
+	if row == 0 {
+		return row, cell
+	}
+	t.rows = append(t.rows[:row], t.rows[row+1:]...)
+	return min(row, len(t.rows)-1), cell
+}
+
+// insertTableColumn 在当前列的左侧或右侧插入空列
+func insertTableColumn(right bool) func(t *mdTable, row, cell int) (int, int) {
+	return func(t *mdTable, row, cell int) (int, int) {
+		at := cell
+		if right {
+			at++
+		}
+		for i, r := range t.rows {
+			t.rows[i] = append(r[:at], append([]string{""}, r[at:]...)...)
+		}
+		t.align = append(t.align[:at], append([]tableAlign{alignNone}, t.align[at:]...)...)
+		return row, at
+	}
+}
+
+// deleteTableColumn 删除当前列，只剩一列时不删除
+func deleteTableColumn(t *mdTable, row, cell int) (int, int) {
+	if t.columns() == 1 {
+		return row, cell
+	}
+	for i, r := range t.rows {
+		t.rows[i] = append(r[:cell], r[cell+1:]...)
+	}
+	t.align = append(t.align[:cell], t.align[cell+1:]...)
+	return row, min(cell, t.columns()-1)
+}
+
+// alignTableColumn 设置当前列的对齐方式
+func alignTableColumn(align tableAlign) func(t *mdTable, row, cell int) (int, int) {
+	return func(t *mdTable, row, cell int) (int, int) {
+		t.align[cell] = align
+		return row, cell
+	}
+}
+
+// sortTable 按当前列排序表头以外的行，两个单元格都是数字时按数值比较
+func sortTable(desc bool) func(t *mdTable, row, cell int) (int, int) {
+	return func(t *mdTable, row, cell int) (int, int) {
+		body := t.rows[1:]
+		sort.SliceStable(body, func(i, j int) bool {
+			a, b := body[i][cell], body[j][cell]
+			if desc {
+				a, b = b, a
+			}
+			x, errX := strconv.ParseFloat(strings.ReplaceAll(a, ",", ""), 64)
+			y, errY := strconv.ParseFloat(strings.ReplaceAll(b, ",", ""), 64)
+			if errX == nil && errY == nil {
+				return x < y
+			}
+			return strings.ToLower(a) < strings.ToLower(b)
+		})
+		return row, cell
+	}
+}
+
+// delimitedTable 把 CSV 或 TSV 文本转换为表格，第一行作为表头
+func delimitedTable(text string) (*mdTable, error) {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	first, _, _ := strings.Cut(text, "\n")
+	r := csv.NewReader(strings.NewReader(text))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	switch {
+	case strings.Contains(first, "\t"):
+		r.Comma = '\t'
+	case strings.Count(first, ";") > strings.Count(first, ","):
+		r.Comma = ';'
+	}
+	records, err := r.ReadAll()
+	if err != nil || len(records) == 0 || (len(records) < 2 && len(records[0]) < 2) {
+		return nil, errNotDelimited
+	}
+
+	t := &mdTable{}
+	for _, record := range records {
+		row := make([]string, len(record))
+		for i, field := range record {
+			field = strings.TrimSpace(strings.ReplaceAll(field, "\n", "<br>"))
+			row[i] = strings.ReplaceAll(field, "|", `\|`)
+		}
+		t.rows = append(t.rows, row)
+	}
+	t.normalize()
+	return t, nil
+}
+
+// isTSV 判断粘贴的文字是否是从电子表格复制的制表符分隔数据：至少两行，每行的制表符数量相同
+func isTSV(text string) bool {
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	if len(lines) < 2 {
+		return false
+	}
+	tabs := strings.Count(lines[0], "\t")
+	indented := 0
+	for _, line := range lines {
+		if strings.Count(line, "\t") != tabs {
+			return false
+		}
+		if strings.HasPrefix(line, "\t") {
+			indented++
+		}
+	}
+	// 每行都以制表符开头的多半是缩进的代码
+	return tabs > 0 && indented < len(lines)
+}
+
+// insertTable 在光标处插入表格，表格前后各占独立的行
+func (e *noteEntry) insertTable(t *mdTable) {
+	text := strings.Join(t.format(), "\n") + "\n"
+	line := []rune(lineAt(e.Text, e.CursorRow))
+	if strings.TrimSpace(string(line[:min(e.CursorColumn, len(line))])) != "" {
+		text = "\n\n" + text
+	}
+	e.insertText(text)
+}
+
+// pasteTSV 把从电子表格粘贴的数据转换为表格
+func (m *MarkdownEditor) pasteTSV(editor *noteEntry) bool {
+	text := m.window.Clipboard().Content()
+	if !isTSV(text) {
+		return false
+	}
+	t, err := delimitedTable(text)
+	if err != nil {
+		return false
+	}
+	editor.insertTable(t)
+	return true
+}
+
+// pasteTable 把剪贴板中的 CSV 或 TSV 数据作为表格插入
+func (m *MarkdownEditor) pasteTable() {
+	_, editor := m.currentFile()
+	if editor == nil {
+		return
+	}
+	t, err := delimitedTable(m.window.Clipboard().Content())
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	editor.insertTable(t)
+}
+
+// editCurrentTable 修改当前编辑器中光标所在的表格
+func (m *MarkdownEditor) editCurrentTable(edit func(t *mdTable, row, cell int) (int, int)) {
+	_, editor := m.currentFile()
+	if editor == nil {
+		return
+	}
+	if err := editor.editTable(edit); err != nil {
+		dialog.ShowError(err, m.window)
+	}
+}
+
+// tableMenu 返回工具菜单中的表格子菜单
+func (m *MarkdownEditor) tableMenu() *fyne.Menu {
+	item := func(label string, edit func(t *mdTable, row, cell int) (int, int)) *fyne.MenuItem {
+		return fyne.NewMenuItem(label, func() { m.editCurrentTable(edit) })
+	}
+	keep := func(t *mdTable, row, cell int) (int, int) { return row, cell }
+	return fyne.NewMenu("",
+		item("Format Table", keep),
+		fyne.NewMenuItemSeparator(),
+		item("Insert Row Above", insertTableRow(false)),
+		item("Insert Row Below", insertTableRow(true)),
+		item("Delete Row", deleteTableRow),
+		item("Insert Column Left", insertTableColumn(false)),
+		item("Insert Column Right", insertTableColumn(true)),
+		item("Delete Column", deleteTableColumn),
+		fyne.NewMenuItemSeparator(),
+		item("Align Left", alignTableColumn(alignLeft)),
+		item("Align Center", alignTableColumn(alignCenter)),
+		item("Align Right", alignTableColumn(alignRight)),
+		item("Clear Alignment", alignTableColumn(alignNone)),
+		fyne.NewMenuItemSeparator(),
+		item("Sort Ascending", sortTable(false)),
+		item("Sort Descending", sortTable(true)),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Paste CSV/TSV as Table", m.pasteTable),
+	)
+}
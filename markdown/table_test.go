@@ -0,0 +1,162 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines string
+		row   int
+		want  *mdTable
+	}{
+		{
+			name:  "basic",
+			lines: "intro\n| a | b |\n|---|:-:|\n| 1 | 2 |\nafter",
+			row:   3,
+			want: &mdTable{start: 1, end: 3, align: []tableAlign{alignNone, alignCenter},
+				rows: [][]string{{"a", "b"}, {"1", "2"}}},
+		},
+		{
+			name:  "no outer pipes and short rows",
+			lines: "a | b | c\n:-- | --: | ---\n1 |",
+			row:   0,
+			want: &mdTable{start: 0, end: 2, align: []tableAlign{alignLeft, alignRight, alignNone},
+				rows: [][]string{{"a", "b", "c"}, {"1", "", ""}}},
+		},
+		{
+			name:  "indented with escaped pipe",
+			lines: "  | x | y |\n  | - | - |\n  | a \\| b | c |",
+			row:   2,
+			want: &mdTable{start: 0, end: 2, indent: "  ", align: []tableAlign{alignNone, alignNone},
+				rows: [][]string{{"x", "y"}, {`a \| b`, "c"}}},
+		},
+		{
+			name:  "skips pipe lines before the header",
+			lines: "a|b\n| h |\n| --- |\n| v |",
+			row:   3,
+			want:  &mdTable{start: 1, end: 3, align: []tableAlign{alignNone}, rows: [][]string{{"h"}, {"v"}}},
+		},
+		{name: "no delimiter", lines: "| a | b |\n| 1 | 2 |", row: 0},
+		{name: "row outside", lines: "text\n| a |\n| - |", row: 0},
+		{name: "row past end", lines: "| a |\n| - |", row: 5},
+	}
+	for _, tt := range tests {
+		got := parseTable(strings.Split(tt.lines, "\n"), tt.row)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseTable() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTableFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		table mdTable
+		want  []string
+	}{
+		{
+			name:  "pads to widest cell",
+			table: mdTable{align: []tableAlign{alignNone, alignNone}, rows: [][]string{{"a", "long header"}, {"wide cell", "x"}}},
+			want: []string{
+				"| a         | long header |",
+				"| --------- | ----------- |",
+				"| wide cell | x           |",
+			},
+		},
+		{
+			name:  "alignment",
+			table: mdTable{align: []tableAlign{alignLeft, alignCenter, alignRight}, rows: [][]string{{"l", "c", "r"}, {"1", "22", "333"}}},
+			want: []string{
+				"| l   |  c  |   r |",
+				"| :-- | :-: | --: |",
+				"| 1   | 22  | 333 |",
+			},
+		},
+		{
+			name:  "wide characters and indent",
+			table: mdTable{indent: "  ", align: []tableAlign{alignNone}, rows: [][]string{{"名称"}, {"a"}}},
+			want: []string{
+				"  | 名称 |",
+				"  | ---- |",
+				"  | a    |",
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.table.format(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: format() =\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestTableRoundTrip(t *testing.T) {
+	// 格式化后再次解析和格式化，结果不应变化
+	lines := strings.Split("|name|qty|\n|:--|--:|\n|apple|3|\n|pear \\| fig|12|", "\n")
+	first := parseTable(lines, 0).format()
+	second := parseTable(first, 0).format()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("format is not stable:\n%s\n---\n%s", strings.Join(first, "\n"), strings.Join(second, "\n"))
+	}
+}
+
+func TestTableEdits(t *testing.T) {
+	newTable := func() *mdTable {
+		return &mdTable{align: []tableAlign{alignNone, alignNone}, rows: [][]string{{"h1", "h2"}, {"b", "10"}, {"a", "9"}}}
+	}
+	tests := []struct {
+		name     string
+		edit     func(t *mdTable, row, cell int) (int, int)
+		row      int
+		cell     int
+		rows     [][]string
+		wantRow  int
+		wantCell int
+	}{
+		{"insert row below", insertTableRow(true), 1, 0, [][]string{{"h1", "h2"}, {"b", "10"}, {"", ""}, {"a", "9"}}, 2, 0},
+		{"insert row above header", insertTableRow(false), 0, 1, [][]string{{"h1", "h2"}, {"", ""}, {"b", "10"}, {"a", "9"}}, 1, 1},
+		{"delete row", deleteTableRow, 2, 0, [][]string{{"h1", "h2"}, {"b", "10"}}, 1, 0},
+		{"delete header", deleteTableRow, 0, 0, [][]string{{"h1", "h2"}, {"b", "10"}, {"a", "9"}}, 0, 0},
+		{"insert column right", insertTableColumn(true), 1, 0, [][]string{{"h1", "", "h2"}, {"b", "", "10"}, {"a", "", "9"}}, 1, 1},
+		{"delete column", deleteTableColumn, 1, 1, [][]string{{"h1"}, {"b"}, {"a"}}, 1, 0},
+		{"sort numbers", sortTable(false), 1, 1, [][]string{{"h1", "h2"}, {"a", "9"}, {"b", "10"}}, 1, 1},
+		{"sort text desc", sortTable(true), 1, 0, [][]string{{"h1", "h2"}, {"b", "10"}, {"a", "9"}}, 1, 0},
+	}
+	for _, tt := range tests {
+		table := newTable()
+		row, cell := tt.edit(table, tt.row, tt.cell)
+		if !reflect.DeepEqual(table.rows, tt.rows) || row != tt.wantRow || cell != tt.wantCell {
+			t.Errorf("%s: rows = %q at (%d, %d), want %q at (%d, %d)", tt.name, table.rows, row, cell, tt.rows, tt.wantRow, tt.wantCell)
+		}
+		if len(table.align) != len(table.rows[0]) {
+			t.Errorf("%s: %d alignments for %d columns", tt.name, len(table.align), len(table.rows[0]))
+		}
+	}
+}
+
+func TestDelimitedTable(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		rows [][]string
+		err  error
+	}{
+		{"tsv", "a\tb\n1\t2\n", [][]string{{"a", "b"}, {"1", "2"}}, nil},
+		{"csv with quotes", "name,note\r\n\"x, y\",\"a|b\"", [][]string{{"name", "note"}, {"x, y", `a\|b`}}, nil},
+		{"semicolons", "a;b;c\n1;2", [][]string{{"a", "b", "c"}, {"1", "2", ""}}, nil},
+		{"single value", "hello", nil, errNotDelimited},
+	}
+	for _, tt := range tests {
+		table, err := delimitedTable(tt.text)
+		if err != tt.err {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(table.rows, tt.rows) {
+			t.Errorf("%s: rows = %q, want %q", tt.name, table.rows, tt.rows)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	// 任务行可以位于引用块中，前面带有 > 标记
+	taskPattern     = regexp.MustCompile(`^((?:\s*>)*\s*(?:[-*+]|\d+[.)])\s+)\[([ xX])\](\s|$)(.*)$`)
+	taskDuePattern  = regexp.MustCompile(`(?:📅\s*|\bdue:)(\d{4}-\d{2}-\d{2})`)
+	taskPrioPattern = regexp.MustCompile(`(?i)\bpriority:(highest|high|medium|low|lowest)\b`)
+	tagPattern      = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+)
+
+// 任务优先级，数值越大越优先
+const (
+	priorityLowest = iota - 2
+	priorityLow
+	priorityNone
+	priorityMedium
+	priorityHigh
+	priorityHighest
+)
+
+var priorityEmoji = map[string]int{
+	"⏬": priorityLowest,
+	"🔽": priorityLow,
+	"🔼": priorityMedium,
+	"⏫": priorityHigh,
+	"🔺": priorityHighest,
+}
+
+var priorityNames = map[string]int{
+	"lowest":  priorityLowest,
+	"low":     priorityLow,
+	"medium":  priorityMedium,
+	"high":    priorityHigh,
+	"highest": priorityHighest,
+}
+
+// taskItem 是笔记中的一个 GFM 任务项
+type taskItem struct {
+	Path     string
+	Line     int
+	Text     string // 复选框之后的文本
+	Done     bool
+	Due      time.Time // 没有截止日期时为零值
+	Priority int
+	Tags     []string
+}
+
+// parseTask 解析一行文本，不是任务项时返回 false
+func parseTask(line string) (taskItem, bool) {
+	match := taskPattern.FindStringSubmatch(line)
+	if match == nil {
+		return taskItem{}, false
+	}
+
+	task := taskItem{
+		Text: strings.TrimSpace(match[4]),
+		Done: match[2] != " ",
+	}
+	if due := taskDuePattern.FindStringSubmatch(task.Text); due != nil {
+		if t, err := time.ParseInLocation(dateLayout, due[1], time.Local); err == nil {
+			task.Due = t
+		}
+	}
+	for emoji, p := range priorityEmoji {
+		if strings.Contains(task.Text, emoji) {
+			task.Priority = p
+		}
+	}
+	if prio := taskPrioPattern.FindStringSubmatch(task.Text); prio != nil {
+		task.Priority = priorityNames[strings.ToLower(prio[1])]
+	}
+	task.Tags = extractTags(task.Text)
+	return task, true
+}
+
+// extractTags 提取文本中的 #标签，纯数字（如 #1）不视为标签
+func extractTags(text string) []string {
+	var tags []string
+	for _, match := range tagPattern.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, match[1])
+	}
+	return tags
+}
+
+// toggleTaskLine 切换任务行的完成状态
+func toggleTaskLine(line string) (string, bool) {
+	match := taskPattern.FindStringSubmatchIndex(line)
+	if match == nil {
+		return line, false
+	}
+	mark := "x"
+	if line[match[4]:match[5]] != " " {
+		mark = " "
+	}
+	return line[:match[4]] + mark + line[match[5]:], true
+}
+
+// scanTasks 扫描笔记库中所有笔记的任务项
+func (m *MarkdownEditor) scanTasks() ([]taskItem, error) {
+	var tasks []taskItem
+	err := walkNotes(m.rootPath, func(path string) error {
+		content, err := m.noteContent(path)
+		if errors.Is(err, errEncryptedNote) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		forEachTextLine(content, func(i int, line string) {
+			if task, ok := parseTask(line); ok {
+				task.Path = path
+				task.Line = i
+				tasks = append(tasks, task)
+			}
+		})
+		return nil
+	})
+	return tasks, err
+}
+
+// noteContent 返回笔记的内容，已打开的笔记以编辑器中的内容为准。
+// 加密笔记（包括已解密打开的）返回 errEncryptedNote，索引、导出和发布都不会读到其中的内容
+func (m *MarkdownEditor) noteContent(path string) (string, error) {
+	if editor, ok := m.openFiles[path]; ok {
+		if editor.key != nil {
+			return "", errEncryptedNote
+		}
+		return editor.Text, nil
+	}
+	data, err := os.ReadFile(path)
+	if err == nil && isEncrypted(data) {
+		return "", errEncryptedNote
+	}
+	return string(data), err
+}
+
+// updateNoteLine 修改笔记中的一行。笔记已打开时修改编辑器内容并保持光标位置，
+// 若修改前没有未保存的内容则同时写回文件；未打开时直接修改文件
+func (m *MarkdownEditor) updateNoteLine(path string, line int, update func(string) (string, bool)) error {
+	editor, open := m.openFiles[path]
+	if !open {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(string(data), "\n")
+		if line >= len(lines) {
+			return errors.New("笔记内容已发生变化")
+		}
+		newLine, ok := update(strings.TrimSuffix(lines[line], "\r"))
+		if !ok {
+			return errors.New("笔记内容已发生变化")
+		}
+		if strings.HasSuffix(lines[line], "\r") {
+			newLine += "\r"
+		}
+		lines[line] = newLine
+		return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+	}
+
+	dirty := m.isDirty(editor)
+	if err := editNoteLine(editor, line, update); err != nil {
+		return err
+	}
+	if !dirty {
+		return m.saveFile(path, editor)
+	}
+	return nil
+}
+
+// editNoteLine 在编辑器中修改一行，只替换发生变化的部分，光标保持在原来的文字处
+func editNoteLine(editor *noteEntry, line int, update func(string) (string, bool)) error {
+	lines := strings.Split(editor.Text, "\n")
+	if line >= len(lines) {
+		return errors.New("笔记内容已发生变化")
+	}
+	oldLine := []rune(lines[line])
+	updated, ok := update(lines[line])
+	if !ok {
+		return errors.New("笔记内容已发生变化")
+	}
+	newLine := []rune(updated)
+
+	prefix := 0
+	for prefix < len(oldLine) && prefix < len(newLine) && oldLine[prefix] == newLine[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLine)-prefix && suffix < len(newLine)-prefix &&
+		oldLine[len(oldLine)-1-suffix] == newLine[len(newLine)-1-suffix] {
+		suffix++
+	}
+	removed := len(oldLine) - prefix - suffix
+	inserted := newLine[prefix : len(newLine)-suffix]
+
+	row, col := editor.CursorRow, editor.CursorColumn
+	editor.replaceRange(line, prefix, removed, string(inserted))
+	if row == line && col > prefix {
+		col += len(inserted) - removed
+		if col < prefix {
+			col = prefix
+		}
+	}
+	editor.CursorRow, editor.CursorColumn = row, col
+	editor.Refresh()
+	return nil
+}
+
+// openFileAt 打开笔记并把光标移动到指定行
+func (m *MarkdownEditor) openFileAt(path string, line int) {
+	m.openFile(path)
+	editor, ok := m.openFiles[path]
+	if !ok {
+		return
+	}
+	editor.clearSelection()
+	editor.CursorRow, editor.CursorColumn = line, 0
+	editor.Refresh()
+	m.window.Canvas().Focus(editor)
+}
+
+// 任务视图的分组方式
+const (
+	groupByNote = "Note"
+	groupByDue  = "Due date"
+	groupByTag  = "Tag"
+)
+
+// taskRow 是任务视图列表中的一行，task 为 nil 时表示分组标题
+type taskRow struct {
+	title string
+	task  *taskItem
+}
+
+// showTasks 打开汇总所有任务的标签页
+func (m *MarkdownEditor) showTasks() {
+	var rows []taskRow
+	groupBy := groupByNote
+	showDone := false
+
+	list := widget.NewList(
+		func() int { return len(rows) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewCheck("", nil), nil, widget.NewLabel(""))
+		},
+		nil,
+	)
+	status := widget.NewLabel("")
+
+	var reload func()
+	list.UpdateItem = func(id widget.ListItemID, item fyne.CanvasObject) {
+		row := rows[id]
+		c := item.(*fyne.Container)
+		label := c.Objects[0].(*widget.Label)
+		check := c.Objects[1].(*widget.Check)
+
+		if row.task == nil {
+			check.Hide()
+			label.TextStyle = fyne.TextStyle{Bold: true}
+			label.SetText(row.title)
+			return
+		}
+
+		task := row.task
+		check.Show()
+		check.OnChanged = nil
+		check.SetChecked(task.Done)
+		check.OnChanged = func(bool) {
+			err := m.updateNoteLine(task.Path, task.Line, func(line string) (string, bool) {
+				if t, ok := parseTask(line); !ok || t.Text != task.Text {
+					return line, false
+				}
+				return toggleTaskLine(line)
+			})
+			if err != nil {
+				dialog.ShowError(err, m.window)
+			}
+			reload()
+		}
+		label.TextStyle = fyne.TextStyle{}
+		label.SetText(m.taskLabel(task, groupBy))
+	}
+	list.OnSelected = func(id widget.ListItemID) {
+		list.UnselectAll()
+		if task := rows[id].task; task != nil {
+			m.openFileAt(task.Path, task.Line)
+		}
+	}
+
+	reload = func() {
+		tasks, err := m.scanTasks()
+		if err != nil {
+			dialog.ShowError(err, m.window)
+		}
+		if !showDone {
+			open := tasks[:0]
+			for _, t := range tasks {
+				if !t.Done {
+					open = append(open, t)
+				}
+			}
+			tasks = open
+		}
+		rows = m.groupTasks(tasks, groupBy)
+		status.SetText(fmt.Sprintf("%d tasks", len(tasks)))
+		list.Refresh()
+	}
+
+	groupSelect := widget.NewSelect([]string{groupByNote, groupByDue, groupByTag}, func(s string) {
+		groupBy = s
+		reload()
+	})
+	groupSelect.SetSelected(groupBy)
+	doneCheck := widget.NewCheck("Show completed", func(b bool) {
+		showDone = b
+		reload()
+	})
+	toolbar := container.NewHBox(
+		widget.NewLabel("Group by"), groupSelect, doneCheck,
+		widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() { reload() }),
+		status,
+	)
+
+	reload()
+	m.showViewTab("Tasks", container.NewBorder(toolbar, nil, nil, nil, list))
+}
+
+func (m *MarkdownEditor) taskLabel(task *taskItem, groupBy string) string {
+	label := task.Text
+	if groupBy != groupByNote {
+		rel, _ := filepath.Rel(m.rootPath, task.Path)
+		label += "  — " + strings.TrimSuffix(filepath.ToSlash(rel), noteExt)
+	}
+	return label
+}
+
+// groupTasks 按分组方式整理任务，组内按优先级和截止日期排序
+func (m *MarkdownEditor) groupTasks(tasks []taskItem, groupBy string) []taskRow {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.Due.IsZero() != b.Due.IsZero() {
+			return !a.Due.IsZero()
+		}
+		return a.Due.Before(b.Due)
+	})
+
+	groups := map[string][]*taskItem{}
+	var keys []string
+	add := func(key string, t *taskItem) {
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], t)
+	}
+
+	today := time.Now().Format(dateLayout)
+	for i := range tasks {
+		t := &tasks[i]
+		switch groupBy {
+		case groupByDue:
+			if t.Due.IsZero() {
+				add("~", t)
+			} else {
+				add(t.Due.Format(dateLayout), t)
+			}
+		case groupByTag:
+			if len(t.Tags) == 0 {
+				add("~", t)
+			}
+			for _, tag := range t.Tags {
+				add("#"+tag, t)
+			}
+		default:
+			rel, _ := filepath.Rel(m.rootPath, t.Path)
+			add(strings.TrimSuffix(filepath.ToSlash(rel), noteExt), t)
+		}
+	}
+
+	// "~" 排在所有日期和标签之后，用于没有截止日期或标签的任务
+	sort.Strings(keys)
+	var rows []taskRow
+	for _, key := range keys {
+		title := key
+		switch {
+		case key == "~" && groupBy == groupByDue:
+			title = "No due date"
+		case key == "~":
+			title = "No tag"
+		case groupBy == groupByDue && key < today:
+			title = key + " (overdue)"
+		case groupBy == groupByDue && key == today:
+			title = key + " (today)"
+		}
+		rows = append(rows, taskRow{title: title})
+		for _, t := range groups[key] {
+			rows = append(rows, taskRow{task: t})
+		}
+	}
+	return rows
+}
+
+// showViewTab 在标签页中显示一个非笔记视图，同名视图已打开时替换其内容
+func (m *MarkdownEditor) showViewTab(title string, content fyne.CanvasObject) {
+	for _, tab := range m.tabs.Items {
+		if tab.Text == title {
+			if tabEditor(tab) == nil {
+				tab.Content = content
+				m.tabs.Select(tab)
+				m.tabs.Refresh()
+				return
+			}
+		}
+	}
+	tab := container.NewTabItem(title, content)
+	m.tabs.Append(tab)
+	m.tabs.Select(tab)
+}
@@ -0,0 +1,68 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTask(t *testing.T) {
+	due := func(s string) time.Time {
+		d, _ := time.ParseInLocation(dateLayout, s, time.Local)
+		return d
+	}
+	tests := []struct {
+		line string
+		want taskItem
+		ok   bool
+	}{
+		{"- [ ] buy milk", taskItem{Text: "buy milk"}, true},
+		{"* [x] done", taskItem{Text: "done", Done: true}, true},
+		{"  + [X] nested", taskItem{Text: "nested", Done: true}, true},
+		{"1. [ ] ordered", taskItem{Text: "ordered"}, true},
+		{"2) [ ] paren", taskItem{Text: "paren"}, true},
+		{"> - [ ] quoted", taskItem{Text: "quoted"}, true},
+		{"> > * [x] nested quote", taskItem{Text: "nested quote", Done: true}, true},
+		{"- [ ]", taskItem{}, true},
+		{"- [ ] report 📅 2026-03-01", taskItem{Text: "report 📅 2026-03-01", Due: due("2026-03-01")}, true},
+		{"- [ ] report due:2026-03-01", taskItem{Text: "report due:2026-03-01", Due: due("2026-03-01")}, true},
+		{"- [ ] bad date due:2026-13-45", taskItem{Text: "bad date due:2026-13-45"}, true},
+		{"- [ ] urgent ⏫", taskItem{Text: "urgent ⏫", Priority: priorityHigh}, true},
+		{"- [ ] later priority:LOW", taskItem{Text: "later priority:LOW", Priority: priorityLow}, true},
+		{"- [ ] tagged #work #a/b #1", taskItem{Text: "tagged #work #a/b #1", Tags: []string{"work", "a/b"}}, true},
+		{"- [] not a task", taskItem{}, false},
+		{"- [ ]no space", taskItem{}, false},
+		{"[ ] no marker", taskItem{}, false},
+		{"> [ ] quoted without marker", taskItem{}, false},
+		{"- plain item", taskItem{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseTask(tt.line)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTask(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestToggleTaskLine(t *testing.T) {
+	tests := []struct {
+		line, want string
+		ok         bool
+	}{
+		{"- [ ] a", "- [x] a", true},
+		{"- [x] a", "- [ ] a", true},
+		{"- [X] a", "- [ ] a", true},
+		{"  1. [ ] nested [ ] text", "  1. [x] nested [ ] text", true},
+		{"> - [ ] quoted", "> - [x] quoted", true},
+		{"- [ ]", "- [x]", true},
+		{"- [ ] a\r", "- [x] a\r", true},
+		{"- a", "- a", false},
+		{"text [ ] box", "text [ ] box", false},
+	}
+	for _, tt := range tests {
+		got, ok := toggleTaskLine(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("toggleTaskLine(%q) = %q, %v; want %q, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package markdown
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// maxEmbedDepth 是嵌入笔记的最大层数，超过时不再展开
+const maxEmbedDepth = 4
+
+// blockIDPattern 匹配行尾的块 ID，如 "一段文字 ^glossary-api"
+var blockIDPattern = regexp.MustCompile(`(?:^|[ \t])\^([A-Za-z0-9-]+)[ \t]*$`)
+
+// transcluder 在预览中展开 ![[笔记]]、![[笔记#标题]] 和 ![[笔记#^块ID]] 嵌入
+type transcluder struct {
+	m *MarkdownEditor
+	// stack 是正在展开的嵌入，元素为 "路径#锚点"，用于检测循环嵌入
+	stack []string
+}
+
+// renderEmbed 渲染一个嵌入：图片显示为图片，笔记显示为可点击的标题加上被嵌入的内容
+func (t *transcluder) renderEmbed(notePath string, n *wikiLinkNode) []widget.RichTextSegment {
+	ext := strings.ToLower(filepath.Ext(n.Target))
+	if isImage(n.Target) {
+		path := t.m.findAttachment(notePath, n.Target)
+		if path == "" {
+			return []widget.RichTextSegment{embedNotice("图片不存在: " + n.Target)}
+		}
+		width, _ := strconv.Atoi(n.Label)
+		return []widget.RichTextSegment{&imageSegment{path: path, title: n.Target, width: float32(max(width, 0))}}
+	}
+	if ext != "" && ext != noteExt {
+		return []widget.RichTextSegment{&widget.TextSegment{Style: widget.RichTextStyleInline, Text: n.Label}}
+	}
+
+	// 笔记按 wiki 链接的规则在笔记库索引中查找，与普通链接和反向链接保持一致
+	path := notePath
+	if n.Target != "" {
+		idx, err := t.m.queryIndex()
+		if err != nil {
+			return []widget.RichTextSegment{embedNotice(err.Error())}
+		}
+		path = idx.resolveName(notePath, n.Target)
+	}
+	if path == "" {
+		return []widget.RichTextSegment{embedNotice("笔记不存在: " + n.Target)}
+	}
+	key := path + "#" + n.Anchor
+	for _, k := range t.stack {
+		if k == key {
+			return []widget.RichTextSegment{embedNotice("循环嵌入: " + n.Label)}
+		}
+	}
+	// stack 的第一个元素是正在预览的笔记本身
+	if len(t.stack) > maxEmbedDepth {
+		return []widget.RichTextSegment{embedNotice("嵌入层数过多: " + n.Label)}
+	}
+
+	content, err := t.m.noteContent(path)
+	if errors.Is(err, errEncryptedNote) {
+		return []widget.RichTextSegment{embedNotice("加密笔记: " + n.Label)}
+	}
+	if err != nil {
+		return []widget.RichTextSegment{embedNotice(err.Error())}
+	}
+	section, line, ok := noteSection(content, n.Anchor)
+	if !ok {
+		return []widget.RichTextSegment{embedNotice("找不到嵌入的内容: " + n.Label)}
+	}
+
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if n.Anchor != "" {
+		title += " › " + strings.TrimPrefix(n.Anchor, "^")
+	}
+	header := &widget.HyperlinkSegment{Alignment: fyne.TextAlignLeading, Text: "↪ " + title}
+	header.OnTapped = func() { t.m.openFileAt(path, line) }
+
+	inner := newPreviewRenderer(section, path)
+	inner.embeds = &transcluder{m: t.m, stack: append(append([]string(nil), t.stack...), key)}
+	inner.queries = func(query string) []widget.RichTextSegment { return t.m.renderQuery(path, query) }
+	segments := []widget.RichTextSegment{
+		&widget.SeparatorSegment{},
+		header,
+		&widget.TextSegment{Style: widget.RichTextStyleParagraph},
+	}
+	segments = append(segments, inner.render()...)
+	return append(segments, &widget.SeparatorSegment{})
+}
+
+func embedNotice(text string) widget.RichTextSegment {
+	seg := &widget.TextSegment{Style: widget.RichTextStyleInline, Text: "[" + text + "]"}
+	seg.Style.TextStyle.Italic = true
+	return seg
+}
+
+// noteSection 返回笔记中被嵌入的部分和它在笔记中的起始行。anchor 为空时返回整篇正文；
+// 为标题时返回该标题到下一个同级或更高级标题之前的内容；以 ^ 开头时返回带有该块 ID 的段落或列表项
+func noteSection(content, anchor string) (string, int, bool) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	start := frontMatterEnd(lines)
+	if anchor == "" {
+		return strings.Join(lines[start:], "\n"), start, true
+	}
+
+	// 只在代码块之外查找标题和块 ID
+	var textLines []int
+	forEachTextLine(strings.Join(lines, "\n"), func(i int, _ string) {
+		textLines = append(textLines, i)
+	})
+
+	if id, ok := strings.CutPrefix(anchor, "^"); ok {
+		for _, i := range textLines {
+			if m := blockIDPattern.FindStringSubmatch(lines[i]); m != nil && m[1] == id {
+				return blockSection(lines, i), i, true
+			}
+		}
+		return "", 0, false
+	}
+
+	want := headingID(anchor)
+	for j, i := range textLines {
+		level, text, ok := parseHeading(lines[i])
+		if !ok || headingID(text) != want {
+			continue
+		}
+		end := len(lines)
+		for _, k := range textLines[j+1:] {
+			if l, _, ok := parseHeading(lines[k]); ok && l <= level {
+				end = k
+				break
+			}
+		}
+		return strings.TrimRight(strings.Join(lines[i:end], "\n"), "\n"), i, true
+	}
+	return "", 0, false
+}
+
+// blockSection 返回第 i 行块 ID 所标记的块，并去掉块 ID。
+// 块 ID 单独成行时标记的是上面的块；列表项只取这一项
+func blockSection(lines []string, i int) string {
+	strip := func(s string) string {
+		return strings.TrimRight(blockIDPattern.ReplaceAllString(s, ""), " \t")
+	}
+	if strings.TrimSpace(strip(lines[i])) == "" {
+		// 块 ID 单独成行，跳过它与上一个块之间的空行
+		for i > 0 && strings.TrimSpace(lines[i-1]) == "" {
+			i--
+		}
+		if i == 0 {
+			return ""
+		}
+		end := i
+		start := end - 1
+		for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+			start--
+		}
+		return strings.Join(lines[start:end], "\n")
+	}
+	if mdListPattern.MatchString(lines[i]) {
+		return strip(lines[i])
+	}
+
+	start, end := i, i+1
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" && !mdListPattern.MatchString(lines[start-1]) {
+		start--
+	}
+	for end < len(lines) && strings.TrimSpace(lines[end]) != "" && !mdListPattern.MatchString(lines[end]) {
+		end++
+	}
+	block := append([]string(nil), lines[start:end]...)
+	block[i-start] = strip(block[i-start])
+	return strings.Join(block, "\n")
+}
+
+// refreshDependentPreviews 在笔记保存后重新渲染其他包含嵌入或查询块的笔记的预览，嵌入可能是多层的，所以不按笔记名筛选
+func (m *MarkdownEditor) refreshDependentPreviews(saved string) {
+	m.queryCache = nil
+	for path, editor := range m.openFiles {
+		if path == saved || !strings.Contains(editor.Text, "![[") && !queryFencePattern.MatchString(editor.Text) {
+			continue
+		}
+		if preview := tabPreview(m.tabOf(editor)); preview != nil {
+			preview.Segments = m.renderEditorPreview(editor, path)
+			preview.Refresh()
+		}
+	}
+}
+
+// tabPreview 返回笔记标签页中的预览
+func tabPreview(tab *container.TabItem) *CustomRichText {
+	if tab == nil {
+		return nil
+	}
+	if split := tabSplit(tab); split != nil {
+		if scroll, ok := split.Trailing.(*container.Scroll); ok {
+			preview, _ := scroll.Content.(*CustomRichText)
+			return preview
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderEmbedResolvesNotes(t *testing.T) {
+	m := newTestEditor(t)
+	files := map[string]string{
+		"home.md":          "",
+		"projects/plan.md": "# Plan\nship it",
+		"a/dup.md":         "alpha",
+		"b/dup.md":         "beta",
+		"b/here.md":        "",
+		"a/todo.md":        "shallow",
+		"deep/x/todo.md":   "deep",
+	}
+	for name, content := range files {
+		path := filepath.Join(m.rootPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		from   string
+		target string
+		want   string
+	}{
+		{"by name in another folder", "home.md", "plan", "ship it"},
+		{"with extension", "home.md", "plan.md", "ship it"},
+		{"by vault path", "home.md", "projects/plan", "ship it"},
+		{"case insensitive", "home.md", "PLAN", "ship it"},
+		{"same folder wins", "b/here.md", "dup", "beta"},
+		{"by partial path", "home.md", "x/todo", "deep"},
+		{"missing", "home.md", "nope", "笔记不存在: nope"},
+	}
+	for _, tt := range tests {
+		from := filepath.Join(m.rootPath, filepath.FromSlash(tt.from))
+		embeds := &transcluder{m: m, stack: []string{from + "#"}}
+		segs := embeds.renderEmbed(from, &wikiLinkNode{Target: tt.target, Label: tt.target, Embed: true})
+		if text := segmentText(segs); !strings.Contains(text, tt.want) {
+			t.Errorf("%s: embed of %q renders %q, want it to contain %q", tt.name, tt.target, text, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package markdown
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// metaDir 是笔记目录下保存应用数据的隐藏目录
+const metaDir = ".nodian"
+
+// vaultConfig 是保存在 .nodian/config.json 中的笔记库设置
+type vaultConfig struct {
+	// AttachmentFolder 是附件目录，相对于笔记库根目录；为空时附件与笔记放在同一目录
+	AttachmentFolder string `json:"attachmentFolder"`
+	// ExportWikiLinks 为 true 时导出 HTML 把 wiki 链接转换为相对链接，否则输出为纯文本
+	ExportWikiLinks bool `json:"exportWikiLinks"`
+	// PublishFolder 和 PublishDir 记录上一次发布网站时选择的目录
+	PublishFolder string `json:"publishFolder,omitempty"`
+	PublishDir    string `json:"publishDir,omitempty"`
+	// LineNumbers 和 MonospaceFont 控制源码编辑区是否显示行号、是否使用等宽字体
+	LineNumbers   bool `json:"lineNumbers"`
+	MonospaceFont bool `json:"monospaceFont"`
+	// VimMode 为 true 时编辑器使用 Vim 按键
+	VimMode bool `json:"vimMode"`
+	// FormatOnSave 为 true 时保存笔记前先格式化
+	FormatOnSave bool `json:"formatOnSave"`
+	// LockTimeout 是加密笔记在没有操作多少分钟后自动锁定，为 0 时不自动锁定
+	LockTimeout int `json:"lockTimeout"`
+	// SpellCheck 为 true 时用 Hunspell 字典检查拼写。SpellDictionaries 是字典目录，
+	// 为空时使用 .nodian/dictionaries；SpellLanguages 是逗号分隔的字典名（如 en_US），为空时加载目录中的所有字典
+	SpellCheck        bool   `json:"spellCheck"`
+	SpellDictionaries string `json:"spellDictionaries,omitempty"`
+	SpellLanguages    string `json:"spellLanguages,omitempty"`
+}
+
+func defaultVaultConfig() vaultConfig {
+	return vaultConfig{
+		AttachmentFolder: "attachments",
+		ExportWikiLinks:  true,
+		LockTimeout:      10,
+	}
+}
+
+func (m *MarkdownEditor) metaPath(name string) string {
+	return filepath.Join(m.rootPath, metaDir, name)
+}
+
+func (m *MarkdownEditor) loadConfig() {
+	m.config = defaultVaultConfig()
+	data, err := os.ReadFile(m.metaPath("config.json"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fyne.LogError("Failed to read vault config", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &m.config); err != nil {
+		fyne.LogError("Failed to parse vault config", err)
+	}
+}
+
+func (m *MarkdownEditor) saveConfig() error {
+	return m.writeMeta("config.json", m.config)
+}
+
+// readMeta 读取 .nodian 目录中的 JSON 文件，文件不存在时不修改 v
+func (m *MarkdownEditor) readMeta(name string, v interface{}) error {
+	data, err := os.ReadFile(m.metaPath(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeMeta 将 v 以 JSON 格式写入 .nodian 目录
+func (m *MarkdownEditor) writeMeta(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(m.rootPath, metaDir), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.metaPath(name), data, 0644)
+}
+
+// isHidden 判断文件名是否为隐藏文件，隐藏文件不在目录树中显示
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+func isNote(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), noteExt)
+}
+
+// walkNotes 遍历 root 下的所有笔记文件，跳过隐藏文件和目录
+func walkNotes(root string, fn func(path string) error) error {
+	return walkFiles(root, func(path string) error {
+		if !isNote(path) {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// walkFiles 遍历 root 下的所有普通文件，跳过隐藏文件和目录
+func walkFiles(root string, fn func(path string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && isHidden(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// showSettings 显示笔记库设置对话框
+func (m *MarkdownEditor) showSettings() {
+	attachmentEntry := widget.NewEntry()
+	attachmentEntry.SetText(m.config.AttachmentFolder)
+	attachmentEntry.SetPlaceHolder("留空表示与笔记放在同一目录")
+	lineNumbers := widget.NewCheck("Show line numbers", nil)
+	lineNumbers.SetChecked(m.config.LineNumbers)
+	monospace := widget.NewCheck("Monospace font", nil)
+	monospace.SetChecked(m.config.MonospaceFont)
+	vimMode := widget.NewCheck("Vim mode", nil)
+	vimMode.SetChecked(m.config.VimMode)
+	formatOnSave := widget.NewCheck("Format on save", nil)
+	formatOnSave.SetChecked(m.config.FormatOnSave)
+	lockEntry := widget.NewEntry()
+	lockEntry.SetText(strconv.Itoa(m.config.LockTimeout))
+	lockEntry.SetPlaceHolder("0 表示不自动锁定")
+	spellCheck := widget.NewCheck("Check spelling", nil)
+	spellCheck.SetChecked(m.config.SpellCheck)
+	dictEntry := widget.NewEntry()
+	dictEntry.SetText(m.config.SpellDictionaries)
+	dictEntry.SetPlaceHolder("留空表示使用 .nodian/dictionaries")
+	langEntry := widget.NewEntry()
+	langEntry.SetText(m.config.SpellLanguages)
+	langEntry.SetPlaceHolder("如 en_US，留空表示全部")
+
+	m.showCustomFormDialog("Settings", "Save", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Attachment folder", attachmentEntry),
+		widget.NewFormItem("Editor", container.NewHBox(lineNumbers, monospace, vimMode, formatOnSave)),
+		widget.NewFormItem("Lock encrypted notes after (minutes)", lockEntry),
+		widget.NewFormItem("Spelling", spellCheck),
+		widget.NewFormItem("Dictionary folder", dictEntry),
+		widget.NewFormItem("Dictionaries", langEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		folder := strings.Trim(filepath.ToSlash(strings.TrimSpace(attachmentEntry.Text)), "/")
+		if folder != "" && !m.insideRoot(filepath.Join(m.rootPath, filepath.FromSlash(folder))) {
+			dialog.ShowError(errOutsideRoot, m.window)
+			return
+		}
+		lockTimeout, err := strconv.Atoi(strings.TrimSpace(lockEntry.Text))
+		if err != nil || lockTimeout < 0 {
+			dialog.ShowError(errors.New("自动锁定时间必须是非负整数"), m.window)
+			return
+		}
+		m.config.AttachmentFolder = folder
+		m.config.LockTimeout = lockTimeout
+		m.config.LineNumbers = lineNumbers.Checked
+		m.config.MonospaceFont = monospace.Checked
+		m.config.VimMode = vimMode.Checked
+		m.config.FormatOnSave = formatOnSave.Checked
+		m.config.SpellCheck = spellCheck.Checked
+		m.config.SpellDictionaries = strings.TrimSpace(dictEntry.Text)
+		m.config.SpellLanguages = strings.TrimSpace(langEntry.Text)
+		for _, tab := range m.tabs.Items {
+			if source := tabSource(tab); source != nil {
+				source.setLineNumbers(m.config.LineNumbers)
+				source.setMonospace(m.config.MonospaceFont)
+				m.setVimMode(source.entry, m.config.VimMode)
+			}
+		}
+		m.updateStatus()
+		m.resetLockTimer()
+
+		if err := m.saveConfig(); err != nil {
+			dialog.ShowError(err, m.window)
+		}
+		if err := m.applySpellCheck(); err != nil {
+			dialog.ShowError(err, m.window)
+		}
+	}, m.window)
+}
@@ -0,0 +1,1428 @@
+package markdown
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// vimMode 是 Vim 模式下编辑器所处的模式
+type vimMode int
+
+const (
+	vimNormal vimMode = iota
+	vimInsert
+	vimVisual
+	vimVisualLine
+	vimCommandLine // 正在输入 : 命令
+)
+
+func (mode vimMode) String() string {
+	switch mode {
+	case vimInsert:
+		return "INSERT"
+	case vimVisual:
+		return "VISUAL"
+	case vimVisualLine:
+		return "VISUAL LINE"
+	case vimCommandLine:
+		return "COMMAND"
+	}
+	return "NORMAL"
+}
+
+const (
+	vimMotions    = "hjklwbeWBE0^$G{}%;, \r+-" // 单个按键的移动命令，空格等同于 l
+	vimOperators  = "dcy"
+	vimCommands   = "xXDCsSYpPiaIAoOJu~vV:." // 普通模式中不带操作符的命令
+	vimVisualOps  = "dxXDcsCSyYpPJ~uUo"      // 可视模式中作用于选区的命令
+	vimObjects    = "wW\"'`()b[]{}B<>p"      // i 和 a 之后的文本对象
+	vimRegisters  = "\"_+*"
+	vimLinewiseOp = "XDCSY" // 可视模式中按整行操作的命令
+)
+
+// 普通模式和可视模式中特殊键对应的命令
+var vimKeyRunes = map[fyne.KeyName]rune{
+	fyne.KeyLeft:      'h',
+	fyne.KeyRight:     'l',
+	fyne.KeyUp:        'k',
+	fyne.KeyDown:      'j',
+	fyne.KeyHome:      '0',
+	fyne.KeyEnd:       '$',
+	fyne.KeyReturn:    '\r',
+	fyne.KeyEnter:     '\r',
+	fyne.KeyBackspace: 'h',
+	fyne.KeyDelete:    'x',
+}
+
+// vimKey 是插入模式中的一次按键，用于 . 重复和带次数的插入。r 为 0 时表示 name 指定的特殊键
+type vimKey struct {
+	r    rune
+	name fyne.KeyName
+}
+
+type vimRegister struct {
+	text     string
+	linewise bool
+}
+
+// vimCommand 是解析后的一条普通模式或可视模式命令
+type vimCommand struct {
+	register rune
+	count    int    // 次数，0 表示未指定；操作符和移动命令的次数已经相乘
+	op       rune   // 操作符 d、c、y，没有时为 0
+	motion   string // 移动命令、文本对象（如 iw）或其它命令；_ 表示当前行（dd、cc、yy）
+	arg      rune   // f、t、r 等命令后输入的字符
+}
+
+// vimChange 记录最近一次修改，用于 . 重复
+type vimChange struct {
+	cmd    vimCommand
+	insert []vimKey
+}
+
+// vimState 是编辑器的 Vim 模式状态。普通模式和可视模式中的按键由它处理，
+// 插入模式中的按键仍交给输入框，只是记录下来
+type vimState struct {
+	e    *noteEntry
+	mode vimMode
+	keys []rune // 尚未输入完整的命令
+
+	registers  map[rune]vimRegister
+	lastFind   vimCommand // 最近一次 f、t、F、T，用于 ; 和 ,
+	lastChange *vimChange
+	insertCmd  vimCommand // 进入插入模式的命令
+	inserted   []vimKey   // 本次插入模式中的按键
+	replaying  bool
+
+	anchor  int // 可视模式选区的起点
+	cursor  int // 可视模式中光标的位置
+	wantCol int // 上下移动时保持的列，-1 表示行尾
+	cmdline string
+	message string // 状态栏中显示的提示
+
+	clipboard fyne.Clipboard
+	onStatus  func()
+	onCommand func(cmd string) error
+}
+
+func newVimState(e *noteEntry) *vimState {
+	return &vimState{e: e, registers: make(map[rune]vimRegister)}
+}
+
+// status 返回状态栏中显示的文字
+func (v *vimState) status() string {
+	switch {
+	case v.mode == vimCommandLine:
+		return ":" + v.cmdline
+	case v.message != "":
+		return v.message
+	}
+	s := "-- " + v.mode.String() + " --"
+	if len(v.keys) > 0 {
+		s += "  " + strings.ReplaceAll(string(v.keys), "\r", "⏎")
+	}
+	return s
+}
+
+func (v *vimState) notify() {
+	if v.onStatus != nil {
+		v.onStatus()
+	}
+}
+
+// typedRune 处理输入的字符，返回 false 时由输入框按普通方式输入
+func (v *vimState) typedRune(r rune) bool {
+	switch v.mode {
+	case vimInsert:
+		if !v.replaying {
+			v.inserted = append(v.inserted, vimKey{r: r})
+		}
+		return false
+	case vimCommandLine:
+		v.cmdline += string(r)
+		v.notify()
+		return true
+	}
+	v.key(r)
+	return true
+}
+
+// typedKey 处理特殊键，返回 false 时交给输入框
+func (v *vimState) typedKey(key *fyne.KeyEvent) bool {
+	switch v.mode {
+	case vimInsert:
+		if key.Name == fyne.KeyEscape {
+			v.exitInsert()
+			return true
+		}
+		switch key.Name {
+		case fyne.KeyReturn, fyne.KeyEnter, fyne.KeyTab, fyne.KeyBackspace, fyne.KeyDelete,
+			fyne.KeyLeft, fyne.KeyRight, fyne.KeyUp, fyne.KeyDown, fyne.KeyHome, fyne.KeyEnd:
+			if !v.replaying {
+				v.inserted = append(v.inserted, vimKey{name: key.Name})
+			}
+		}
+		return false
+	case vimCommandLine:
+		switch key.Name {
+		case fyne.KeyEscape:
+			v.mode = vimNormal
+		case fyne.KeyReturn, fyne.KeyEnter:
+			v.mode = vimNormal
+			v.runCommand(strings.TrimSpace(v.cmdline))
+		case fyne.KeyBackspace:
+			if v.cmdline == "" {
+				v.mode = vimNormal
+			} else {
+				_, size := utf8.DecodeLastRuneInString(v.cmdline)
+				v.cmdline = v.cmdline[:len(v.cmdline)-size]
+			}
+		}
+		v.notify()
+		return true
+	}
+
+	if key.Name == fyne.KeyEscape {
+		if len(v.keys) > 0 {
+			v.keys = nil
+		} else if v.mode != vimNormal {
+			v.exitVisual(v.cursor)
+		}
+		v.message = ""
+		v.notify()
+		return true
+	}
+	if r, ok := vimKeyRunes[key.Name]; ok {
+		v.key(r)
+	}
+	// 其它按键（包括字母键本身，字符由 typedRune 处理）都不交给输入框
+	return true
+}
+
+// typedShortcut 处理 Ctrl+R 重做
+func (v *vimState) typedShortcut(shortcut *desktop.CustomShortcut) bool {
+	if v.mode != vimNormal || shortcut.KeyName != fyne.KeyR || shortcut.Modifier != fyne.KeyModifierControl {
+		return false
+	}
+	for i := 0; i < max(v.takeCount(), 1); i++ {
+		v.e.Redo()
+	}
+	v.setCursor(v.position())
+	v.notify()
+	return true
+}
+
+// takeCount 取出已经输入的次数，用于 Ctrl+R 这类不经过 key 的命令
+func (v *vimState) takeCount() int {
+	n, _ := strconv.Atoi(string(v.keys))
+	v.keys = nil
+	return n
+}
+
+// key 在普通模式和可视模式中输入一个按键，命令完整时执行
+func (v *vimState) key(r rune) {
+	v.message = ""
+	v.keys = append(v.keys, r)
+	cmd, state := parseVim(v.keys, v.mode != vimNormal)
+	switch state {
+	case vimParsePending:
+		v.notify()
+		return
+	case vimParseInvalid:
+		v.keys = nil
+		v.notify()
+		return
+	}
+	v.keys = nil
+	v.execute(cmd)
+	v.notify()
+}
+
+type vimParseState int
+
+const (
+	vimParseDone vimParseState = iota
+	vimParsePending
+	vimParseInvalid
+)
+
+// parseVim 解析按键序列，格式为 ["x][count][operator][count]motion
+func parseVim(keys []rune, visual bool) (cmd vimCommand, state vimParseState) {
+	i := 0
+	next := func() (rune, bool) {
+		if i == len(keys) {
+			return 0, false
+		}
+		i++
+		return keys[i-1], true
+	}
+	readCount := func() int {
+		n := 0
+		for i < len(keys) && keys[i] >= '0' && keys[i] <= '9' && (n > 0 || keys[i] != '0') {
+			n = n*10 + int(keys[i]-'0')
+			i++
+		}
+		return n
+	}
+
+	if keys[0] == '"' {
+		i = 1
+		r, ok := next()
+		if !ok {
+			return cmd, vimParsePending
+		}
+		if !validRegister(r) {
+			return cmd, vimParseInvalid
+		}
+		cmd.register = r
+	}
+	count := readCount()
+	r, ok := next()
+	if !ok {
+		return cmd, vimParsePending
+	}
+	cmd.count = count
+
+	if visual && strings.ContainsRune(vimVisualOps, r) {
+		cmd.motion = string(r)
+		return cmd, vimParseDone
+	}
+	if !visual && strings.ContainsRune(vimOperators, r) {
+		cmd.op = r
+		if n := readCount(); n > 0 {
+			cmd.count = max(count, 1) * n
+		}
+		if r, ok = next(); !ok {
+			return cmd, vimParsePending
+		}
+		if r == cmd.op {
+			cmd.motion = "_"
+			return cmd, vimParseDone
+		}
+	}
+
+	switch {
+	case strings.ContainsRune(vimMotions, r):
+		cmd.motion = string(r)
+	case r == 'g':
+		g, ok := next()
+		if !ok {
+			return cmd, vimParsePending
+		}
+		if g != 'g' {
+			return cmd, vimParseInvalid
+		}
+		cmd.motion = "gg"
+	case strings.ContainsRune("fFtT", r) || (r == 'r' && cmd.op == 0 && !visual):
+		arg, ok := next()
+		if !ok {
+			return cmd, vimParsePending
+		}
+		cmd.motion, cmd.arg = string(r), arg
+	case (r == 'i' || r == 'a') && (cmd.op != 0 || visual):
+		obj, ok := next()
+		if !ok {
+			return cmd, vimParsePending
+		}
+		if !strings.ContainsRune(vimObjects, obj) {
+			return cmd, vimParseInvalid
+		}
+		cmd.motion = string(r) + string(obj)
+	case cmd.op == 0 && strings.ContainsRune(vimCommands, r) && (!visual || strings.ContainsRune("vV:", r)):
+		cmd.motion = string(r)
+	default:
+		return cmd, vimParseInvalid
+	}
+	return cmd, vimParseDone
+}
+
+func validRegister(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(vimRegisters, r))
+}
+
+// isMotion 判断命令是否是移动命令
+func isMotion(motion string) bool {
+	return (len(motion) == 1 && strings.ContainsRune(vimMotions+"fFtT", rune(motion[0]))) || motion == "gg"
+}
+
+// execute 执行一条完整的命令
+func (v *vimState) execute(cmd vimCommand) {
+	if v.mode == vimVisual || v.mode == vimVisualLine {
+		v.executeVisual(cmd)
+		return
+	}
+	t := newVimText(v.e.Text)
+	pos := v.position()
+
+	// 简写命令转换为操作符加移动命令
+	switch cmd.motion {
+	case "x":
+		cmd.op, cmd.motion = 'd', "l"
+	case "X":
+		cmd.op, cmd.motion = 'd', "h"
+	case "D":
+		cmd.op, cmd.motion = 'd', "$"
+	case "C":
+		cmd.op, cmd.motion = 'c', "$"
+	case "s":
+		cmd.op, cmd.motion = 'c', "l"
+	case "S":
+		cmd.op, cmd.motion = 'c', "_"
+	case "Y":
+		cmd.op, cmd.motion = 'y', "_"
+	}
+	if !v.replaying && (cmd.op == 'd' || (cmd.op == 0 && strings.ContainsRune("pPrJ~", rune(cmd.motion[0])))) {
+		v.lastChange = &vimChange{cmd: cmd}
+	}
+
+	if cmd.op != 0 {
+		v.operate(t, pos, cmd)
+		return
+	}
+	if isMotion(cmd.motion) {
+		if target, _, _, ok := v.motion(t, pos, cmd); ok {
+			v.moveTo(t, target, cmd.motion)
+		}
+		return
+	}
+
+	count := max(cmd.count, 1)
+	row := t.row(pos)
+	switch cmd.motion {
+	case "i", "a", "I", "A", "o", "O":
+		v.insert(t, pos, cmd)
+	case "p", "P":
+		v.put(t, pos, cmd)
+	case "r":
+		end := pos + count
+		if end > t.lineEnd(row) {
+			return
+		}
+		arg := cmd.arg
+		if arg == '\r' {
+			arg = '\n'
+		}
+		v.replace(t, pos, end, strings.Repeat(string(arg), count))
+		v.setCursor(end - 1)
+	case "~":
+		end := min(pos+count, t.lineEnd(row))
+		if end <= pos {
+			return
+		}
+		v.replace(t, pos, end, toggleCase(string(t.r[pos:end])))
+		v.setCursor(end)
+	case "J":
+		last := min(row+max(count, 2)-1, len(t.starts)-1)
+		if last == row {
+			return
+		}
+		v.join(t, row, last)
+	case "u":
+		for i := 0; i < count; i++ {
+			v.e.Undo()
+		}
+		v.setCursor(v.position())
+	case "v", "V":
+		v.mode = vimVisual
+		if cmd.motion == "V" {
+			v.mode = vimVisualLine
+		}
+		v.anchor, v.cursor = pos, pos
+		v.updateSelection()
+	case ":":
+		v.mode = vimCommandLine
+		v.cmdline = ""
+	case ".":
+		v.repeat(cmd.count)
+	}
+}
+
+// repeat 重复最近一次修改，count 大于 0 时替换原来的次数
+func (v *vimState) repeat(count int) {
+	if v.lastChange == nil {
+		return
+	}
+	change := *v.lastChange
+	cmd := change.cmd
+	if count > 0 {
+		cmd.count = count
+	}
+	v.replaying = true
+	defer func() { v.replaying = false }()
+	v.execute(cmd)
+	if v.mode == vimInsert {
+		v.typeKeys(change.insert)
+		v.inserted = change.insert
+		v.exitInsert()
+	}
+}
+
+// typeKeys 把记录的按键重新输入到编辑器中
+func (v *vimState) typeKeys(keys []vimKey) {
+	for _, k := range keys {
+		if k.r != 0 {
+			v.e.TypedRune(k.r)
+		} else {
+			v.e.TypedKey(&fyne.KeyEvent{Name: k.name})
+		}
+	}
+}
+
+// insert 执行 i、a、I、A、o、O，进入插入模式
+func (v *vimState) insert(t *vimText, pos int, cmd vimCommand) {
+	row := t.row(pos)
+	switch cmd.motion {
+	case "a":
+		if pos < t.lineEnd(row) {
+			pos++
+		}
+	case "I":
+		pos = t.firstNonBlank(row)
+	case "A":
+		pos = t.lineEnd(row)
+	case "o", "O":
+		// 新的一行使用与当前行相同的缩进
+		line := t.line(row)
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if cmd.motion == "o" {
+			end := t.lineEnd(row)
+			v.replace(t, end, end, "\n"+indent)
+			v.e.setCursor(row+1, utf8.RuneCountInString(indent))
+		} else {
+			start := t.starts[row]
+			v.replace(t, start, start, indent+"\n")
+			v.e.setCursor(row, utf8.RuneCountInString(indent))
+		}
+		v.startInsert(cmd)
+		return
+	}
+	v.e.setCursor(row, pos-t.starts[row])
+	v.startInsert(cmd)
+}
+
+func (v *vimState) startInsert(cmd vimCommand) {
+	v.mode = vimInsert
+	v.insertCmd = cmd
+	v.inserted = nil
+}
+
+// exitInsert 回到普通模式。i、a 等命令带次数时把输入的内容再重复 count-1 次
+func (v *vimState) exitInsert() {
+	keys := v.inserted
+	if cmd := v.insertCmd; cmd.count > 1 && cmd.op == 0 && strings.Contains("iaIA", cmd.motion) {
+		replaying := v.replaying
+		v.replaying = true
+		for i := 1; i < cmd.count; i++ {
+			v.typeKeys(keys)
+		}
+		v.replaying = replaying
+	}
+	if !v.replaying {
+		v.lastChange = &vimChange{cmd: v.insertCmd, insert: keys}
+	}
+	v.mode = vimNormal
+	v.inserted = nil
+	if v.e.CursorColumn > 0 {
+		v.e.setCursor(v.e.CursorRow, v.e.CursorColumn-1)
+	} else {
+		v.e.setCursor(v.e.CursorRow, 0)
+	}
+	v.notify()
+}
+
+// motion 计算移动命令的目标位置。linewise 表示操作符按整行作用，inclusive 表示作用范围包含目标字符
+func (v *vimState) motion(t *vimText, pos int, cmd vimCommand) (target int, linewise, inclusive, ok bool) {
+	count := max(cmd.count, 1)
+	row := t.row(pos)
+	start, end := t.starts[row], t.lineEnd(row)
+
+	switch cmd.motion {
+	case "h":
+		return max(start, pos-count), false, false, pos > start
+	case "l", " ":
+		limit := end
+		if cmd.op == 0 && end > start {
+			limit = end - 1
+		}
+		return min(limit, pos+count), false, false, pos < limit
+	case "0":
+		return start, false, false, true
+	case "^":
+		return t.firstNonBlank(row), false, false, true
+	case "$":
+		last := min(row+count-1, len(t.starts)-1)
+		return t.lineEnd(last), false, false, true
+	case "j", "k", "\r", "+", "-", "_":
+		next := row
+		switch cmd.motion {
+		case "j", "\r", "+":
+			next = min(row+count, len(t.starts)-1)
+		case "k", "-":
+			next = max(row-count, 0)
+		case "_":
+			next = min(row+count-1, len(t.starts)-1)
+		}
+		if next == row && cmd.motion != "_" {
+			return pos, true, false, false
+		}
+		if cmd.motion == "j" || cmd.motion == "k" {
+			return t.column(next, v.wantCol), true, false, true
+		}
+		return t.firstNonBlank(next), true, false, true
+	case "G", "gg":
+		next := 0
+		if cmd.motion == "G" {
+			next = len(t.starts) - 1
+		}
+		if cmd.count > 0 {
+			next = min(cmd.count-1, len(t.starts)-1)
+		}
+		return t.firstNonBlank(next), true, false, true
+	case "w", "W":
+		big := cmd.motion == "W"
+		if cmd.op == 'c' && pos < len(t.r) && !unicode.IsSpace(t.r[pos]) {
+			// cw 与 ce 相同，不删除单词后的空白
+			// 光标已经在单词的最后一个字符上时只修改这个字符
+			target = pos
+			for i := 0; i < count; i++ {
+				if i == 0 && (target+1 >= len(t.r) || vimClass(t.r[target+1], big) != vimClass(t.r[target], big)) {
+					continue
+				}
+				target = wordEnd(t.r, target, big)
+			}
+			return target, false, true, true
+		}
+		target = pos
+		for i := 0; i < count; i++ {
+			target = wordForward(t.r, target, big)
+		}
+		if cmd.op != 0 {
+			// 操作符作用到下一行时只到当前单词所在行的行尾为止
+			j := target
+			for j > pos && unicode.IsSpace(t.r[j-1]) {
+				j--
+			}
+			if j > pos {
+				if k := indexRune(t.r[j:target], '\n'); k >= 0 {
+					target = j + k
+				}
+			}
+		}
+		return target, false, false, target != pos
+	case "b", "B":
+		target = pos
+		for i := 0; i < count; i++ {
+			target = wordBackward(t.r, target, cmd.motion == "B")
+		}
+		return target, false, false, target != pos
+	case "e", "E":
+		target = pos
+		for i := 0; i < count; i++ {
+			target = wordEnd(t.r, target, cmd.motion == "E")
+		}
+		return target, false, true, target != pos
+	case "f", "F", "t", "T":
+		v.lastFind = cmd
+		target, ok = findInLine(t, pos, cmd.motion, cmd.arg, count, false)
+		return target, false, cmd.motion == "f" || cmd.motion == "t", ok
+	case ";", ",":
+		if v.lastFind.motion == "" {
+			return pos, false, false, false
+		}
+		motion := v.lastFind.motion
+		if cmd.motion == "," {
+			motion = map[string]string{"f": "F", "F": "f", "t": "T", "T": "t"}[motion]
+		}
+		target, ok = findInLine(t, pos, motion, v.lastFind.arg, count, true)
+		return target, false, motion == "f" || motion == "t", ok
+	case "{", "}":
+		next := row
+		for i := 0; i < count; i++ {
+			next = t.paragraph(next, cmd.motion == "}")
+		}
+		if cmd.motion == "}" && next == len(t.starts)-1 && !t.blank(next) {
+			return len(t.r), false, false, pos != len(t.r)
+		}
+		return t.starts[next], false, false, next != row
+	case "%":
+		target, ok = matchBracket(t, pos)
+		return target, false, true, ok
+	}
+	return pos, false, false, false
+}
+
+// moveTo 在普通模式中移动光标，上下移动以外的命令会重新记录要保持的列
+func (v *vimState) moveTo(t *vimText, target int, motion string) {
+	switch motion {
+	case "j", "k":
+	case "$":
+		v.wantCol = -1
+	default:
+		v.wantCol = target - t.starts[t.row(target)]
+	}
+	v.setCursor(target)
+}
+
+// operate 执行带操作符的命令
+func (v *vimState) operate(t *vimText, pos int, cmd vimCommand) {
+	var start, end int
+	var linewise bool
+	if strings.HasPrefix(cmd.motion, "i") || strings.HasPrefix(cmd.motion, "a") {
+		var ok bool
+		start, end, linewise, ok = textObject(t, pos, cmd.motion)
+		if !ok {
+			return
+		}
+	} else {
+		target, lw, inclusive, ok := v.motion(t, pos, cmd)
+		if !ok {
+			return
+		}
+		start, end, linewise = min(pos, target), max(pos, target), lw
+		if inclusive && end < len(t.r) {
+			end++
+		}
+	}
+	v.apply(t, cmd, pos, start, end, linewise)
+}
+
+// apply 对 [start, end) 执行操作符，pos 是原来的光标位置。
+// linewise 时 end 是最后一行中的某个位置，作用于 start 到 end 所在的所有整行
+func (v *vimState) apply(t *vimText, cmd vimCommand, pos, start, end int, linewise bool) {
+	if linewise {
+		first, last := t.row(start), t.row(end)
+		from, to := t.starts[first], t.lineEnd(last)
+		reg := vimRegister{text: string(t.r[from:to]) + "\n", linewise: true}
+		v.store(cmd.register, reg, cmd.op == 'y')
+		switch cmd.op {
+		case 'y':
+			if row := t.row(pos); row > first {
+				pos = t.column(first, pos-t.starts[row])
+			}
+			v.setCursor(pos)
+		case 'd':
+			// 删除整行及行尾的换行符，删除最后一行时删除它前面的换行符
+			switch {
+			case to < len(t.r):
+				v.replace(t, from, to+1, "")
+			case from > 0:
+				v.replace(t, from-1, to, "")
+			default:
+				v.replace(t, from, to, "")
+			}
+			t = newVimText(v.e.Text)
+			v.setCursor(t.firstNonBlank(min(first, len(t.starts)-1)))
+		case 'c':
+			line := t.line(first)
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			v.replace(t, from, to, indent)
+			v.e.setCursor(first, utf8.RuneCountInString(indent))
+			v.startInsert(cmd)
+		}
+		return
+	}
+
+	v.store(cmd.register, vimRegister{text: string(t.r[start:end])}, cmd.op == 'y')
+	switch cmd.op {
+	case 'y':
+		v.setCursor(start)
+	case 'd':
+		v.replace(t, start, end, "")
+		v.setCursor(start)
+	case 'c':
+		v.replace(t, start, end, "")
+		row := t.row(start)
+		v.e.setCursor(row, start-t.starts[row])
+		v.startInsert(cmd)
+	}
+}
+
+// put 执行 p 和 P
+func (v *vimState) put(t *vimText, pos int, cmd vimCommand) {
+	reg, ok := v.register(cmd.register)
+	if !ok {
+		v.message = "寄存器为空"
+		return
+	}
+	text := strings.Repeat(reg.text, max(cmd.count, 1))
+	row := t.row(pos)
+	if reg.linewise {
+		if cmd.motion == "p" {
+			end := t.lineEnd(row)
+			v.replace(t, end, end, "\n"+strings.TrimSuffix(text, "\n"))
+			row++
+		} else {
+			start := t.starts[row]
+			v.replace(t, start, start, text)
+		}
+		v.setCursor(newVimText(v.e.Text).firstNonBlank(row))
+		return
+	}
+	at := pos
+	if cmd.motion == "p" && pos < t.lineEnd(row) {
+		at++
+	}
+	v.replace(t, at, at, text)
+	v.setCursor(at + utf8.RuneCountInString(text) - 1)
+}
+
+// join 把 first 到 last 行合并为一行，各行之间用一个空格分隔
+func (v *vimState) join(t *vimText, first, last int) {
+	joined := strings.TrimRight(t.line(first), " \t")
+	col := 0
+	for row := first + 1; row <= last; row++ {
+		next := strings.TrimLeft(t.line(row), " \t")
+		col = utf8.RuneCountInString(joined)
+		if next != "" && joined != "" && !strings.HasPrefix(next, ")") {
+			joined += " "
+		}
+		joined += next
+	}
+	v.replace(t, t.starts[first], t.lineEnd(last), joined)
+	v.e.setCursor(first, col)
+}
+
+// executeVisual 在可视模式中执行命令
+func (v *vimState) executeVisual(cmd vimCommand) {
+	t := newVimText(v.e.Text)
+	switch {
+	case cmd.motion == "v" || cmd.motion == "V":
+		mode := vimVisual
+		if cmd.motion == "V" {
+			mode = vimVisualLine
+		}
+		if v.mode == mode {
+			v.exitVisual(v.cursor)
+			return
+		}
+		v.mode = mode
+	case cmd.motion == "o":
+		v.anchor, v.cursor = v.cursor, v.anchor
+	case cmd.motion == ":":
+		v.exitVisual(v.cursor)
+		v.mode = vimCommandLine
+		v.cmdline = ""
+		return
+	case len(cmd.motion) == 2 && (cmd.motion[0] == 'i' || cmd.motion[0] == 'a'):
+		start, end, _, ok := textObject(t, v.cursor, cmd.motion)
+		if !ok || end <= start {
+			return
+		}
+		v.anchor, v.cursor = start, end-1
+	case isMotion(cmd.motion):
+		target, _, _, ok := v.motion(t, v.cursor, cmd)
+		if !ok {
+			return
+		}
+		if cmd.motion == "$" && target > t.starts[t.row(target)] {
+			target--
+		}
+		if cmd.motion != "j" && cmd.motion != "k" {
+			v.wantCol = target - t.starts[t.row(target)]
+		}
+		v.cursor = target
+	default:
+		v.operateVisual(t, cmd)
+		return
+	}
+	v.updateSelection()
+}
+
+// operateVisual 对选区执行 d、c、y 等命令，然后回到普通模式
+func (v *vimState) operateVisual(t *vimText, cmd vimCommand) {
+	lo, hi := min(v.anchor, v.cursor), max(v.anchor, v.cursor)
+	start, end := lo, min(hi+1, len(t.r))
+	linewise := v.mode == vimVisualLine || strings.Contains(vimLinewiseOp, cmd.motion)
+	if linewise {
+		start, end = t.starts[t.row(lo)], t.lineEnd(t.row(hi))
+	}
+	v.mode = vimNormal
+	v.e.clearSelection()
+
+	switch cmd.motion {
+	case "d", "x", "X", "D":
+		cmd.op = 'd'
+	case "c", "s", "C", "S":
+		cmd.op = 'c'
+	case "y", "Y":
+		cmd.op = 'y'
+	case "p", "P":
+		reg, ok := v.register(cmd.register)
+		if !ok {
+			v.message = "寄存器为空"
+			v.setCursor(start)
+			return
+		}
+		text := reg.text
+		if reg.linewise && !linewise {
+			text = "\n" + text
+		} else if linewise {
+			text = strings.TrimSuffix(text, "\n")
+		}
+		v.replace(t, start, end, text)
+		v.setCursor(start)
+		return
+	case "J":
+		first, last := t.row(lo), t.row(hi)
+		v.join(t, first, max(last, min(first+1, len(t.starts)-1)))
+		return
+	case "~", "u", "U":
+		text := string(t.r[start:end])
+		switch cmd.motion {
+		case "u":
+			text = strings.ToLower(text)
+		case "U":
+			text = strings.ToUpper(text)
+		default:
+			text = toggleCase(text)
+		}
+		v.replace(t, start, end, text)
+		v.setCursor(start)
+		return
+	}
+	v.apply(t, cmd, start, start, end, linewise)
+}
+
+// exitVisual 退出可视模式，光标移到 pos
+func (v *vimState) exitVisual(pos int) {
+	v.mode = vimNormal
+	v.e.clearSelection()
+	v.setCursor(pos)
+}
+
+// updateSelection 按可视模式的起点和光标位置设置输入框的选区
+func (v *vimState) updateSelection() {
+	t := newVimText(v.e.Text)
+	v.anchor = min(v.anchor, len(t.r))
+	v.cursor = min(v.cursor, len(t.r))
+	a, c := v.anchor, v.cursor
+	if v.mode == vimVisualLine {
+		if c >= a {
+			a, c = t.starts[t.row(a)], t.lineEnd(t.row(c))
+		} else {
+			a, c = t.lineEnd(t.row(a)), t.starts[t.row(c)]
+		}
+	} else if c >= a {
+		c = min(c+1, len(t.r))
+	} else {
+		a = min(a+1, len(t.r))
+	}
+	ar, ac := t.rowCol(a)
+	cr, cc := t.rowCol(c)
+	v.e.selectBetween(ar, ac, cr, cc)
+	if v.e.OnCursorChanged != nil {
+		v.e.OnCursorChanged()
+	}
+}
+
+// runCommand 执行 : 命令，数字表示跳转到该行
+func (v *vimState) runCommand(cmd string) {
+	if cmd == "" {
+		return
+	}
+	if n, err := strconv.Atoi(cmd); err == nil {
+		t := newVimText(v.e.Text)
+		v.setCursor(t.firstNonBlank(min(max(n, 1), len(t.starts)) - 1))
+		return
+	}
+	if v.onCommand == nil {
+		return
+	}
+	if err := v.onCommand(cmd); err != nil {
+		v.message = err.Error()
+	}
+}
+
+// store 把删除或复制的文字存入寄存器。大写字母的寄存器表示追加，_ 表示丢弃，+ 和 * 是系统剪贴板
+func (v *vimState) store(name rune, reg vimRegister, yank bool) {
+	switch {
+	case name == '_':
+		return
+	case name >= 'A' && name <= 'Z':
+		name = unicode.ToLower(name)
+		old := v.registers[name]
+		reg = vimRegister{text: old.text + reg.text, linewise: old.linewise || reg.linewise}
+		v.registers[name] = reg
+	case name == '+' || name == '*':
+		if v.clipboard != nil {
+			v.clipboard.SetContent(reg.text)
+		}
+	case name != 0 && name != '"':
+		v.registers[name] = reg
+	}
+	v.registers['"'] = reg
+	if yank && name == 0 {
+		v.registers['0'] = reg
+	}
+}
+
+// register 读取寄存器，没有内容时返回 false
+func (v *vimState) register(name rune) (vimRegister, bool) {
+	switch name {
+	case 0:
+		name = '"'
+	case '+', '*':
+		if v.clipboard == nil {
+			return vimRegister{}, false
+		}
+		text := v.clipboard.Content()
+		return vimRegister{text: text, linewise: strings.HasSuffix(text, "\n")}, text != ""
+	}
+	reg, ok := v.registers[unicode.ToLower(name)]
+	return reg, ok && reg.text != ""
+}
+
+// position 返回输入框光标的字符偏移
+func (v *vimState) position() int {
+	t := newVimText(v.e.Text)
+	return t.offset(v.e.CursorRow, v.e.CursorColumn)
+}
+
+// setCursor 移动光标到 pos。普通模式中光标不能停在行尾换行符上，空行除外
+func (v *vimState) setCursor(pos int) {
+	t := newVimText(v.e.Text)
+	pos = min(max(pos, 0), len(t.r))
+	row := t.row(pos)
+	if v.mode == vimNormal && pos == t.lineEnd(row) && pos > t.starts[row] {
+		pos--
+	}
+	v.e.setCursor(row, pos-t.starts[row])
+}
+
+// replace 把 [start, end) 替换为 s，修改会进入撤销记录
+func (v *vimState) replace(t *vimText, start, end int, s string) {
+	row, col := t.rowCol(start)
+	v.e.replaceRange(row, col, end-start, s)
+}
+
+// setVimMode 为编辑器开启或关闭 Vim 模式
+func (m *MarkdownEditor) setVimMode(editor *noteEntry, on bool) {
+	switch {
+	case !on:
+		editor.vim = nil
+	case editor.vim == nil:
+		editor.vim = newVimState(editor)
+		editor.vim.clipboard = m.window.Clipboard()
+		editor.vim.onStatus = m.updateStatus
+		editor.vim.onCommand = m.vimCommand
+	}
+}
+
+// vimCommand 执行 Vim 模式中的 : 命令
+func (m *MarkdownEditor) vimCommand(cmd string) error {
+	switch cmd {
+	case "w", "write":
+		m.saveCurrentFile()
+		return nil
+	}
+	return fmt.Errorf("不支持的命令：%s", cmd)
+}
+
+// vimText 是编辑器文本的字符数组及每行的起始位置，Vim 命令都按字符偏移计算
+type vimText struct {
+	r      []rune
+	starts []int
+}
+
+func newVimText(s string) *vimText {
+	t := &vimText{r: []rune(s), starts: []int{0}}
+	for i, r := range t.r {
+		if r == '\n' {
+			t.starts = append(t.starts, i+1)
+		}
+	}
+	return t
+}
+
+// row 返回 pos 所在的行
+func (t *vimText) row(pos int) int {
+	return sort.Search(len(t.starts), func(i int) bool { return t.starts[i] > pos }) - 1
+}
+
+// lineEnd 返回行尾换行符的位置，最后一行返回文本长度
+func (t *vimText) lineEnd(row int) int {
+	if row+1 < len(t.starts) {
+		return t.starts[row+1] - 1
+	}
+	return len(t.r)
+}
+
+func (t *vimText) line(row int) string {
+	return string(t.r[t.starts[row]:t.lineEnd(row)])
+}
+
+func (t *vimText) blank(row int) bool {
+	return strings.TrimSpace(t.line(row)) == ""
+}
+
+func (t *vimText) rowCol(pos int) (int, int) {
+	row := t.row(pos)
+	return row, pos - t.starts[row]
+}
+
+// offset 返回 (row, col) 对应的偏移，超出范围时取行尾
+func (t *vimText) offset(row, col int) int {
+	row = min(max(row, 0), len(t.starts)-1)
+	return min(t.starts[row]+max(col, 0), t.lineEnd(row))
+}
+
+// column 返回第 row 行中第 col 列的位置，col 为 -1 或超出行尾时取最后一个字符
+func (t *vimText) column(row, col int) int {
+	start, end := t.starts[row], t.lineEnd(row)
+	if col < 0 || start+col >= end {
+		return max(end-1, start)
+	}
+	return start + col
+}
+
+func (t *vimText) firstNonBlank(row int) int {
+	pos, end := t.starts[row], t.lineEnd(row)
+	for pos < end && (t.r[pos] == ' ' || t.r[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+// paragraph 返回从 row 开始向后（或向前）的下一个段落边界，即空行
+func (t *vimText) paragraph(row int, forward bool) int {
+	step := 1
+	if !forward {
+		step = -1
+	}
+	next := row + step
+	for next >= 0 && next < len(t.starts) && t.blank(next) {
+		next += step
+	}
+	for next >= 0 && next < len(t.starts) && !t.blank(next) {
+		next += step
+	}
+	return min(max(next, 0), len(t.starts)-1)
+}
+
+// vimClass 返回字符类别：0 为空白，1 为标点，2 为单词字符；big 为 true 时所有非空白字符都是同一类
+func vimClass(r rune, big bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case big || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 2
+	}
+	return 1
+}
+
+// emptyLine 判断 pos 是否是空行的位置
+func emptyLine(text []rune, pos int) bool {
+	return text[pos] == '\n' && (pos == 0 || text[pos-1] == '\n')
+}
+
+// wordForward 返回下一个单词的开头，空行也算一个单词
+func wordForward(text []rune, pos int, big bool) int {
+	n := len(text)
+	if pos >= n {
+		return n
+	}
+	i := pos
+	if c := vimClass(text[i], big); c != 0 {
+		for i < n && vimClass(text[i], big) == c {
+			i++
+		}
+	}
+	for i < n && vimClass(text[i], big) == 0 {
+		if i > pos && emptyLine(text, i) {
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// wordBackward 返回前一个单词的开头
+func wordBackward(text []rune, pos int, big bool) int {
+	i := min(pos, len(text)) - 1
+	for i >= 0 && vimClass(text[i], big) == 0 {
+		if emptyLine(text, i) {
+			return i
+		}
+		i--
+	}
+	if i < 0 {
+		return 0
+	}
+	c := vimClass(text[i], big)
+	for i > 0 && vimClass(text[i-1], big) == c {
+		i--
+	}
+	return i
+}
+
+// wordEnd 返回下一个单词的结尾
+func wordEnd(text []rune, pos int, big bool) int {
+	n := len(text)
+	i := pos + 1
+	for i < n && vimClass(text[i], big) == 0 {
+		i++
+	}
+	if i >= n {
+		return max(n-1, 0)
+	}
+	c := vimClass(text[i], big)
+	for i+1 < n && vimClass(text[i+1], big) == c {
+		i++
+	}
+	return i
+}
+
+// findInLine 在当前行中查找字符。repeat 为 true 时是 ; 或 , 重复的查找，t 和 T 会跳过紧挨着的目标字符
+func findInLine(t *vimText, pos int, motion string, arg rune, count int, repeat bool) (int, bool) {
+	row := t.row(pos)
+	start, end := t.starts[row], t.lineEnd(row)
+	forward := motion == "f" || motion == "t"
+	i := pos
+	for n := 0; n < count; n++ {
+		skip := 1
+		if repeat && n == 0 && (motion == "t" || motion == "T") {
+			skip = 2
+		}
+		found := -1
+		if forward {
+			for j := i + skip; j < end; j++ {
+				if t.r[j] == arg {
+					found = j
+					break
+				}
+			}
+		} else {
+			for j := i - skip; j >= start; j-- {
+				if t.r[j] == arg {
+					found = j
+					break
+				}
+			}
+		}
+		if found < 0 {
+			return pos, false
+		}
+		i = found
+	}
+	switch motion {
+	case "t":
+		i--
+	case "T":
+		i++
+	}
+	return i, true
+}
+
+var vimBrackets = map[rune]rune{'(': ')', '[': ']', '{': '}', ')': '(', ']': '[', '}': '{'}
+
+// matchBracket 从光标处向后找到本行的第一个括号，返回与它匹配的括号的位置
+func matchBracket(t *vimText, pos int) (int, bool) {
+	end := t.lineEnd(t.row(pos))
+	for ; pos < end; pos++ {
+		if _, ok := vimBrackets[t.r[pos]]; ok {
+			break
+		}
+	}
+	if pos >= end {
+		return pos, false
+	}
+	open := t.r[pos]
+	step := 1
+	if strings.ContainsRune(")]}", open) {
+		step = -1
+	}
+	depth := 0
+	for i := pos; i >= 0 && i < len(t.r); i += step {
+		switch t.r[i] {
+		case open:
+			depth++
+		case vimBrackets[open]:
+			depth--
+			if depth == 0 {
+				return i, true
+			}
+		}
+	}
+	return pos, false
+}
+
+// textObject 返回文本对象 iw、a(、ip 等的范围 [start, end)
+func textObject(t *vimText, pos int, obj string) (start, end int, linewise, ok bool) {
+	inner := obj[0] == 'i'
+	kind, _ := utf8.DecodeRuneInString(obj[1:])
+	if len(t.r) == 0 {
+		return 0, 0, false, false
+	}
+	pos = min(pos, len(t.r)-1)
+	row := t.row(pos)
+	lineStart, lineEnd := t.starts[row], t.lineEnd(row)
+
+	switch kind {
+	case 'w', 'W':
+		if pos == lineEnd {
+			return 0, 0, false, false
+		}
+		big := kind == 'W'
+		c := vimClass(t.r[pos], big)
+		start, end = pos, pos+1
+		for start > lineStart && vimClass(t.r[start-1], big) == c {
+			start--
+		}
+		for end < lineEnd && vimClass(t.r[end], big) == c {
+			end++
+		}
+		if !inner {
+			if c == 0 {
+				// 在空白上时 aw 包括空白和其后的单词
+				if end < lineEnd {
+					c = vimClass(t.r[end], big)
+					for end < lineEnd && vimClass(t.r[end], big) == c {
+						end++
+					}
+				}
+			} else if e := skipBlank(t.r, end, lineEnd); e > end {
+				end = e
+			} else {
+				for start > lineStart && (t.r[start-1] == ' ' || t.r[start-1] == '\t') {
+					start--
+				}
+			}
+		}
+		return start, end, false, true
+	case '"', '\'', '`':
+		var quotes []int
+		for i := lineStart; i < lineEnd; i++ {
+			if t.r[i] == kind && (i == lineStart || t.r[i-1] != '\\') {
+				quotes = append(quotes, i)
+			}
+		}
+		for i := 0; i+1 < len(quotes); i += 2 {
+			open, close := quotes[i], quotes[i+1]
+			if close < pos {
+				continue
+			}
+			if inner {
+				return open + 1, close, false, true
+			}
+			return open, skipBlank(t.r, close+1, lineEnd), false, true
+		}
+		return 0, 0, false, false
+	case 'p':
+		blank := t.blank(row)
+		first, last := row, row
+		for first > 0 && t.blank(first-1) == blank {
+			first--
+		}
+		for last < len(t.starts)-1 && t.blank(last+1) == blank {
+			last++
+		}
+		if !inner {
+			if last < len(t.starts)-1 {
+				last++
+				for last < len(t.starts)-1 && t.blank(last+1) != blank {
+					last++
+				}
+			} else {
+				for first > 0 && t.blank(first-1) != blank {
+					first--
+				}
+			}
+		}
+		return t.starts[first], t.lineEnd(last), true, true
+	}
+
+	open, close := kind, rune(0)
+	switch kind {
+	case 'b', ')':
+		open = '('
+	case 'B', '}':
+		open = '{'
+	case ']':
+		open = '['
+	case '>':
+		open = '<'
+	}
+	close = map[rune]rune{'(': ')', '{': '}', '[': ']', '<': '>'}[open]
+
+	// 向前找到包含光标的左括号，再向后找到匹配的右括号
+	depth := 0
+	start = -1
+	for i := pos; i >= 0; i-- {
+		switch {
+		case t.r[i] == close && i != pos:
+			depth++
+		case t.r[i] == open:
+			if depth == 0 {
+				start = i
+			}
+			depth--
+		}
+		if start >= 0 {
+			break
+		}
+	}
+	if start < 0 {
+		return 0, 0, false, false
+	}
+	depth = 0
+	end = -1
+	for i := start + 1; i < len(t.r); i++ {
+		if t.r[i] == open {
+			depth++
+		} else if t.r[i] == close {
+			if depth == 0 {
+				end = i
+				break
+			}
+			depth--
+		}
+	}
+	if end < 0 {
+		return 0, 0, false, false
+	}
+	if !inner {
+		return start, end + 1, false, true
+	}
+	start++
+	if start < end && t.r[start] == '\n' {
+		// 多行的括号内容不包括左括号后的换行和右括号前的缩进
+		start++
+		if closeRow := t.row(end); t.firstNonBlank(closeRow) == end && t.starts[closeRow] > start {
+			end = t.starts[closeRow]
+		}
+	}
+	return start, end, false, true
+}
+
+func skipBlank(text []rune, pos, end int) int {
+	for pos < end && (text[pos] == ' ' || text[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+func indexRune(text []rune, r rune) int {
+	for i, c := range text {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// toggleCase 切换每个字母的大小写
+func toggleCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
@@ -0,0 +1,256 @@
+package markdown
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+)
+
+func TestParseVim(t *testing.T) {
+	tests := []struct {
+		keys   string
+		visual bool
+		want   vimCommand
+		state  vimParseState
+	}{
+		{"w", false, vimCommand{motion: "w"}, vimParseDone},
+		{"3w", false, vimCommand{count: 3, motion: "w"}, vimParseDone},
+		{"0", false, vimCommand{motion: "0"}, vimParseDone},
+		{"10j", false, vimCommand{count: 10, motion: "j"}, vimParseDone},
+		{"dw", false, vimCommand{op: 'd', motion: "w"}, vimParseDone},
+		{"2d3w", false, vimCommand{op: 'd', count: 6, motion: "w"}, vimParseDone},
+		{"d3w", false, vimCommand{op: 'd', count: 3, motion: "w"}, vimParseDone},
+		{"dd", false, vimCommand{op: 'd', motion: "_"}, vimParseDone},
+		{"\"ayy", false, vimCommand{register: 'a', op: 'y', motion: "_"}, vimParseDone},
+		{"\"_dd", false, vimCommand{register: '_', op: 'd', motion: "_"}, vimParseDone},
+		{"ciw", false, vimCommand{op: 'c', motion: "iw"}, vimParseDone},
+		{"da(", false, vimCommand{op: 'd', motion: "a("}, vimParseDone},
+		{"fx", false, vimCommand{motion: "f", arg: 'x'}, vimParseDone},
+		{"dt)", false, vimCommand{op: 'd', motion: "t", arg: ')'}, vimParseDone},
+		{"rx", false, vimCommand{motion: "r", arg: 'x'}, vimParseDone},
+		{"gg", false, vimCommand{motion: "gg"}, vimParseDone},
+		{"iw", true, vimCommand{motion: "iw"}, vimParseDone},
+		{"d", true, vimCommand{motion: "d"}, vimParseDone},
+
+		{"2", false, vimCommand{}, vimParsePending},
+		{"d", false, vimCommand{op: 'd'}, vimParsePending},
+		{"d2", false, vimCommand{op: 'd', count: 2}, vimParsePending},
+		{"f", false, vimCommand{}, vimParsePending},
+		{"ci", false, vimCommand{op: 'c'}, vimParsePending},
+		{"\"", false, vimCommand{}, vimParsePending},
+		{"g", false, vimCommand{}, vimParsePending},
+
+		{"gx", false, vimCommand{}, vimParseInvalid},
+		{"ciq", false, vimCommand{op: 'c'}, vimParseInvalid},
+		{"\"?", false, vimCommand{}, vimParseInvalid},
+		{"\"中", false, vimCommand{}, vimParseInvalid},
+		{"iw", false, vimCommand{motion: "i"}, vimParseDone},
+	}
+	for _, tt := range tests {
+		cmd, state := parseVim([]rune(tt.keys), tt.visual)
+		if state != tt.state || (state != vimParseInvalid && cmd != tt.want) {
+			t.Errorf("parseVim(%q, %v) = %+v, %d; want %+v, %d", tt.keys, tt.visual, cmd, state, tt.want, tt.state)
+		}
+	}
+}
+
+func TestWordMotions(t *testing.T) {
+	text := []rune("foo.bar baz\n\n  qux")
+	cjk := []rune("你好，世界 ok")
+	tests := []struct {
+		name string
+		move func(text []rune, pos int, big bool) int
+		text []rune
+		pos  int
+		big  bool
+		want int
+	}{
+		{"w word", wordForward, text, 0, false, 3},
+		{"w punct", wordForward, text, 3, false, 4},
+		{"w space", wordForward, text, 4, false, 8},
+		{"w to empty line", wordForward, text, 8, false, 12},
+		{"w from empty line", wordForward, text, 12, false, 15},
+		{"w at end", wordForward, text, 15, false, len(text)},
+		{"W", wordForward, text, 0, true, 8},
+		{"w cjk", wordForward, cjk, 0, false, 2},
+		{"w cjk punct", wordForward, cjk, 2, false, 3},
+		{"w cjk space", wordForward, cjk, 3, false, 6},
+		{"W cjk", wordForward, cjk, 0, true, 6},
+		{"b to empty line", wordBackward, text, 15, false, 12},
+		{"b from empty line", wordBackward, text, 12, false, 8},
+		{"b word", wordBackward, text, 8, false, 4},
+		{"b punct", wordBackward, text, 4, false, 3},
+		{"b mid word", wordBackward, text, 6, false, 4},
+		{"b at start", wordBackward, text, 0, false, 0},
+		{"B", wordBackward, text, 8, true, 0},
+		{"b cjk", wordBackward, cjk, 6, false, 3},
+		{"b cjk punct", wordBackward, cjk, 3, false, 2},
+		{"e", wordEnd, text, 0, false, 2},
+		{"e punct", wordEnd, text, 2, false, 3},
+		{"E", wordEnd, text, 0, true, 6},
+		{"e cjk", wordEnd, cjk, 0, false, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.move(tt.text, tt.pos, tt.big); got != tt.want {
+			t.Errorf("%s: from %d = %d, want %d", tt.name, tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestMatchBracket(t *testing.T) {
+	tests := []struct {
+		text string
+		pos  int
+		want int
+		ok   bool
+	}{
+		{"f(a[b]c) {x}", 0, 7, true},
+		{"f(a[b]c) {x}", 3, 5, true},
+		{"f(a[b]c) {x}", 5, 3, true},
+		{"f(a[b]c) {x}", 7, 1, true},
+		{"f(a[b]c) {x}", 8, 11, true},
+		{"{\n  x\n}", 0, 6, true},
+		{"(a", 0, 0, false},
+		{"abc", 0, 0, false},
+		// 只在光标所在行中寻找括号
+		{"a\n(b)", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := matchBracket(newVimText(tt.text), tt.pos)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("matchBracket(%q, %d) = %d, %v; want %d, %v", tt.text, tt.pos, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTextObject(t *testing.T) {
+	const parens = "say (hello, (big) world) now"
+	tests := []struct {
+		text     string
+		pos      int
+		obj      string
+		want     string
+		linewise bool
+		ok       bool
+	}{
+		{parens, 6, "iw", "hello", false, true},
+		{parens, 6, "aw", "hello", false, true},
+		{parens, 0, "aw", "say ", false, true},
+		{parens, 3, "iw", " ", false, true},
+		{parens, 3, "aw", " (", false, true},
+		{"a b  c", 4, "aw", "  c", false, true},
+		{"one two", 4, "aw", " two", false, true},
+		{parens, 6, "i(", "hello, (big) world", false, true},
+		{parens, 6, "a(", "(hello, (big) world)", false, true},
+		{parens, 14, "ib", "big", false, true},
+		{parens, 12, "i)", "big", false, true},
+		{parens, 16, "i(", "big", false, true},
+		{parens, 18, "i(", "hello, (big) world", false, true},
+		{parens, 0, "i(", "", false, false},
+		{"f {\n  x\n}", 6, "i{", "  x\n", false, true},
+		{"f {\n  x\n}", 6, "aB", "{\n  x\n}", false, true},
+		{"a [x] b", 3, "i]", "x", false, true},
+		{"<b>", 1, "i>", "b", false, true},
+		{`a "b c" d`, 0, `i"`, "b c", false, true},
+		{`a "b c" d`, 4, `a"`, `"b c" `, false, true},
+		{`a "b \" c" d`, 4, `i"`, `b \" c`, false, true},
+		{"x 'y'", 0, "i'", "y", false, true},
+		{`a "b`, 3, `i"`, "", false, false},
+		{"a\nb\n\nc", 0, "ip", "a\nb", true, true},
+		{"a\nb\n\nc", 0, "ap", "a\nb\n", true, true},
+		{"a\n\nc", 3, "ap", "\nc", true, true},
+		{"中文 abc", 0, "iw", "中文", false, true},
+		{"", 0, "iw", "", false, false},
+	}
+	for _, tt := range tests {
+		text := newVimText(tt.text)
+		start, end, linewise, ok := textObject(text, tt.pos, tt.obj)
+		if ok != tt.ok {
+			t.Errorf("textObject(%q, %d, %s) ok = %v, want %v", tt.text, tt.pos, tt.obj, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got := string(text.r[start:end]); got != tt.want || linewise != tt.linewise {
+			t.Errorf("textObject(%q, %d, %s) = %q, %v; want %q, %v", tt.text, tt.pos, tt.obj, got, linewise, tt.want, tt.linewise)
+		}
+	}
+}
+
+func TestVimCommands(t *testing.T) {
+	test.NewApp()
+	tests := []struct {
+		name     string
+		text     string
+		row, col int
+		keys     string
+		want     string
+		wantRow  int
+		wantCol  int
+	}{
+		{"dw", "one two three", 0, 0, "dw", "two three", 0, 0},
+		{"count before operator", "one two three", 0, 0, "2dw", "three", 0, 0},
+		{"count after operator", "one two three", 0, 0, "d2w", "three", 0, 0},
+		{"de", "one two", 0, 0, "de", " two", 0, 0},
+		{"d$", "one two", 0, 4, "d$", "one ", 0, 3},
+		{"dd", "a\nb\nc", 1, 0, "dd", "a\nc", 1, 0},
+		{"dd last line", "a\nb", 1, 0, "dd", "a", 0, 0},
+		{"3dd", "a\nb\nc\nd", 0, 0, "3dd", "d", 0, 0},
+		{"dj", "a\nb\nc", 0, 0, "dj", "c", 0, 0},
+		{"cw", "one two", 0, 0, "cwONE\x1b", "ONE two", 0, 2},
+		{"ciw", "say hello now", 0, 5, "ciwbye\x1b", "say bye now", 0, 6},
+		{"ci(", "f(a, b)", 0, 3, "ci(x\x1b", "f(x)", 0, 2},
+		{"da\"", `say "hi" now`, 0, 5, `da"`, "say now", 0, 4},
+		{"dt", "a, b)", 0, 0, "dt)", ")", 0, 0},
+		{"df", "a, b)", 0, 0, "df,", " b)", 0, 0},
+		{"3x", "abcdef", 0, 0, "3x", "def", 0, 0},
+		{"x repeat", "abcdef", 0, 0, "x..", "def", 0, 0},
+		{"dw repeat", "one two three", 0, 0, "dw.", "three", 0, 0},
+		{"change repeat", "aa bb cc", 0, 0, "ciwX\x1bw.", "X X cc", 0, 2},
+		{"insert repeat with count", "", 0, 0, "iab\x1b3.", "aabababb", 0, 6},
+		{"count insert", "", 0, 0, "3ix\x1b", "xxx", 0, 2},
+		{"r", "abc", 0, 1, "rx", "axc", 0, 1},
+		{"yyp", "a\nb", 0, 0, "yyp", "a\na\nb", 1, 0},
+		{"yw P", "foo bar", 0, 0, "ywP", "foo foo bar", 0, 3},
+		{"named register", "foo bar", 0, 0, "\"ayiwwdw\"aP", "foofoo ", 0, 5},
+		{"black hole register", "a\nb", 0, 0, "yyj\"_ddp", "a\na", 1, 0},
+		{"delete fills unnamed", "a\nb", 0, 0, "ddp", "b\na", 1, 0},
+		{"J", "a\n  b", 0, 0, "J", "a b", 0, 1},
+		{"o", "a", 0, 0, "ob\x1b", "a\nb", 1, 0},
+		{"u", "one two", 0, 0, "dwu", "one two", -1, -1},
+		{"u count", "abc", 0, 0, "xx2u", "abc", -1, -1},
+		{"visual d", "abcdef", 0, 0, "vlld", "def", 0, 0},
+		{"visual line d", "a\nb\nc", 0, 0, "Vjd", "c", 0, 0},
+		{"visual iw", "say hello now", 0, 5, "viwd", "say  now", 0, 4},
+		{"visual ~", "abc", 0, 0, "vl~", "ABc", 0, 0},
+		{"cjk w", "你好，世界 ok", 0, 0, "wwx", "你好，界 ok", 0, 3},
+		{"cjk dw", "你好，世界", 0, 0, "dw", "，世界", 0, 0},
+		{"cjk ciw", "说 你好 吧", 0, 2, "ciw嗨\x1b", "说 嗨 吧", 0, 2},
+		{"%", "f(a[b]c)", 0, 0, "%x", "f(a[b]c", 0, 6},
+		{"d%", "x (a b) y", 0, 2, "d%", "x  y", 0, 2},
+		{"gg G", "a\nb\nc", 1, 0, "Gx", "a\nb\n", 2, 0},
+		{"p linewise count", "a", 0, 0, "yy2p", "a\na\na", 1, 0},
+		{"f ;", "a-b-c-d", 0, 0, "f-;x", "a-bc-d", 0, 3},
+	}
+	for _, tt := range tests {
+		e := newNoteEntry()
+		e.SetText(tt.text)
+		e.vim = newVimState(e)
+		e.CursorRow, e.CursorColumn = tt.row, tt.col
+		for _, r := range tt.keys {
+			if r == '\x1b' {
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyEscape})
+				continue
+			}
+			e.TypedRune(r)
+		}
+		// 撤销后光标的位置由输入框决定，不检查
+		cursorOK := tt.wantRow < 0 || (e.CursorRow == tt.wantRow && e.CursorColumn == tt.wantCol)
+		if e.Text != tt.want || !cursorOK {
+			t.Errorf("%s: %q on %q = %q at %d:%d; want %q at %d:%d", tt.name, tt.keys, tt.text,
+				e.Text, e.CursorRow, e.CursorColumn, tt.want, tt.wantRow, tt.wantCol)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// kindWikiLink 是 [[wiki]] 链接节点的类型
+var kindWikiLink = ast.NewNodeKind("WikiLink")
+
+// wikiLinkNode 表示 [[目标#标题|显示文本]] 或 ![[嵌入]]，导出时由调用方解析并填写 Href
+type wikiLinkNode struct {
+	ast.BaseInline
+
+	Target string
+	Anchor string
+	Label  string
+	Embed  bool
+
+	Href string // 解析后的链接地址，为空表示按纯文本输出
+}
+
+func (n *wikiLinkNode) Kind() ast.NodeKind {
+	return kindWikiLink
+}
+
+func (n *wikiLinkNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Target": n.Target,
+		"Anchor": n.Anchor,
+		"Label":  n.Label,
+	}, nil)
+}
+
+type wikiLinkParser struct{}
+
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'[', '!'}
+}
+
+func (p *wikiLinkParser) Parse(_ ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	embed := false
+	if bytes.HasPrefix(line, []byte("!")) {
+		embed = true
+		line = line[1:]
+	}
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line[2:], []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := string(line[2 : 2+end])
+	if inner == "" || strings.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+
+	label := ""
+	if bar := strings.Index(inner, "|"); bar >= 0 {
+		inner, label = inner[:bar], inner[bar+1:]
+	}
+	target, anchor := splitAnchor(inner)
+	if label == "" {
+		label = inner
+	}
+
+	consumed := 2 + end + 2
+	if embed {
+		consumed++
+	}
+	block.Advance(consumed)
+	return &wikiLinkNode{
+		Target: strings.TrimSpace(target),
+		Anchor: strings.TrimSpace(anchor),
+		Label:  strings.TrimSpace(label),
+		Embed:  embed,
+	}
+}
+
+// wikiLinks 是解析 wiki 链接的 goldmark 扩展，优先级高于普通链接
+type wikiLinks struct{}
+
+func (wikiLinks) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(&wikiLinkParser{}, 199),
+	))
+}